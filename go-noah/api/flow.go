@@ -100,7 +100,7 @@ type FlowNodeItemInput struct {
 
 // StartFlowRequest 发起流程请求
 type StartFlowRequest struct {
-	BusinessType string `json:"businessType" binding:"required"` // 业务类型：order_ddl, order_dml, order_export
+	BusinessType string `json:"businessType" binding:"required"` // 业务类型：order_ddl, order_dml, order_export, order_archive, order_migrate
 	BusinessID   string `json:"businessId" binding:"required"`   // 业务ID
 	Title        string `json:"title" binding:"required"`        // 流程标题
 	InitiatorID  uint   `json:"initiatorId"`                     // 发起人ID
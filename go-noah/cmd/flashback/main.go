@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"go-noah/internal/model/insight"
+	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/internal/repository"
+	"go-noah/pkg/config"
+	"go-noah/pkg/global"
+	"go-noah/pkg/log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+)
+
+func main() {
+	var envConf = flag.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
+	var instanceID = flag.String("instance", "", "instance_id of db config, eg: -instance 0c6c6d7e-...（与 -host 二选一）")
+	var host = flag.String("host", "", "mysql host")
+	var port = flag.Int("port", 3306, "mysql port")
+	var user = flag.String("user", "", "mysql user")
+	var password = flag.String("password", "", "mysql password")
+	var startFile = flag.String("start-file", "", "start binlog file, eg: mysql-bin.000001")
+	var startPos = flag.Int64("start-pos", 4, "start binlog position")
+	var stopFile = flag.String("stop-file", "", "stop binlog file (default: current master status)")
+	var stopPos = flag.Int64("stop-pos", 0, "stop binlog position")
+	var startTime = flag.String("start-time", "", "start time, eg: \"2006-01-02 15:04:05\"")
+	var stopTime = flag.String("stop-time", "", "stop time, eg: \"2006-01-02 15:04:05\"")
+	var schemas = flag.String("schemas", "", "schema filter, comma separated")
+	var tables = flag.String("tables", "", "table filter (table or schema.table), comma separated")
+	var events = flag.String("events", "", "event type filter (INSERT,UPDATE,DELETE), comma separated")
+	var output = flag.String("output", "", "output file (default: flashback_<time>.sql)")
+	flag.Parse()
+
+	conf := config.NewConfig(*envConf)
+	logger := log.NewLog(conf)
+	global.Logger = logger
+	global.Conf = conf // 实例密码在连接时使用 secret 配置的主密钥解密
+
+	binlogConfig := &mysqlpkg.BinlogConfig{
+		Hostname: *host,
+		Port:     *port,
+		UserName: *user,
+		Password: *password,
+	}
+	if *instanceID != "" {
+		db := repository.NewDB(conf, logger)
+		var dbConfig insight.DBConfig
+		if err := db.Where("instance_id = ?", *instanceID).First(&dbConfig).Error; err != nil {
+			panic(fmt.Errorf("instance %s not found: %w", *instanceID, err))
+		}
+		binlogConfig.Hostname = dbConfig.Hostname
+		binlogConfig.Port = dbConfig.Port
+		binlogConfig.UserName = dbConfig.UserName
+		binlogConfig.Password = dbConfig.Password
+		binlogConfig.ConnOptions = dbConfig.ConnOptions()
+	}
+	if binlogConfig.Hostname == "" {
+		panic("either -instance or -host is required")
+	}
+
+	options := &mysqlpkg.FlashbackOptions{
+		StartFile:     *startFile,
+		StartPosition: *startPos,
+		StopFile:      *stopFile,
+		StopPosition:  *stopPos,
+		StartTime:     parseTime(*startTime),
+		StopTime:      parseTime(*stopTime),
+		Schemas:       splitList(*schemas),
+		Tables:        splitList(*tables),
+		EventTypes:    splitList(*events),
+	}
+
+	if *output == "" {
+		*output = fmt.Sprintf("flashback_%s.sql", time.Now().Format("20060102150405"))
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		panic(err)
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	result, err := mysqlpkg.NewFlashback(binlogConfig, options).Run(ctx, w)
+	if err != nil {
+		panic(err)
+	}
+	if err := w.Flush(); err != nil {
+		panic(err)
+	}
+	fmt.Printf("flashback %s:%d -> %s:%d, insert=%d update=%d delete=%d, written to %s (%d bytes)\n",
+		result.StartFile, result.StartPosition, result.StopFile, result.StopPosition,
+		result.InsertEvents, result.UpdateEvents, result.DeleteEvents, *output, result.Bytes)
+}
+
+func parseTime(value string) time.Time {
+	if value == "" {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		panic(fmt.Errorf("invalid time %q: %w", value, err))
+	}
+	return t
+}
+
+func splitList(value string) []string {
+	var list []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			list = append(list, v)
+		}
+	}
+	return list
+}
@@ -1,21 +1,52 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"go-noah/internal/model"
+	"go-noah/internal/model/insight"
 	"go-noah/internal/repository"
 	"go-noah/pkg/config"
 	"go-noah/pkg/log"
+	"go-noah/pkg/secret"
+	"os"
 
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
+// 子命令：
+//
+//	reset-password 重置管理员密码（默认）
+//	gen-key        生成实例密码加密主密钥
+//	rotate-key     轮换主密钥并重新加密所有实例密码和导出文件密钥
 func main() {
-	var envConf = flag.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
-	var user = flag.String("user", "admin", "username to reset")
-	var password = flag.String("password", "123456", "new password")
-	flag.Parse()
+	command := "reset-password"
+	args := os.Args[1:]
+	if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+		command, args = args[0], args[1:]
+	}
+
+	switch command {
+	case "reset-password":
+		resetPassword(args)
+	case "gen-key":
+		genKey()
+	case "rotate-key":
+		rotateKey(args)
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q, available: reset-password, gen-key, rotate-key\n", command)
+		os.Exit(2)
+	}
+}
+
+func resetPassword(args []string) {
+	fs := flag.NewFlagSet("reset-password", flag.ExitOnError)
+	var envConf = fs.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
+	var user = fs.String("user", "admin", "username to reset")
+	var password = fs.String("password", "123456", "new password")
+	_ = fs.Parse(args)
 
 	conf := config.NewConfig(*envConf)
 	logger := log.NewLog(conf)
@@ -33,3 +64,137 @@ func main() {
 	}
 	fmt.Printf("reset password for user '%s' affected=%d\n", *user, res.RowsAffected)
 }
+
+func genKey() {
+	key, err := secret.GenerateKey()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(key)
+}
+
+// rotateKey 使用新主密钥重新加密 db_configs 中的密码、SSH 凭据和 TLS 私钥，
+// 以及 order_records 中的导出文件密钥（包括加密前写入的明文）
+// 未指定新主密钥时使用当前主密钥重新加密，可用于加密历史明文数据
+//
+// 滚动发布时的步骤：
+//  1. 将新主密钥加入各服务的 secret.retired_keys 并重启（此时可以解密新旧两种密文）
+//  2. 执行 rotate-key
+//  3. 将 secret.master_key 改为新主密钥，旧主密钥从 retired_keys 中移除后重启
+func rotateKey(args []string) {
+	fs := flag.NewFlagSet("rotate-key", flag.ExitOnError)
+	var envConf = fs.String("conf", "config/local.yml", "config path, eg: -conf ./config/local.yml")
+	var newKey = fs.String("new-key", "", "new master key (base64), empty to re-encrypt with the current key")
+	var newKeyFile = fs.String("new-key-file", "", "env file containing NOAH_MASTER_KEY=<new master key>")
+	var dryRun = fs.Bool("dry-run", false, "only count rows to re-encrypt")
+	_ = fs.Parse(args)
+
+	conf := config.NewConfig(*envConf)
+	logger := log.NewLog(conf)
+
+	// 当前主密钥，未配置时只能处理明文
+	current, err := secret.LoadKeyring(conf)
+	if err != nil && !errors.Is(err, secret.ErrNoMasterKey) {
+		panic(err)
+	}
+
+	target := current
+	if *newKeyFile != "" {
+		if target, err = secret.LoadKeyFile(*newKeyFile); err != nil {
+			panic(err)
+		}
+	} else if *newKey != "" {
+		if target, err = secret.NewKeyring(*newKey); err != nil {
+			panic(err)
+		}
+	}
+	if target == nil {
+		panic(secret.ErrNoMasterKey)
+	}
+
+	db := repository.NewDB(conf, logger)
+
+	// reencrypt 解密（明文原样返回）后使用新主密钥重新加密
+	reencrypt := func(table string, id uint, column, value string) (string, error) {
+		plaintext := value
+		if secret.IsEncrypted(plaintext) {
+			if current == nil {
+				return "", fmt.Errorf("%s id=%d: %w", table, id, secret.ErrNoMasterKey)
+			}
+			decrypted, err := current.Decrypt(plaintext)
+			if err != nil {
+				return "", fmt.Errorf("%s id=%d %s: %w", table, id, column, err)
+			}
+			plaintext = decrypted
+		}
+		return target.Encrypt(plaintext)
+	}
+
+	var rows, fields int
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var configs []insight.DBConfig
+		if err := tx.Unscoped().Select(append([]string{"id"}, insight.DBConfigSecretColumns...)).
+			Find(&configs).Error; err != nil {
+			return err
+		}
+		for i := range configs {
+			cfg := &configs[i]
+			updates := make(map[string]interface{})
+			for j, field := range cfg.SecretFields() {
+				if *field == "" {
+					continue
+				}
+				encrypted, err := reencrypt("db_configs", cfg.ID, insight.DBConfigSecretColumns[j], *field)
+				if err != nil {
+					return err
+				}
+				updates[insight.DBConfigSecretColumns[j]] = encrypted
+			}
+			if len(updates) == 0 {
+				continue
+			}
+			rows++
+			fields += len(updates)
+			if *dryRun {
+				continue
+			}
+			if err := tx.Model(&insight.DBConfig{}).Unscoped().Where("id = ?", cfg.ID).UpdateColumns(updates).Error; err != nil {
+				return err
+			}
+		}
+
+		var orders []insight.OrderRecord
+		if err := tx.Unscoped().Select("id", "export_encryption_key").
+			Where("export_encryption_key <> ''").Find(&orders).Error; err != nil {
+			return err
+		}
+		for _, order := range orders {
+			encrypted, err := reencrypt("order_records", order.ID, "export_encryption_key", order.ExportEncryptionKey)
+			if err != nil {
+				return err
+			}
+			rows++
+			fields++
+			if *dryRun {
+				continue
+			}
+			if err := tx.Model(&insight.OrderRecord{}).Unscoped().Where("id = ?", order.ID).
+				UpdateColumn("export_encryption_key", encrypted).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	if *dryRun {
+		fmt.Printf("dry run: %d rows (%d fields) to re-encrypt\n", rows, fields)
+		return
+	}
+	fmt.Printf("re-encrypted %d rows (%d fields)\n", rows, fields)
+	if *newKey != "" || *newKeyFile != "" {
+		fmt.Println("update secret.master_key (or the key file) to the new key and restart the services; keep the old key in retired_keys until all services are restarted")
+	}
+}
@@ -4,6 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"go-noah/pkg/dbconn"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/secret"
 	"time"
 
 	"github.com/go-sql-driver/mysql"
@@ -12,35 +15,42 @@ import (
 
 // MySQLDB MySQL数据库连接
 type MySQLDB struct {
-	User     string
-	Password string
-	Host     string
-	Port     int
-	Database string
-	Params   map[string]string
-	Ctx      context.Context
+	InstanceID  string // 实例ID，用于复用实例连接池
+	User        string
+	Password    string
+	Host        string
+	Port        int
+	Database    string
+	Params      map[string]string
+	ConnOptions dbconn.Options // SSH 跳板机、TLS
+	Ctx         context.Context
 }
 
-// Open 打开数据库连接
-func (d *MySQLDB) Open() (*sql.DB, error) {
+// Open 从实例连接池获取连接，使用完成后需要 Close 归还
+func (d *MySQLDB) Open() (*dbpool.Conn, error) {
+	password, err := secret.Decrypt(d.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密实例密码失败: %w", err)
+	}
 	config := mysql.Config{
 		User:                 d.User,
-		Passwd:               d.Password,
+		Passwd:               password,
 		Addr:                 fmt.Sprintf("%s:%d", d.Host, d.Port),
 		Net:                  "tcp",
-		DBName:               d.Database,
 		AllowNativePasswords: true,
-		Params:               d.Params,
 		Timeout:              3 * time.Second,
 	}
-
-	DSN := config.FormatDSN()
-	db, err := sql.Open("mysql", DSN)
-	if err != nil {
+	if err := d.ConnOptions.ApplyMySQL(&config); err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(1)
-	return db, nil
+
+	return dbpool.Get(d.Ctx, dbpool.Spec{
+		InstanceID: d.InstanceID,
+		Role:       dbpool.RoleDAS,
+		DSN:        config.FormatDSN(),
+		Database:   d.Database,
+		Params:     d.Params,
+	})
 }
 
 // Query 执行查询并返回结果
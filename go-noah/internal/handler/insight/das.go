@@ -221,13 +221,15 @@ func (h *DASHandler) ExecuteQuery(c *gin.Context) {
 	defer cancel()
 
 	db := &dao.MySQLDB{
-		User:     config.UserName,
-		Password: config.Password,
-		Host:     config.Hostname,
-		Port:     config.Port,
-		Database: req.Schema,
-		Params:   req.Params,
-		Ctx:      ctx,
+		InstanceID:  config.InstanceID.String(),
+		User:        config.UserName,
+		Password:    config.Password,
+		Host:        config.Hostname,
+		Port:        config.Port,
+		Database:    req.Schema,
+		Params:      req.Params,
+		ConnOptions: config.ConnOptions(),
+		Ctx:         ctx,
 	}
 
 	// 执行查询（使用重写后的 SQL）
@@ -335,11 +337,13 @@ func (h *DASHandler) GetSchemas(c *gin.Context) {
 	defer cancel()
 
 	db := &dao.MySQLDB{
-		User:     config.UserName,
-		Password: config.Password,
-		Host:     config.Hostname,
-		Port:     config.Port,
-		Ctx:      ctx,
+		InstanceID:  config.InstanceID.String(),
+		User:        config.UserName,
+		Password:    config.Password,
+		Host:        config.Hostname,
+		Port:        config.Port,
+		ConnOptions: config.ConnOptions(),
+		Ctx:         ctx,
 	}
 
 	schemas, err := db.GetSchemas()
@@ -388,12 +392,14 @@ func (h *DASHandler) GetTables(c *gin.Context) {
 	defer cancel()
 
 	db := &dao.MySQLDB{
-		User:     config.UserName,
-		Password: config.Password,
-		Host:     config.Hostname,
-		Port:     config.Port,
-		Ctx:      ctx,
-		Params:   map[string]string{"group_concat_max_len": "4194304"},
+		InstanceID:  config.InstanceID.String(),
+		User:        config.UserName,
+		Password:    config.Password,
+		Host:        config.Hostname,
+		Port:        config.Port,
+		Ctx:         ctx,
+		Params:      map[string]string{"group_concat_max_len": "4194304"},
+		ConnOptions: config.ConnOptions(),
 	}
 
 	// 获取用户名
@@ -490,11 +496,13 @@ func (h *DASHandler) GetTableColumns(c *gin.Context) {
 	defer cancel()
 
 	db := &dao.MySQLDB{
-		User:     config.UserName,
-		Password: config.Password,
-		Host:     config.Hostname,
-		Port:     config.Port,
-		Ctx:      ctx,
+		InstanceID:  config.InstanceID.String(),
+		User:        config.UserName,
+		Password:    config.Password,
+		Host:        config.Hostname,
+		Port:        config.Port,
+		ConnOptions: config.ConnOptions(),
+		Ctx:         ctx,
 	}
 
 	columns, err := db.GetTableColumns(schema, table)
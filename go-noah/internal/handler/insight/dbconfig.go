@@ -2,8 +2,11 @@ package insight
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-noah/api"
 	"go-noah/internal/model/insight"
+	"go-noah/internal/orders/executor"
 	"go-noah/internal/service"
 	"net/http"
 	"strconv"
@@ -72,7 +75,7 @@ func (h *DBConfigHandler) GetDBConfigs(c *gin.Context) {
 
 	// 隐藏密码
 	for i := range configs {
-		configs[i].Password = "******"
+		hideDBConfigSecrets(&configs[i])
 	}
 
 	api.HandleSuccess(c, configs)
@@ -101,7 +104,7 @@ func (h *DBConfigHandler) GetDBConfig(c *gin.Context) {
 	}
 
 	// 隐藏密码
-	config.Password = "******"
+	hideDBConfigSecrets(config)
 
 	api.HandleSuccess(c, config)
 }
@@ -118,6 +121,31 @@ type CreateDBConfigRequest struct {
 	OrganizationKey string                 `json:"organization_key"`
 	Remark          string                 `json:"remark"`
 	InspectParams   map[string]interface{} `json:"inspect_params,omitempty"` // 审核参数（JSON对象）
+	DDLEngine       string                 `json:"ddl_engine"`               // 在线DDL引擎（gh-ost/pt-osc/native/auto）
+	// 执行限流配置
+	ThrottleMaxReplicaLag     int    `json:"throttle_max_replica_lag"`     // 最大从库延迟（秒）
+	ThrottleMaxThreadsRunning int    `json:"throttle_max_threads_running"` // 最大 Threads_running
+	ThrottleQuery             string `json:"throttle_query"`               // 自定义限流查询（返回值大于0时限流）
+	// SSH 跳板机（ssh_host 为空表示直连）
+	SSHHost       string `json:"ssh_host"`
+	SSHPort       int    `json:"ssh_port"`
+	SSHUser       string `json:"ssh_user"`
+	SSHPassword   string `json:"ssh_password"`
+	SSHPrivateKey string `json:"ssh_private_key"` // 私钥（PEM），与密码至少配置一种
+	SSHPassphrase string `json:"ssh_passphrase"`  // 私钥密码
+	SSHHostKey    string `json:"ssh_host_key"`    // 跳板机公钥（authorized_keys 格式）
+
+	// 不校验跳板机公钥（未配置公钥时必须显式开启，仅用于测试环境）
+	SSHInsecureIgnoreHostKey bool `json:"ssh_insecure_ignore_host_key"`
+
+	// TLS
+	TLSEnabled    bool   `json:"tls_enabled"`
+	TLSCA         string `json:"tls_ca"`   // CA 证书（PEM），为空时使用系统根证书
+	TLSCert       string `json:"tls_cert"` // 客户端证书（PEM）
+	TLSKey        string `json:"tls_key"`  // 客户端私钥（PEM）
+	TLSSkipVerify bool   `json:"tls_skip_verify"`
+
+	SourceInstanceID string `json:"source_instance_id"` // 本实例为从库或沙箱时填写源实例ID，为空表示不是从库或沙箱
 }
 
 // CreateDBConfig 创建数据库配置
@@ -136,6 +164,11 @@ func (h *DBConfigHandler) CreateDBConfig(c *gin.Context) {
 		return
 	}
 
+	if !executor.IsValidDDLEngine(req.DDLEngine) {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("不支持的在线DDL引擎: %s", req.DDLEngine), nil)
+		return
+	}
+
 	// 显式生成 instance_id
 	instanceID, err := uuid.NewUUID()
 	if err != nil {
@@ -154,6 +187,38 @@ func (h *DBConfigHandler) CreateDBConfig(c *gin.Context) {
 		Environment:     req.Environment,
 		OrganizationKey: req.OrganizationKey,
 		Remark:          req.Remark,
+		DDLEngine:       req.DDLEngine,
+
+		ThrottleMaxReplicaLag:     req.ThrottleMaxReplicaLag,
+		ThrottleMaxThreadsRunning: req.ThrottleMaxThreadsRunning,
+		ThrottleQuery:             req.ThrottleQuery,
+
+		SSHHost:       req.SSHHost,
+		SSHPort:       req.SSHPort,
+		SSHUser:       req.SSHUser,
+		SSHPassword:   req.SSHPassword,
+		SSHPrivateKey: req.SSHPrivateKey,
+		SSHPassphrase: req.SSHPassphrase,
+		SSHHostKey:    req.SSHHostKey,
+		TLSEnabled:    req.TLSEnabled,
+		TLSCA:         req.TLSCA,
+		TLSCert:       req.TLSCert,
+		TLSKey:        req.TLSKey,
+		TLSSkipVerify: req.TLSSkipVerify,
+
+		SSHInsecureIgnoreHostKey: req.SSHInsecureIgnoreHostKey,
+		SourceInstanceID:         req.SourceInstanceID,
+	}
+	if err := validateSourceInstanceID(req.SourceInstanceID, ""); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	if config.SSHPort == 0 {
+		config.SSHPort = 22
+	}
+	if err := config.ConnOptions().Validate(); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
 	}
 	if req.InspectParams != nil {
 		if bs, err := json.Marshal(req.InspectParams); err == nil {
@@ -170,7 +235,7 @@ func (h *DBConfigHandler) CreateDBConfig(c *gin.Context) {
 	}
 
 	// 隐藏密码
-	config.Password = "******"
+	hideDBConfigSecrets(config)
 	api.HandleSuccess(c, config)
 }
 
@@ -186,6 +251,30 @@ type UpdateDBConfigRequest struct {
 	OrganizationKey *string                 `json:"organization_key,omitempty"`
 	Remark          *string                 `json:"remark,omitempty"`
 	InspectParams   *map[string]interface{} `json:"inspect_params,omitempty"` // 审核参数（JSON对象）
+	DDLEngine       *string                 `json:"ddl_engine,omitempty"`
+	// 执行限流配置
+	ThrottleMaxReplicaLag     *int    `json:"throttle_max_replica_lag,omitempty"`
+	ThrottleMaxThreadsRunning *int    `json:"throttle_max_threads_running,omitempty"`
+	ThrottleQuery             *string `json:"throttle_query,omitempty"`
+	// SSH 跳板机和 TLS（密码、私钥为空字符串时不更新）
+	SSHHost       *string `json:"ssh_host,omitempty"`
+	SSHPort       *int    `json:"ssh_port,omitempty"`
+	SSHUser       *string `json:"ssh_user,omitempty"`
+	SSHPassword   *string `json:"ssh_password,omitempty"`
+	SSHPrivateKey *string `json:"ssh_private_key,omitempty"`
+	SSHPassphrase *string `json:"ssh_passphrase,omitempty"`
+	SSHHostKey    *string `json:"ssh_host_key,omitempty"`
+
+	// 不校验跳板机公钥
+	SSHInsecureIgnoreHostKey *bool `json:"ssh_insecure_ignore_host_key,omitempty"`
+
+	TLSEnabled    *bool   `json:"tls_enabled,omitempty"`
+	TLSCA         *string `json:"tls_ca,omitempty"`
+	TLSCert       *string `json:"tls_cert,omitempty"`
+	TLSKey        *string `json:"tls_key,omitempty"`
+	TLSSkipVerify *bool   `json:"tls_skip_verify,omitempty"`
+
+	SourceInstanceID *string `json:"source_instance_id,omitempty"` // 从库或沙箱所属的源实例ID
 }
 
 // UpdateDBConfig 更新数据库配置
@@ -243,6 +332,34 @@ func (h *DBConfigHandler) UpdateDBConfig(c *gin.Context) {
 	if req.Remark != nil {
 		updates["remark"] = *req.Remark
 	}
+	if req.DDLEngine != nil {
+		if !executor.IsValidDDLEngine(*req.DDLEngine) {
+			api.HandleError(c, http.StatusBadRequest, fmt.Errorf("不支持的在线DDL引擎: %s", *req.DDLEngine), nil)
+			return
+		}
+		updates["ddl_engine"] = *req.DDLEngine
+	}
+	if req.ThrottleMaxReplicaLag != nil {
+		updates["throttle_max_replica_lag"] = *req.ThrottleMaxReplicaLag
+	}
+	if req.ThrottleMaxThreadsRunning != nil {
+		updates["throttle_max_threads_running"] = *req.ThrottleMaxThreadsRunning
+	}
+	if req.ThrottleQuery != nil {
+		updates["throttle_query"] = *req.ThrottleQuery
+	}
+	if req.SourceInstanceID != nil {
+		config, err := service.InsightServiceApp.GetDBConfigByID(c.Request.Context(), uint(id))
+		if err != nil {
+			api.HandleError(c, http.StatusNotFound, api.ErrNotFound, nil)
+			return
+		}
+		if err := validateSourceInstanceID(*req.SourceInstanceID, config.InstanceID.String()); err != nil {
+			api.HandleError(c, http.StatusBadRequest, err, nil)
+			return
+		}
+		updates["source_instance_id"] = *req.SourceInstanceID
+	}
 	if req.InspectParams != nil {
 		bs, err := json.Marshal(*req.InspectParams)
 		if err != nil {
@@ -252,6 +369,19 @@ func (h *DBConfigHandler) UpdateDBConfig(c *gin.Context) {
 		updates["inspect_params"] = datatypes.JSON(bs)
 	}
 
+	if req.hasConnOptions() {
+		config, err := service.InsightServiceApp.GetDBConfigByID(c.Request.Context(), uint(id))
+		if err != nil {
+			api.HandleError(c, http.StatusNotFound, api.ErrNotFound, nil)
+			return
+		}
+		req.applyConnOptions(config, updates)
+		if err := config.ConnOptions().Validate(); err != nil {
+			api.HandleError(c, http.StatusBadRequest, err, nil)
+			return
+		}
+	}
+
 	if len(updates) == 0 {
 		api.HandleSuccess(c, nil)
 		return
@@ -264,6 +394,74 @@ func (h *DBConfigHandler) UpdateDBConfig(c *gin.Context) {
 	api.HandleSuccess(c, nil)
 }
 
+// hasConnOptions 是否修改了 SSH 跳板机或 TLS 配置
+func (req *UpdateDBConfigRequest) hasConnOptions() bool {
+	return req.SSHHost != nil || req.SSHPort != nil || req.SSHUser != nil || req.SSHPassword != nil ||
+		req.SSHPrivateKey != nil || req.SSHPassphrase != nil || req.SSHHostKey != nil || req.SSHInsecureIgnoreHostKey != nil ||
+		req.TLSEnabled != nil || req.TLSCA != nil || req.TLSCert != nil || req.TLSKey != nil || req.TLSSkipVerify != nil
+}
+
+// applyConnOptions 将 SSH 跳板机和 TLS 修改应用到当前配置（用于校验）并写入 updates
+func (req *UpdateDBConfigRequest) applyConnOptions(config *insight.DBConfig, updates map[string]interface{}) {
+	setString := func(value *string, field *string, column string, secret bool) {
+		// 密码、私钥为空字符串时不更新
+		if value == nil || (secret && *value == "") {
+			return
+		}
+		*field = *value
+		updates[column] = *value
+	}
+	setString(req.SSHHost, &config.SSHHost, "ssh_host", false)
+	setString(req.SSHUser, &config.SSHUser, "ssh_user", false)
+	setString(req.SSHPassword, &config.SSHPassword, "ssh_password", true)
+	setString(req.SSHPrivateKey, &config.SSHPrivateKey, "ssh_private_key", true)
+	setString(req.SSHPassphrase, &config.SSHPassphrase, "ssh_passphrase", true)
+	setString(req.SSHHostKey, &config.SSHHostKey, "ssh_host_key", false)
+	setString(req.TLSCA, &config.TLSCA, "tls_ca", false)
+	setString(req.TLSCert, &config.TLSCert, "tls_cert", false)
+	setString(req.TLSKey, &config.TLSKey, "tls_key", true)
+	if req.SSHPort != nil {
+		config.SSHPort = *req.SSHPort
+		updates["ssh_port"] = *req.SSHPort
+	}
+	if req.SSHInsecureIgnoreHostKey != nil {
+		config.SSHInsecureIgnoreHostKey = *req.SSHInsecureIgnoreHostKey
+		updates["ssh_insecure_ignore_host_key"] = *req.SSHInsecureIgnoreHostKey
+	}
+	if req.TLSEnabled != nil {
+		config.TLSEnabled = *req.TLSEnabled
+		updates["tls_enabled"] = *req.TLSEnabled
+	}
+	if req.TLSSkipVerify != nil {
+		config.TLSSkipVerify = *req.TLSSkipVerify
+		updates["tls_skip_verify"] = *req.TLSSkipVerify
+	}
+}
+
+// validateSourceInstanceID 校验从库或沙箱所属的源实例ID（为空表示不是从库或沙箱）
+func validateSourceInstanceID(sourceInstanceID, instanceID string) error {
+	if sourceInstanceID == "" {
+		return nil
+	}
+	if _, err := uuid.Parse(sourceInstanceID); err != nil {
+		return fmt.Errorf("源实例ID格式错误: %s", sourceInstanceID)
+	}
+	if sourceInstanceID == instanceID {
+		return errors.New("源实例不能是实例本身")
+	}
+	return nil
+}
+
+// hideDBConfigSecrets 隐藏密码、SSH 凭据和 TLS 私钥（存储的密文也不返回）
+func hideDBConfigSecrets(config *insight.DBConfig) {
+	config.Password = "******"
+	for _, field := range config.SecretFields() {
+		if *field != "" {
+			*field = "******"
+		}
+	}
+}
+
 // DeleteDBConfig 删除数据库配置
 // @Summary 删除数据库配置
 // @Tags 数据库配置
@@ -0,0 +1,182 @@
+package insight
+
+import (
+	"fmt"
+	"go-noah/api"
+	"go-noah/internal/handler"
+	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/internal/service"
+	"go-noah/pkg/global"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// FlashbackHandlerApp 全局 Handler 实例
+var FlashbackHandlerApp = new(FlashbackHandler)
+
+// FlashbackHandler binlog闪回 Handler
+type FlashbackHandler struct{}
+
+// CreateFlashbackRequest 创建闪回任务请求
+type CreateFlashbackRequest struct {
+	InstanceID    string   `json:"instance_id" binding:"required"`
+	StartFile     string   `json:"start_file"`     // 起始binlog文件（与 start_time 至少指定一个）
+	StartPosition int64    `json:"start_position"` // 起始位置
+	StopFile      string   `json:"stop_file"`      // 结束binlog文件（为空使用当前位置）
+	StopPosition  int64    `json:"stop_position"`  // 结束位置
+	StartTime     string   `json:"start_time"`     // 起始时间，格式 2006-01-02 15:04:05
+	StopTime      string   `json:"stop_time"`      // 结束时间，格式 2006-01-02 15:04:05
+	Schemas       []string `json:"schemas"`        // 库名过滤
+	Tables        []string `json:"tables"`         // 表名过滤（table 或 schema.table）
+	EventTypes    []string `json:"event_types"`    // 事件类型过滤（INSERT/UPDATE/DELETE）
+}
+
+// CreateFlashback 创建binlog闪回任务
+// @Summary 创建binlog闪回任务
+// @Tags binlog闪回
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body CreateFlashbackRequest true "闪回参数"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/flashback [post]
+func (h *FlashbackHandler) CreateFlashback(c *gin.Context) {
+	var req CreateFlashbackRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	options := &mysqlpkg.FlashbackOptions{
+		StartFile:     req.StartFile,
+		StartPosition: req.StartPosition,
+		StopFile:      req.StopFile,
+		StopPosition:  req.StopPosition,
+		Schemas:       req.Schemas,
+		Tables:        req.Tables,
+		EventTypes:    req.EventTypes,
+	}
+	var err error
+	if options.StartTime, err = parseFlashbackTime(req.StartTime); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	if options.StopTime, err = parseFlashbackTime(req.StopTime); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	if options.StartFile == "" && options.StartTime.IsZero() {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("请指定起始binlog位置或起始时间"), nil)
+		return
+	}
+	for _, eventType := range options.EventTypes {
+		switch strings.ToUpper(eventType) {
+		case mysqlpkg.FlashbackEventInsert, mysqlpkg.FlashbackEventUpdate, mysqlpkg.FlashbackEventDelete:
+		default:
+			api.HandleError(c, http.StatusBadRequest, fmt.Errorf("不支持的事件类型: %s", eventType), nil)
+			return
+		}
+	}
+
+	username := h.getUsername(c)
+	job, err := service.FlashbackServiceApp.StartFlashback(c.Request.Context(), username, req.InstanceID, options)
+	if err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	global.Logger.Info("创建binlog闪回任务",
+		zap.String("job_id", job.JobID),
+		zap.String("instance_id", req.InstanceID),
+		zap.String("username", username),
+	)
+	api.HandleSuccess(c, job)
+}
+
+// GetFlashback 获取闪回任务状态
+// @Summary 获取闪回任务状态
+// @Tags binlog闪回
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param job_id path string true "任务ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/flashback/{job_id} [get]
+func (h *FlashbackHandler) GetFlashback(c *gin.Context) {
+	job, ok := h.getOwnJob(c)
+	if !ok {
+		return
+	}
+	api.HandleSuccess(c, job)
+}
+
+// DownloadFlashback 下载闪回SQL文件
+// @Summary 下载闪回SQL文件
+// @Tags binlog闪回
+// @Security Bearer
+// @Produce octet-stream
+// @Param job_id path string true "任务ID"
+// @Success 200 {file} file
+// @Router /api/v1/insight/flashback/{job_id}/download [get]
+func (h *FlashbackHandler) DownloadFlashback(c *gin.Context) {
+	job, ok := h.getOwnJob(c)
+	if !ok {
+		return
+	}
+	if job.Status != service.FlashbackStatusSuccess {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("闪回任务未完成，当前状态: %s", job.Status), nil)
+		return
+	}
+	if _, err := os.Stat(job.FilePath); err != nil {
+		api.HandleError(c, http.StatusNotFound, fmt.Errorf("闪回文件不存在或已被清理"), nil)
+		return
+	}
+	c.Header("Content-Type", "application/sql")
+	c.FileAttachment(job.FilePath, job.FileName)
+}
+
+// getOwnJob 获取当前用户创建的闪回任务，失败时已写入响应
+func (h *FlashbackHandler) getOwnJob(c *gin.Context) (*service.FlashbackJob, bool) {
+	jobID := c.Param("job_id")
+	if jobID == "" {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return nil, false
+	}
+	job, err := service.FlashbackServiceApp.GetFlashbackJob(c.Request.Context(), jobID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return nil, false
+	}
+	if job.Username != h.getUsername(c) {
+		api.HandleError(c, http.StatusForbidden, api.ErrForbidden, nil)
+		return nil, false
+	}
+	return job, true
+}
+
+// getUsername 获取当前用户名
+func (h *FlashbackHandler) getUsername(c *gin.Context) string {
+	userId := handler.GetUserIdFromCtx(c)
+	if userId > 0 {
+		if user, err := service.AdminServiceApp.GetAdminUser(c, userId); err == nil {
+			return user.Username
+		}
+	}
+	return ""
+}
+
+// parseFlashbackTime 解析闪回时间参数（为空返回零值）
+func parseFlashbackTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("时间格式错误: %s", value)
+	}
+	return t, nil
+}
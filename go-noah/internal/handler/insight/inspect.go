@@ -2,6 +2,7 @@ package insight
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"go-noah/api"
@@ -43,38 +44,8 @@ func normalizeInspectParams(params *config.InspectParams, instanceID string) {
 	}
 }
 
-// InspectHandlerApp 全局 Handler 实例
-var InspectHandlerApp = new(InspectHandler)
-
-// InspectHandler SQL审核 Handler
-type InspectHandler struct{}
-
-// InspectSQLRequest 审核SQL请求
-type InspectSQLRequest struct {
-	Content    string `json:"content" binding:"required"` // SQL内容
-	DBType     string `json:"db_type"`                    // MySQL/TiDB
-	SQLType    string `json:"sql_type"`                   // DDL/DML
-	InstanceID string `json:"instance_id"`                // 可选，用于获取实例配置的审核参数
-	Schema     string `json:"schema"`                     // 数据库名
-}
-
-// InspectSQL 审核SQL
-// @Summary 审核SQL
-// @Tags SQL审核
-// @Security Bearer
-// @Accept json
-// @Produce json
-// @Param request body InspectSQLRequest true "审核请求"
-// @Success 200 {object} api.Response
-// @Router /api/v1/insight/inspect/sql [post]
-func (h *InspectHandler) InspectSQL(c *gin.Context) {
-	var req InspectSQLRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		api.HandleError(c, http.StatusBadRequest, err, nil)
-		return
-	}
-
-	// 获取审核参数和数据库配置
+// loadInspectParams 加载审核参数：先合并全局参数表，再用实例参数覆盖（instanceID 为空时不加载实例配置）
+func loadInspectParams(ctx context.Context, instanceID string) (*config.InspectParams, *insight.DBConfig, error) {
 	var params *config.InspectParams
 	var dbConfig *insight.DBConfig
 
@@ -101,7 +72,7 @@ func (h *InspectHandler) InspectSQL(c *gin.Context) {
 				// 转换为结构体（从默认值开始，用合并后的参数覆盖）
 				params = config.DefaultInspectParams()
 				if err := json.Unmarshal(jsonData, params); err == nil {
-					normalizeInspectParams(params, req.InstanceID)
+					normalizeInspectParams(params, instanceID)
 					global.Logger.Info("加载审核参数（全局参数表，合并所有记录）",
 						zap.Int("global_params_count", len(globalParams)),
 						zap.Bool("ENABLE_COLUMN_BLOB_TYPE", params.ENABLE_COLUMN_BLOB_TYPE),
@@ -119,12 +90,11 @@ func (h *InspectHandler) InspectSQL(c *gin.Context) {
 	}
 
 	// 2) 再用实例参数覆盖（如果提供了 instance_id）
-	if req.InstanceID != "" {
+	if instanceID != "" {
 		var err error
-		dbConfig, err = service.InsightServiceApp.GetDBConfigByInstanceID(c.Request.Context(), req.InstanceID)
+		dbConfig, err = service.InsightServiceApp.GetDBConfigByInstanceID(ctx, instanceID)
 		if err != nil {
-			api.HandleError(c, http.StatusOK, fmt.Errorf("获取数据库配置失败: %s", err.Error()), nil)
-			return
+			return nil, nil, fmt.Errorf("获取数据库配置失败: %s", err.Error())
 		}
 		// 实例级参数覆盖全局参数
 		if !isEmptyJSONValue(dbConfig.InspectParams) {
@@ -132,13 +102,13 @@ func (h *InspectHandler) InspectSQL(c *gin.Context) {
 			if err := decoder.Decode(params); err != nil {
 				global.Logger.Warn("反序列化实例审核参数失败，保持全局参数",
 					zap.Error(err),
-					zap.String("instance_id", req.InstanceID),
+					zap.String("instance_id", instanceID),
 					zap.String("raw_params", string(dbConfig.InspectParams)),
 				)
 			} else {
-				normalizeInspectParams(params, req.InstanceID)
+				normalizeInspectParams(params, instanceID)
 				global.Logger.Info("加载审核参数（实例配置覆盖）",
-					zap.String("instance_id", req.InstanceID),
+					zap.String("instance_id", instanceID),
 					zap.String("raw_params", string(dbConfig.InspectParams)),
 					zap.Bool("ENABLE_COLUMN_BLOB_TYPE", params.ENABLE_COLUMN_BLOB_TYPE),
 					zap.Bool("ENABLE_COLUMN_NOT_NULL", params.ENABLE_COLUMN_NOT_NULL),
@@ -146,17 +116,79 @@ func (h *InspectHandler) InspectSQL(c *gin.Context) {
 			}
 		}
 	}
+	return params, dbConfig, nil
+}
+
+// inspectContent 按实例审核参数审核 SQL 内容（用于服务端生成的工单，如回滚工单）
+func inspectContent(ctx context.Context, instanceID, dbType, sqlType, schema, content string) ([]*checker.AuditResult, error) {
+	params, dbConfig, err := loadInspectParams(ctx, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	if err := parser.CheckSqlType(content, sqlType); err != nil {
+		return nil, err
+	}
+
+	chk := checker.NewChecker(params, dbType)
+	if dbConfig != nil {
+		chk.SetDBInfo(dbConfig.InstanceID.String(), dbConfig.Hostname, dbConfig.Port, dbConfig.UserName, dbConfig.Password, schema)
+		chk.DBConnOpts = dbConfig.ConnOptions()
+	}
+	results, err := chk.Check(content)
+	if err != nil {
+		return nil, fmt.Errorf("SQL语法错误: %s", err.Error())
+	}
+	return results, nil
+}
+
+// InspectHandlerApp 全局 Handler 实例
+var InspectHandlerApp = new(InspectHandler)
+
+// InspectHandler SQL审核 Handler
+type InspectHandler struct{}
+
+// InspectSQLRequest 审核SQL请求
+type InspectSQLRequest struct {
+	Content    string `json:"content" binding:"required"` // SQL内容
+	DBType     string `json:"db_type"`                    // MySQL/TiDB
+	SQLType    string `json:"sql_type"`                   // DDL/DML
+	InstanceID string `json:"instance_id"`                // 可选，用于获取实例配置的审核参数
+	Schema     string `json:"schema"`                     // 数据库名
+}
+
+// InspectSQL 审核SQL
+// @Summary 审核SQL
+// @Tags SQL审核
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body InspectSQLRequest true "审核请求"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/inspect/sql [post]
+func (h *InspectHandler) InspectSQL(c *gin.Context) {
+	var req InspectSQLRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	// 获取审核参数和数据库配置
+	params, dbConfig, err := loadInspectParams(c.Request.Context(), req.InstanceID)
+	if err != nil {
+		api.HandleError(c, http.StatusOK, err, nil)
+		return
+	}
 
 	// 判断SQL类型是否匹配，DML工单仅允许提交DML语句，DDL工单仅允许提交DDL语句
-	if req.SQLType != "" && req.SQLType != "EXPORT" {
+	if req.SQLType != "" && req.SQLType != "EXPORT" && req.SQLType != "ARCHIVE" && req.SQLType != "MIGRATE" {
 		if err := parser.CheckSqlType(req.Content, req.SQLType); err != nil {
 			api.HandleError(c, http.StatusOK, err, nil)
 			return
 		}
 	}
 
-	// 导出工单和 ClickHouse 不审核
-	if req.SQLType == "EXPORT" || req.DBType == "ClickHouse" {
+	// 导出工单、归档和数据迁移工单（创建工单时校验语句）以及 ClickHouse 不审核
+	if req.SQLType == "EXPORT" || req.SQLType == "ARCHIVE" || req.SQLType == "MIGRATE" || req.DBType == "ClickHouse" {
 		api.HandleSuccess(c, []interface{}{})
 		return
 	}
@@ -167,7 +199,8 @@ func (h *InspectHandler) InspectSQL(c *gin.Context) {
 	// 设置数据库连接信息（用于表存在性检查）
 	// 注意：即使 req.Schema 为空，也应该设置连接信息，因为 SQL 中可能指定了数据库名
 	if dbConfig != nil {
-		chk.SetDBInfo(dbConfig.Hostname, dbConfig.Port, dbConfig.UserName, dbConfig.Password, req.Schema)
+		chk.SetDBInfo(dbConfig.InstanceID.String(), dbConfig.Hostname, dbConfig.Port, dbConfig.UserName, dbConfig.Password, req.Schema)
+		chk.DBConnOpts = dbConfig.ConnOptions()
 		global.Logger.Info("设置数据库连接信息",
 			zap.String("instance_id", req.InstanceID),
 			zap.String("host", dbConfig.Hostname),
@@ -0,0 +1,201 @@
+package insight
+
+import (
+	"go-noah/api"
+	"go-noah/internal/handler"
+	"go-noah/internal/model/insight"
+	"go-noah/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandlerApp 全局 Handler 实例
+var MaintenanceHandlerApp = new(MaintenanceHandler)
+
+// MaintenanceHandler 维护窗口管理Handler
+type MaintenanceHandler struct{}
+
+// GetMaintenanceWindows 获取维护窗口列表
+// @Summary 获取维护窗口列表
+// @Tags 维护窗口
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param environment query int false "环境ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/maintenance-windows [get]
+func (h *MaintenanceHandler) GetMaintenanceWindows(c *gin.Context) {
+	environment, _ := strconv.Atoi(c.Query("environment"))
+	windows, err := service.InsightServiceApp.GetMaintenanceWindows(c.Request.Context(), environment)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	api.HandleSuccess(c, windows)
+}
+
+// MaintenanceWindowRequest 创建/更新维护窗口请求
+type MaintenanceWindowRequest struct {
+	Environment int                     `json:"environment" binding:"required"`
+	InstanceID  string                  `json:"instance_id"` // 为空表示整个环境
+	Type        insight.MaintenanceType `json:"type" binding:"required"`
+	Weekdays    string                  `json:"weekdays"`     // 每周窗口：星期（0-6，0为周日，逗号分隔）
+	StartTime   string                  `json:"start_time"`   // 每周窗口：开始时间 HH:MM
+	EndTime     string                  `json:"end_time"`     // 每周窗口：结束时间 HH:MM（不大于开始时间表示跨天）
+	FreezeStart string                  `json:"freeze_start"` // 冻结期：开始时间，格式 2006-01-02 15:04:05
+	FreezeEnd   string                  `json:"freeze_end"`   // 冻结期：结束时间，格式 2006-01-02 15:04:05
+	Remark      string                  `json:"remark"`
+}
+
+// apply 将请求写入维护窗口
+func (req *MaintenanceWindowRequest) apply(window *insight.MaintenanceWindow) error {
+	window.Environment = req.Environment
+	window.InstanceID = req.InstanceID
+	window.Type = req.Type
+	window.Weekdays = req.Weekdays
+	window.StartTime = req.StartTime
+	window.EndTime = req.EndTime
+	window.Remark = req.Remark
+	window.FreezeStart, window.FreezeEnd = nil, nil
+	if req.Type == insight.MaintenanceTypeFreeze {
+		start, err := time.ParseInLocation(time.DateTime, req.FreezeStart, time.Local)
+		if err != nil {
+			return err
+		}
+		end, err := time.ParseInLocation(time.DateTime, req.FreezeEnd, time.Local)
+		if err != nil {
+			return err
+		}
+		window.FreezeStart, window.FreezeEnd = &start, &end
+	}
+	return nil
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+// @Summary 创建维护窗口
+// @Tags 维护窗口
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body MaintenanceWindowRequest true "维护窗口"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/maintenance-windows [post]
+func (h *MaintenanceHandler) CreateMaintenanceWindow(c *gin.Context) {
+	var req MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	window := &insight.MaintenanceWindow{}
+	if err := req.apply(window); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	if userId := handler.GetUserIdFromCtx(c); userId > 0 {
+		if user, err := service.AdminServiceApp.GetAdminUser(c, userId); err == nil {
+			window.CreatedBy = user.Username
+		}
+	}
+
+	if err := service.InsightServiceApp.CreateMaintenanceWindow(c.Request.Context(), window); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	api.HandleSuccess(c, window)
+}
+
+// UpdateMaintenanceWindow 更新维护窗口
+// @Summary 更新维护窗口
+// @Tags 维护窗口
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "维护窗口ID"
+// @Param request body MaintenanceWindowRequest true "维护窗口"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/maintenance-windows/{id} [put]
+func (h *MaintenanceHandler) UpdateMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return
+	}
+
+	var req MaintenanceWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	window, err := service.InsightServiceApp.GetMaintenanceWindow(c.Request.Context(), uint(id))
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, api.ErrNotFound, nil)
+		return
+	}
+	if err := req.apply(window); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	if err := service.InsightServiceApp.UpdateMaintenanceWindow(c.Request.Context(), window); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	api.HandleSuccess(c, window)
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+// @Summary 删除维护窗口
+// @Tags 维护窗口
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "维护窗口ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/maintenance-windows/{id} [delete]
+func (h *MaintenanceHandler) DeleteMaintenanceWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return
+	}
+
+	if err := service.InsightServiceApp.DeleteMaintenanceWindow(c.Request.Context(), uint(id)); err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	api.HandleSuccess(c, nil)
+}
+
+// GetMaintenanceStatus 查询实例当前是否允许执行工单及下一个维护窗口
+// @Summary 查询维护窗口状态
+// @Tags 维护窗口
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param instance_id query string true "实例ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/maintenance-windows/status [get]
+func (h *MaintenanceHandler) GetMaintenanceStatus(c *gin.Context) {
+	instanceID := c.Query("instance_id")
+	if instanceID == "" {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "instance_id 不能为空")
+		return
+	}
+	dbConfig, err := service.InsightServiceApp.GetDBConfigByInstanceID(c.Request.Context(), instanceID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, api.ErrNotFound, nil)
+		return
+	}
+
+	status, err := service.InsightServiceApp.CheckMaintenanceWindow(c.Request.Context(), dbConfig.Environment, instanceID, time.Now())
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	api.HandleSuccess(c, status)
+}
@@ -3,10 +3,12 @@ package insight
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-noah/api"
 	"go-noah/internal/das/dao"
 	"go-noah/internal/handler"
+	"go-noah/internal/inspect/checker"
 	"go-noah/internal/inspect/parser"
 	"go-noah/internal/model"
 	"go-noah/internal/model/insight"
@@ -15,9 +17,11 @@ import (
 	insightRepo "go-noah/internal/repository/insight"
 	"go-noah/internal/service"
 	"go-noah/pkg/global"
+	"go-noah/pkg/lease"
 	"go-noah/pkg/notifier"
 	"go-noah/pkg/utils"
 	"net/http"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -697,14 +701,58 @@ func (h *OrderHandler) GetOrder(c *gin.Context) {
 		logs = []insight.OrderOpLog{}
 	}
 
+	// 获取关联工单：源工单（当前为回滚工单时）以及基于当前工单创建的回滚工单
+	var sourceOrder *RelatedOrder
+	if order.HookOrderID != uuid.Nil {
+		if source, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), order.HookOrderID.String()); err == nil {
+			sourceOrder = newRelatedOrder(&source.OrderRecord)
+		}
+	}
+	rollbackOrders := []*RelatedOrder{}
+	if hookOrders, err := service.InsightServiceApp.GetOrdersByHookOrderID(c.Request.Context(), orderID); err == nil {
+		for i := range hookOrders {
+			rollbackOrders = append(rollbackOrders, newRelatedOrder(&hookOrders[i]))
+		}
+	}
+
+	// 获取最近一次试运行结果（仅相关人员可见）
+	var dryRun *insight.OrderDryRun
+	if isRelatedUser && order.SQLType == insight.SQLTypeDML {
+		dryRun = service.InsightServiceApp.GetLatestDryRun(c.Request.Context(), orderID)
+	}
+
 	api.HandleSuccess(c, gin.H{
-		"order":        order,
-		"tasks":        tasks,
-		"logs":         logs,
-		"flowInstance": flowInstance,
+		"order":          order,
+		"tasks":          tasks,
+		"logs":           logs,
+		"flowInstance":   flowInstance,
+		"sourceOrder":    sourceOrder,
+		"rollbackOrders": rollbackOrders,
+		"dryRun":         dryRun,
 	})
 }
 
+// RelatedOrder 关联工单摘要
+type RelatedOrder struct {
+	OrderID   string           `json:"order_id"`
+	Title     string           `json:"title"`
+	SQLType   insight.SQLType  `json:"sql_type"`
+	Progress  insight.Progress `json:"progress"`
+	Applicant string           `json:"applicant"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+func newRelatedOrder(order *insight.OrderRecord) *RelatedOrder {
+	return &RelatedOrder{
+		OrderID:   order.OrderID.String(),
+		Title:     order.Title,
+		SQLType:   order.SQLType,
+		Progress:  order.Progress,
+		Applicant: order.Applicant,
+		CreatedAt: order.CreatedAt,
+	}
+}
+
 // FlexibleTime 灵活的时间类型，支持多种时间格式
 type FlexibleTime struct {
 	*time.Time
@@ -764,6 +812,40 @@ type CreateOrderRequest struct {
 	ScheduleTime       FlexibleTime `json:"schedule_time"`
 	FixVersion         string       `json:"fix_version"`
 	ExportFileFormat   string       `json:"export_file_format"`
+	DDLEngine          string       `json:"ddl_engine"`             // 在线DDL引擎（gh-ost/pt-osc/native/auto，空为使用实例配置）
+	ChunkedDML         bool         `json:"chunked_dml"`            // DML按主键分批执行
+	ChunkSize          int          `json:"chunk_size"`             // 每批行数（0使用系统默认）
+	ChunkSleepMs       int          `json:"chunk_sleep_ms"`         // 批次间隔毫秒（0使用系统默认）
+	MissedPolicy       string       `json:"schedule_missed_policy"` // 定时执行错过计划时间的处理策略（run_late/skip/ask，空为使用系统默认）
+
+	// 周期工单：审批一次后按 cron 表达式在有效期内重复执行（仅支持 MySQL/TiDB 的 DML 工单）
+	Recurring *RecurringOrderRequest `json:"recurring"`
+
+	// 归档工单选项（仅 ARCHIVE 工单）
+	Archive *insight.ArchiveOptions `json:"archive_options"`
+	// 数据迁移工单选项（仅 MIGRATE 工单）
+	Migrate *insight.MigrateOptions `json:"migrate_options"`
+}
+
+// RecurringOrderRequest 周期工单执行计划
+type RecurringOrderRequest struct {
+	Cron                   string       `json:"cron" binding:"required"`  // cron表达式（分 时 日 月 周）
+	ExpireAt               FlexibleTime `json:"expire_at"`                // 有效期截止时间
+	MaxAffectedRows        int64        `json:"max_affected_rows"`        // 单次执行影响行数上限（超过时回滚）
+	MaxConsecutiveFailures int          `json:"max_consecutive_failures"` // 连续失败多少次后自动暂停（0使用系统默认）
+}
+
+// toSchedule 转换为执行计划（OrderID 在工单创建后设置）
+func (r *RecurringOrderRequest) toSchedule() *insight.RecurringSchedule {
+	schedule := &insight.RecurringSchedule{
+		CronExpr:               strings.TrimSpace(r.Cron),
+		MaxAffectedRows:        r.MaxAffectedRows,
+		MaxConsecutiveFailures: r.MaxConsecutiveFailures,
+	}
+	if r.ExpireAt.Time != nil {
+		schedule.ExpireAt = *r.ExpireAt.Time
+	}
+	return schedule
 }
 
 // CreateOrder 创建工单
@@ -792,6 +874,15 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		}
 	}
 
+	if !executor.IsValidDDLEngine(req.DDLEngine) {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("不支持的在线DDL引擎: %s", req.DDLEngine), nil)
+		return
+	}
+	if !insight.IsValidMissedRunPolicy(req.MissedPolicy) {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("不支持的错过计划时间处理策略: %s", req.MissedPolicy), nil)
+		return
+	}
+
 	// 解析 InstanceID
 	instanceUUID, err := uuid.Parse(req.InstanceID)
 	if err != nil {
@@ -815,6 +906,29 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		FixVersion:         req.FixVersion,
 		ExportFileFormat:   insight.ExportFileFormat(req.ExportFileFormat),
 		GhostOkToDropTable: false, // 默认false，由审核人在审核时设置
+		DDLEngine:          req.DDLEngine,
+		ChunkedDML:         req.ChunkedDML,
+		ChunkSize:          req.ChunkSize,
+		ChunkSleepMs:       req.ChunkSleepMs,
+	}
+	order.ScheduleMissedPolicy = insight.MissedRunPolicy(req.MissedPolicy)
+	if req.Recurring != nil {
+		if err := service.InsightServiceApp.ValidateRecurringOrder(order, req.Recurring.toSchedule()); err != nil {
+			api.HandleError(c, http.StatusBadRequest, err, nil)
+			return
+		}
+	}
+	switch order.SQLType {
+	case insight.SQLTypeArchive:
+		if err := service.InsightServiceApp.ValidateArchiveOrder(c.Request.Context(), order, req.Archive); err != nil {
+			api.HandleError(c, http.StatusBadRequest, err, nil)
+			return
+		}
+	case insight.SQLTypeMigrate:
+		if err := service.InsightServiceApp.ValidateMigrateOrder(c.Request.Context(), order, req.Migrate, userId); err != nil {
+			api.HandleError(c, http.StatusBadRequest, err, nil)
+			return
+		}
 	}
 
 	// 转换 JSON 字段
@@ -831,10 +945,33 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		order.CC, _ = jsonMarshal(req.CC)
 	}
 
+	if !h.submitOrder(c, &req, order, userId, username) {
+		return
+	}
+
+	api.HandleSuccess(c, order)
+}
+
+// submitOrder 保存工单、启动流程引擎、记录操作日志并异步发送通知
+// 返回是否成功，失败时已写入响应
+func (h *OrderHandler) submitOrder(c *gin.Context, req *CreateOrderRequest, order *insight.OrderRecord, userId uint, username string) bool {
 	// 创建工单
 	if err := service.InsightServiceApp.CreateOrder(c.Request.Context(), order); err != nil {
 		api.HandleError(c, http.StatusInternalServerError, err, nil)
-		return
+		return false
+	}
+
+	// 周期工单：创建执行计划，审批通过后由 TaskServer 按计划执行
+	if req.Recurring != nil {
+		schedule := req.Recurring.toSchedule()
+		schedule.OrderID = order.OrderID
+		if err := service.InsightServiceApp.CreateRecurringSchedule(c.Request.Context(), schedule); err != nil {
+			baseRepo := repository.NewRepository(global.Logger, global.DB, global.Enforcer)
+			repo := insightRepo.NewInsightRepository(baseRepo, global.Logger, global.Enforcer)
+			_ = repo.DeleteOrder(c.Request.Context(), order.OrderID.String())
+			api.HandleError(c, http.StatusInternalServerError, err, nil)
+			return false
+		}
 	}
 
 	// 启动流程引擎（必须，如果流程引擎未配置，返回错误）
@@ -854,7 +991,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		repo := insightRepo.NewInsightRepository(baseRepo, global.Logger, global.Enforcer)
 		_ = repo.DeleteOrder(c.Request.Context(), order.OrderID.String())
 		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("流程引擎未配置，请先为业务类型 %s 配置流程定义", businessType), nil)
-		return
+		return false
 	}
 
 	// 关联流程实例ID到工单
@@ -1028,7 +1165,7 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 		)
 	}()
 
-	api.HandleSuccess(c, order)
+	return true
 }
 
 // UpdateOrderProgressRequest 更新工单进度请求
@@ -1097,6 +1234,8 @@ type ApproveOrderRequest struct {
 	Status             string `json:"status" binding:"required,oneof=pass reject"` // pass: 通过, reject: 驳回
 	Msg                string `json:"msg"`                                         // 审批意见
 	GhostOkToDropTable bool   `json:"ghost_ok_to_drop_table"`                      // gh-ost执行成功后自动删除旧表（仅DDL工单有效）
+	// gh-ost推迟cut-over，数据同步完成后由执行人手动或按计划时间cut-over（仅DDL工单有效）
+	GhostPostponeCutOver bool `json:"ghost_postpone_cut_over"`
 }
 
 // ApproveOrder 审批工单
@@ -1170,7 +1309,8 @@ func (h *OrderHandler) ApproveOrder(c *gin.Context) {
 	if req.Status == "pass" && order.SQLType == insight.SQLTypeDDL {
 		// 使用 UpdateDBConfigFields 的方式更新单个字段
 		updates := map[string]interface{}{
-			"ghost_ok_to_drop_table": req.GhostOkToDropTable,
+			"ghost_ok_to_drop_table":  req.GhostOkToDropTable,
+			"ghost_postpone_cut_over": req.GhostPostponeCutOver,
 		}
 		if err := service.InsightServiceApp.UpdateOrderFields(c.Request.Context(), req.OrderID, updates); err != nil {
 			api.HandleError(c, http.StatusInternalServerError, err, nil)
@@ -1271,6 +1411,478 @@ func (h *OrderHandler) GetTaskRollbackSQL(c *gin.Context) {
 	})
 }
 
+// GetTaskRollbackSQLPage 分页获取任务回滚SQL
+// @Summary 分页获取任务回滚SQL
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param order_id path string true "工单ID"
+// @Param task_id path string true "任务ID"
+// @Param page query int false "页码"
+// @Param page_size query int false "每页语句数"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/orders/{order_id}/tasks/{task_id}/rollback-sql/page [get]
+func (h *OrderHandler) GetTaskRollbackSQLPage(c *gin.Context) {
+	orderID := c.Param("order_id")
+	taskID := c.Param("task_id")
+	if !h.checkTaskAccess(c, orderID, taskID) {
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "100"))
+	statements, total, err := service.InsightServiceApp.GetTaskRollbackSQLPage(c.Request.Context(), taskID, page, pageSize)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	api.HandleSuccess(c, gin.H{
+		"list":      statements,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// DownloadTaskRollbackSQL 下载任务回滚SQL文件
+// @Summary 下载任务回滚SQL文件
+// @Tags 工单管理
+// @Security Bearer
+// @Produce octet-stream
+// @Param order_id path string true "工单ID"
+// @Param task_id path string true "任务ID"
+// @Success 200 {file} file
+// @Router /api/v1/insight/orders/{order_id}/tasks/{task_id}/rollback-sql/download [get]
+func (h *OrderHandler) DownloadTaskRollbackSQL(c *gin.Context) {
+	orderID := c.Param("order_id")
+	taskID := c.Param("task_id")
+	if !h.checkTaskAccess(c, orderID, taskID) {
+		return
+	}
+
+	reader, size, err := service.InsightServiceApp.OpenTaskRollbackSQL(c.Request.Context(), taskID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	defer reader.Close()
+
+	fileName := fmt.Sprintf("rollback_%s.sql", taskID)
+	c.DataFromReader(http.StatusOK, size, "application/sql", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, fileName),
+	})
+}
+
+// DownloadTaskExecuteLog 下载任务完整执行日志
+// @Summary 下载任务完整执行日志
+// @Tags 工单管理
+// @Security Bearer
+// @Produce octet-stream
+// @Param order_id path string true "工单ID"
+// @Param task_id path string true "任务ID"
+// @Success 200 {file} file
+// @Router /api/v1/insight/orders/{order_id}/tasks/{task_id}/execute-log [get]
+func (h *OrderHandler) DownloadTaskExecuteLog(c *gin.Context) {
+	orderID := c.Param("order_id")
+	taskID := c.Param("task_id")
+	if !h.checkTaskAccess(c, orderID, taskID) {
+		return
+	}
+
+	reader, size, err := service.InsightServiceApp.OpenTaskExecuteLog(c.Request.Context(), taskID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	defer reader.Close()
+
+	fileName := fmt.Sprintf("execute_%s.log", taskID)
+	c.DataFromReader(http.StatusOK, size, "text/plain; charset=utf-8", reader, map[string]string{
+		"Content-Disposition": fmt.Sprintf(`attachment; filename="%s"`, fileName),
+	})
+}
+
+// checkTaskAccess 检查当前用户是否可以查看工单任务，且任务属于该工单，失败时已写入响应
+func (h *OrderHandler) checkTaskAccess(c *gin.Context, orderID, taskID string) bool {
+	if orderID == "" || taskID == "" {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return false
+	}
+	isRelatedUser, err := h.checkOrderAccess(c, orderID)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return false
+	}
+	if !isRelatedUser {
+		api.HandleError(c, http.StatusForbidden, api.ErrForbidden, nil)
+		return false
+	}
+	task, err := service.InsightServiceApp.GetTaskByID(c.Request.Context(), taskID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, api.ErrNotFound, nil)
+		return false
+	}
+	if task.OrderID.String() != orderID {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("任务不属于该工单"), nil)
+		return false
+	}
+	return true
+}
+
+// CreateRollbackOrderRequest 创建回滚工单请求
+type CreateRollbackOrderRequest struct {
+	OrderID string   `json:"order_id" binding:"required"` // 源工单ID
+	TaskIDs []string `json:"task_ids"`                    // 需要回滚的任务ID，为空表示所有有回滚SQL的任务
+	Title   string   `json:"title"`                       // 为空时使用 "回滚: <源工单标题>"
+	Remark  string   `json:"remark"`
+}
+
+// CreateRollbackOrder 基于任务回滚SQL创建回滚工单
+// 新工单通过 HookOrderID 关联源工单，按任务执行的逆序拼接回滚SQL，审核通过后走正常审批流程
+// @Summary 创建回滚工单
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body CreateRollbackOrderRequest true "回滚工单信息"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/orders/rollback [post]
+func (h *OrderHandler) CreateRollbackOrder(c *gin.Context) {
+	var req CreateRollbackOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	// 获取当前用户
+	userId := handler.GetUserIdFromCtx(c)
+	username := ""
+	if userId > 0 {
+		user, err := service.AdminServiceApp.GetAdminUser(c, userId)
+		if err == nil {
+			username = user.Username
+		}
+	}
+
+	source, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), req.OrderID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	isRelatedUser, err := h.checkOrderAccess(c, req.OrderID)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	if !isRelatedUser {
+		api.HandleError(c, http.StatusForbidden, api.ErrForbidden, nil)
+		return
+	}
+	if source.SQLType == insight.SQLTypeExport {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("导出工单不支持回滚"), nil)
+		return
+	}
+
+	tasks, err := service.InsightServiceApp.GetOrderTasks(c.Request.Context(), req.OrderID)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	selected := make(map[string]bool, len(req.TaskIDs))
+	for _, taskID := range req.TaskIDs {
+		selected[taskID] = true
+	}
+
+	// 按任务执行的逆序拼接回滚SQL，后执行的语句先回滚
+	var statements []string
+	for i := len(tasks) - 1; i >= 0; i-- {
+		taskID := tasks[i].TaskID.String()
+		if len(selected) > 0 {
+			if !selected[taskID] {
+				continue
+			}
+			delete(selected, taskID)
+		}
+		rollbackSQL, err := service.InsightServiceApp.GetTaskRollbackSQL(c.Request.Context(), taskID)
+		if err != nil {
+			api.HandleError(c, http.StatusInternalServerError, err, nil)
+			return
+		}
+		if rollbackSQL = strings.TrimSpace(rollbackSQL); rollbackSQL != "" {
+			statements = append(statements, rollbackSQL)
+		}
+	}
+	if len(selected) > 0 {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("任务不属于当前工单: %s", strings.Join(mapKeys(selected), ",")), nil)
+		return
+	}
+	if len(statements) == 0 {
+		api.HandleError(c, http.StatusBadRequest, fmt.Errorf("所选任务没有可用的回滚SQL"), nil)
+		return
+	}
+	content := strings.Join(statements, "\n")
+
+	// 审核回滚SQL，存在错误级别的审核结果时不允许提交
+	results, err := inspectContent(c.Request.Context(), source.InstanceID.String(), string(source.DBType), string(source.SQLType), source.Schema, content)
+	if err != nil {
+		api.HandleError(c, http.StatusOK, err, nil)
+		return
+	}
+	for _, r := range results {
+		if r.Level == checker.LevelError {
+			api.HandleError(c, http.StatusOK, fmt.Errorf("回滚SQL审核未通过"), results)
+			return
+		}
+	}
+
+	title := req.Title
+	if title == "" {
+		title = fmt.Sprintf("回滚: %s", source.Title)
+	}
+	remark := req.Remark
+	if remark == "" {
+		remark = fmt.Sprintf("回滚工单，源工单: %s", source.OrderID.String())
+	}
+	order := &insight.OrderRecord{
+		Title:            title,
+		Remark:           remark,
+		HookOrderID:      source.OrderID,
+		IsRestrictAccess: source.IsRestrictAccess,
+		DBType:           source.DBType,
+		SQLType:          source.SQLType,
+		Environment:      source.Environment,
+		InstanceID:       source.InstanceID,
+		Schema:           source.Schema,
+		Content:          content,
+		Applicant:        username,
+		Progress:         insight.ProgressPending,
+		Approver:         source.Approver,
+		Executor:         source.Executor,
+		Reviewer:         source.Reviewer,
+		CC:               source.CC,
+		DDLEngine:        source.DDLEngine,
+	}
+
+	// 通知沿用源工单的相关人员
+	createReq := &CreateOrderRequest{
+		Title:       order.Title,
+		Environment: order.Environment,
+		Approver:    unmarshalUsers(source.Approver),
+		Executor:    unmarshalUsers(source.Executor),
+		Reviewer:    unmarshalUsers(source.Reviewer),
+		CC:          unmarshalUsers(source.CC),
+	}
+	if !h.submitOrder(c, createReq, order, userId, username) {
+		return
+	}
+
+	_ = service.InsightServiceApp.CreateOpLog(c.Request.Context(), &insight.OrderOpLog{
+		Username: username,
+		OrderID:  source.OrderID,
+		Msg:      fmt.Sprintf("创建回滚工单: %s", order.OrderID.String()),
+	})
+
+	api.HandleSuccess(c, gin.H{
+		"order":   order,
+		"inspect": results,
+	})
+}
+
+// DryRunOrderRequest 试运行工单请求
+type DryRunOrderRequest struct {
+	OrderID          string `json:"order_id" binding:"required"`
+	TargetInstanceID string `json:"target_instance_id"` // 试运行实例（工单实例的从库或沙箱），为空使用第一个配置的从库或沙箱
+}
+
+// DryRunOrder 试运行DML工单（在事务中执行后回滚，统计实际影响行数并采样前后镜像）
+// @Summary 试运行DML工单
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body DryRunOrderRequest true "试运行参数"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/orders/dry-run [post]
+func (h *OrderHandler) DryRunOrder(c *gin.Context) {
+	var req DryRunOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	// 获取当前用户
+	userId := handler.GetUserIdFromCtx(c)
+	username := ""
+	if userId > 0 {
+		user, err := service.AdminServiceApp.GetAdminUser(c, userId)
+		if err == nil {
+			username = user.Username
+		}
+	}
+
+	// 仅审核人和 DBA 可以试运行
+	order, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), req.OrderID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	allowed := service.InsightServiceApp.IsDBA(userId)
+	for _, user := range unmarshalUsers(order.Approver) {
+		if username != "" && user == username {
+			allowed = true
+		}
+	}
+	if !allowed {
+		api.HandleError(c, http.StatusForbidden, api.ErrForbidden, nil)
+		return
+	}
+
+	dryRun, err := service.InsightServiceApp.DryRunOrder(c.Request.Context(), req.OrderID, username, req.TargetInstanceID)
+	if err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	api.HandleSuccess(c, dryRun)
+}
+
+// unmarshalUsers 解析工单人员 JSON（兼容字符串数组和 {"user": xxx} 对象数组）
+func unmarshalUsers(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	var users []string
+	if err := json.Unmarshal(data, &users); err == nil {
+		return users
+	}
+	var objs []map[string]interface{}
+	if err := json.Unmarshal(data, &objs); err != nil {
+		return nil
+	}
+	for _, obj := range objs {
+		if user, ok := obj["user"].(string); ok && user != "" {
+			users = append(users, user)
+		}
+	}
+	return users
+}
+
+// mapKeys 返回 map 的键列表
+func mapKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// getApplicantExportFile 获取导出文件信息，仅工单申请人有权限
+// 返回 (导出文件, 工单, 是否继续处理)，失败时已写入响应
+func (h *OrderHandler) getApplicantExportFile(c *gin.Context) (*executor.ExportFile, *insight.OrderRecord, bool) {
+	orderID := c.Param("order_id")
+	taskID := c.Param("task_id")
+	if orderID == "" || taskID == "" {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return nil, nil, false
+	}
+
+	// 获取当前用户
+	userId := handler.GetUserIdFromCtx(c)
+	username := ""
+	if userId > 0 {
+		user, err := service.AdminServiceApp.GetAdminUser(c, userId)
+		if err == nil {
+			username = user.Username
+		}
+	}
+
+	order, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), orderID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return nil, nil, false
+	}
+	if username == "" || order.Applicant != username {
+		api.HandleError(c, http.StatusForbidden, api.ErrForbidden, "仅工单申请人可获取导出文件")
+		return nil, nil, false
+	}
+
+	exportFile, err := service.InsightServiceApp.GetTaskExportFile(c.Request.Context(), orderID, taskID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return nil, nil, false
+	}
+	return exportFile, &order.OrderRecord, true
+}
+
+// DownloadExportFile 下载导出文件（仅工单申请人可下载）
+// @Summary 下载导出文件
+// @Tags 工单管理
+// @Security Bearer
+// @Produce octet-stream
+// @Param order_id path string true "工单ID"
+// @Param task_id path string true "任务ID"
+// @Success 200 {file} file
+// @Router /api/v1/insight/orders/{order_id}/tasks/{task_id}/export-file [get]
+func (h *OrderHandler) DownloadExportFile(c *gin.Context) {
+	exportFile, order, ok := h.getApplicantExportFile(c)
+	if !ok {
+		return
+	}
+
+	// 检查下载链接是否过期
+	if exportFile.ExpireTime != "" {
+		expireTime, err := time.ParseInLocation("2006-01-02 15:04:05", exportFile.ExpireTime, time.Local)
+		if err == nil && time.Now().After(expireTime) {
+			api.HandleError(c, http.StatusGone, fmt.Errorf("下载链接已过期"), nil)
+			return
+		}
+	}
+	if _, err := os.Stat(exportFile.FilePath); err != nil {
+		api.HandleError(c, http.StatusNotFound, fmt.Errorf("导出文件不存在或已被清理"), nil)
+		return
+	}
+
+	// 记录下载日志
+	_ = service.InsightServiceApp.CreateOpLog(c.Request.Context(), &insight.OrderOpLog{
+		Username: order.Applicant,
+		OrderID:  order.OrderID,
+		Msg:      fmt.Sprintf("下载导出文件: %s（来源IP: %s）", exportFile.FileName, c.ClientIP()),
+	})
+
+	if exportFile.ContentType != "" {
+		c.Header("Content-Type", exportFile.ContentType)
+	}
+	c.FileAttachment(exportFile.FilePath, exportFile.FileName)
+}
+
+// GetExportFileKey 获取导出文件解压密码（仅工单申请人可见）
+// @Summary 获取导出文件解压密码
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param order_id path string true "工单ID"
+// @Param task_id path string true "任务ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/orders/{order_id}/tasks/{task_id}/export-key [get]
+func (h *OrderHandler) GetExportFileKey(c *gin.Context) {
+	exportFile, order, ok := h.getApplicantExportFile(c)
+	if !ok {
+		return
+	}
+	key, err := service.InsightServiceApp.GetOrderExportKey(order, exportFile)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+
+	api.HandleSuccess(c, gin.H{
+		"encryption_key": key,
+		"expire_time":    exportFile.ExpireTime,
+	})
+}
+
 // UpdateTaskProgressRequest 更新任务进度请求
 type UpdateTaskProgressRequest struct {
 	TaskID   string `json:"task_id" binding:"required"`
@@ -1485,8 +2097,11 @@ func (h *OrderHandler) getUsersFromFlowNode(ctx context.Context, node *model.Flo
 
 // ExecuteTaskRequest 执行任务请求
 type ExecuteTaskRequest struct {
-	TaskID  string `json:"task_id"`  // 执行单个任务时使用
-	OrderID string `json:"order_id"` // 执行全部任务时使用
+	TaskID         string `json:"task_id"`         // 执行单个任务时使用
+	OrderID        string `json:"order_id"`        // 执行全部任务时使用
+	Queue          bool   `json:"queue"`           // 不在维护窗口内时排队到下一个维护窗口自动执行（仅执行全部任务时有效）
+	Override       bool   `json:"override"`        // 紧急执行，忽略维护窗口（需要紧急执行角色）
+	OverrideReason string `json:"override_reason"` // 紧急执行原因（记录操作日志）
 }
 
 // ExecuteTask 执行工单任务（支持单个任务和全部任务）
@@ -1517,7 +2132,7 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 
 	// 如果提供了 order_id，执行全部任务
 	if req.OrderID != "" {
-		h.executeAllTasks(c, req.OrderID, username, userId)
+		h.executeAllTasks(c, &req, username, userId)
 		return
 	}
 
@@ -1534,6 +2149,24 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 		return
 	}
 
+	// 获取工单执行锁，持有锁之后重新读取任务状态，避免多个节点同时执行
+	lock, err := service.InsightServiceApp.AcquireOrderLock(task.OrderID.String())
+	if err != nil {
+		api.HandleError(c, http.StatusConflict, err, nil)
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			lock.Release()
+		}
+	}()
+	task, err = service.InsightServiceApp.GetTaskByID(c.Request.Context(), req.TaskID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+
 	// 获取工单信息
 	order, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), task.OrderID.String())
 	if err != nil {
@@ -1546,6 +2179,10 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 		api.HandleError(c, http.StatusForbidden, err, nil)
 		return
 	}
+	if err := service.InsightServiceApp.EnsureOrderExportKey(c.Request.Context(), &order.OrderRecord); err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
 
 	// 检查任务状态（避免重复执行）
 	if task.Progress == insight.TaskProgressCompleted {
@@ -1556,6 +2193,10 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "当前任务正在执行中，请勿重复执行")
 		return
 	}
+	if task.Progress == insight.TaskProgressUnknown {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "当前任务执行结果待确认，请先人工确认实际执行结果并更新任务状态")
+		return
+	}
 
 	// 检查是否有其他任务正在执行中
 	noExecutingTasks, err := service.InsightServiceApp.CheckTasksProgressIsDoing(c.Request.Context(), task.OrderID.String())
@@ -1568,6 +2209,11 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 		return
 	}
 
+	// 检查维护窗口
+	if !h.checkMaintenanceWindow(c, &order.OrderRecord, &req, username, userId) {
+		return
+	}
+
 	// 获取数据库配置
 	dbConfig, err := service.InsightServiceApp.GetDBConfigByInstanceID(c.Request.Context(), order.InstanceID.String())
 	if err != nil {
@@ -1576,7 +2222,7 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 	}
 
 	// 使用事务同时更新任务状态和工单状态
-	err = service.InsightServiceApp.UpdateTaskAndOrderProgress(c.Request.Context(), req.TaskID, task.OrderID.String(), insight.TaskProgressExecuting, insight.ProgressExecuting)
+	err = service.InsightServiceApp.UpdateTaskAndOrderProgress(c.Request.Context(), req.TaskID, task.OrderID.String(), lock.Fence(), insight.TaskProgressExecuting, insight.ProgressExecuting)
 	if err != nil {
 		api.HandleError(c, http.StatusInternalServerError, err, nil)
 		return
@@ -1595,20 +2241,7 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 		zap.Bool("ghost_ok_to_drop_table", order.GhostOkToDropTable),
 		zap.String("sql_type", string(task.SQLType)),
 	)
-	execConfig := &executor.DBConfig{
-		Hostname:           dbConfig.Hostname,
-		Port:               dbConfig.Port,
-		UserName:           dbConfig.UserName,
-		Password:           dbConfig.Password,
-		Schema:             order.Schema,
-		DBType:             string(dbConfig.DbType),
-		SQLType:            string(task.SQLType),
-		SQL:                task.SQL,
-		OrderID:            order.OrderID.String(),
-		TaskID:             task.TaskID.String(),
-		ExportFileFormat:   string(order.ExportFileFormat),
-		GhostOkToDropTable: order.GhostOkToDropTable,
-	}
+	execConfig := service.NewExecutorConfig(&order.OrderRecord, task, dbConfig)
 
 	// 记录执行开始日志（用于调试）
 	global.Logger.Info("Starting task execution (async)",
@@ -1624,10 +2257,13 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 		"order_id": task.OrderID.String(),
 	})
 
-	// 在后台 goroutine 中异步执行任务
+	// 在后台 goroutine 中异步执行任务，执行结束后释放执行锁
 	// 使用独立的 context，不依赖 HTTP 请求的 context（避免 HTTP 超时导致执行中断）
+	started = true
 	go func() {
+		defer lock.Release()
 		ctx := context.Background() // 使用独立的 context
+		fence := lock.Fence()
 
 		// 创建执行器
 		exec, err := executor.NewExecuteSQL(execConfig)
@@ -1637,7 +2273,7 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 				zap.String("order_id", task.OrderID.String()),
 				zap.Error(err),
 			)
-			_ = service.InsightServiceApp.UpdateTaskProgress(ctx, req.TaskID, insight.TaskProgressFailed, nil)
+			_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, req.TaskID, fence, insight.TaskProgressFailed, nil)
 			_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
 				Username: username,
 				OrderID:  order.OrderID,
@@ -1651,17 +2287,27 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 			zap.String("task_id", req.TaskID),
 			zap.String("order_id", task.OrderID.String()),
 		)
-		result, err := exec.Run()
+		result, err := exec.Run(lock.Context()) // 执行锁丢失时中断执行，任务标记为待确认
 
 		// 保存执行结果
-		resultJSON, _ := json.Marshal(result)
+		resultJSON := service.InsightServiceApp.MarshalTaskResult(ctx, req.TaskID, result)
+		if errors.Is(err, executor.ErrTaskCancelled) {
+			// 任务被取消（取消操作已记录操作日志）
+			_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, req.TaskID, fence, insight.TaskProgressCancelled, resultJSON)
+			return
+		}
+		if errors.Is(err, lease.ErrLost) {
+			// 执行锁丢失，执行结果待确认
+			service.InsightServiceApp.MarkTaskLeaseLost(ctx, order.OrderID, req.TaskID, fence, resultJSON)
+			return
+		}
 		if err != nil {
 			global.Logger.Error("Task execution failed",
 				zap.String("task_id", req.TaskID),
 				zap.String("order_id", task.OrderID.String()),
 				zap.Error(err),
 			)
-			_ = service.InsightServiceApp.UpdateTaskProgress(ctx, req.TaskID, insight.TaskProgressFailed, resultJSON)
+			_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, req.TaskID, fence, insight.TaskProgressFailed, resultJSON)
 			_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
 				Username: username,
 				OrderID:  order.OrderID,
@@ -1678,7 +2324,7 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 			zap.String("order_id", task.OrderID.String()),
 			zap.Int64("affected_rows", result.AffectedRows),
 		)
-		_ = service.InsightServiceApp.UpdateTaskProgress(ctx, req.TaskID, insight.TaskProgressCompleted, resultJSON)
+		_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, req.TaskID, fence, insight.TaskProgressCompleted, resultJSON)
 		_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
 			Username: username,
 			OrderID:  order.OrderID,
@@ -1690,6 +2336,116 @@ func (h *OrderHandler) ExecuteTask(c *gin.Context) {
 	}()
 }
 
+// CancelTaskRequest 取消任务请求
+type CancelTaskRequest struct {
+	TaskID string `json:"task_id" binding:"required"`
+}
+
+// CancelTask 取消正在执行的任务
+// @Summary 取消正在执行的任务
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body CancelTaskRequest true "任务信息"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/orders/tasks/cancel [post]
+func (h *OrderHandler) CancelTask(c *gin.Context) {
+	var req CancelTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	// 获取当前用户
+	userId := handler.GetUserIdFromCtx(c)
+	username := ""
+	if userId > 0 {
+		user, err := service.AdminServiceApp.GetAdminUser(c, userId)
+		if err == nil {
+			username = user.Username
+		}
+	}
+
+	task, err := service.InsightServiceApp.GetTaskByID(c.Request.Context(), req.TaskID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+
+	// 检查执行权限（与执行任务一致）
+	if err := h.checkOrderStatus(c.Request.Context(), task.OrderID.String(), username, userId); err != nil {
+		api.HandleError(c, http.StatusForbidden, err, nil)
+		return
+	}
+
+	if err := service.InsightServiceApp.CancelTask(c.Request.Context(), req.TaskID, username); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	api.HandleSuccess(c, gin.H{
+		"message": "已发送取消指令",
+		"task_id": req.TaskID,
+	})
+}
+
+// checkMaintenanceWindow 检查维护窗口，不允许执行时写入响应并返回 false
+// 紧急执行需要紧急执行角色并填写原因，记录操作日志；执行全部任务时可以排队到下一个维护窗口自动执行
+func (h *OrderHandler) checkMaintenanceWindow(c *gin.Context, order *insight.OrderRecord, req *ExecuteTaskRequest, username string, userID uint) bool {
+	ctx := c.Request.Context()
+	status, err := service.InsightServiceApp.CheckOrderMaintenanceWindow(ctx, order)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return false
+	}
+	if status.Allowed {
+		return true
+	}
+
+	if req.Override {
+		if !service.InsightServiceApp.CanOverrideMaintenanceWindow(userID) {
+			api.HandleError(c, http.StatusForbidden, api.ErrForbidden, "没有紧急执行权限，"+status.Message())
+			return false
+		}
+		reason := strings.TrimSpace(req.OverrideReason)
+		if reason == "" {
+			api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "紧急执行需要填写原因")
+			return false
+		}
+		global.Logger.Warn("维护窗口外紧急执行工单",
+			zap.String("order_id", order.OrderID.String()),
+			zap.String("task_id", req.TaskID),
+			zap.String("username", username),
+			zap.String("window", status.Reason),
+			zap.String("reason", reason),
+		)
+		_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
+			Username: username,
+			OrderID:  order.OrderID,
+			Msg:      fmt.Sprintf("紧急执行（%s），原因: %s", status.Reason, reason),
+		})
+		return true
+	}
+
+	if req.Queue && req.TaskID == "" && status.NextWindow != nil {
+		if err := service.InsightServiceApp.QueueOrderToMaintenanceWindow(ctx, order.OrderID.String(), *status.NextWindow, username); err != nil {
+			api.HandleError(c, http.StatusBadRequest, err, nil)
+			return false
+		}
+		api.HandleSuccess(c, gin.H{
+			"type":          "info",
+			"message":       "不在维护窗口内，工单将在 " + status.NextWindow.Format(time.DateTime) + " 自动执行",
+			"order_id":      order.OrderID.String(),
+			"schedule_time": status.NextWindow,
+		})
+		return false
+	}
+
+	api.HandleError(c, http.StatusForbidden, errors.New(status.Message()), status)
+	return false
+}
+
 // checkOrderStatus 检查工单状态和执行权限
 func (h *OrderHandler) checkOrderStatus(ctx context.Context, orderID string, username string, userID uint) error {
 	order, err := service.InsightServiceApp.GetOrderByID(ctx, orderID)
@@ -1734,6 +2490,15 @@ func (h *OrderHandler) checkOrderStatus(ctx context.Context, orderID string, use
 		return api.ErrBadRequest
 	}
 
+	// 周期工单由 TaskServer 按计划执行
+	recurring, err := service.InsightServiceApp.GetRecurringSchedule(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if recurring != nil {
+		return errors.New("周期工单由系统按计划执行，不能手动执行")
+	}
+
 	return nil
 }
 
@@ -1827,7 +2592,9 @@ func (h *OrderHandler) updateOrderStatusToFinish(ctx context.Context, orderID st
 }
 
 // executeAllTasks 执行工单的所有任务
-func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username string, userID uint) {
+func (h *OrderHandler) executeAllTasks(c *gin.Context, req *ExecuteTaskRequest, username string, userID uint) {
+	orderID := req.OrderID
+
 	// 获取工单信息
 	order, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), orderID)
 	if err != nil {
@@ -1835,11 +2602,28 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 		return
 	}
 
+	// 获取工单执行锁，持有锁之后再检查工单和任务状态，避免多个节点同时执行
+	lock, err := service.InsightServiceApp.AcquireOrderLock(orderID)
+	if err != nil {
+		api.HandleError(c, http.StatusConflict, err, nil)
+		return
+	}
+	started := false
+	defer func() {
+		if !started {
+			lock.Release()
+		}
+	}()
+
 	// 检查执行权限和工单状态
 	if err := h.checkOrderStatus(c.Request.Context(), orderID, username, userID); err != nil {
 		api.HandleError(c, http.StatusForbidden, err, nil)
 		return
 	}
+	if err := service.InsightServiceApp.EnsureOrderExportKey(c.Request.Context(), &order.OrderRecord); err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
 
 	// 检查是否有任务正在执行中
 	noExecutingTasks, err := service.InsightServiceApp.CheckTasksProgressIsDoing(c.Request.Context(), orderID)
@@ -1863,6 +2647,17 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 		return
 	}
 
+	// 检查是否有执行结果待确认的任务
+	noUnknownTasks, err := service.InsightServiceApp.CheckTasksProgressIsUnknown(c.Request.Context(), orderID)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	if !noUnknownTasks {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "当前有任务执行结果待确认，请先人工确认并更新任务状态")
+		return
+	}
+
 	// 获取工单的所有任务
 	tasks, err := service.InsightServiceApp.GetOrderTasks(c.Request.Context(), orderID)
 	if err != nil {
@@ -1878,6 +2673,11 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 		return
 	}
 
+	// 检查维护窗口
+	if !h.checkMaintenanceWindow(c, &order.OrderRecord, req, username, userID) {
+		return
+	}
+
 	// 更新工单状态为执行中
 	_ = service.InsightServiceApp.UpdateOrderProgress(c.Request.Context(), orderID, insight.ProgressExecuting)
 
@@ -1896,10 +2696,13 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 		"task_count": len(tasks),
 	})
 
-	// 在后台 goroutine 中异步执行所有任务
+	// 在后台 goroutine 中异步执行所有任务，执行结束后释放执行锁
 	// 使用独立的 context，不依赖 HTTP 请求的 context（避免 HTTP 超时导致执行中断）
+	started = true
 	go func() {
+		defer lock.Release()
 		ctx := context.Background() // 使用独立的 context
+		fence := lock.Fence()
 
 		var executedCount, successCount, failCount int
 		var typeResult string
@@ -1921,10 +2724,26 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 				continue
 			}
 
+			// 执行锁丢失（续期失败或被其他节点接管）后不再执行后续任务
+			if err := lock.Err(); err != nil {
+				global.Logger.Error("执行锁已丢失，停止执行后续任务",
+					zap.String("order_id", orderID),
+					zap.Error(err),
+				)
+				break
+			}
+
 			executedCount++
 
 			// 更新任务状态为执行中
-			_ = service.InsightServiceApp.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressExecuting, nil)
+			if err := service.InsightServiceApp.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressExecuting, nil); err != nil {
+				global.Logger.Error("更新任务状态失败，停止执行",
+					zap.String("task_id", task.TaskID.String()),
+					zap.String("order_id", orderID),
+					zap.Error(err),
+				)
+				return
+			}
 
 			// 创建执行器配置
 			global.Logger.Info("创建执行器配置（批量执行）",
@@ -1932,20 +2751,7 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 				zap.Bool("ghost_ok_to_drop_table", order.GhostOkToDropTable),
 				zap.String("sql_type", string(task.SQLType)),
 			)
-			execConfig := &executor.DBConfig{
-				Hostname:           dbConfig.Hostname,
-				Port:               dbConfig.Port,
-				UserName:           dbConfig.UserName,
-				Password:           dbConfig.Password,
-				Schema:             order.Schema,
-				DBType:             string(dbConfig.DbType),
-				SQLType:            string(task.SQLType),
-				SQL:                task.SQL,
-				OrderID:            order.OrderID.String(),
-				TaskID:             task.TaskID.String(),
-				ExportFileFormat:   string(order.ExportFileFormat),
-				GhostOkToDropTable: order.GhostOkToDropTable,
-			}
+			execConfig := service.NewExecutorConfig(&order.OrderRecord, &task, dbConfig)
 
 			// 创建执行器
 			exec, err := executor.NewExecuteSQL(execConfig)
@@ -1957,7 +2763,7 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 					zap.Error(err),
 				)
 				resultJSON, _ := json.Marshal(map[string]interface{}{"error": err.Error()})
-				_ = service.InsightServiceApp.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressFailed, resultJSON)
+				_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressFailed, resultJSON)
 				_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
 					Username: username,
 					OrderID:  order.OrderID,
@@ -1971,10 +2777,20 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 				zap.String("task_id", task.TaskID.String()),
 				zap.String("order_id", orderID),
 			)
-			result, err := exec.Run()
+			result, err := exec.Run(lock.Context()) // 执行锁丢失时中断执行，任务标记为待确认
 
 			// 保存执行结果
-			resultJSON, _ := json.Marshal(result)
+			resultJSON := service.InsightServiceApp.MarshalTaskResult(ctx, task.TaskID.String(), result)
+			if errors.Is(err, executor.ErrTaskCancelled) {
+				// 任务被取消，停止执行后续任务
+				_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressCancelled, resultJSON)
+				break
+			}
+			if errors.Is(err, lease.ErrLost) {
+				// 执行锁丢失，执行结果待确认，停止执行后续任务
+				service.InsightServiceApp.MarkTaskLeaseLost(ctx, order.OrderID, task.TaskID.String(), fence, resultJSON)
+				break
+			}
 			if err != nil {
 				failCount++
 				global.Logger.Error("Task execution failed",
@@ -1982,7 +2798,7 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 					zap.String("order_id", orderID),
 					zap.Error(err),
 				)
-				_ = service.InsightServiceApp.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressFailed, resultJSON)
+				_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressFailed, resultJSON)
 				_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
 					Username: username,
 					OrderID:  order.OrderID,
@@ -1995,7 +2811,7 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 					zap.String("order_id", orderID),
 					zap.Int64("affected_rows", result.AffectedRows),
 				)
-				_ = service.InsightServiceApp.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressCompleted, resultJSON)
+				_ = service.InsightServiceApp.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressCompleted, resultJSON)
 				_ = service.InsightServiceApp.CreateOpLog(ctx, &insight.OrderOpLog{
 					Username: username,
 					OrderID:  order.OrderID,
@@ -2033,13 +2849,15 @@ func (h *OrderHandler) executeAllTasks(c *gin.Context, orderID string, username
 
 // ControlGhostRequest gh-ost 控制请求
 type ControlGhostRequest struct {
-	OrderID string `json:"order_id" binding:"required"` // 工单ID
-	Action  string `json:"action" binding:"required"`   // 操作类型：throttle(暂停), unthrottle(恢复), panic(取消), chunk-size(调节速度)
-	Value   *int   `json:"value,omitempty"`             // 操作值（仅用于 chunk-size，表示新的 chunk-size 值）
+	OrderID string   `json:"order_id" binding:"required"` // 工单ID
+	Action  string   `json:"action" binding:"required"`   // 操作类型：throttle(暂停), unthrottle(恢复), panic(取消), chunk-size(调节速度), max-lag-millis(最大延迟), nice-ratio(休眠比例), cut-over(执行切换)
+	Value   *float64 `json:"value,omitempty"`             // 操作值（chunk-size/max-lag-millis 为正整数，nice-ratio 为非负小数）
+	// 计划 cut-over 时间（仅用于 cut-over，格式 2006-01-02 15:04:05；为空表示立即执行）
+	CutOverTime string `json:"cut_over_time,omitempty"`
 }
 
-// ControlGhost 控制 gh-ost 执行
-// @Summary 控制 gh-ost 执行（暂停/取消/速度调节）
+// ControlGhost 控制 gh-ost 执行（TiDB 工单控制正在执行的 DDL Job）
+// @Summary 控制 gh-ost 执行（暂停/取消/速度调节/cut-over）
 // @Tags 工单管理
 // @Security Bearer
 // @Accept json
@@ -2056,20 +2874,50 @@ func (h *OrderHandler) ControlGhost(c *gin.Context) {
 
 	// 验证操作类型
 	validActions := map[string]bool{
-		"throttle":   true, // 暂停
-		"unthrottle": true, // 恢复
-		"panic":      true, // 取消
-		"chunk-size": true, // 调节速度
+		"throttle":       true, // 暂停
+		"unthrottle":     true, // 恢复
+		"panic":          true, // 取消
+		"chunk-size":     true, // 调节速度
+		"max-lag-millis": true, // 调节最大延迟
+		"nice-ratio":     true, // 调节休眠比例
+		"cut-over":       true, // 执行（或计划执行）被推迟的 cut-over
 	}
 	if !validActions[req.Action] {
-		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "不支持的操作类型，支持的操作：throttle(暂停), unthrottle(恢复), panic(取消), chunk-size(调节速度)")
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "不支持的操作类型，支持的操作：throttle(暂停), unthrottle(恢复), panic(取消), chunk-size(调节速度), max-lag-millis(最大延迟), nice-ratio(休眠比例), cut-over(执行切换)")
+		return
+	}
+
+	// chunk-size/max-lag-millis 操作需要提供正整数 value，nice-ratio 需要提供非负 value
+	switch req.Action {
+	case "chunk-size", "max-lag-millis":
+		if req.Value == nil || *req.Value <= 0 || *req.Value != float64(int64(*req.Value)) {
+			api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, fmt.Sprintf("%s 操作需要提供有效的 value 值（正整数）", req.Action))
+			return
+		}
+	case "nice-ratio":
+		if req.Value == nil || *req.Value < 0 {
+			api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "nice-ratio 操作需要提供有效的 value 值（大于等于 0）")
+			return
+		}
+	}
+
+	// TiDB 工单没有 gh-ost，控制命令转换为 ADMIN PAUSE/RESUME/CANCEL DDL JOBS
+	if jobID, err := utils.GetTiDBDDLJobID(req.OrderID); err == nil {
+		h.controlTiDBDDL(c, req.OrderID, jobID, req.Action)
 		return
 	}
 
-	// chunk-size 操作需要提供 value
-	if req.Action == "chunk-size" {
-		if req.Value == nil || *req.Value <= 0 {
-			api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "chunk-size 操作需要提供有效的 value 值（大于 0）")
+	// 计划 cut-over 时间
+	var cutOverTime time.Time
+	if req.Action == "cut-over" && req.CutOverTime != "" {
+		var err error
+		cutOverTime, err = time.ParseInLocation("2006-01-02 15:04:05", req.CutOverTime, time.Local)
+		if err != nil {
+			api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "cut_over_time 格式错误，应为 2006-01-02 15:04:05")
+			return
+		}
+		if !cutOverTime.After(time.Now()) {
+			api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "cut_over_time 必须晚于当前时间")
 			return
 		}
 	}
@@ -2145,12 +2993,37 @@ func (h *OrderHandler) ControlGhost(c *gin.Context) {
 	case "panic":
 		command = "panic"
 	case "chunk-size":
-		command = fmt.Sprintf("chunk-size=%d", *req.Value)
+		command = fmt.Sprintf("chunk-size=%d", int64(*req.Value))
+	case "max-lag-millis":
+		command = fmt.Sprintf("max-lag-millis=%d", int64(*req.Value))
+	case "nice-ratio":
+		command = fmt.Sprintf("nice-ratio=%g", *req.Value)
+	case "cut-over":
+		command = "unpostpone"
 	default:
 		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "不支持的操作类型")
 		return
 	}
 
+	// 计划 cut-over：保存计划时间，由执行器轮询 gh-ost 状态时到期触发
+	if !cutOverTime.IsZero() {
+		if err := utils.SetGhostCutOverTime(req.OrderID, cutOverTime); err != nil {
+			api.HandleError(c, http.StatusInternalServerError, err, nil)
+			return
+		}
+		message := fmt.Sprintf("gh-ost 已计划于 %s 执行 cut-over", cutOverTime.Format("2006-01-02 15:04:05"))
+		if err := utils.PublishMessageToChannel(req.OrderID, message, "ghost"); err != nil {
+			global.Logger.Warn("Failed to publish ghost control message",
+				zap.String("order_id", req.OrderID),
+				zap.Error(err),
+			)
+		}
+		api.HandleSuccess(c, gin.H{
+			"message": message,
+		})
+		return
+	}
+
 	// 发送命令给 gh-ost
 	if err := utils.GhostControl(socketPath, command); err != nil {
 		global.Logger.Error("Failed to control gh-ost",
@@ -2165,8 +3038,12 @@ func (h *OrderHandler) ControlGhost(c *gin.Context) {
 
 	// 推送消息到 WebSocket
 	message := fmt.Sprintf("gh-ost 控制命令已发送：%s", command)
-	if req.Action == "chunk-size" {
-		message = fmt.Sprintf("gh-ost 速度已调节：chunk-size=%d", *req.Value)
+	switch req.Action {
+	case "chunk-size":
+		message = fmt.Sprintf("gh-ost 速度已调节：chunk-size=%d", int64(*req.Value))
+	case "cut-over":
+		message = "gh-ost cut-over 命令已发送"
+		utils.DeleteGhostCutOverTime(req.OrderID)
 	}
 	if err := utils.PublishMessageToChannel(req.OrderID, message, "ghost"); err != nil {
 		global.Logger.Warn("Failed to publish ghost control message",
@@ -2180,6 +3057,55 @@ func (h *OrderHandler) ControlGhost(c *gin.Context) {
 	})
 }
 
+// controlTiDBDDL 控制 TiDB DDL Job：throttle 暂停、unthrottle 恢复、panic 取消
+func (h *OrderHandler) controlTiDBDDL(c *gin.Context, orderID string, jobID int64, action string) {
+	var jobAction string
+	switch action {
+	case "throttle":
+		jobAction = executor.TiDBDDLActionPause
+	case "unthrottle":
+		jobAction = executor.TiDBDDLActionResume
+	case "panic":
+		jobAction = executor.TiDBDDLActionCancel
+	default:
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, "TiDB DDL 仅支持 throttle(暂停), unthrottle(恢复), panic(取消)")
+		return
+	}
+
+	order, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), orderID)
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	dbConfig, err := service.InsightServiceApp.GetDBConfigByInstanceID(c.Request.Context(), order.InstanceID.String())
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	execConfig := service.NewExecutorConfig(&order.OrderRecord, &insight.OrderTask{}, dbConfig)
+	if err := executor.ControlTiDBDDLJob(c.Request.Context(), execConfig, jobID, jobAction); err != nil {
+		global.Logger.Error("Failed to control TiDB DDL job",
+			zap.String("order_id", orderID),
+			zap.Int64("job_id", jobID),
+			zap.String("action", jobAction),
+			zap.Error(err),
+		)
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	message := fmt.Sprintf("TiDB DDL Job %d 已执行 ADMIN %s DDL JOBS", jobID, jobAction)
+	if err := utils.PublishMessageToChannel(orderID, message, "ghost"); err != nil {
+		global.Logger.Warn("Failed to publish TiDB DDL control message",
+			zap.String("order_id", orderID),
+			zap.Error(err),
+		)
+	}
+	api.HandleSuccess(c, gin.H{
+		"message": message,
+	})
+}
+
 // GetOrderTables 获取工单场景的表列表（不检查 DAS 查询权限）
 // @Summary 获取工单表列表
 // @Tags 工单管理
@@ -2211,12 +3137,14 @@ func (h *OrderHandler) GetOrderTables(c *gin.Context) {
 	defer cancel()
 
 	db := &dao.MySQLDB{
-		User:     config.UserName,
-		Password: config.Password,
-		Host:     config.Hostname,
-		Port:     config.Port,
-		Ctx:      ctx,
-		Params:   map[string]string{"group_concat_max_len": "4194304"},
+		InstanceID:  config.InstanceID.String(),
+		User:        config.UserName,
+		Password:    config.Password,
+		Host:        config.Hostname,
+		Port:        config.Port,
+		Ctx:         ctx,
+		Params:      map[string]string{"group_concat_max_len": "4194304"},
+		ConnOptions: config.ConnOptions(),
 	}
 
 	// 直接获取所有表，不检查用户的 DAS 查询权限
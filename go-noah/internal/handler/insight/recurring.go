@@ -0,0 +1,148 @@
+package insight
+
+import (
+	"go-noah/api"
+	"go-noah/internal/handler"
+	"go-noah/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecurringHandlerApp 全局 Handler 实例
+var RecurringHandlerApp = new(RecurringHandler)
+
+// RecurringHandler 周期工单Handler
+type RecurringHandler struct{}
+
+// GetRecurringOrdersRequest 获取周期工单列表请求
+type GetRecurringOrdersRequest struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"size"`
+	State    string `form:"state"` // active/suspended/expired
+}
+
+// GetRecurringOrders 获取周期工单列表
+// @Summary 获取周期工单列表
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Param state query string false "状态（active/suspended/expired）"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/recurring-orders [get]
+func (h *RecurringHandler) GetRecurringOrders(c *gin.Context) {
+	var req GetRecurringOrdersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	schedules, total, err := service.InsightServiceApp.GetRecurringSchedules(c.Request.Context(), req.Page, req.PageSize, req.State)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	api.HandleSuccess(c, gin.H{
+		"list":  schedules,
+		"total": total,
+	})
+}
+
+// GetRecurringRuns 获取周期工单的执行记录
+// @Summary 获取周期工单的执行记录
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param order_id path string true "工单ID"
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/recurring-orders/{order_id}/runs [get]
+func (h *RecurringHandler) GetRecurringRuns(c *gin.Context) {
+	var req GetRecurringOrdersRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return
+	}
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	orderID := c.Param("order_id")
+	schedule, err := service.InsightServiceApp.GetRecurringSchedule(c.Request.Context(), orderID)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	if schedule == nil {
+		api.HandleError(c, http.StatusNotFound, api.ErrNotFound, "工单不是周期工单")
+		return
+	}
+	runs, total, err := service.InsightServiceApp.GetRecurringRuns(c.Request.Context(), orderID, req.Page, req.PageSize)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+	api.HandleSuccess(c, gin.H{
+		"schedule": schedule,
+		"list":     runs,
+		"total":    total,
+	})
+}
+
+// SuspendRecurringOrder 暂停周期工单
+// @Summary 暂停周期工单
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param order_id path string true "工单ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/recurring-orders/{order_id}/suspend [post]
+func (h *RecurringHandler) SuspendRecurringOrder(c *gin.Context) {
+	if err := service.InsightServiceApp.SuspendRecurringOrder(c.Request.Context(), c.Param("order_id"), h.currentUsername(c)); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	api.HandleSuccess(c, nil)
+}
+
+// ResumeRecurringOrder 恢复已暂停的周期工单
+// @Summary 恢复已暂停的周期工单
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param order_id path string true "工单ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/recurring-orders/{order_id}/resume [post]
+func (h *RecurringHandler) ResumeRecurringOrder(c *gin.Context) {
+	if err := service.InsightServiceApp.ResumeRecurringOrder(c.Request.Context(), c.Param("order_id"), h.currentUsername(c)); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	api.HandleSuccess(c, nil)
+}
+
+// currentUsername 获取当前用户名
+func (h *RecurringHandler) currentUsername(c *gin.Context) string {
+	if userId := handler.GetUserIdFromCtx(c); userId > 0 {
+		if user, err := service.AdminServiceApp.GetAdminUser(c, userId); err == nil {
+			return user.Username
+		}
+	}
+	return ""
+}
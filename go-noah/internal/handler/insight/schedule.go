@@ -0,0 +1,163 @@
+package insight
+
+import (
+	"go-noah/api"
+	"go-noah/internal/handler"
+	"go-noah/internal/model/insight"
+	insightRepo "go-noah/internal/repository/insight"
+	"go-noah/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScheduleHandlerApp 全局 Handler 实例
+var ScheduleHandlerApp = new(ScheduleHandler)
+
+// ScheduleHandler 工单定时执行Handler
+type ScheduleHandler struct{}
+
+// GetScheduledExecutionsRequest 获取定时执行记录请求
+type GetScheduledExecutionsRequest struct {
+	Page     int    `form:"page"`
+	PageSize int    `form:"size"`
+	State    string `form:"state"`    // 为空时返回未结束的记录（等待执行、执行中、等待确认）
+	OrderID  string `form:"order_id"` // 按工单过滤（此时返回全部状态的记录）
+}
+
+// GetScheduledExecutions 获取定时执行记录列表
+// @Summary 获取定时执行记录列表
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param page query int false "页码"
+// @Param size query int false "每页数量"
+// @Param state query string false "状态（pending/running/asking/succeeded/failed/skipped/cancelled）"
+// @Param order_id query string false "工单ID"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/scheduled-executions [get]
+func (h *ScheduleHandler) GetScheduledExecutions(c *gin.Context) {
+	var req GetScheduledExecutionsRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return
+	}
+
+	// 设置默认分页值
+	if req.Page <= 0 {
+		req.Page = 1
+	}
+	if req.PageSize <= 0 {
+		req.PageSize = 10
+	}
+
+	params := &insightRepo.ScheduledExecutionQueryParams{
+		Page:     req.Page,
+		PageSize: req.PageSize,
+		State:    req.State,
+		OrderID:  req.OrderID,
+		Upcoming: req.OrderID == "",
+	}
+	// DBA 查看全部记录，其他用户只能查看自己可见的工单的记录
+	userId := handler.GetUserIdFromCtx(c)
+	if !service.InsightServiceApp.IsDBA(userId) {
+		user, err := service.AdminServiceApp.GetAdminUser(c, userId)
+		if err != nil {
+			api.HandleError(c, http.StatusUnauthorized, api.ErrUnauthorized, nil)
+			return
+		}
+		params.Viewer = user.Username
+	}
+	executions, total, err := service.InsightServiceApp.GetScheduledExecutions(c.Request.Context(), params)
+	if err != nil {
+		api.HandleError(c, http.StatusInternalServerError, err, nil)
+		return
+	}
+
+	api.HandleSuccess(c, gin.H{
+		"list":  executions,
+		"total": total,
+	})
+}
+
+// ConfirmScheduledExecutionRequest 确认错过计划时间的定时执行请求
+type ConfirmScheduledExecutionRequest struct {
+	Action string `json:"action" binding:"required,oneof=run skip"` // run: 继续执行；skip: 跳过执行
+}
+
+// ConfirmScheduledExecution 确认错过计划时间的定时执行
+// @Summary 确认错过计划时间的定时执行
+// @Tags 工单管理
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path int true "定时执行记录ID"
+// @Param request body ConfirmScheduledExecutionRequest true "确认操作"
+// @Success 200 {object} api.Response
+// @Router /api/v1/insight/scheduled-executions/{id}/confirm [post]
+func (h *ScheduleHandler) ConfirmScheduledExecution(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		api.HandleError(c, http.StatusBadRequest, api.ErrBadRequest, nil)
+		return
+	}
+	var req ConfirmScheduledExecutionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+
+	userId := handler.GetUserIdFromCtx(c)
+	username := ""
+	if userId > 0 {
+		if user, err := service.AdminServiceApp.GetAdminUser(c, userId); err == nil {
+			username = user.Username
+		}
+	}
+
+	// 与执行工单相同，只有 DBA 和工单的申请人、审核人、执行人可以确认
+	current, err := service.InsightServiceApp.GetScheduledExecution(c.Request.Context(), uint(id))
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	order, err := service.InsightServiceApp.GetOrderByID(c.Request.Context(), current.OrderID.String())
+	if err != nil {
+		api.HandleError(c, http.StatusNotFound, err, nil)
+		return
+	}
+	if !canOperateOrder(&order.OrderRecord, userId, username) {
+		api.HandleError(c, http.StatusForbidden, api.ErrForbidden, nil)
+		return
+	}
+
+	execution, err := service.InsightServiceApp.ConfirmScheduledExecution(c.Request.Context(), uint(id), req.Action == "run", username)
+	if err != nil {
+		api.HandleError(c, http.StatusBadRequest, err, nil)
+		return
+	}
+	api.HandleSuccess(c, execution)
+}
+
+// canOperateOrder 用户是否可以操作工单（DBA，或工单的申请人、审核人、执行人）
+func canOperateOrder(order *insight.OrderRecord, userID uint, username string) bool {
+	if service.InsightServiceApp.IsDBA(userID) {
+		return true
+	}
+	if username == "" {
+		return false
+	}
+	if order.Applicant == username {
+		return true
+	}
+	for _, users := range [][]string{unmarshalUsers(order.Approver), unmarshalUsers(order.Executor)} {
+		for _, user := range users {
+			if user == username {
+				return true
+			}
+		}
+	}
+	return false
+}
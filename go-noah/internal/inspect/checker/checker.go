@@ -7,6 +7,7 @@ import (
 	"go-noah/internal/inspect/config"
 	"go-noah/internal/inspect/dao"
 	"go-noah/internal/inspect/parser"
+	"go-noah/pkg/dbconn"
 	"go-noah/pkg/global"
 	"go-noah/pkg/kv"
 	"go-noah/pkg/query"
@@ -42,11 +43,13 @@ type Checker struct {
 	Params     *config.InspectParams // 审核参数
 	DBType     string                // 数据库类型
 	Results    []*AuditResult        // 审核结果
+	DBInstance string                // 实例ID（用于复用实例连接池）
 	DBHost     string                // 数据库主机
 	DBPort     int                   // 数据库端口
 	DBUser     string                // 数据库用户
 	DBPassword string                // 数据库密码
 	DBSchema   string                // 数据库名
+	DBConnOpts dbconn.Options        // 连接选项（SSH 跳板机、TLS）
 }
 
 // NewChecker 创建审核器
@@ -62,7 +65,8 @@ func NewChecker(params *config.InspectParams, dbType string) *Checker {
 }
 
 // SetDBInfo 设置数据库连接信息
-func (c *Checker) SetDBInfo(host string, port int, user, password, schema string) {
+func (c *Checker) SetDBInfo(instanceID, host string, port int, user, password, schema string) {
+	c.DBInstance = instanceID
 	c.DBHost = host
 	c.DBPort = port
 	c.DBUser = user
@@ -74,11 +78,13 @@ func (c *Checker) SetDBInfo(host string, port int, user, password, schema string
 func (c *Checker) Check(sqlText string) ([]*AuditResult, error) {
 	// 初始化数据库连接
 	db := &dao.DB{
-		User:     c.DBUser,
-		Password: c.DBPassword,
-		Host:     c.DBHost,
-		Port:     c.DBPort,
-		Database: c.DBSchema,
+		InstanceID:  c.DBInstance,
+		User:        c.DBUser,
+		Password:    c.DBPassword,
+		Host:        c.DBHost,
+		Port:        c.DBPort,
+		Database:    c.DBSchema,
+		ConnOptions: c.DBConnOpts,
 	}
 
 	// 创建 KVCache（使用简单ID，因为没有 gin.Context）
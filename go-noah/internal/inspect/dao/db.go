@@ -1,6 +1,7 @@
 package dao
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -9,7 +10,10 @@ import (
 	"time"
 
 	"go-noah/internal/inspect/parser"
+	"go-noah/pkg/dbconn"
+	"go-noah/pkg/dbpool"
 	"go-noah/pkg/kv"
+	"go-noah/pkg/secret"
 	"go-noah/pkg/utils"
 
 	"github.com/go-sql-driver/mysql"
@@ -18,24 +22,41 @@ import (
 
 // DB 数据库连接结构
 type DB struct {
-	User     string
-	Password string
-	Host     string
-	Port     int
-	Database string
+	InstanceID  string // 实例ID，用于复用实例连接池
+	User        string
+	Password    string
+	Host        string
+	Port        int
+	Database    string
+	ConnOptions dbconn.Options // SSH 跳板机、TLS
 }
 
-// Open 打开数据库连接
-func (d *DB) Open() (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local&timeout=3s&readTimeout=3s&writeTimeout=3s",
-		d.User, d.Password, d.Host, d.Port, d.Database)
-	db, err := sql.Open("mysql", dsn)
+// Open 从实例连接池获取连接，使用完成后需要 Close 归还
+func (d *DB) Open() (*dbpool.Conn, error) {
+	password, err := secret.Decrypt(d.Password)
 	if err != nil {
+		return nil, fmt.Errorf("解密实例密码失败: %w", err)
+	}
+	config := mysql.NewConfig()
+	config.User = d.User
+	config.Passwd = password
+	config.Net = "tcp"
+	config.Addr = fmt.Sprintf("%s:%d", d.Host, d.Port)
+	config.ParseTime = true
+	config.Loc = time.Local
+	config.Timeout = 3 * time.Second
+	config.ReadTimeout = 3 * time.Second
+	config.WriteTimeout = 3 * time.Second
+	config.Params = map[string]string{"charset": "utf8mb4"}
+	if err := d.ConnOptions.ApplyMySQL(config); err != nil {
 		return nil, err
 	}
-	db.SetMaxOpenConns(1)
-	db.SetConnMaxLifetime(5 * time.Second)
-	return db, nil
+	return dbpool.Get(context.Background(), dbpool.Spec{
+		InstanceID: d.InstanceID,
+		Role:       dbpool.RoleInspect,
+		DSN:        config.FormatDSN(),
+		Database:   d.Database,
+	})
 }
 
 // Execute 执行SQL语句（不返回结果）
@@ -46,7 +67,7 @@ func (d *DB) Execute(query string) error {
 	}
 	defer db.Close()
 
-	_, err = db.Exec(query)
+	_, err = db.ExecContext(context.Background(), query)
 	return err
 }
 
@@ -59,7 +80,7 @@ func (d *DB) Query(query string) (*[]map[string]interface{}, error) {
 	defer db.Close()
 
 	// 执行查询
-	rows, err := db.Query(query)
+	rows, err := db.QueryContext(context.Background(), query)
 	if err != nil {
 		return nil, err
 	}
@@ -114,14 +135,9 @@ func (d *DB) Query(query string) (*[]map[string]interface{}, error) {
 // - 如果数据库不存在: ("数据库`xxx`不存在", error)
 // - 如果连接失败: ("", error)
 func (d *DB) CheckIfTableExists(table string) (string, error) {
+	// 获取连接时会切换到目标库，据此捕获数据库不存在的错误
 	db, err := d.Open()
 	if err != nil {
-		return "", fmt.Errorf("数据库连接失败: %w", err)
-	}
-	defer db.Close()
-
-	// 尝试连接（Ping）来捕获数据库不存在的错误
-	if err := db.Ping(); err != nil {
 		var mysqlErr *mysql.MySQLError
 		if errors.As(err, &mysqlErr) {
 			// MySQL error 1049: Unknown database
@@ -137,10 +153,11 @@ func (d *DB) CheckIfTableExists(table string) (string, error) {
 		// 其他连接错误，静默失败（不阻止审核）
 		return "", err
 	}
+	defer db.Close()
 
 	// 连接成功，检查表是否存在
 	query := fmt.Sprintf("DESC `%s`", table)
-	_, err = db.Exec(query)
+	_, err = db.ExecContext(context.Background(), query)
 	if err != nil {
 		// 检查是否是表不存在的错误 (MySQL error 1146)
 		var mysqlErr *mysql.MySQLError
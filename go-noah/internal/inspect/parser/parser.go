@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pingcap/tidb/pkg/parser"
 	"github.com/pingcap/tidb/pkg/parser/ast"
@@ -165,6 +166,61 @@ func SplitSQLText(sqltext string) ([]string, error) {
 	return sqls, nil
 }
 
+// SplitSQLTextByDelimiter 按分号拆分SQL文本（不依赖TiDB语法解析，用于ClickHouse等方言）
+// 忽略引号、反引号以及注释中的分号
+func SplitSQLTextByDelimiter(sqltext string) []string {
+	var (
+		sqls    []string
+		current strings.Builder
+		quote   rune
+	)
+	runes := []rune(sqltext)
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" {
+			sqls = append(sqls, stmt)
+		}
+		current.Reset()
+	}
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			current.WriteRune(c)
+			if c == '\\' && i+1 < len(runes) {
+				i++
+				current.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			current.WriteRune(c)
+		case c == '-' && i+1 < len(runes) && runes[i+1] == '-', c == '#':
+			// 单行注释
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+			current.WriteRune('\n')
+		case c == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			// 多行注释
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+			current.WriteRune(' ')
+		case c == ';':
+			flush()
+		default:
+			current.WriteRune(c)
+		}
+	}
+	flush()
+	return sqls
+}
+
 // GetSqlStatement 获取SQL语句类型（用于执行器判断执行方式）
 // 返回：CreateDatabase, CreateTable, CreateView, DropTable, DropIndex, TruncateTable,
 //      RenameTable, CreateIndex, DropDatabase, AlterTable 等
@@ -67,7 +67,7 @@ type FlowDefinition struct {
 	gorm.Model
 	Code        string         `gorm:"type:varchar(50);uniqueIndex;comment:'流程编码'" json:"code"`
 	Name        string         `gorm:"type:varchar(100);not null;comment:'流程名称'" json:"name"`
-	Type        string         `gorm:"type:varchar(50);index;comment:'业务类型:order_ddl,order_dml,order_export'" json:"type"`
+	Type        string         `gorm:"type:varchar(50);index;comment:'业务类型:order_ddl,order_dml,order_export,order_archive,order_migrate'" json:"type"`
 	Description string         `gorm:"type:varchar(500);comment:'流程描述'" json:"description"`
 	Version     int            `gorm:"default:1;comment:'版本号'" json:"version"`
 	Status      int8           `gorm:"default:1;comment:'状态:1启用,0禁用'" json:"status"`
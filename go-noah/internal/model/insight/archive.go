@@ -0,0 +1,51 @@
+package insight
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ArchiveMode 归档目标类型
+type ArchiveMode string
+
+const (
+	ArchiveModeTable ArchiveMode = "table" // 归档到表（同实例或其他实例）
+	ArchiveModeFile  ArchiveMode = "file"  // 归档到压缩文件
+)
+
+// ArchiveOptions 归档工单选项（工单内容为 SELECT * FROM 源表 WHERE 归档条件，每条语句生成一个任务）
+type ArchiveOptions struct {
+	Mode             ArchiveMode `json:"mode"`               // 归档目标类型（table/file）
+	TargetInstanceID string      `json:"target_instance_id"` // 目标实例（table 模式，为空时使用源实例）
+	TargetSchema     string      `json:"target_schema"`      // 目标库（table 模式，为空时使用源库）
+	TargetTable      string      `json:"target_table"`       // 目标表（table 模式，为空时使用 源表名_archive，不存在时按源表结构创建）
+	KeepSource       bool        `json:"keep_source"`        // 只复制不删除源表数据
+}
+
+// IsValidArchiveMode 是否为支持的归档目标类型
+func IsValidArchiveMode(mode string) bool {
+	switch ArchiveMode(mode) {
+	case ArchiveModeTable, ArchiveModeFile:
+		return true
+	}
+	return false
+}
+
+// ArchiveCheckpoint 归档任务的断点（每批归档完成后更新，任务中断后重新执行时从断点继续）
+type ArchiveCheckpoint struct {
+	gorm.Model
+	TaskID        uuid.UUID `gorm:"type:char(36);not null;uniqueIndex;comment:关联order_tasks的task_id" json:"task_id"`
+	OrderID       uuid.UUID `gorm:"type:char(36);not null;index;comment:关联order_records的order_id" json:"order_id"`
+	LastPK        string    `gorm:"type:varchar(255);not null;default:'';comment:已归档的最大主键" json:"last_pk"`
+	ArchivedRows  int64     `gorm:"type:bigint;not null;default:0;comment:已归档行数" json:"archived_rows"`
+	DeletedRows   int64     `gorm:"type:bigint;not null;default:0;comment:已从源表删除行数" json:"deleted_rows"`
+	FilePath      string    `gorm:"type:varchar(512);not null;default:'';comment:归档文件路径(file模式)" json:"file_path"`
+	FileOffset    int64     `gorm:"type:bigint;not null;default:0;comment:已确认的归档文件长度(file模式)" json:"file_offset"`
+	PendingPK     string    `gorm:"type:varchar(255);not null;default:'';comment:已写入文件、等待删除源数据的批次主键上界" json:"pending_pk"`
+	PendingOffset int64     `gorm:"type:bigint;not null;default:0;comment:等待删除源数据的批次写入后的文件长度" json:"pending_offset"`
+	PendingRows   int64     `gorm:"type:bigint;not null;default:0;comment:等待删除源数据的批次行数" json:"pending_rows"`
+}
+
+func (ArchiveCheckpoint) TableName() string {
+	return "archive_checkpoints"
+}
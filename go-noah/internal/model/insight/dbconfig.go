@@ -1,6 +1,9 @@
 package insight
 
 import (
+	"go-noah/pkg/dbconn"
+	"go-noah/pkg/secret"
+
 	"github.com/google/uuid"
 	"gorm.io/datatypes"
 	"gorm.io/gorm"
@@ -30,7 +33,7 @@ type DBConfig struct {
 	Hostname         string         `gorm:"type:varchar(128);not null;default:'';uniqueIndex:uniq_hostname;comment:主机名" json:"hostname"`
 	Port             int            `gorm:"type:int;not null;default:3306;uniqueIndex:uniq_hostname;comment:端口" json:"port"`
 	UserName         string         `gorm:"type:varchar(128);not null;default:'';comment:账号" json:"user_name"`
-	Password         string         `gorm:"type:varchar(256);not null;default:'';comment:密码" json:"password"`
+	Password         string         `gorm:"type:varchar(1024);not null;default:'';comment:密码(加密)" json:"password"`
 	UseType          UseType        `gorm:"type:varchar(20);default:'工单';uniqueIndex:uniq_hostname;comment:用途" json:"use_type"`
 	DbType           DbType         `gorm:"type:varchar(20);default:'MySQL';comment:数据库类型" json:"db_type"`
 	Environment      int            `gorm:"type:int;null;default:null;comment:环境;index" json:"environment"`
@@ -38,12 +41,89 @@ type DBConfig struct {
 	OrganizationKey  string         `gorm:"type:varchar(256);not null;index:organization_key;comment:搜索路径" json:"organization_key"`
 	OrganizationPath datatypes.JSON `gorm:"type:json;null;default:null;comment:绝对路径" json:"organization_path"`
 	Remark           string         `gorm:"type:varchar(256);not null;default:'';comment:备注" json:"remark"`
+	DDLEngine        string         `gorm:"type:varchar(20);not null;default:'';comment:在线DDL引擎(gh-ost/pt-osc/native/auto，空为gh-ost)" json:"ddl_engine"`
+	// 执行限流配置（工单执行时在语句/批次之间检查，0或空表示不限制）
+	ThrottleMaxReplicaLag     int    `gorm:"type:int;not null;default:0;comment:限流-最大从库延迟(秒)" json:"throttle_max_replica_lag"`
+	ThrottleMaxThreadsRunning int    `gorm:"type:int;not null;default:0;comment:限流-最大Threads_running" json:"throttle_max_threads_running"`
+	ThrottleQuery             string `gorm:"type:varchar(1024);not null;default:'';comment:限流-自定义查询(返回值大于0时限流)" json:"throttle_query"`
+	// SSH 跳板机（SSHHost 为空表示直连）
+	SSHHost       string `gorm:"type:varchar(128);not null;default:'';comment:SSH跳板机地址" json:"ssh_host"`
+	SSHPort       int    `gorm:"type:int;not null;default:22;comment:SSH跳板机端口" json:"ssh_port"`
+	SSHUser       string `gorm:"type:varchar(128);not null;default:'';comment:SSH用户" json:"ssh_user"`
+	SSHPassword   string `gorm:"type:varchar(1024);not null;default:'';comment:SSH密码(加密)" json:"ssh_password"`
+	SSHPrivateKey string `gorm:"type:text;null;comment:SSH私钥(PEM，加密)" json:"ssh_private_key"`
+	SSHPassphrase string `gorm:"type:varchar(1024);not null;default:'';comment:SSH私钥密码(加密)" json:"ssh_passphrase"`
+	SSHHostKey    string `gorm:"type:varchar(1024);not null;default:'';comment:SSH跳板机公钥" json:"ssh_host_key"`
+
+	// 不校验跳板机公钥，需显式开启
+	SSHInsecureIgnoreHostKey bool `gorm:"type:boolean;not null;default:false;comment:SSH不校验跳板机公钥" json:"ssh_insecure_ignore_host_key"`
+
+	// TLS
+	TLSEnabled    bool   `gorm:"type:boolean;not null;default:false;comment:启用TLS" json:"tls_enabled"`
+	TLSCA         string `gorm:"column:tls_ca;type:text;null;comment:TLS CA证书(PEM)" json:"tls_ca"`
+	TLSCert       string `gorm:"type:text;null;comment:TLS客户端证书(PEM)" json:"tls_cert"`
+	TLSKey        string `gorm:"type:text;null;comment:TLS客户端私钥(PEM，加密)" json:"tls_key"`
+	TLSSkipVerify bool   `gorm:"type:boolean;not null;default:false;comment:TLS跳过证书校验" json:"tls_skip_verify"`
+
+	// 本实例为其他实例的从库或沙箱时填写源实例ID，可作为源实例 DML 工单的试运行实例
+	SourceInstanceID string `gorm:"type:varchar(36);not null;default:'';index;comment:从库或沙箱所属的源实例ID" json:"source_instance_id"`
 }
 
 func (DBConfig) TableName() string {
 	return "db_configs"
 }
 
+// ConnOptions 实例连接选项（SSH 跳板机、TLS）
+func (u *DBConfig) ConnOptions() dbconn.Options {
+	var opts dbconn.Options
+	if u.SSHHost != "" {
+		opts.SSH = &dbconn.SSHOptions{
+			Host:       u.SSHHost,
+			Port:       u.SSHPort,
+			User:       u.SSHUser,
+			Password:   u.SSHPassword,
+			PrivateKey: u.SSHPrivateKey,
+			Passphrase: u.SSHPassphrase,
+			HostKey:    u.SSHHostKey,
+
+			InsecureIgnoreHostKey: u.SSHInsecureIgnoreHostKey,
+		}
+	}
+	if u.TLSEnabled {
+		opts.TLS = &dbconn.TLSOptions{
+			CA:         u.TLSCA,
+			Cert:       u.TLSCert,
+			Key:        u.TLSKey,
+			SkipVerify: u.TLSSkipVerify,
+		}
+	}
+	return opts
+}
+
+// DBConfigSecretColumns 加密存储的列（密码、SSH 凭据、TLS 私钥）
+var DBConfigSecretColumns = []string{"password", "ssh_password", "ssh_private_key", "ssh_passphrase", "tls_key"}
+
+// SecretFields 加密存储的字段，与 DBConfigSecretColumns 一一对应
+func (u *DBConfig) SecretFields() []*string {
+	return []*string{&u.Password, &u.SSHPassword, &u.SSHPrivateKey, &u.SSHPassphrase, &u.TLSKey}
+}
+
+// EncryptSecrets 加密密码、SSH 凭据和 TLS 私钥（已加密的字段不重复加密），保存前调用
+// 解密在建立连接时进行，见 secret.Decrypt
+func (u *DBConfig) EncryptSecrets() error {
+	for _, field := range u.SecretFields() {
+		if *field == "" || secret.IsEncrypted(*field) {
+			continue
+		}
+		encrypted, err := secret.Encrypt(*field)
+		if err != nil {
+			return err
+		}
+		*field = encrypted
+	}
+	return nil
+}
+
 func (u *DBConfig) BeforeCreate(tx *gorm.DB) (err error) {
 	u.InstanceID, _ = uuid.NewUUID()
 	return
@@ -60,4 +140,3 @@ type DBSchema struct {
 func (DBSchema) TableName() string {
 	return "db_schemas"
 }
-
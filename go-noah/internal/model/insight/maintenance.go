@@ -0,0 +1,35 @@
+package insight
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// MaintenanceType 维护窗口类型
+type MaintenanceType string
+
+const (
+	MaintenanceTypeWeekly MaintenanceType = "weekly" // 每周重复的可执行窗口
+	MaintenanceTypeFreeze MaintenanceType = "freeze" // 冻结期（期间禁止执行）
+)
+
+// MaintenanceWindow 工单执行维护窗口（按环境配置，可指定实例）
+// 配置了每周窗口时只能在窗口内执行，实例级别的每周窗口优先于环境级别；冻结期对环境和实例同时生效
+type MaintenanceWindow struct {
+	gorm.Model
+	Environment int             `gorm:"type:int;not null;index:idx_maintenance_scope;comment:环境ID" json:"environment"`
+	InstanceID  string          `gorm:"type:varchar(36);not null;default:'';index:idx_maintenance_scope;comment:实例ID(为空表示整个环境)" json:"instance_id"`
+	Type        MaintenanceType `gorm:"type:varchar(20);not null;default:'weekly';comment:类型(weekly/freeze)" json:"type"`
+	Weekdays    string          `gorm:"type:varchar(32);not null;default:'';comment:每周窗口-星期(0-6，0为周日，逗号分隔)" json:"weekdays"`
+	StartTime   string          `gorm:"type:varchar(5);not null;default:'';comment:每周窗口-开始时间(HH:MM)" json:"start_time"`
+	EndTime     string          `gorm:"type:varchar(5);not null;default:'';comment:每周窗口-结束时间(HH:MM，不大于开始时间表示跨天)" json:"end_time"`
+	FreezeStart *time.Time      `gorm:"type:datetime;null;default:null;comment:冻结期开始时间" json:"freeze_start"`
+	FreezeEnd   *time.Time      `gorm:"type:datetime;null;default:null;comment:冻结期结束时间" json:"freeze_end"`
+	Remark      string          `gorm:"type:varchar(256);not null;default:'';comment:备注" json:"remark"`
+	CreatedBy   string          `gorm:"type:varchar(64);not null;default:'';comment:创建人" json:"created_by"`
+}
+
+func (MaintenanceWindow) TableName() string {
+	return "maintenance_windows"
+}
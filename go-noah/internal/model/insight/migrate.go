@@ -0,0 +1,31 @@
+package insight
+
+// ConflictStrategy 数据迁移的主键冲突处理策略
+type ConflictStrategy string
+
+const (
+	ConflictSkip    ConflictStrategy = "skip"    // 保留目标表中已存在的行
+	ConflictReplace ConflictStrategy = "replace" // 使用源数据覆盖目标表中已存在的行
+	ConflictFail    ConflictStrategy = "fail"    // 出现主键冲突时停止迁移
+)
+
+// IsValidConflictStrategy 是否为支持的冲突处理策略（空表示 fail）
+func IsValidConflictStrategy(strategy string) bool {
+	switch ConflictStrategy(strategy) {
+	case "", ConflictSkip, ConflictReplace, ConflictFail:
+		return true
+	}
+	return false
+}
+
+// MigrateOptions 数据迁移工单选项（工单内容为 SELECT * FROM 源表 [WHERE 过滤条件]，每条语句生成一个任务）
+type MigrateOptions struct {
+	TargetInstanceID string            `json:"target_instance_id"`    // 目标实例
+	TargetSchema     string            `json:"target_schema"`         // 目标库（为空时使用源库）
+	TargetTable      string            `json:"target_table"`          // 目标表（为空时使用源表名，仅单条语句时可指定）
+	ColumnMapping    map[string]string `json:"column_mapping"`        // 列映射（源列 -> 目标列，未列出的列同名复制，目标列为空表示不复制）
+	Masking          map[string]string `json:"masking"`               // 脱敏规则（源列 -> null/hash/phone/email/partial/fixed:值）
+	ConflictStrategy ConflictStrategy  `json:"conflict_strategy"`     // 主键冲突处理策略（skip/replace/fail，默认 fail）
+	BatchSize        int               `json:"batch_size"`            // 每条 INSERT 写入的行数（0使用默认值）
+	MaskSecret       string            `json:"mask_secret,omitempty"` // 哈希脱敏密钥（创建工单时随机生成，加密保存）
+}
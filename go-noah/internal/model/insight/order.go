@@ -15,6 +15,11 @@ const (
 	SQLTypeDML    SQLType = "DML"
 	SQLTypeDDL    SQLType = "DDL"
 	SQLTypeExport SQLType = "EXPORT"
+
+	// SQLTypeArchive 数据归档：按主键分批将满足条件的行迁移到归档表或压缩文件，并删除源数据
+	SQLTypeArchive SQLType = "ARCHIVE"
+	// SQLTypeMigrate 数据迁移：按主键分批将表数据复制到其他实例，支持列映射和脱敏
+	SQLTypeMigrate SQLType = "MIGRATE"
 )
 
 // Progress 工单进度
@@ -39,6 +44,8 @@ const (
 	TaskProgressCompleted TaskProgress = "已完成"
 	TaskProgressFailed    TaskProgress = "已失败"
 	TaskProgressPaused    TaskProgress = "已暂停"
+	TaskProgressCancelled TaskProgress = "已取消"
+	TaskProgressUnknown   TaskProgress = "待确认" // 执行中断且无法核对执行结果，需要人工确认
 )
 
 // ExportFileFormat 导出文件格式
@@ -74,9 +81,26 @@ type OrderRecord struct {
 	FixVersion          string           `gorm:"type:varchar(128);not null;default:'';comment:上线版本;index" json:"fix_version"`
 	Content             string           `gorm:"type:text;null;comment:工单内容" json:"content"`
 	ExportFileFormat    ExportFileFormat `gorm:"type:varchar(10);default:'XLSX';comment:导出文件格式" json:"export_file_format"`
+	ExportEncryptionKey string           `gorm:"type:varchar(512);not null;default:'';comment:导出文件加密密钥(加密)" json:"-"` // 仅申请人可通过专用接口获取
 	FlowInstanceID      uint             `gorm:"index;comment:'关联流程实例ID'" json:"flow_instance_id"`
 	GhostOkToDropTable  bool             `gorm:"type:tinyint(1);not null;default:0;comment:gh-ost执行成功后自动删除旧表" json:"ghost_ok_to_drop_table"`
+	DDLEngine           string           `gorm:"type:varchar(20);not null;default:'';comment:在线DDL引擎(空为使用实例配置)" json:"ddl_engine"`
+	ChunkedDML          bool             `gorm:"type:tinyint(1);not null;default:0;comment:DML按主键分批执行" json:"chunked_dml"`
+	ChunkSize           int              `gorm:"type:int;not null;default:0;comment:分批执行每批行数(0使用系统默认)" json:"chunk_size"`
+	ChunkSleepMs        int              `gorm:"type:int;not null;default:0;comment:分批执行批次间隔毫秒(0使用系统默认)" json:"chunk_sleep_ms"`
 	SchedulerRegistered bool             `gorm:"type:tinyint(1);not null;default:0;comment:定时任务是否已注册到调度器;index" json:"scheduler_registered"`
+
+	// gh-ost推迟cut-over（数据同步完成后等待手动或计划时间执行cut-over）
+	GhostPostponeCutOver bool `gorm:"type:tinyint(1);not null;default:0;comment:gh-ost推迟cut-over" json:"ghost_postpone_cut_over"`
+
+	// 定时执行错过计划时间（如调度节点全部宕机）后的处理策略
+	ScheduleMissedPolicy MissedRunPolicy `gorm:"type:varchar(20);not null;default:'';comment:错过计划时间的处理策略(空为使用系统默认)" json:"schedule_missed_policy"`
+
+	// 归档工单选项（ArchiveOptions）
+	ArchiveOptions datatypes.JSON `gorm:"type:json;null;default:null;comment:归档选项" json:"archive_options"`
+
+	// 数据迁移工单选项（MigrateOptions）
+	MigrateOptions datatypes.JSON `gorm:"type:json;null;default:null;comment:数据迁移选项" json:"migrate_options"`
 }
 
 func (OrderRecord) TableName() string {
@@ -111,6 +135,8 @@ type OrderTask struct {
 	SQL      string         `gorm:"type:text;null;comment:SQL语句" json:"sql"`
 	Progress TaskProgress   `gorm:"type:varchar(20);default:'未执行';comment:进度" json:"progress"`
 	Result   datatypes.JSON `gorm:"type:json;null;default:null;comment:执行结果" json:"result"`
+	// 最近一次执行持有的执行锁 fencing token，执行结果只能由 token 不小于该值的持有者写入
+	FenceToken int64 `gorm:"type:bigint;not null;default:0;comment:执行锁fencing token" json:"-"`
 }
 
 func (OrderTask) TableName() string {
@@ -122,6 +148,21 @@ func (o *OrderTask) BeforeCreate(tx *gorm.DB) (err error) {
 	return
 }
 
+// OrderDryRun DML工单试运行记录（在事务中执行后回滚，供审批人查看实际影响行数和前后镜像）
+type OrderDryRun struct {
+	gorm.Model
+	OrderID          uuid.UUID      `gorm:"type:char(36);comment:关联order_records的order_id;index" json:"order_id"`
+	Username         string         `gorm:"type:varchar(32);not null;default:'';comment:操作用户" json:"username"`
+	TargetInstanceID uuid.UUID      `gorm:"type:char(36);comment:试运行实例ID（工单实例或指定的从库/沙箱）" json:"target_instance_id"`
+	AffectedRows     int64          `gorm:"type:bigint;not null;default:0;comment:实际影响总行数" json:"affected_rows"`
+	Success          bool           `gorm:"type:tinyint(1);not null;default:0;comment:所有任务是否试运行成功" json:"success"`
+	Result           datatypes.JSON `gorm:"type:json;null;default:null;comment:各任务试运行结果" json:"result"`
+}
+
+func (OrderDryRun) TableName() string {
+	return "order_dry_runs"
+}
+
 // OrderMessage 消息推送记录
 type OrderMessage struct {
 	gorm.Model
@@ -0,0 +1,65 @@
+package insight
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/datatypes"
+	"gorm.io/gorm"
+)
+
+// RecurringState 周期工单状态
+type RecurringState string
+
+const (
+	RecurringStateActive    RecurringState = "active"    // 工单审批通过后按计划执行
+	RecurringStateSuspended RecurringState = "suspended" // 已暂停（连续失败次数达到上限或手动暂停）
+	RecurringStateExpired   RecurringState = "expired"   // 已过有效期，不再执行
+)
+
+// RecurringRunState 周期工单单次执行状态
+type RecurringRunState string
+
+const (
+	RecurringRunRunning   RecurringRunState = "running"
+	RecurringRunSucceeded RecurringRunState = "succeeded"
+	RecurringRunFailed    RecurringRunState = "failed"
+	RecurringRunSkipped   RecurringRunState = "skipped" // 上一次执行尚未结束，本次跳过
+)
+
+// RecurringSchedule 周期工单的执行计划（审批一次，按 cron 表达式在有效期内重复执行工单的DML）
+type RecurringSchedule struct {
+	gorm.Model
+	OrderID                uuid.UUID      `gorm:"type:char(36);not null;uniqueIndex;comment:关联order_records的order_id" json:"order_id"`
+	CronExpr               string         `gorm:"type:varchar(64);not null;comment:cron表达式(分 时 日 月 周)" json:"cron_expr"`
+	ExpireAt               time.Time      `gorm:"type:datetime;not null;comment:有效期截止时间" json:"expire_at"`
+	MaxAffectedRows        int64          `gorm:"type:bigint;not null;default:0;comment:单次执行影响行数上限(超过时回滚)" json:"max_affected_rows"`
+	MaxConsecutiveFailures int            `gorm:"type:int;not null;default:3;comment:连续失败多少次后自动暂停" json:"max_consecutive_failures"`
+	State                  RecurringState `gorm:"type:varchar(20);not null;default:'active';index;comment:状态" json:"state"`
+	NextRunAt              *time.Time     `gorm:"type:datetime;null;default:null;index;comment:下次执行时间(审批通过后计算)" json:"next_run_at"`
+	LastRunAt              *time.Time     `gorm:"type:datetime;null;default:null;comment:上次执行时间" json:"last_run_at"`
+	RunCount               int            `gorm:"type:int;not null;default:0;comment:已执行次数" json:"run_count"`
+	ConsecutiveFailures    int            `gorm:"type:int;not null;default:0;comment:连续失败次数" json:"consecutive_failures"`
+	SuspendReason          string         `gorm:"type:varchar(1024);not null;default:'';comment:暂停原因" json:"suspend_reason"`
+}
+
+func (RecurringSchedule) TableName() string {
+	return "recurring_schedules"
+}
+
+// RecurringRun 周期工单的单次执行记录
+type RecurringRun struct {
+	gorm.Model
+	ScheduleID   uint              `gorm:"not null;index;comment:关联recurring_schedules的id" json:"schedule_id"`
+	OrderID      uuid.UUID         `gorm:"type:char(36);not null;index;comment:关联order_records的order_id" json:"order_id"`
+	State        RecurringRunState `gorm:"type:varchar(20);not null;default:'running';comment:状态" json:"state"`
+	AffectedRows int64             `gorm:"type:bigint;not null;default:0;comment:影响行数" json:"affected_rows"`
+	Result       datatypes.JSON    `gorm:"type:json;null;default:null;comment:执行结果" json:"result"`
+	Error        string            `gorm:"type:varchar(1024);not null;default:'';comment:失败原因" json:"error"`
+	StartedAt    time.Time         `gorm:"type:datetime;not null;comment:开始时间" json:"started_at"`
+	FinishedAt   *time.Time        `gorm:"type:datetime;null;default:null;comment:结束时间" json:"finished_at"`
+}
+
+func (RecurringRun) TableName() string {
+	return "recurring_runs"
+}
@@ -0,0 +1,60 @@
+package insight
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ScheduleState 定时执行状态
+type ScheduleState string
+
+const (
+	ScheduleStatePending   ScheduleState = "pending"   // 等待执行（包括失败后等待重试）
+	ScheduleStateRunning   ScheduleState = "running"   // 已被调度节点认领，正在触发执行
+	ScheduleStateAsking    ScheduleState = "asking"    // 错过计划时间，等待人工确认是否执行
+	ScheduleStateSucceeded ScheduleState = "succeeded" // 已触发执行
+	ScheduleStateFailed    ScheduleState = "failed"    // 重试次数用尽
+	ScheduleStateSkipped   ScheduleState = "skipped"   // 错过计划时间已跳过
+	ScheduleStateCancelled ScheduleState = "cancelled" // 工单状态变化，不再执行
+)
+
+// MissedRunPolicy 错过计划时间（如调度节点全部宕机）后的处理策略
+type MissedRunPolicy string
+
+const (
+	MissedRunLate MissedRunPolicy = "run_late" // 立即补执行
+	MissedRunSkip MissedRunPolicy = "skip"     // 跳过
+	MissedRunAsk  MissedRunPolicy = "ask"      // 通知执行人确认
+)
+
+// IsValidMissedRunPolicy 是否为支持的错过计划时间处理策略（空表示使用系统默认）
+func IsValidMissedRunPolicy(policy string) bool {
+	switch MissedRunPolicy(policy) {
+	case "", MissedRunLate, MissedRunSkip, MissedRunAsk:
+		return true
+	}
+	return false
+}
+
+// ScheduledExecution 工单定时执行记录（由选举出的调度节点轮询执行，服务重启不丢失）
+type ScheduledExecution struct {
+	gorm.Model
+	OrderID       uuid.UUID       `gorm:"type:char(36);not null;index;comment:关联order_records的order_id" json:"order_id"`
+	DueAt         time.Time       `gorm:"type:datetime;not null;comment:计划执行时间" json:"due_at"`
+	NextAttemptAt time.Time       `gorm:"type:datetime;not null;index:idx_schedule_poll,priority:2;comment:下次尝试时间(失败重试时退避)" json:"next_attempt_at"`
+	State         ScheduleState   `gorm:"type:varchar(20);not null;default:'pending';index:idx_schedule_poll,priority:1;comment:状态" json:"state"`
+	MissedPolicy  MissedRunPolicy `gorm:"type:varchar(20);not null;default:'run_late';comment:错过计划时间的处理策略" json:"missed_policy"`
+	Attempts      int             `gorm:"type:int;not null;default:0;comment:已尝试次数" json:"attempts"`
+	MaxAttempts   int             `gorm:"type:int;not null;default:3;comment:最大尝试次数" json:"max_attempts"`
+	Owner         string          `gorm:"type:varchar(128);not null;default:'';comment:认领的调度节点" json:"owner"`
+	ClaimedUntil  *time.Time      `gorm:"type:datetime;null;default:null;comment:认领有效期(节点宕机后重新认领)" json:"claimed_until"`
+	Username      string          `gorm:"type:varchar(64);not null;default:'';comment:执行人" json:"username"`
+	LastError     string          `gorm:"type:varchar(1024);not null;default:'';comment:最近一次失败原因" json:"last_error"`
+	FinishedAt    *time.Time      `gorm:"type:datetime;null;default:null;comment:结束时间" json:"finished_at"`
+}
+
+func (ScheduledExecution) TableName() string {
+	return "scheduled_executions"
+}
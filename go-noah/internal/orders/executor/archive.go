@@ -0,0 +1,614 @@
+package executor
+
+import (
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/global"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultArchivePath 默认归档文件存放目录
+	defaultArchivePath = "./storage/archive"
+	// archiveNullValue 归档文件中 NULL 的表示（与 LOAD DATA 默认格式一致）
+	archiveNullValue = `\N`
+	// defaultArchiveRetentionDays 默认归档文件保留天数
+	defaultArchiveRetentionDays = 90
+)
+
+// ArchiveCheckpoint 归档断点
+type ArchiveCheckpoint struct {
+	LastPK        string // 已归档的最大主键
+	ArchivedRows  int64  // 已归档行数
+	DeletedRows   int64  // 已从源表删除行数
+	FilePath      string // 归档文件路径（file 模式）
+	FileOffset    int64  // 已确认的归档文件长度（file 模式）
+	PendingPK     string // 已写入文件、等待删除源数据的批次主键上界（file 模式）
+	PendingOffset int64  // 等待删除源数据的批次写入后的文件长度
+	PendingRows   int64  // 等待删除源数据的批次行数
+}
+
+// InstanceResolver 获取其他实例的连接配置（由 service 层实现）
+type InstanceResolver interface {
+	TargetConfig(ctx context.Context, instanceID string) (*DBConfig, error)
+}
+
+// ArchiveStore 归档任务依赖的外部存储（由 service 层实现）
+type ArchiveStore interface {
+	InstanceResolver
+	// LoadCheckpoint 获取任务断点，尚未开始归档时返回 nil
+	LoadCheckpoint(ctx context.Context, taskID string) (*ArchiveCheckpoint, error)
+	// SaveCheckpoint 保存任务断点
+	SaveCheckpoint(ctx context.Context, taskID string, checkpoint *ArchiveCheckpoint) error
+}
+
+// ArchiveConfig 归档任务配置
+type ArchiveConfig struct {
+	Mode             string       // 归档目标类型（table/file）
+	TargetInstanceID string       // 目标实例（为空时使用源实例）
+	TargetSchema     string       // 目标库（为空时使用源库）
+	TargetTable      string       // 目标表（为空时使用 源表名_archive）
+	KeepSource       bool         // 只复制不删除源表数据
+	Store            ArchiveStore // 断点和目标实例配置存储
+}
+
+// GetArchivePath 获取归档文件存放目录
+func GetArchivePath() string {
+	if global.Conf != nil {
+		if path := global.Conf.GetString("archive.path"); path != "" {
+			return path
+		}
+	}
+	return defaultArchivePath
+}
+
+// GetArchiveRetention 获取归档文件保留时长，超过后由定时任务清理，返回 0 表示不自动清理
+func GetArchiveRetention() time.Duration {
+	days := defaultArchiveRetentionDays
+	if global.Conf != nil && global.Conf.IsSet("archive.retention_days") {
+		days = global.Conf.GetInt("archive.retention_days")
+	}
+	if days <= 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ValidateArchiveSQL 校验归档语句（SELECT * FROM 源表 WHERE 归档条件，创建工单时调用）
+func ValidateArchiveSQL(sqltext, defaultSchema string) error {
+	_, err := buildTableScanPlan(sqltext, defaultSchema, true)
+	return err
+}
+
+// archiveSink 归档目标
+type archiveSink interface {
+	// Write 写入一批行并校验写入行数，返回 nil 后才能删除源数据
+	Write(ctx context.Context, batch *rowBatch) error
+	// Offset 已写入的文件长度（table 模式恒为 0）
+	Offset() int64
+	// Discard 丢弃最近一次写入（源数据删除失败时调用）
+	Discard() error
+	// Describe 归档目标描述（用于执行日志）
+	Describe() string
+	Close() error
+}
+
+// ExecuteArchive 按主键分批将满足条件的行归档到目标表或压缩文件，校验写入行数后删除源数据
+// 每批完成后保存断点，任务中断后重新执行时从断点继续（类似 pt-archiver）
+func (e *MySQLExecutor) ExecuteArchive(ctx context.Context) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+	fail := func(err error) (ReturnData, error) {
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	opts := e.Config.Archive
+	if opts == nil || opts.Store == nil || e.Config.TaskID == "" {
+		err := errors.New("归档任务缺少归档选项")
+		logMessage(err.Error())
+		return fail(err)
+	}
+	plan, err := buildTableScanPlan(e.Config.SQL, e.Config.Schema, true)
+	if err != nil {
+		logMessage(err.Error())
+		return fail(err)
+	}
+	chunkSize, sleep := getChunkSettings(e.Config)
+
+	logMessage(fmt.Sprintf("连接数据库 %s:%d...", e.Config.Hostname, e.Config.Port))
+	db, err := e.Connect()
+	if err != nil {
+		logMessage(fmt.Sprintf("连接失败: %s", err.Error()))
+		return fail(err)
+	}
+	defer db.Close()
+	logMessage("连接成功")
+
+	pk, err := getPrimaryKeyColumn(ctx, db, plan.Schema, plan.Table)
+	if err != nil {
+		logMessage(fmt.Sprintf("获取主键失败: %s", err.Error()))
+		return fail(err)
+	}
+	pkRef := quoteIdentifier(plan.Qualify) + "." + quoteIdentifier(pk)
+
+	// 加载断点
+	checkpoint, err := opts.Store.LoadCheckpoint(ctx, e.Config.TaskID)
+	if err != nil {
+		logMessage(fmt.Sprintf("加载归档断点失败: %s", err.Error()))
+		return fail(err)
+	}
+	if checkpoint == nil {
+		checkpoint = &ArchiveCheckpoint{}
+	} else {
+		logMessage(fmt.Sprintf("从断点继续归档：已归档 %d 行，已删除 %d 行，主键 %s > %s",
+			checkpoint.ArchivedRows, checkpoint.DeletedRows, pk, checkpoint.LastPK))
+	}
+	if checkpoint.PendingPK != "" {
+		if err := e.resolveArchivePending(ctx, db, plan, pkRef, checkpoint, logMessage); err != nil {
+			logMessage(fmt.Sprintf("核对上次中断的批次失败: %s", err.Error()))
+			return fail(err)
+		}
+	}
+
+	var sink archiveSink
+	switch opts.Mode {
+	case "table":
+		sink, err = e.newArchiveTableSink(ctx, db, plan, pk)
+	case "file":
+		sink, err = e.newArchiveFileSink(checkpoint)
+	default:
+		err = fmt.Errorf("不支持的归档目标类型: %s", opts.Mode)
+	}
+	if err != nil {
+		logMessage(fmt.Sprintf("准备归档目标失败: %s", err.Error()))
+		return fail(err)
+	}
+	defer sink.Close()
+
+	// 统计待归档行数（仅用于进度展示）
+	cond := fmt.Sprintf("(%s)", plan.Where)
+	var args []interface{}
+	if checkpoint.LastPK != "" {
+		cond += fmt.Sprintf(" AND %s > ?", pkRef)
+		args = append(args, pkArg(checkpoint.LastPK))
+	}
+	var remaining int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", plan.TableRef, cond), args...).Scan(&remaining); err != nil {
+		logMessage(fmt.Sprintf("统计待归档行数失败: %s", err.Error()))
+		return fail(err)
+	}
+	total := checkpoint.ArchivedRows + remaining
+	action := "删除源数据"
+	if opts.KeepSource {
+		action = "保留源数据"
+	}
+	logMessage(fmt.Sprintf("归档 %s.%s 到 %s：主键 %s，每批 %d 行，批次间隔 %s，%s，待归档 %d 行",
+		plan.Schema, plan.Table, sink.Describe(), pk, chunkSize, sleep, action, remaining))
+
+	throttle := newThrottler(e.Config, db, logMessage)
+	defer throttle.Close()
+
+	startTime := time.Now()
+	chunkIndex := 0
+	interrupted := func(err error) (ReturnData, error) {
+		logMessage(fmt.Sprintf("归档已中断，已归档 %d 行，重新执行任务将从断点继续", checkpoint.ArchivedRows))
+		data.AffectedRows = checkpoint.ArchivedRows
+		return fail(err)
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return interrupted(err)
+		}
+		// 每批执行前检查限流条件
+		if err := throttle.Wait(ctx); err != nil {
+			return interrupted(err)
+		}
+		chunkIndex++
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			logMessage(fmt.Sprintf("第%d批开启事务失败: %s", chunkIndex, err.Error()))
+			return interrupted(err)
+		}
+		// 删除源数据时锁定本批行，避免写入归档后、删除前被修改
+		batch, err := selectRowBatch(ctx, tx, plan, pk, pkRef, checkpoint.LastPK, chunkSize, !opts.KeepSource)
+		if err != nil {
+			tx.Rollback()
+			logMessage(fmt.Sprintf("第%d批读取数据失败: %s", chunkIndex, err.Error()))
+			return interrupted(err)
+		}
+		if len(batch.rows) == 0 {
+			tx.Rollback()
+			break
+		}
+		n := int64(len(batch.rows))
+
+		if err := sink.Write(ctx, batch); err != nil {
+			tx.Rollback()
+			_ = sink.Discard()
+			logMessage(fmt.Sprintf("第%d批写入归档失败: %s", chunkIndex, err.Error()))
+			return interrupted(err)
+		}
+
+		var deleted int64
+		if !opts.KeepSource {
+			// file 模式先记录待删除的批次，删除提交后进程中断时可据此确认文件中的数据有效
+			if opts.Mode == "file" {
+				checkpoint.PendingPK, checkpoint.PendingOffset, checkpoint.PendingRows = batch.upper, sink.Offset(), n
+				if err := opts.Store.SaveCheckpoint(ctx, e.Config.TaskID, checkpoint); err != nil {
+					tx.Rollback()
+					_ = sink.Discard()
+					checkpoint.PendingPK, checkpoint.PendingOffset, checkpoint.PendingRows = "", 0, 0
+					logMessage(fmt.Sprintf("第%d批保存断点失败: %s", chunkIndex, err.Error()))
+					return interrupted(err)
+				}
+			}
+			if deleted, err = deleteArchiveBatch(ctx, tx, plan, pk, batch); err == nil && deleted != n {
+				err = fmt.Errorf("删除行数 %d 与归档行数 %d 不一致", deleted, n)
+			}
+			if err != nil {
+				tx.Rollback()
+				_ = sink.Discard()
+				checkpoint.PendingPK, checkpoint.PendingOffset, checkpoint.PendingRows = "", 0, 0
+				_ = opts.Store.SaveCheckpoint(ctx, e.Config.TaskID, checkpoint)
+				logMessage(fmt.Sprintf("第%d批删除源数据失败，已回滚本批: %s", chunkIndex, err.Error()))
+				return interrupted(err)
+			}
+		}
+		if err := tx.Commit(); err != nil {
+			logMessage(fmt.Sprintf("第%d批提交失败: %s", chunkIndex, err.Error()))
+			return interrupted(err)
+		}
+
+		checkpoint.LastPK = batch.upper
+		checkpoint.ArchivedRows += n
+		checkpoint.DeletedRows += deleted
+		checkpoint.FileOffset = sink.Offset()
+		checkpoint.PendingPK, checkpoint.PendingOffset, checkpoint.PendingRows = "", 0, 0
+		if err := opts.Store.SaveCheckpoint(ctx, e.Config.TaskID, checkpoint); err != nil {
+			logMessage(fmt.Sprintf("第%d批保存断点失败: %s", chunkIndex, err.Error()))
+			return interrupted(err)
+		}
+
+		logMessage(fmt.Sprintf("第%d批归档成功，归档 %d 行，删除 %d 行，累计: %d/%d", chunkIndex, n, deleted, checkpoint.ArchivedRows, total))
+		e.publishChunkProgress(chunkIndex, checkpoint.ArchivedRows, total)
+
+		if n < int64(chunkSize) {
+			break
+		}
+		if sleep > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(sleep):
+			}
+		}
+	}
+
+	executeCostTime := time.Since(startTime).String()
+	logMessage(fmt.Sprintf("归档完成，累计归档 %d 行，删除 %d 行，耗时: %s", checkpoint.ArchivedRows, checkpoint.DeletedRows, executeCostTime))
+	e.publishChunkProgress(chunkIndex, checkpoint.ArchivedRows, checkpoint.ArchivedRows)
+
+	if fileSink, ok := sink.(*archiveFileSink); ok {
+		data.ExportFile = fileSink.exportFile(e.Config, checkpoint.ArchivedRows)
+	}
+	data.AffectedRows = checkpoint.ArchivedRows
+	data.ExecuteCostTime = executeCostTime
+	data.ExecuteLog = strings.Join(executeLog, "\n")
+	return data, nil
+}
+
+// resolveArchivePending 核对上次中断时已写入文件、等待删除源数据的批次：
+// 源表中已不存在该批次的行说明删除已提交，确认文件中的数据；否则丢弃文件中的该批次重新归档
+func (e *MySQLExecutor) resolveArchivePending(ctx context.Context, db *dbpool.Conn, plan *tableScanPlan, pkRef string, checkpoint *ArchiveCheckpoint, logMessage func(string)) error {
+	cond := fmt.Sprintf("(%s) AND %s <= ?", plan.Where, pkRef)
+	args := []interface{}{pkArg(checkpoint.PendingPK)}
+	if checkpoint.LastPK != "" {
+		cond += fmt.Sprintf(" AND %s > ?", pkRef)
+		args = append(args, pkArg(checkpoint.LastPK))
+	}
+	var remaining int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", plan.TableRef, cond), args...).Scan(&remaining); err != nil {
+		return err
+	}
+	if remaining == 0 {
+		logMessage(fmt.Sprintf("上次中断的批次（%d 行）已删除源数据，确认归档文件中的数据", checkpoint.PendingRows))
+		checkpoint.LastPK = checkpoint.PendingPK
+		checkpoint.ArchivedRows += checkpoint.PendingRows
+		checkpoint.DeletedRows += checkpoint.PendingRows
+		checkpoint.FileOffset = checkpoint.PendingOffset
+	} else {
+		logMessage("上次中断的批次未删除源数据，丢弃归档文件中的该批次后重新归档")
+	}
+	checkpoint.PendingPK, checkpoint.PendingOffset, checkpoint.PendingRows = "", 0, 0
+	return e.Config.Archive.Store.SaveCheckpoint(ctx, e.Config.TaskID, checkpoint)
+}
+
+// deleteArchiveBatch 按主键删除本批已归档的源数据
+func deleteArchiveBatch(ctx context.Context, tx *sql.Tx, plan *tableScanPlan, pk string, batch *rowBatch) (int64, error) {
+	query := fmt.Sprintf("DELETE FROM %s.%s WHERE %s IN (%s)",
+		quoteIdentifier(plan.Schema), quoteIdentifier(plan.Table), quoteIdentifier(pk), placeholders(len(batch.pks)))
+	result, err := tx.ExecContext(ctx, query, batch.pks...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// archiveTableSink 归档到表（同实例或其他实例）
+type archiveTableSink struct {
+	db     *dbpool.Conn
+	schema string
+	table  string
+	pk     string
+}
+
+// newArchiveTableSink 连接目标实例，目标表不存在时按源表结构创建
+func (e *MySQLExecutor) newArchiveTableSink(ctx context.Context, source *dbpool.Conn, plan *tableScanPlan, pk string) (*archiveTableSink, error) {
+	opts := e.Config.Archive
+	sink := &archiveTableSink{schema: opts.TargetSchema, table: opts.TargetTable, pk: pk}
+	if sink.schema == "" {
+		sink.schema = plan.Schema
+	}
+	if sink.table == "" {
+		sink.table = plan.Table + "_archive"
+	}
+
+	sameInstance := opts.TargetInstanceID == "" || opts.TargetInstanceID == e.Config.InstanceID
+	if sameInstance && sink.schema == plan.Schema && sink.table == plan.Table {
+		return nil, fmt.Errorf("归档目标表不能与源表相同")
+	}
+	db, err := e.connectInstance(ctx, opts.Store, opts.TargetInstanceID, sink.schema)
+	if err != nil {
+		return nil, err
+	}
+	sink.db = db
+
+	if err := createTableLike(ctx, source, db, plan.Schema, plan.Table, sink.schema, sink.table); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// 目标表需要与源表主键相同，重复写入同一批次时按主键去重
+	targetPK, err := getPrimaryKeyColumn(ctx, db, sink.schema, sink.table)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+	if !strings.EqualFold(targetPK, pk) {
+		db.Close()
+		return nil, fmt.Errorf("归档表主键 %s 与源表主键 %s 不一致", targetPK, pk)
+	}
+	return sink, nil
+}
+
+func (s *archiveTableSink) Write(ctx context.Context, batch *rowBatch) error {
+	// 主键冲突时保留归档表中的行（可能是上次中断前已写入的本批数据，不使用 INSERT IGNORE，避免数据截断等错误被忽略），
+	// 写入后逐行比对内容，归档表中已存在主键相同的其他数据时停止归档，不删除源表数据
+	suffix := fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", quoteIdentifier(s.pk), quoteIdentifier(s.pk))
+	if _, err := insertRows(ctx, s.db, s.schema, s.table, batch.columns, batch.rows, 0, suffix); err != nil {
+		return err
+	}
+
+	// 校验归档表中本批主键对应的行与源表一致
+	pkIndex := columnIndex(batch.columns, s.pk)
+	checksums, err := queryRowChecksums(ctx, s.db, s.schema, s.table, batch.columns, pkIndex, batch.pks)
+	if err != nil {
+		return fmt.Errorf("校验归档数据失败: %w", err)
+	}
+	for _, row := range batch.rows {
+		key := formatExportValue(row[pkIndex])
+		checksum, ok := checksums[key]
+		if !ok {
+			return fmt.Errorf("归档表中缺少主键为 %s 的行", key)
+		}
+		if checksum != rowChecksum(row) {
+			return fmt.Errorf("归档表中主键为 %s 的行与源表不一致（归档表中已存在主键相同的其他数据），已停止归档，源表数据未删除", key)
+		}
+	}
+	return nil
+}
+
+func (s *archiveTableSink) Offset() int64 { return 0 }
+
+// Discard 已写入归档表的行保留，重新归档时按主键去重并比对内容
+func (s *archiveTableSink) Discard() error { return nil }
+
+func (s *archiveTableSink) Describe() string {
+	return fmt.Sprintf("表 %s.%s", s.schema, s.table)
+}
+
+func (s *archiveTableSink) Close() error {
+	return s.db.Close()
+}
+
+// archiveFileSink 归档到 gzip 压缩的 CSV 文件
+// 每批写入一个独立的 gzip 成员并落盘，中断后按断点记录的文件长度截断，丢弃未确认的批次
+type archiveFileSink struct {
+	file    *os.File
+	path    string
+	offset  int64 // 已写入的文件长度
+	confirm int64 // 最近一次写入前的文件长度（Discard 时截断到该长度）
+}
+
+// newArchiveFileSink 打开归档文件（断点续传时截断到断点记录的长度）
+func (e *MySQLExecutor) newArchiveFileSink(checkpoint *ArchiveCheckpoint) (*archiveFileSink, error) {
+	if checkpoint.FilePath == "" {
+		dir := filepath.Join(GetArchivePath(), e.Config.OrderID)
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("创建归档目录失败: %s", err.Error())
+		}
+		checkpoint.FilePath = filepath.Join(dir, fmt.Sprintf("%s_%s.csv.gz", e.Config.TaskID, time.Now().Format("20060102150405")))
+		checkpoint.FileOffset = 0
+	}
+	file, err := os.OpenFile(checkpoint.FilePath, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("打开归档文件失败: %s", err.Error())
+	}
+	if stat, err := file.Stat(); err != nil || stat.Size() < checkpoint.FileOffset {
+		file.Close()
+		return nil, fmt.Errorf("归档文件 %s 已被删除或截断，无法从断点继续", checkpoint.FilePath)
+	}
+	sink := &archiveFileSink{file: file, path: checkpoint.FilePath}
+	if err := sink.truncate(checkpoint.FileOffset); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("截断归档文件失败: %s", err.Error())
+	}
+	return sink, nil
+}
+
+func (s *archiveFileSink) truncate(size int64) error {
+	if err := s.file.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := s.file.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	s.offset, s.confirm = size, size
+	return nil
+}
+
+func (s *archiveFileSink) Write(ctx context.Context, batch *rowBatch) error {
+	s.confirm = s.offset
+	gz := gzip.NewWriter(s.file)
+	writer := csv.NewWriter(gz)
+	header := s.offset == 0
+	if header {
+		if err := writer.Write(batch.columns); err != nil {
+			return err
+		}
+	}
+	record := make([]string, len(batch.columns))
+	checksums := make([]uint64, 0, len(batch.rows))
+	for _, row := range batch.rows {
+		for i, v := range row {
+			if v == nil {
+				record[i] = archiveNullValue
+			} else {
+				record[i] = formatExportValue(v)
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		checksums = append(checksums, archiveRecordChecksum(record))
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+	offset, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	s.offset = offset
+
+	// 落盘后重新读取本批数据，行数和逐行校验和一致后才能删除源数据
+	if err := s.verify(header, checksums); err != nil {
+		return fmt.Errorf("校验归档文件失败，源表数据未删除: %w", err)
+	}
+	return nil
+}
+
+// verify 重新读取最近写入的 gzip 成员，核对行数和逐行校验和（gzip 读取到成员末尾时同时校验 CRC32）
+func (s *archiveFileSink) verify(header bool, checksums []uint64) error {
+	file, err := os.Open(s.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err := file.Seek(s.confirm, io.SeekStart); err != nil {
+		return err
+	}
+	gz, err := gzip.NewReader(io.LimitReader(file, s.offset-s.confirm))
+	if err != nil {
+		return err
+	}
+	gz.Multistream(false)
+	reader := csv.NewReader(gz)
+	reader.FieldsPerRecord = -1
+	if header {
+		if _, err := reader.Read(); err != nil {
+			return fmt.Errorf("读取表头失败: %w", err)
+		}
+	}
+
+	var n int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if n >= len(checksums) {
+			return fmt.Errorf("文件中本批行数多于写入行数 %d", len(checksums))
+		}
+		if archiveRecordChecksum(record) != checksums[n] {
+			return fmt.Errorf("文件中本批第 %d 行与源表数据不一致", n+1)
+		}
+		n++
+	}
+	if n != len(checksums) {
+		return fmt.Errorf("文件中本批行数 %d 与写入行数 %d 不一致", n, len(checksums))
+	}
+	return nil
+}
+
+// archiveRecordChecksum 计算归档文件中一行的校验和
+// encoding/csv 读取时会将引号内的 \r\n 规范化为 \n，计算前统一处理，避免写入和读取的结果不一致
+func archiveRecordChecksum(record []string) uint64 {
+	values := make([]interface{}, len(record))
+	for i, field := range record {
+		values[i] = strings.ReplaceAll(field, "\r\n", "\n")
+	}
+	return rowChecksum(values)
+}
+
+func (s *archiveFileSink) Offset() int64 { return s.offset }
+
+func (s *archiveFileSink) Discard() error {
+	return s.truncate(s.confirm)
+}
+
+func (s *archiveFileSink) Describe() string {
+	return "文件 " + filepath.Base(s.path)
+}
+
+func (s *archiveFileSink) Close() error {
+	return s.file.Close()
+}
+
+// exportFile 归档文件信息（复用导出文件的下载接口）
+func (s *archiveFileSink) exportFile(config *DBConfig, rows int64) ExportFile {
+	file := ExportFile{
+		FileName:    filepath.Base(s.path),
+		FilePath:    s.path,
+		FileSize:    s.offset,
+		ContentType: "application/gzip",
+		ExportRows:  rows,
+		DownloadUrl: GetExportDownloadUrl(config.OrderID, config.TaskID),
+	}
+	if retention := GetArchiveRetention(); retention > 0 {
+		file.ExpireTime = time.Now().Add(retention).Format("2006-01-02 15:04:05")
+	}
+	return file
+}
@@ -0,0 +1,117 @@
+package executor
+
+import (
+	"compress/gzip"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestArchiveFileSinkWrite(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.csv.gz")
+	sink, err := (&MySQLExecutor{Config: &DBConfig{}}).newArchiveFileSink(&ArchiveCheckpoint{FilePath: path})
+	if err != nil {
+		t.Fatalf("打开归档文件失败: %v", err)
+	}
+	defer sink.Close()
+
+	columns := []string{"id", "name", "remark", "created_at"}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	batches := [][][]interface{}{
+		{
+			{int64(1), "alice", nil, created},
+			{int64(2), []byte("bob"), "含,逗号和\"引号\"", created},
+		},
+		{
+			{int64(3), "carol", "多行\n文本", nil},
+			{int64(4), "dave", "Windows 换行\r\n文本", created},
+			{int64(5), "", "", created},
+		},
+	}
+	for i, rows := range batches {
+		if err := sink.Write(t.Context(), &rowBatch{columns: columns, rows: rows}); err != nil {
+			t.Fatalf("第%d批写入失败: %v", i+1, err)
+		}
+	}
+
+	// 多个 gzip 成员按顺序读取，表头只写一次
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(gz).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 6 {
+		t.Fatalf("期望 6 行（含表头），实际 %d 行", len(records))
+	}
+	if got := strings.Join(records[0], ","); got != "id,name,remark,created_at" {
+		t.Errorf("期望表头 %q，实际 %q", "id,name,remark,created_at", got)
+	}
+	if records[1][2] != archiveNullValue || records[3][3] != archiveNullValue {
+		t.Errorf("NULL 应写为 %s，实际 %q %q", archiveNullValue, records[1][2], records[3][3])
+	}
+	if records[1][3] != "2026-01-02 03:04:05" {
+		t.Errorf("期望时间 %q，实际 %q", "2026-01-02 03:04:05", records[1][3])
+	}
+}
+
+func TestArchiveFileSinkVerify(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "archive.csv.gz")
+	sink, err := (&MySQLExecutor{Config: &DBConfig{}}).newArchiveFileSink(&ArchiveCheckpoint{FilePath: path})
+	if err != nil {
+		t.Fatalf("打开归档文件失败: %v", err)
+	}
+	defer sink.Close()
+
+	batch := &rowBatch{columns: []string{"id", "name"}, rows: [][]interface{}{{int64(1), "alice"}, {int64(2), "bob"}}}
+	if err := sink.Write(t.Context(), batch); err != nil {
+		t.Fatalf("写入失败: %v", err)
+	}
+	checksums := []uint64{
+		archiveRecordChecksum([]string{"1", "alice"}),
+		archiveRecordChecksum([]string{"2", "bob"}),
+	}
+
+	testCases := []struct {
+		Name      string
+		Checksums []uint64
+		ExpectErr string
+	}{
+		{Name: "行数和内容一致", Checksums: checksums},
+		{Name: "内容不一致", Checksums: []uint64{checksums[0], archiveRecordChecksum([]string{"2", "bobby"})}, ExpectErr: "第 2 行与源表数据不一致"},
+		{Name: "文件中的行数少于写入行数", Checksums: append(checksums, checksums[0]), ExpectErr: "行数 2 与写入行数 3 不一致"},
+		{Name: "文件中的行数多于写入行数", Checksums: checksums[:1], ExpectErr: "多于写入行数 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := sink.verify(true, tc.Checksums)
+			if tc.ExpectErr == "" {
+				if err != nil {
+					t.Fatalf("期望校验通过，实际 %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+				t.Fatalf("期望错误包含 %q，实际 %v", tc.ExpectErr, err)
+			}
+		})
+	}
+
+	// 文件在落盘后被截断时 gzip 成员不完整，校验失败
+	if err := os.Truncate(path, sink.offset-4); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.verify(true, checksums); err == nil {
+		t.Fatalf("期望截断的文件校验失败，实际 %v", err)
+	}
+}
@@ -0,0 +1,473 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-noah/internal/inspect/parser"
+	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/global"
+	"go-noah/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+	"github.com/pingcap/tidb/pkg/parser/format"
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultChunkSize 默认分批执行每批行数
+	defaultChunkSize = 1000
+	// defaultChunkSleepMs 默认批次间隔（毫秒）
+	defaultChunkSleepMs = 100
+)
+
+// chunkPlan 分批执行计划（由单表 UPDATE/DELETE 改写而来）
+type chunkPlan struct {
+	Schema   string // 库名
+	Table    string // 表名
+	TableRef string // 表引用（含别名，用于边界查询）
+	Qualify  string // 主键列限定名（别名或表名）
+	BaseSQL  string // 去掉 WHERE 条件后的语句
+	Where    string // 原 WHERE 条件
+	PK       string // 主键列名
+	SetCols  []string
+}
+
+// getChunkSettings 获取分批执行参数（工单级别优先，其次使用系统配置）
+func getChunkSettings(config *DBConfig) (int, time.Duration) {
+	size, sleepMs := defaultChunkSize, defaultChunkSleepMs
+	if global.Conf != nil {
+		if v := global.Conf.GetInt("dml_chunk.size"); v > 0 {
+			size = v
+		}
+		if global.Conf.IsSet("dml_chunk.sleep_ms") {
+			sleepMs = global.Conf.GetInt("dml_chunk.sleep_ms")
+		}
+	}
+	if config.ChunkSize > 0 {
+		size = config.ChunkSize
+	}
+	if config.ChunkSleepMs > 0 {
+		sleepMs = config.ChunkSleepMs
+	}
+	return size, time.Duration(sleepMs) * time.Millisecond
+}
+
+// buildChunkPlan 解析SQL，判断是否可以按主键分批执行
+// 仅支持带 WHERE 条件、不含 ORDER BY/LIMIT/CTE 的单表 UPDATE/DELETE
+func buildChunkPlan(sqltext, defaultSchema string) (*chunkPlan, error) {
+	audit, _, err := parser.ParseSQL(sqltext)
+	if err != nil {
+		return nil, fmt.Errorf("SQL解析错误: %s", err.Error())
+	}
+	if len(audit.TiStmt) != 1 {
+		return nil, fmt.Errorf("分批执行仅支持单条语句")
+	}
+
+	var (
+		refs  *ast.TableRefsClause
+		where ast.ExprNode
+		plan  = &chunkPlan{}
+	)
+	switch stmt := audit.TiStmt[0].(type) {
+	case *ast.UpdateStmt:
+		if stmt.MultipleTable || stmt.With != nil || stmt.Order != nil || stmt.Limit != nil {
+			return nil, fmt.Errorf("分批执行不支持多表、CTE、ORDER BY或LIMIT")
+		}
+		refs, where = stmt.TableRefs, stmt.Where
+		for _, assign := range stmt.List {
+			plan.SetCols = append(plan.SetCols, assign.Column.Name.L)
+		}
+		stmt.Where = nil
+		if plan.BaseSQL, err = restoreNode(stmt); err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	case *ast.DeleteStmt:
+		if stmt.IsMultiTable || stmt.With != nil || stmt.Order != nil || stmt.Limit != nil {
+			return nil, fmt.Errorf("分批执行不支持多表、CTE、ORDER BY或LIMIT")
+		}
+		refs, where = stmt.TableRefs, stmt.Where
+		stmt.Where = nil
+		if plan.BaseSQL, err = restoreNode(stmt); err != nil {
+			return nil, err
+		}
+		stmt.Where = where
+	default:
+		return nil, fmt.Errorf("分批执行仅支持UPDATE/DELETE语句")
+	}
+
+	if where == nil {
+		return nil, fmt.Errorf("分批执行要求语句包含WHERE条件")
+	}
+	if refs == nil || refs.TableRefs == nil || refs.TableRefs.Right != nil {
+		return nil, fmt.Errorf("分批执行仅支持单表语句")
+	}
+	source, ok := refs.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil, fmt.Errorf("分批执行仅支持单表语句")
+	}
+	table, ok := source.Source.(*ast.TableName)
+	if !ok {
+		return nil, fmt.Errorf("分批执行仅支持单表语句")
+	}
+
+	plan.Schema = table.Schema.O
+	if plan.Schema == "" {
+		plan.Schema = defaultSchema
+	}
+	plan.Table = table.Name.O
+	plan.Qualify = table.Name.O
+	if source.AsName.O != "" {
+		plan.Qualify = source.AsName.O
+	}
+	if plan.TableRef, err = restoreNode(source); err != nil {
+		return nil, err
+	}
+	if plan.Where, err = restoreNode(where); err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// restoreNode 将语法树节点还原为SQL文本
+func restoreNode(node ast.Node) (string, error) {
+	var sb strings.Builder
+	if err := node.Restore(format.NewRestoreCtx(format.DefaultRestoreFlags|format.RestoreStringWithoutCharset, &sb)); err != nil {
+		return "", fmt.Errorf("SQL还原失败: %s", err.Error())
+	}
+	return sb.String(), nil
+}
+
+// getPrimaryKeyColumn 获取表的单列主键
+func getPrimaryKeyColumn(ctx context.Context, db *dbpool.Conn, schema, table string) (string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+		ORDER BY ORDINAL_POSITION`, schema, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var columns []string
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return "", err
+		}
+		columns = append(columns, column)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	switch len(columns) {
+	case 0:
+		return "", fmt.Errorf("表 %s.%s 没有主键", schema, table)
+	case 1:
+		return columns[0], nil
+	default:
+		return "", fmt.Errorf("表 %s.%s 为联合主键，暂不支持", schema, table)
+	}
+}
+
+// quoteIdentifier 反引号转义标识符
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// ExecuteChunkedDML 按主键范围分批执行 UPDATE/DELETE，每批独立提交并生成回滚SQL
+func (e *MySQLExecutor) ExecuteChunkedDML(ctx context.Context, plan *chunkPlan) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+	backupStrategy := BackupStrategyNone
+	fail := func(err error) (ReturnData, error) {
+		data.BackupStrategy = backupStrategy
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	chunkSize, sleep := getChunkSettings(e.Config)
+
+	logMessage(fmt.Sprintf("连接数据库 %s:%d...", e.Config.Hostname, e.Config.Port))
+	db, err := e.Connect()
+	if err != nil {
+		logMessage(fmt.Sprintf("连接失败: %s", err.Error()))
+		return fail(err)
+	}
+	defer db.Close()
+	logMessage("连接成功")
+
+	pk, err := getPrimaryKeyColumn(ctx, db, plan.Schema, plan.Table)
+	if err != nil {
+		logMessage(fmt.Sprintf("获取主键失败: %s", err.Error()))
+		return fail(err)
+	}
+	for _, col := range plan.SetCols {
+		if strings.EqualFold(col, pk) {
+			err := fmt.Errorf("分批执行不支持修改主键列 %s", pk)
+			logMessage(err.Error())
+			return fail(err)
+		}
+	}
+	plan.PK = pk
+	pkRef := quoteIdentifier(plan.Qualify) + "." + quoteIdentifier(pk)
+
+	connectionID, err := mysqlpkg.GetConnectionID(db)
+	if err != nil {
+		logMessage(fmt.Sprintf("获取Connection ID失败: %s", err.Error()))
+		return fail(err)
+	}
+	logMessage(fmt.Sprintf("Connection ID: %d", connectionID))
+
+	var ch1 chan int64
+	if e.Config.OrderID != "" {
+		ch1 = make(chan int64)
+		go e.GetProcesslist(e.Config.OrderID, connectionID, ch1)
+		defer close(ch1)
+		select {
+		case ch1 <- 1:
+		default:
+		}
+	}
+
+	// 统计待处理行数（仅用于进度展示）
+	var total int64
+	countSQL := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE (%s)", plan.TableRef, plan.Where)
+	if err := db.QueryRowContext(ctx, countSQL).Scan(&total); err != nil {
+		logMessage(fmt.Sprintf("统计待处理行数失败: %s", err.Error()))
+		return fail(err)
+	}
+	logMessage(fmt.Sprintf("分批执行：主键 %s，每批 %d 行，批次间隔 %s，预计处理 %d 行", pk, chunkSize, sleep, total))
+	if err := e.checkAffectedRows(total); err != nil {
+		err = fmt.Errorf("预计%w，未执行", err)
+		logMessage(err.Error())
+		return fail(err)
+	}
+
+	// binlog 不可用时每批使用前镜像备份生成回滚SQL
+	binlogErr := checkBinlogUsable(ctx, db, e.Config.DBType)
+	var checkpointFile string
+	var checkpointPos int64
+	if binlogErr == nil {
+		checkpointFile, checkpointPos, binlogErr = mysqlpkg.GetBinlogPos(db)
+	}
+	defer e.saveCheckpoint(connectionID, checkpointFile, checkpointPos, true)()
+	var preImage *preImageBackup
+	if binlogErr != nil {
+		logMessage(fmt.Sprintf("无法解析binlog生成回滚SQL: %s", binlogErr.Error()))
+		if preImage, err = newPreImageBackup(ctx, db, e.Config.SQL, e.Config.Schema); err != nil {
+			logMessage(fmt.Sprintf("无法使用前镜像备份（%s），不生成回滚SQL", err.Error()))
+		} else {
+			logMessage("使用前镜像备份生成回滚SQL")
+		}
+	}
+
+	throttle := newThrottler(e.Config, db, logMessage)
+	defer throttle.Close()
+
+	startTime := time.Now()
+	var (
+		lower        interface{}
+		chunkIndex   int
+		affectedRows int64
+		rollbackSQLs []string
+		backupCost   time.Duration
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			logMessage(fmt.Sprintf("执行已中断，已完成 %d 批，影响行数: %d", chunkIndex, affectedRows))
+			data.AffectedRows = affectedRows
+			data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+			return fail(err)
+		}
+		// 每批执行前检查限流条件
+		if err := throttle.Wait(ctx); err != nil {
+			logMessage(fmt.Sprintf("等待限流解除时中断，已完成 %d 批，影响行数: %d", chunkIndex, affectedRows))
+			data.AffectedRows = affectedRows
+			data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+			return fail(err)
+		}
+		chunkIndex++
+
+		// 计算本批次主键上界
+		cond := fmt.Sprintf("(%s)", plan.Where)
+		var args []interface{}
+		if lower != nil {
+			cond += fmt.Sprintf(" AND %s > ?", pkRef)
+			args = append(args, lower)
+		}
+		boundarySQL := fmt.Sprintf("SELECT %s FROM %s WHERE %s ORDER BY %s LIMIT 1 OFFSET %d",
+			pkRef, plan.TableRef, cond, pkRef, chunkSize-1)
+		var upper interface{}
+		lastChunk := false
+		if err := db.QueryRowContext(ctx, boundarySQL, args...).Scan(&upper); err != nil {
+			if !errors.Is(err, sql.ErrNoRows) {
+				logMessage(fmt.Sprintf("计算第%d批主键范围失败: %s", chunkIndex, err.Error()))
+				return fail(err)
+			}
+			lastChunk = true
+		}
+		if b, ok := upper.([]byte); ok {
+			upper = string(b)
+		}
+		if !lastChunk {
+			cond += fmt.Sprintf(" AND %s <= ?", pkRef)
+			args = append(args, upper)
+		}
+
+		var startFile string
+		var startPosition int64
+		if binlogErr == nil {
+			if startFile, startPosition, err = mysqlpkg.GetBinlogPos(db); err != nil {
+				logMessage(fmt.Sprintf("第%d批获取Binlog Position失败: %s", chunkIndex, err.Error()))
+				startFile = ""
+			}
+		}
+
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			logMessage(fmt.Sprintf("第%d批开启事务失败: %s", chunkIndex, err.Error()))
+			return fail(err)
+		}
+
+		// 前镜像备份本批次受影响的行
+		var preImageSQL string
+		if preImage != nil {
+			backupStart := time.Now()
+			preImageSQL, _, err = preImage.Capture(ctx, tx, cond, args...)
+			backupCost += time.Since(backupStart)
+			if errors.Is(err, errPreImageTooLarge) {
+				logMessage(fmt.Sprintf("第%d批前镜像备份失败: %s，后续批次不生成回滚SQL", chunkIndex, err.Error()))
+				preImage = nil
+			} else if err != nil {
+				tx.Rollback()
+				logMessage(fmt.Sprintf("第%d批前镜像备份失败，已回滚本批: %s", chunkIndex, err.Error()))
+				data.AffectedRows = affectedRows
+				data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+				return fail(err)
+			}
+		}
+		result, err := tx.ExecContext(ctx, plan.BaseSQL+" WHERE "+cond, args...)
+		if err != nil {
+			tx.Rollback()
+			logMessage(fmt.Sprintf("第%d批执行失败，已回滚本批: %s", chunkIndex, err.Error()))
+			data.AffectedRows = affectedRows
+			data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+			return fail(err)
+		}
+		rows, _ := result.RowsAffected()
+		// 执行期间新增的行可能使累计影响行数超过上限，回滚本批并停止
+		if err := e.checkAffectedRows(affectedRows + rows); err != nil {
+			tx.Rollback()
+			logMessage(fmt.Sprintf("第%d批执行失败，已回滚本批: %s", chunkIndex, err.Error()))
+			data.AffectedRows = affectedRows
+			data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+			return fail(err)
+		}
+		if err := tx.Commit(); err != nil {
+			logMessage(fmt.Sprintf("第%d批提交失败: %s", chunkIndex, err.Error()))
+			data.AffectedRows = affectedRows
+			data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+			return fail(err)
+		}
+		affectedRows += rows
+
+		// 生成本批次回滚SQL
+		if rows > 0 && preImageSQL != "" {
+			rollbackSQLs = append(rollbackSQLs, fmt.Sprintf("-- 第%d批\n%s", chunkIndex, strings.TrimSuffix(preImageSQL, "\n")))
+			backupStrategy = BackupStrategyPreImage
+		} else if rows > 0 && startFile != "" {
+			backupStart := time.Now()
+			if rollbackSQL, err := e.chunkRollbackSQL(db, connectionID, startFile, startPosition); err != nil {
+				logMessage(fmt.Sprintf("第%d批生成回滚SQL失败: %s", chunkIndex, err.Error()))
+			} else if rollbackSQL != "" {
+				rollbackSQLs = append(rollbackSQLs, fmt.Sprintf("-- 第%d批\n%s", chunkIndex, rollbackSQL))
+				backupStrategy = BackupStrategyBinlog
+			}
+			backupCost += time.Since(backupStart)
+		}
+
+		logMessage(fmt.Sprintf("第%d批执行成功，影响行数: %d，累计: %d/%d", chunkIndex, rows, affectedRows, total))
+		e.publishChunkProgress(chunkIndex, affectedRows, total)
+
+		if lastChunk {
+			break
+		}
+		lower = upper
+
+		if sleep > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(sleep):
+			}
+		}
+	}
+
+	executeCostTime := time.Since(startTime).String()
+	logMessage(fmt.Sprintf("分批执行完成，共 %d 批，影响行数: %d，耗时: %s", chunkIndex, affectedRows, executeCostTime))
+
+	data.AffectedRows = affectedRows
+	data.ExecuteCostTime = executeCostTime
+	data.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+	data.BackupStrategy = backupStrategy
+	if len(rollbackSQLs) > 0 {
+		data.BackupCostTime = backupCost.String()
+	}
+	data.ExecuteLog = strings.Join(executeLog, "\n")
+	return data, nil
+}
+
+// chunkRollbackSQL 解析单个批次的 binlog 生成回滚SQL
+func (e *MySQLExecutor) chunkRollbackSQL(db *dbpool.Conn, connectionID int64, startFile string, startPosition int64) (string, error) {
+	endFile, endPosition, err := mysqlpkg.GetBinlogPos(db)
+	if err != nil {
+		return "", err
+	}
+	binlog := mysqlpkg.Binlog{
+		Config: &mysqlpkg.BinlogConfig{
+			Hostname: e.Config.Hostname,
+			Port:     e.Config.Port,
+			UserName: e.Config.UserName,
+			Password: e.Config.Password,
+			Schema:   e.Config.Schema,
+
+			ConnOptions: e.Config.ConnOptions,
+		},
+		ConnectionID:  connectionID,
+		StartFile:     startFile,
+		StartPosition: startPosition,
+		EndFile:       endFile,
+		EndPosition:   endPosition,
+	}
+	return binlog.Run()
+}
+
+// publishChunkProgress 推送分批执行进度（复用 ghost-progress 消息类型，便于前端展示）
+func (e *MySQLExecutor) publishChunkProgress(chunkIndex int, current, total int64) {
+	if e.Config.OrderID == "" {
+		return
+	}
+	percent := 100.0
+	if total > 0 && current < total {
+		percent = float64(current) * 100 / float64(total)
+	}
+	progressData := map[string]interface{}{
+		"current":   current,
+		"total":     total,
+		"percent":   percent,
+		"chunk":     chunkIndex,
+		"operation": "chunk",
+	}
+	if err := utils.PublishMessageToChannel(e.Config.OrderID, progressData, "ghost-progress"); err != nil {
+		global.Logger.Error("Failed to publish chunk progress", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+	if err := utils.SaveGhostProgressToRedis(e.Config.OrderID, progressData); err != nil {
+		global.Logger.Warn("Failed to save chunk progress to Redis cache", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+}
@@ -0,0 +1,62 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestBuildChunkPlan(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		SQL       string
+		Expect    *chunkPlan
+		ExpectErr string // 期望错误包含的消息
+	}{
+		{
+			Name: "单表UPDATE",
+			SQL:  "UPDATE users SET status = 1, name = 'a' WHERE created_at < '2024-01-01'",
+			Expect: &chunkPlan{
+				Schema: "test", Table: "users", TableRef: "`users`", Qualify: "users",
+				BaseSQL: "UPDATE `users` SET `status`=1, `name`='a'",
+				Where:   "`created_at`<'2024-01-01'",
+				SetCols: []string{"status", "name"},
+			},
+		},
+		{
+			Name: "带库名和别名的DELETE",
+			SQL:  "DELETE FROM db1.orders AS o WHERE o.status = 3",
+			Expect: &chunkPlan{
+				Schema: "db1", Table: "orders", TableRef: "`db1`.`orders` AS `o`", Qualify: "o",
+				BaseSQL: "DELETE FROM `db1`.`orders` AS `o`",
+				Where:   "`o`.`status`=3",
+			},
+		},
+		{Name: "缺少WHERE", SQL: "DELETE FROM users", ExpectErr: "WHERE"},
+		{Name: "LIMIT", SQL: "DELETE FROM users WHERE id > 1 LIMIT 10", ExpectErr: "LIMIT"},
+		{Name: "ORDER BY", SQL: "UPDATE users SET a = 1 WHERE id > 1 ORDER BY id", ExpectErr: "ORDER BY"},
+		{Name: "多表UPDATE", SQL: "UPDATE a JOIN b ON a.id = b.id SET a.x = 1 WHERE b.y = 2", ExpectErr: "单表"},
+		{Name: "多表DELETE", SQL: "DELETE a FROM a JOIN b ON a.id = b.id WHERE b.y = 2", ExpectErr: "多表"},
+		{Name: "多条语句", SQL: "DELETE FROM a WHERE id = 1; DELETE FROM b WHERE id = 1", ExpectErr: "单条语句"},
+		{Name: "INSERT", SQL: "INSERT INTO a VALUES (1)", ExpectErr: "UPDATE/DELETE"},
+		{Name: "语法错误", SQL: "DELETE FROM WHERE", ExpectErr: "SQL解析错误"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			plan, err := buildChunkPlan(tc.SQL, "test")
+			if tc.ExpectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tc.ExpectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("生成分批执行计划失败: %v", err)
+			}
+			if fmt.Sprintf("%+v", *plan) != fmt.Sprintf("%+v", *tc.Expect) {
+				t.Errorf("期望 %+v\n实际 %+v", *tc.Expect, *plan)
+			}
+		})
+	}
+}
@@ -0,0 +1,574 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-noah/pkg/global"
+	"go-noah/pkg/secret"
+	"go-noah/pkg/utils"
+	"net"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"go.uber.org/zap"
+)
+
+var (
+	// 匹配 mutation 语句：ALTER TABLE db.tbl [ON CLUSTER xxx] UPDATE/DELETE ...
+	chMutationRegex = regexp.MustCompile("(?is)^\\s*ALTER\\s+TABLE\\s+([`\"\\w.]+)(?:\\s+ON\\s+CLUSTER\\s+('[^']+'|`[^`]+`|[\\w.{}-]+))?\\s+(UPDATE|DELETE)\\b")
+	// 匹配分布式DDL：... ON CLUSTER xxx ...
+	chOnClusterRegex = regexp.MustCompile("(?is)\\bON\\s+CLUSTER\\s+('[^']+'|`[^`]+`|[\\w.{}-]+)")
+	// 匹配 DROP DATABASE
+	chDropDatabaseRegex = regexp.MustCompile(`(?is)^\s*DROP\s+DATABASE\b`)
+	// 匹配 INSERT 语句
+	chInsertRegex = regexp.MustCompile(`(?is)^\s*INSERT\s+INTO\b`)
+)
+
+const (
+	// mutationCaptureTimeout 提交后等待本次提交的 mutation 出现在 system.mutations 中的最长时间
+	mutationCaptureTimeout = time.Minute
+)
+
+// errMutationMismatch 提交后新出现的 mutation 命令均与本次提交不一致（可能是其他用户同时提交的）
+var errMutationMismatch = errors.New("提交后新出现的mutation与本次提交的命令不一致")
+
+// ClickHouseExecutor ClickHouse执行器
+type ClickHouseExecutor struct {
+	Config *DBConfig
+}
+
+// NewClickHouseExecutor 创建ClickHouse执行器
+func NewClickHouseExecutor(config *DBConfig) *ClickHouseExecutor {
+	return &ClickHouseExecutor{Config: config}
+}
+
+// Run 执行SQL
+func (e *ClickHouseExecutor) Run(ctx context.Context) (ReturnData, error) {
+	switch e.Config.SQLType {
+	case "DDL":
+		return e.ExecuteDDL(ctx)
+	case "DML":
+		return e.ExecuteDML(ctx)
+	case "EXPORT":
+		return e.ExecuteExport(ctx)
+	default:
+		return ReturnData{Error: fmt.Sprintf("不支持的SQL类型: %s", e.Config.SQLType)}, fmt.Errorf("不支持的SQL类型: %s", e.Config.SQLType)
+	}
+}
+
+// Connect 连接数据库（使用 native 协议）
+func (e *ClickHouseExecutor) Connect() (*sql.DB, error) {
+	password, err := secret.Decrypt(e.Config.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密实例密码失败: %w", err)
+	}
+	tlsConfig, err := e.Config.ConnOptions.TLSConfig(e.Config.Hostname)
+	if err != nil {
+		return nil, err
+	}
+	options := &clickhouse.Options{
+		Addr: []string{fmt.Sprintf("%s:%d", e.Config.Hostname, e.Config.Port)},
+		Auth: clickhouse.Auth{
+			Database: e.Config.Schema,
+			Username: e.Config.UserName,
+			Password: password,
+		},
+		DialTimeout: 10 * time.Second,
+		ReadTimeout: 300 * time.Second,
+		TLS:         tlsConfig,
+	}
+	if !e.Config.ConnOptions.IsDirect() {
+		options.DialContext = func(ctx context.Context, addr string) (net.Conn, error) {
+			return e.Config.ConnOptions.DialContext(ctx, "tcp", addr)
+		}
+	}
+	db := clickhouse.OpenDB(options)
+
+	db.SetMaxOpenConns(1)
+	db.SetMaxIdleConns(1)
+	db.SetConnMaxLifetime(5 * time.Minute)
+
+	// 测试连接
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// newLogger 创建执行日志记录函数（同时推送到 WebSocket）
+func (e *ClickHouseExecutor) newLogger(executeLog *[]string) func(string) {
+	return func(msg string) {
+		timestamp := time.Now().Format("2006-01-02 15:04:05")
+		logMsg := fmt.Sprintf("[%s] %s", timestamp, msg)
+		*executeLog = append(*executeLog, logMsg)
+		// 发布消息到 Redis（用于 WebSocket 推送）
+		if e.Config.OrderID != "" {
+			_ = utils.PublishMessageToChannel(e.Config.OrderID, logMsg, "")
+		}
+	}
+}
+
+// ExecuteDDL 执行DDL语句
+// 普通DDL（包括 ON CLUSTER 分布式DDL）直接执行；mutation 语句（ALTER TABLE ... UPDATE/DELETE）
+// 提交后轮询 system.mutations 跟踪进度
+func (e *ClickHouseExecutor) ExecuteDDL(ctx context.Context) (ReturnData, error) {
+	if chDropDatabaseRegex.MatchString(e.Config.SQL) {
+		return ReturnData{Error: "【风险】禁止执行drop database操作"}, errors.New("【风险】禁止执行drop database操作")
+	}
+	if chMutationRegex.MatchString(e.Config.SQL) {
+		return e.ExecuteMutation(ctx)
+	}
+	return e.executeStatement(ctx)
+}
+
+// ExecuteDML 执行DML语句
+// ClickHouse 仅支持 INSERT；UPDATE/DELETE 需使用 ALTER TABLE ... UPDATE/DELETE（mutation）
+func (e *ClickHouseExecutor) ExecuteDML(ctx context.Context) (ReturnData, error) {
+	if chMutationRegex.MatchString(e.Config.SQL) {
+		return e.ExecuteMutation(ctx)
+	}
+	if !chInsertRegex.MatchString(e.Config.SQL) {
+		errMsg := "ClickHouse仅支持INSERT语句，更新或删除数据请使用ALTER TABLE ... UPDATE/DELETE语法"
+		return ReturnData{Error: errMsg}, errors.New(errMsg)
+	}
+	return e.executeStatement(ctx)
+}
+
+// executeStatement 直接执行单条语句
+func (e *ClickHouseExecutor) executeStatement(ctx context.Context) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+
+	// 连接数据库
+	logMessage(fmt.Sprintf("连接数据库 %s:%d...", e.Config.Hostname, e.Config.Port))
+	db, err := e.Connect()
+	if err != nil {
+		logMessage(fmt.Sprintf("连接失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+	defer db.Close()
+	logMessage("连接成功")
+
+	if matches := chOnClusterRegex.FindStringSubmatch(e.Config.SQL); len(matches) > 1 {
+		logMessage(fmt.Sprintf("检测到分布式DDL，集群: %s", unquoteIdentifier(matches[1])))
+	}
+
+	// 执行SQL
+	logMessage(fmt.Sprintf("执行SQL: %s", truncateSQL(e.Config.SQL, 200)))
+	startTime := time.Now()
+
+	result, err := db.ExecContext(ctx, e.Config.SQL)
+	if err != nil {
+		logMessage(fmt.Sprintf("执行失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	affectedRows, _ := result.RowsAffected()
+	executeCostTime := time.Since(startTime).String()
+
+	logMessage(fmt.Sprintf("执行成功，影响行数: %d，耗时: %s", affectedRows, executeCostTime))
+	if e.Config.SQLType == "DML" {
+		logMessage("ClickHouse不支持生成回滚SQL")
+	}
+
+	data.AffectedRows = affectedRows
+	data.ExecuteCostTime = executeCostTime
+	data.ExecuteLog = strings.Join(executeLog, "\n")
+	return data, nil
+}
+
+// ExecuteMutation 执行 mutation 语句（ALTER TABLE ... UPDATE/DELETE）
+// mutation 在 ClickHouse 中异步执行，提交后轮询 system.mutations 直到完成或失败
+func (e *ClickHouseExecutor) ExecuteMutation(ctx context.Context) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+
+	matches := chMutationRegex.FindStringSubmatchIndex(e.Config.SQL)
+	database, table := e.splitTableName(e.Config.SQL[matches[2]:matches[3]])
+	mutation := &chMutation{
+		database: database,
+		table:    table,
+		command:  normalizeMutationCommand(e.Config.SQL[matches[6]:]),
+	}
+	if matches[4] >= 0 {
+		mutation.cluster = unquoteIdentifier(e.Config.SQL[matches[4]:matches[5]])
+	}
+
+	// 连接数据库
+	logMessage(fmt.Sprintf("连接数据库 %s:%d...", e.Config.Hostname, e.Config.Port))
+	db, err := e.Connect()
+	if err != nil {
+		logMessage(fmt.Sprintf("连接失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+	defer db.Close()
+	logMessage("连接成功")
+
+	if mutation.cluster != "" {
+		logMessage(fmt.Sprintf("检测到分布式mutation，集群: %s", mutation.cluster))
+	}
+
+	// 记录提交前的时间点和已有的 mutation，提交后据此定位本次提交的 mutation_id（system.mutations.create_time 精度为秒）
+	if err := db.QueryRowContext(ctx, "SELECT now()").Scan(&mutation.submitTime); err != nil {
+		logMessage(fmt.Sprintf("获取服务器时间失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+	if mutation.existing, err = mutation.listIDs(ctx, db); err != nil {
+		logMessage(fmt.Sprintf("查询已有mutation失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	// 提交 mutation
+	logMessage(fmt.Sprintf("提交mutation: %s", truncateSQL(e.Config.SQL, 200)))
+	startTime := time.Now()
+	if _, err := db.ExecContext(ctx, e.Config.SQL); err != nil {
+		logMessage(fmt.Sprintf("执行失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+	logMessage(fmt.Sprintf("mutation已提交，开始跟踪执行进度（%s.%s）", database, table))
+
+	// 轮询 system.mutations
+	if err := e.waitMutation(ctx, db, mutation, logMessage); err != nil {
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	executeCostTime := time.Since(startTime).String()
+	logMessage(fmt.Sprintf("mutation执行完成，耗时: %s", executeCostTime))
+	logMessage("ClickHouse不支持生成回滚SQL")
+
+	data.ExecuteCostTime = executeCostTime
+	data.ExecuteLog = strings.Join(executeLog, "\n")
+	return data, nil
+}
+
+// chMutation 本次提交的 mutation，只跟踪和终止提交后捕获到的 mutation_id，不影响其他用户的 mutation
+type chMutation struct {
+	database   string
+	table      string
+	cluster    string
+	command    string // 归一化的 mutation 命令，用于区分同一时间提交的其他 mutation
+	submitTime time.Time
+	existing   map[string]bool // 提交前已存在的 mutation_id
+	ids        []string        // 本次提交的 mutation_id（分布式表各分片可能不同）
+}
+
+// source 查询的 mutation 表，分布式 mutation 查询集群所有副本
+func (m *chMutation) source() string {
+	if m.cluster == "" {
+		return "system.mutations"
+	}
+	return fmt.Sprintf("clusterAllReplicas('%s', system.mutations)", strings.ReplaceAll(m.cluster, "'", "\\'"))
+}
+
+// listIDs 查询目标表在提交时间点之后创建的 mutation_id 和命令
+func (m *chMutation) listIDs(ctx context.Context, db *sql.DB) (map[string]bool, error) {
+	commands, err := m.listCommands(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[string]bool, len(commands))
+	for id := range commands {
+		ids[id] = true
+	}
+	return ids, nil
+}
+
+func (m *chMutation) listCommands(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf(`SELECT DISTINCT mutation_id, command
+		FROM %s
+		WHERE database = ? AND table = ? AND create_time >= ?`, m.source()), m.database, m.table, m.submitTime)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	commands := make(map[string]string)
+	for rows.Next() {
+		var id, command string
+		if err := rows.Scan(&id, &command); err != nil {
+			return nil, err
+		}
+		commands[id] = command
+	}
+	return commands, rows.Err()
+}
+
+// capture 捕获本次提交的 mutation_id：提交前不存在、且命令与本次提交一致的 mutation
+func (m *chMutation) capture(ctx context.Context, db *sql.DB) error {
+	commands, err := m.listCommands(ctx, db)
+	if err != nil {
+		return err
+	}
+	m.ids, err = selectMutationIDs(commands, m.existing, m.command)
+	return err
+}
+
+// selectMutationIDs 从提交后新出现的 mutation 中选出命令与本次提交一致的 mutation_id
+// 新出现的 mutation 命令都不一致时不返回（避免跟踪或终止其他用户的 mutation），返回 errMutationMismatch
+func selectMutationIDs(commands map[string]string, existing map[string]bool, command string) ([]string, error) {
+	var added, matched []string
+	for id, cmd := range commands {
+		if existing[id] {
+			continue
+		}
+		added = append(added, id)
+		if normalizeMutationCommand(cmd) == command {
+			matched = append(matched, id)
+		}
+	}
+	if len(matched) == 0 && len(added) > 0 {
+		sort.Strings(added)
+		return nil, fmt.Errorf("%w: %s", errMutationMismatch, strings.Join(added, ", "))
+	}
+	sort.Strings(matched)
+	return matched, nil
+}
+
+// normalizeMutationCommand 归一化 mutation 命令（去掉空白、引号、末尾分号，不区分大小写）
+func normalizeMutationCommand(command string) string {
+	command = strings.TrimRight(strings.TrimSpace(command), ";")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '`' || r == '"' {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, command)
+}
+
+// waitMutation 等待 mutation 执行完成，并推送进度
+func (e *ClickHouseExecutor) waitMutation(ctx context.Context, db *sql.DB, mutation *chMutation, logMessage func(string)) error {
+	querySQL := fmt.Sprintf(`SELECT mutation_id, parts_to_do, is_done, latest_fail_reason
+		FROM %s
+		WHERE database = ? AND table = ? AND has(?, mutation_id)`, mutation.source())
+
+	var maxPartsToDo int64
+	captureStart := time.Now()
+	var mismatch string // 最近一次记录的命令不一致警告，避免重复记录
+	ticker := time.NewTicker(2 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			// 任务被取消，终止本次提交的 mutation
+			logMessage("执行被中断，终止mutation...")
+			if err := e.killMutation(db, mutation); err != nil {
+				logMessage(fmt.Sprintf("终止mutation失败，请通过system.mutations确认状态: %s", err.Error()))
+			}
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		// mutation 记录可能尚未可见，捕获到 mutation_id 前继续等待；
+		// 其他用户同时提交的 mutation 不跟踪，超过等待时间仍未找到本次提交的 mutation 时停止跟踪
+		if len(mutation.ids) == 0 {
+			err := mutation.capture(ctx, db)
+			if err != nil && !errors.Is(err, errMutationMismatch) {
+				logMessage(fmt.Sprintf("查询mutation失败: %s", err.Error()))
+				return err
+			}
+			if len(mutation.ids) == 0 {
+				if err != nil && err.Error() != mismatch {
+					mismatch = err.Error()
+					logMessage(fmt.Sprintf("警告: %s，继续等待本次提交的mutation", mismatch))
+				}
+				if time.Since(captureStart) > mutationCaptureTimeout {
+					err := fmt.Errorf("提交后 %s 内未找到本次提交的mutation，请通过system.mutations确认执行状态", mutationCaptureTimeout)
+					logMessage(err.Error())
+					return err
+				}
+				continue
+			}
+			logMessage(fmt.Sprintf("本次提交的mutation_id: %s", strings.Join(mutation.ids, ", ")))
+		}
+
+		rows, err := db.QueryContext(ctx, querySQL, mutation.database, mutation.table, mutation.ids)
+		if err != nil {
+			logMessage(fmt.Sprintf("查询mutation进度失败: %s", err.Error()))
+			return err
+		}
+
+		var (
+			found      int
+			partsToDo  int64
+			allDone    = true
+			failReason string
+			mutationID string
+		)
+		for rows.Next() {
+			var (
+				id      string
+				toDo    int64
+				isDone  uint8
+				failMsg string
+			)
+			if err := rows.Scan(&id, &toDo, &isDone, &failMsg); err != nil {
+				rows.Close()
+				logMessage(fmt.Sprintf("读取mutation进度失败: %s", err.Error()))
+				return err
+			}
+			found++
+			mutationID = id
+			partsToDo += toDo
+			if isDone == 0 {
+				allDone = false
+			}
+			if failMsg != "" {
+				failReason = failMsg
+			}
+		}
+		rows.Close()
+
+		if found == 0 {
+			continue
+		}
+
+		if partsToDo > maxPartsToDo {
+			maxPartsToDo = partsToDo
+		}
+		percent := 100.0
+		if maxPartsToDo > 0 {
+			percent = float64(maxPartsToDo-partsToDo) / float64(maxPartsToDo) * 100
+		}
+		e.publishMutationProgress(mutationID, partsToDo, maxPartsToDo, percent)
+
+		if failReason != "" {
+			logMessage(fmt.Sprintf("mutation执行失败: %s", failReason))
+			return fmt.Errorf("mutation执行失败: %s", failReason)
+		}
+		if allDone {
+			return nil
+		}
+		logMessage(fmt.Sprintf("mutation执行中，剩余parts: %d，进度: %.2f%%", partsToDo, percent))
+	}
+}
+
+// killMutation 按 mutation_id 终止本次提交的 mutation
+func (e *ClickHouseExecutor) killMutation(db *sql.DB, mutation *chMutation) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	// 取消时可能尚未捕获到 mutation_id
+	if len(mutation.ids) == 0 {
+		if err := mutation.capture(ctx, db); err != nil {
+			return err
+		}
+		if len(mutation.ids) == 0 {
+			return errors.New("未找到本次提交的mutation")
+		}
+	}
+
+	killSQL := "KILL MUTATION"
+	if mutation.cluster != "" {
+		killSQL += fmt.Sprintf(" ON CLUSTER '%s'", strings.ReplaceAll(mutation.cluster, "'", "\\'"))
+	}
+	killSQL += " WHERE database = ? AND table = ? AND has(?, mutation_id)"
+	_, err := db.ExecContext(ctx, killSQL, mutation.database, mutation.table, mutation.ids)
+	return err
+}
+
+// publishMutationProgress 推送 mutation 进度（复用 ghost-progress 消息类型，便于前端展示）
+func (e *ClickHouseExecutor) publishMutationProgress(mutationID string, partsToDo, totalParts int64, percent float64) {
+	if e.Config.OrderID == "" {
+		return
+	}
+	progressData := map[string]interface{}{
+		"current":     totalParts - partsToDo,
+		"total":       totalParts,
+		"percent":     percent,
+		"mutation_id": mutationID,
+		"operation":   "mutation",
+	}
+	if err := utils.PublishMessageToChannel(e.Config.OrderID, progressData, "ghost-progress"); err != nil {
+		global.Logger.Error("Failed to publish mutation progress", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+	if err := utils.SaveGhostProgressToRedis(e.Config.OrderID, progressData); err != nil {
+		global.Logger.Warn("Failed to save mutation progress to Redis cache", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+}
+
+// ExecuteExport 执行导出
+func (e *ClickHouseExecutor) ExecuteExport(ctx context.Context) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+
+	// 连接数据库
+	logMessage(fmt.Sprintf("连接数据库 %s:%d...", e.Config.Hostname, e.Config.Port))
+	db, err := e.Connect()
+	if err != nil {
+		logMessage(fmt.Sprintf("连接失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+	defer db.Close()
+	logMessage("连接成功")
+
+	// 执行查询
+	logMessage(fmt.Sprintf("执行查询: %s", truncateSQL(e.Config.SQL, 200)))
+	startTime := time.Now()
+
+	rows, err := db.QueryContext(ctx, e.Config.SQL)
+	if err != nil {
+		logMessage(fmt.Sprintf("查询失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+	defer rows.Close()
+
+	// 流式写入导出文件
+	exportFile, err := exportToFile(rows, e.Config, logMessage)
+	if err != nil {
+		logMessage(fmt.Sprintf("导出失败: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	executeCostTime := time.Since(startTime).String()
+	logMessage(fmt.Sprintf("导出成功，文件: %s，大小: %d 字节，行数: %d，耗时: %s", exportFile.FileName, exportFile.FileSize, exportFile.ExportRows, executeCostTime))
+
+	data.ExportFile = exportFile
+	data.ExecuteCostTime = executeCostTime
+	data.ExecuteLog = strings.Join(executeLog, "\n")
+	return data, nil
+}
+
+// splitTableName 拆分表名，未指定库名时使用工单库名
+func (e *ClickHouseExecutor) splitTableName(name string) (string, string) {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) == 2 {
+		return unquoteIdentifier(parts[0]), unquoteIdentifier(parts[1])
+	}
+	return e.Config.Schema, unquoteIdentifier(parts[0])
+}
+
+// unquoteIdentifier 去除标识符两侧的引号
+func unquoteIdentifier(name string) string {
+	return strings.Trim(name, "`\"'")
+}
@@ -0,0 +1,80 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeMutationCommand(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		A, B   string
+		Expect bool
+	}{
+		{Name: "空白和大小写", A: "DELETE WHERE id = 1", B: "delete  where\n id=1;", Expect: true},
+		{Name: "标识符引号", A: "UPDATE `status` = 0 WHERE \"id\" > 10", B: "UPDATE status = 0 WHERE id > 10", Expect: true},
+		{Name: "条件不同", A: "DELETE WHERE id = 1", B: "DELETE WHERE id = 2"},
+		{Name: "操作不同", A: "DELETE WHERE id = 1", B: "UPDATE x = 1 WHERE id = 1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if equal := normalizeMutationCommand(tc.A) == normalizeMutationCommand(tc.B); equal != tc.Expect {
+				t.Errorf("期望相同=%v，实际 %v（%q / %q）", tc.Expect, equal, normalizeMutationCommand(tc.A), normalizeMutationCommand(tc.B))
+			}
+		})
+	}
+}
+
+func TestSelectMutationIDs(t *testing.T) {
+	command := normalizeMutationCommand("DELETE WHERE id = 1")
+	testCases := []struct {
+		Name      string
+		Commands  map[string]string
+		Existing  map[string]bool
+		Expect    string
+		ExpectErr bool // 期望返回 errMutationMismatch
+	}{
+		{Name: "尚未可见", Expect: ""},
+		{
+			Name:     "排除提交前已存在的mutation",
+			Commands: map[string]string{"mutation_1.txt": "DELETE WHERE id = 1", "mutation_2.txt": "DELETE WHERE id = 1"},
+			Existing: map[string]bool{"mutation_1.txt": true},
+			Expect:   "mutation_2.txt",
+		},
+		{
+			Name:     "同时提交的其他mutation",
+			Commands: map[string]string{"mutation_2.txt": "DELETE WHERE id = 1", "mutation_3.txt": "UPDATE x = 1 WHERE 1"},
+			Expect:   "mutation_2.txt",
+		},
+		{
+			Name:     "分片的mutation_id不同",
+			Commands: map[string]string{"0000000003": "DELETE WHERE id = 1", "mutation_7.txt": "DELETE WHERE id = 1"},
+			Expect:   "0000000003,mutation_7.txt",
+		},
+		{
+			Name:      "命令格式不一致时不返回新出现的mutation",
+			Commands:  map[string]string{"mutation_2.txt": "DELETE WHERE equals(id, 1)"},
+			Expect:    "",
+			ExpectErr: true,
+		},
+		{
+			Name:      "只有无关的并发mutation",
+			Commands:  map[string]string{"mutation_3.txt": "UPDATE status = 0 WHERE created < '2026-01-01'", "mutation_4.txt": "DELETE WHERE id = 2"},
+			Existing:  map[string]bool{"mutation_1.txt": true},
+			Expect:    "",
+			ExpectErr: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			ids, err := selectMutationIDs(tc.Commands, tc.Existing, command)
+			if got := strings.Join(ids, ","); got != tc.Expect {
+				t.Errorf("期望 %q，实际 %q", tc.Expect, got)
+			}
+			if tc.ExpectErr != errors.Is(err, errMutationMismatch) {
+				t.Errorf("期望命令不一致错误=%v，实际 %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
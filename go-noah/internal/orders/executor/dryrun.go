@@ -0,0 +1,268 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-noah/internal/inspect/parser"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/global"
+	"strings"
+	"time"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+const (
+	// defaultDryRunSampleRows 默认前后镜像采样行数
+	defaultDryRunSampleRows = 10
+	// defaultDryRunLockWaitTimeout 试运行时的锁等待超时（秒），避免长时间阻塞线上业务
+	defaultDryRunLockWaitTimeout = 5
+)
+
+// DryRunResult 单个任务的试运行结果
+type DryRunResult struct {
+	TaskID       string          `json:"task_id"`
+	SQL          string          `json:"sql"`
+	AffectedRows int64           `json:"affected_rows"` // 实际影响行数
+	CostTime     string          `json:"cost_time"`     // 执行耗时
+	Columns      []string        `json:"columns"`       // 采样列名
+	BeforeRows   [][]interface{} `json:"before_rows"`   // 执行前镜像（UPDATE/DELETE）
+	AfterRows    [][]interface{} `json:"after_rows"`    // 执行后镜像（UPDATE）
+	Notes        []string        `json:"notes"`         // 说明（如无法采样的原因）
+	Error        string          `json:"error"`
+}
+
+// getDryRunSettings 获取试运行参数
+func getDryRunSettings() (sampleRows, lockWaitTimeout int) {
+	sampleRows, lockWaitTimeout = defaultDryRunSampleRows, defaultDryRunLockWaitTimeout
+	if global.Conf != nil {
+		if v := global.Conf.GetInt("dry_run.sample_rows"); v > 0 {
+			sampleRows = v
+		}
+		if v := global.Conf.GetInt("dry_run.lock_wait_timeout"); v > 0 {
+			lockWaitTimeout = v
+		}
+	}
+	return sampleRows, lockWaitTimeout
+}
+
+// dryRunReadOnlyVariables 使实例拒绝写入的只读变量（MySQL 与 TiDB）
+var dryRunReadOnlyVariables = []string{"read_only", "super_read_only", "tidb_super_read_only", "tidb_restricted_read_only"}
+
+// CheckDryRunWritable 检查试运行实例是否只读
+// 从库通常开启 read_only/super_read_only，试运行的 DML 会被拒绝，执行前返回明确的错误
+func (e *MySQLExecutor) CheckDryRunWritable(ctx context.Context) error {
+	db, err := e.Connect()
+	if err != nil {
+		return fmt.Errorf("连接数据库失败: %s", err.Error())
+	}
+	defer db.Close()
+
+	query := fmt.Sprintf("SHOW GLOBAL VARIABLES WHERE Variable_name IN ('%s')", strings.Join(dryRunReadOnlyVariables, "','"))
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("查询只读状态失败: %s", err.Error())
+	}
+	defer rows.Close()
+	variables := make(map[string]string)
+	for rows.Next() {
+		var name, value string
+		if err := rows.Scan(&name, &value); err != nil {
+			return fmt.Errorf("查询只读状态失败: %s", err.Error())
+		}
+		variables[strings.ToLower(name)] = value
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("查询只读状态失败: %s", err.Error())
+	}
+	if name := readOnlyVariable(variables); name != "" {
+		return fmt.Errorf("试运行实例 %s:%d 为只读实例（%s=ON），无法执行试运行，请关闭只读或配置可写的沙箱实例", e.Config.Hostname, e.Config.Port, name)
+	}
+	return nil
+}
+
+// readOnlyVariable 返回第一个已开启的只读变量名，均未开启时返回空
+func readOnlyVariable(variables map[string]string) string {
+	for _, name := range dryRunReadOnlyVariables {
+		switch strings.ToUpper(variables[name]) {
+		case "ON", "1":
+			return name
+		}
+	}
+	return ""
+}
+
+// DryRunDML 在事务中执行DML，统计实际影响行数并采样前后镜像，结束后始终回滚
+func (e *MySQLExecutor) DryRunDML(ctx context.Context) *DryRunResult {
+	result := &DryRunResult{TaskID: e.Config.TaskID, SQL: e.Config.SQL}
+	sampleRows, lockWaitTimeout := getDryRunSettings()
+
+	sample, err := buildDMLTarget(e.Config.SQL, e.Config.Schema)
+	if err != nil {
+		result.Notes = append(result.Notes, fmt.Sprintf("未采样前后镜像: %s", err.Error()))
+	}
+
+	db, err := e.Connect()
+	if err != nil {
+		result.Error = fmt.Sprintf("连接数据库失败: %s", err.Error())
+		return result
+	}
+	defer db.Close()
+
+	// 非事务表的修改无法回滚，执行前检查
+	if err := checkTransactionalTables(ctx, db, e.Config.SQL, e.Config.Schema); err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	// 会话变量对后续事务生效，修改后连接不再放回连接池
+	db.Discard()
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("SET SESSION innodb_lock_wait_timeout = %d", lockWaitTimeout)); err != nil {
+		result.Notes = append(result.Notes, fmt.Sprintf("设置锁等待超时失败: %s", err.Error()))
+	}
+
+	// UPDATE 需要通过主键定位执行后的数据（须在开启事务前查询）
+	var pk string
+	if sample != nil && sample.IsUpdate {
+		if pk, err = getPrimaryKeyColumn(ctx, db, sample.Schema, sample.Table); err != nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("未采样执行后镜像: %s", err.Error()))
+		}
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		result.Error = fmt.Sprintf("开启事务失败: %s", err.Error())
+		return result
+	}
+	// 无论成功与否都回滚
+	defer tx.Rollback()
+
+	var keys []interface{}
+	if sample != nil {
+		pkIndex := -1
+		query := fmt.Sprintf("SELECT %s.* FROM %s", quoteIdentifier(sample.Qualify), sample.TableRef)
+		if sample.Where != "" {
+			query += " WHERE " + sample.Where
+		}
+		if sample.Order != "" {
+			query += " " + sample.Order
+		}
+		query += fmt.Sprintf(" LIMIT %d", sampleRows)
+		result.Columns, result.BeforeRows, err = queryRows(ctx, tx, query)
+		if err != nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("采样执行前镜像失败: %s", err.Error()))
+		}
+		for i, column := range result.Columns {
+			if pk != "" && strings.EqualFold(column, pk) {
+				pkIndex = i
+			}
+		}
+		if pkIndex >= 0 {
+			for _, row := range result.BeforeRows {
+				keys = append(keys, row[pkIndex])
+			}
+		}
+	}
+
+	start := time.Now()
+	res, err := tx.ExecContext(ctx, e.Config.SQL)
+	result.CostTime = time.Since(start).String()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.AffectedRows, _ = res.RowsAffected()
+
+	if sample != nil && sample.IsUpdate && len(keys) > 0 {
+		placeholders := strings.TrimSuffix(strings.Repeat("?,", len(keys)), ",")
+		query := fmt.Sprintf("SELECT * FROM %s.%s WHERE %s IN (%s)",
+			quoteIdentifier(sample.Schema), quoteIdentifier(sample.Table), quoteIdentifier(pk), placeholders)
+		if _, result.AfterRows, err = queryRows(ctx, tx, query, keys...); err != nil {
+			result.Notes = append(result.Notes, fmt.Sprintf("采样执行后镜像失败: %s", err.Error()))
+		}
+	}
+	return result
+}
+
+// tableNameVisitor 收集语句中引用的表
+type tableNameVisitor struct {
+	tables []*ast.TableName
+}
+
+func (v *tableNameVisitor) Enter(in ast.Node) (ast.Node, bool) {
+	if table, ok := in.(*ast.TableName); ok {
+		v.tables = append(v.tables, table)
+	}
+	return in, false
+}
+
+func (v *tableNameVisitor) Leave(in ast.Node) (ast.Node, bool) {
+	return in, true
+}
+
+// checkTransactionalTables 检查语句引用的表均为 InnoDB 表（视图和非事务表的修改在回滚后仍会保留）
+func checkTransactionalTables(ctx context.Context, db *dbpool.Conn, sqltext, defaultSchema string) error {
+	audit, _, err := parser.ParseSQL(sqltext)
+	if err != nil {
+		return fmt.Errorf("SQL解析错误: %s", err.Error())
+	}
+	visitor := &tableNameVisitor{}
+	for _, stmt := range audit.TiStmt {
+		stmt.Accept(visitor)
+	}
+	for _, table := range visitor.tables {
+		schema := table.Schema.O
+		if schema == "" {
+			schema = defaultSchema
+		}
+		var engine sql.NullString
+		err := db.QueryRowContext(ctx, "SELECT ENGINE FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+			schema, table.Name.O).Scan(&engine)
+		if errors.Is(err, sql.ErrNoRows) {
+			// CTE 名称或不存在的表（不存在的表执行时报错）
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("获取表 %s.%s 的存储引擎失败: %s", schema, table.Name.O, err.Error())
+		}
+		if !strings.EqualFold(engine.String, "InnoDB") {
+			return fmt.Errorf("表 %s.%s 不是 InnoDB 表（存储引擎: %s），试运行的修改无法回滚，已拒绝执行", schema, table.Name.O, engine.String)
+		}
+	}
+	return nil
+}
+
+// queryRows 查询并返回列名和行数据（NULL 为 nil，其余转为字符串）
+func queryRows(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, [][]interface{}, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	var data [][]interface{}
+	for rows.Next() {
+		values := make([]sql.RawBytes, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, nil, err
+		}
+		row := make([]interface{}, len(columns))
+		for i, value := range values {
+			if value != nil {
+				row[i] = string(value)
+			}
+		}
+		data = append(data, row)
+	}
+	return columns, data, rows.Err()
+}
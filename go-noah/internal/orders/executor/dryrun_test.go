@@ -0,0 +1,26 @@
+package executor
+
+import "testing"
+
+func TestReadOnlyVariable(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Variables map[string]string
+		Expect    string
+	}{
+		{Name: "可写的主库", Variables: map[string]string{"read_only": "OFF", "super_read_only": "OFF"}, Expect: ""},
+		{Name: "从库开启 read_only", Variables: map[string]string{"read_only": "ON", "super_read_only": "OFF"}, Expect: "read_only"},
+		{Name: "从库开启 super_read_only", Variables: map[string]string{"read_only": "ON", "super_read_only": "ON"}, Expect: "read_only"},
+		{Name: "MariaDB 没有 super_read_only", Variables: map[string]string{"read_only": "OFF"}, Expect: ""},
+		{Name: "TiDB 开启 tidb_super_read_only", Variables: map[string]string{"read_only": "OFF", "tidb_super_read_only": "ON"}, Expect: "tidb_super_read_only"},
+		{Name: "数值形式的取值", Variables: map[string]string{"tidb_restricted_read_only": "1"}, Expect: "tidb_restricted_read_only"},
+		{Name: "没有查询到变量", Variables: map[string]string{}, Expect: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := readOnlyVariable(tc.Variables); got != tc.Expect {
+				t.Errorf("期望 %q，实际 %q", tc.Expect, got)
+			}
+		})
+	}
+}
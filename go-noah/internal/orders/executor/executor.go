@@ -1,6 +1,18 @@
 package executor
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-noah/pkg/utils"
+	"sync"
+)
+
+// ErrTaskCancelled 任务被取消
+var ErrTaskCancelled = errors.New("任务已被取消")
+
+// runningTasks 当前进程内正在执行的任务（task_id -> context.CancelCauseFunc）
+var runningTasks sync.Map
 
 // ExecuteSQL 执行SQL的统一入口
 type ExecuteSQL struct {
@@ -16,8 +28,7 @@ func NewExecuteSQL(config *DBConfig) (*ExecuteSQL, error) {
 	case "MySQL", "TiDB":
 		executor = NewMySQLExecutor(config)
 	case "ClickHouse":
-		// TODO: ClickHouse执行器
-		return nil, fmt.Errorf("ClickHouse执行器暂未实现")
+		executor = NewClickHouseExecutor(config)
 	default:
 		return nil, fmt.Errorf("不支持的数据库类型: %s", config.DBType)
 	}
@@ -29,7 +40,38 @@ func NewExecuteSQL(config *DBConfig) (*ExecuteSQL, error) {
 }
 
 // Run 执行SQL
-func (e *ExecuteSQL) Run() (ReturnData, error) {
-	return e.Executor.Run()
+// 执行期间任务注册到当前进程，可通过 CancelRunningTask 取消；被用户取消时返回 ErrTaskCancelled
+// （仅根据取消标记或取消原因判断，上级上下文因其他原因取消时不视为用户取消）
+func (e *ExecuteSQL) Run(ctx context.Context) (ReturnData, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	if e.Config.TaskID != "" {
+		utils.ClearTaskCancelled(e.Config.TaskID)
+		runningTasks.Store(e.Config.TaskID, cancel)
+		defer runningTasks.Delete(e.Config.TaskID)
+	}
+
+	data, err := e.Executor.Run(ctx)
+	if err != nil && (errors.Is(context.Cause(ctx), ErrTaskCancelled) || utils.IsTaskCancelled(e.Config.TaskID)) {
+		data.Error = ErrTaskCancelled.Error() + ": " + err.Error()
+		return data, fmt.Errorf("%w: %s", ErrTaskCancelled, err.Error())
+	}
+	// 上级上下文以其他原因取消（如执行锁丢失）时返回该原因，由调用方区分处理
+	if cause := context.Cause(ctx); err != nil && cause != nil && !errors.Is(cause, context.Canceled) && !errors.Is(cause, context.DeadlineExceeded) {
+		data.Error = cause.Error() + ": " + err.Error()
+		return data, fmt.Errorf("%w: %s", cause, err.Error())
+	}
+	return data, err
 }
 
+// CancelRunningTask 取消当前进程内正在执行的任务
+// 返回 false 表示任务不在当前进程中执行
+func CancelRunningTask(taskID string) bool {
+	v, ok := runningTasks.Load(taskID)
+	if !ok {
+		return false
+	}
+	v.(context.CancelCauseFunc)(ErrTaskCancelled)
+	return true
+}
@@ -0,0 +1,308 @@
+package executor
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"go-noah/pkg/global"
+	"go-noah/pkg/secret"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+	"github.com/yeka/zip"
+)
+
+const (
+	// defaultExportPath 默认导出文件存放目录
+	defaultExportPath = "./storage/export"
+	// xlsxMaxRows XLSX 单个工作表最大行数（含表头）
+	xlsxMaxRows = 1048576
+	// defaultExportExpireHours 默认导出文件下载有效期（小时）
+	defaultExportExpireHours = 24
+)
+
+// exportWriter 导出文件写入器（逐行写入，不在内存中缓存整个结果集）
+type exportWriter interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []string) error
+	Close() error
+}
+
+// GetExportPath 获取导出文件存放目录
+func GetExportPath() string {
+	if global.Conf != nil {
+		if path := global.Conf.GetString("export.path"); path != "" {
+			return path
+		}
+	}
+	return defaultExportPath
+}
+
+// GetExportExpireDuration 获取导出文件下载有效期
+func GetExportExpireDuration() time.Duration {
+	hours := 0
+	if global.Conf != nil {
+		hours = global.Conf.GetInt("export.expire_hours")
+	}
+	if hours <= 0 {
+		hours = defaultExportExpireHours
+	}
+	return time.Duration(hours) * time.Hour
+}
+
+// GetExportDownloadUrl 获取导出文件下载地址
+func GetExportDownloadUrl(orderID, taskID string) string {
+	return fmt.Sprintf("/api/v1/insight/orders/%s/tasks/%s/export-file", orderID, taskID)
+}
+
+// exportToFile 将查询结果流式写入导出文件
+func exportToFile(rows *sql.Rows, config *DBConfig, logMessage func(string)) (ExportFile, error) {
+	var file ExportFile
+
+	// 解压密码为工单级别的密文，导出前先解密，避免导出完成后才发现密钥不可用
+	if config.EncryptionKey == "" {
+		return file, fmt.Errorf("工单缺少导出文件加密密钥，请重新提交工单")
+	}
+	encryptionKey, err := secret.Decrypt(config.EncryptionKey)
+	if err != nil {
+		return file, fmt.Errorf("解密导出文件加密密钥失败: %s", err.Error())
+	}
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return file, fmt.Errorf("获取列信息失败: %s", err.Error())
+	}
+
+	format := strings.ToUpper(config.ExportFileFormat)
+	if format == "" {
+		format = "XLSX"
+	}
+
+	dir := filepath.Join(GetExportPath(), config.OrderID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return file, fmt.Errorf("创建导出目录失败: %s", err.Error())
+	}
+
+	fileName := fmt.Sprintf("%s_%s.%s", config.TaskID, time.Now().Format("20060102150405"), strings.ToLower(format))
+	filePath := filepath.Join(dir, fileName)
+
+	var writer exportWriter
+	switch format {
+	case "CSV":
+		writer, err = newCSVExportWriter(filePath)
+	case "XLSX":
+		writer, err = newXLSXExportWriter(filePath)
+	default:
+		return file, fmt.Errorf("不支持的导出文件格式: %s", config.ExportFileFormat)
+	}
+	if err != nil {
+		return file, fmt.Errorf("创建导出文件失败: %s", err.Error())
+	}
+
+	// 写入失败时清理不完整的文件
+	success := false
+	defer func() {
+		if !success {
+			_ = writer.Close()
+			_ = os.Remove(filePath)
+		}
+	}()
+
+	if err := writer.WriteHeader(columns); err != nil {
+		return file, fmt.Errorf("写入表头失败: %s", err.Error())
+	}
+
+	values := make([]interface{}, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+
+	var rowCount int64
+	for rows.Next() {
+		if err := rows.Scan(scanArgs...); err != nil {
+			return file, fmt.Errorf("读取数据失败: %s", err.Error())
+		}
+		for i, v := range values {
+			record[i] = formatExportValue(v)
+		}
+		if err := writer.WriteRow(record); err != nil {
+			return file, fmt.Errorf("写入数据失败: %s", err.Error())
+		}
+		rowCount++
+		if rowCount%100000 == 0 {
+			logMessage(fmt.Sprintf("已导出 %d 行", rowCount))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return file, fmt.Errorf("读取数据失败: %s", err.Error())
+	}
+
+	if err := writer.Close(); err != nil {
+		return file, fmt.Errorf("保存导出文件失败: %s", err.Error())
+	}
+
+	// 打包为加密压缩文件，并删除明文文件
+	zipPath := filePath + ".zip"
+	if err := encryptExportFile(filePath, zipPath, fileName, encryptionKey); err != nil {
+		_ = os.Remove(zipPath)
+		return file, fmt.Errorf("加密导出文件失败: %s", err.Error())
+	}
+	_ = os.Remove(filePath)
+	success = true
+	logMessage("导出文件已加密压缩，解压密码仅工单申请人可见")
+
+	stat, err := os.Stat(zipPath)
+	if err != nil {
+		return file, err
+	}
+
+	file.FileName = fileName + ".zip"
+	file.FilePath = zipPath
+	file.FileSize = stat.Size()
+	file.ContentType = "application/zip"
+	file.ExportRows = rowCount
+	file.DownloadUrl = GetExportDownloadUrl(config.OrderID, config.TaskID)
+	file.ExpireTime = time.Now().Add(GetExportExpireDuration()).Format("2006-01-02 15:04:05")
+	return file, nil
+}
+
+// encryptExportFile 将导出文件写入 AES-256 加密的 zip 压缩包
+func encryptExportFile(srcPath, zipPath, entryName, password string) error {
+	src, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(zipPath)
+	if err != nil {
+		return err
+	}
+	defer dst.Close()
+
+	zw := zip.NewWriter(dst)
+	w, err := zw.Encrypt(entryName, password, zip.AES256Encryption)
+	if err != nil {
+		zw.Close()
+		return err
+	}
+	if _, err := io.Copy(w, src); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// formatExportValue 将扫描到的值转换为字符串
+func formatExportValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(val)
+	case string:
+		return val
+	case time.Time:
+		return val.Format("2006-01-02 15:04:05")
+	default:
+		return fmt.Sprint(val)
+	}
+}
+
+// csvExportWriter CSV 写入器
+type csvExportWriter struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+func newCSVExportWriter(filePath string) (*csvExportWriter, error) {
+	f, err := os.Create(filePath)
+	if err != nil {
+		return nil, err
+	}
+	// 写入 UTF-8 BOM，避免 Excel 打开中文乱码
+	if _, err := f.WriteString("\xEF\xBB\xBF"); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &csvExportWriter{file: f, writer: csv.NewWriter(f)}, nil
+}
+
+func (w *csvExportWriter) WriteHeader(columns []string) error {
+	return w.writer.Write(columns)
+}
+
+func (w *csvExportWriter) WriteRow(values []string) error {
+	return w.writer.Write(values)
+}
+
+func (w *csvExportWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	w.writer.Flush()
+	err := w.writer.Error()
+	if cerr := w.file.Close(); err == nil {
+		err = cerr
+	}
+	w.file = nil
+	return err
+}
+
+// xlsxExportWriter XLSX 写入器（使用 excelize StreamWriter 流式写入）
+type xlsxExportWriter struct {
+	filePath string
+	file     *excelize.File
+	stream   *excelize.StreamWriter
+	rowIndex int
+}
+
+func newXLSXExportWriter(filePath string) (*xlsxExportWriter, error) {
+	f := excelize.NewFile()
+	stream, err := f.NewStreamWriter("Sheet1")
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &xlsxExportWriter{filePath: filePath, file: f, stream: stream}, nil
+}
+
+func (w *xlsxExportWriter) WriteHeader(columns []string) error {
+	return w.WriteRow(columns)
+}
+
+func (w *xlsxExportWriter) WriteRow(values []string) error {
+	if w.rowIndex >= xlsxMaxRows {
+		return fmt.Errorf("导出行数超过XLSX单表上限%d，请使用CSV格式导出", xlsxMaxRows-1)
+	}
+	w.rowIndex++
+	cell, err := excelize.CoordinatesToCellName(1, w.rowIndex)
+	if err != nil {
+		return err
+	}
+	row := make([]interface{}, len(values))
+	for i, v := range values {
+		row[i] = v
+	}
+	return w.stream.SetRow(cell, row)
+}
+
+func (w *xlsxExportWriter) Close() error {
+	if w.file == nil {
+		return nil
+	}
+	defer func() {
+		w.file.Close()
+		w.file = nil
+	}()
+	if err := w.stream.Flush(); err != nil {
+		return err
+	}
+	return w.file.SaveAs(w.filePath)
+}
@@ -0,0 +1,120 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"go-noah/pkg/global"
+	"go-noah/pkg/utils"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// defaultGhostStatusInterval 默认 gh-ost 状态轮询间隔
+	defaultGhostStatusInterval = 5 * time.Second
+)
+
+// getGhostStatusInterval 获取 gh-ost 状态轮询间隔
+func getGhostStatusInterval() time.Duration {
+	if global.Conf != nil {
+		if seconds := global.Conf.GetInt("ghost.status_interval_seconds"); seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultGhostStatusInterval
+}
+
+// pollGhostStatus 定期通过 serve socket 查询 gh-ost status，解析后推送进度；
+// 同时检查计划 cut-over 时间，到期后发送 unpostpone 命令。
+// 需要写入执行日志的消息通过 output 发送，由读取 gh-ost 输出的 goroutine 统一记录。
+func (e *MySQLExecutor) pollGhostStatus(ctx context.Context, socketPath string, done <-chan struct{}, output chan<- string) {
+	if e.Config.OrderID != "" {
+		defer utils.DeleteGhostCutOverTime(e.Config.OrderID)
+	}
+
+	emit := func(msg string) {
+		select {
+		case output <- msg + "\n":
+		case <-ctx.Done():
+		case <-done:
+		}
+	}
+
+	ticker := time.NewTicker(getGhostStatusInterval())
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+
+		// gh-ost 启动初期 socket 尚未创建，查询失败时忽略
+		result, err := utils.GhostQuery(socketPath, "status")
+		if err != nil {
+			continue
+		}
+		status, err := utils.ParseGhostStatus(result)
+		if err != nil {
+			continue
+		}
+		e.publishGhostStatus(status)
+
+		if !status.CutOverPostponed || e.Config.OrderID == "" {
+			continue
+		}
+		cutOverTime, ok, err := utils.GetGhostCutOverTime(e.Config.OrderID)
+		if err != nil || !ok || time.Now().Before(cutOverTime) {
+			continue
+		}
+		if err := utils.GhostControl(socketPath, "unpostpone"); err != nil {
+			emit(fmt.Sprintf("到达计划 cut-over 时间 %s，发送 cut-over 命令失败: %s", cutOverTime.Format("2006-01-02 15:04:05"), err.Error()))
+			continue
+		}
+		utils.DeleteGhostCutOverTime(e.Config.OrderID)
+		emit(fmt.Sprintf("到达计划 cut-over 时间 %s，已发送 cut-over 命令", cutOverTime.Format("2006-01-02 15:04:05")))
+	}
+}
+
+// publishGhostStatus 推送 gh-ost 结构化进度（类型为 "ghost-progress"）并缓存到 Redis
+func (e *MySQLExecutor) publishGhostStatus(status *utils.GhostStatus) {
+	if e.Config.OrderID == "" {
+		return
+	}
+	progressData := map[string]interface{}{
+		"percent":            status.Percent,
+		"current":            status.RowsCopied,
+		"total":              status.RowsEstimate,
+		"eta":                status.ETA,
+		"operation":          "ghost",
+		"rows_copied":        status.RowsCopied,
+		"rows_estimate":      status.RowsEstimate,
+		"applied":            status.Applied,
+		"backlog":            status.Backlog,
+		"backlog_capacity":   status.BacklogCapacity,
+		"elapsed_time":       status.ElapsedTime,
+		"lag_seconds":        status.LagSeconds,
+		"heartbeat_lag":      status.HeartbeatLag,
+		"state":              status.State,
+		"chunk_size":         status.ChunkSize,
+		"max_lag_millis":     status.MaxLagMillis,
+		"nice_ratio":         status.NiceRatio,
+		"cut_over_postponed": status.CutOverPostponed,
+	}
+	if cutOverTime, ok, err := utils.GetGhostCutOverTime(e.Config.OrderID); err == nil && ok {
+		progressData["cut_over_time"] = cutOverTime.Format("2006-01-02 15:04:05")
+	}
+
+	if err := utils.PublishMessageToChannel(e.Config.OrderID, progressData, "ghost-progress"); err != nil {
+		global.Logger.Error("Failed to publish ghost progress", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+	if err := utils.SaveGhostProgressToRedis(e.Config.OrderID, progressData); err != nil {
+		global.Logger.Warn("Failed to save ghost progress to Redis cache",
+			zap.String("order_id", e.Config.OrderID),
+			zap.Error(err),
+		)
+	}
+}
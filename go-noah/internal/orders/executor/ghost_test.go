@@ -0,0 +1,265 @@
+package executor
+
+import (
+	"bufio"
+	"fmt"
+	"go-noah/pkg/global"
+	"go-noah/pkg/log"
+	"go-noah/pkg/utils"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+// fakeRedis 只支持 GET/SET/DEL/PUBLISH 的 Redis 服务（RESP2），用于测试计划 cut-over
+type fakeRedis struct {
+	mu   sync.Mutex
+	data map[string]string
+}
+
+func startFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动 Redis 失败: %v", err)
+	}
+	f := &fakeRedis{data: make(map[string]string)}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), Protocol: 2, DisableIdentity: true})
+	prev := global.Redis
+	global.Redis = client
+	t.Cleanup(func() {
+		global.Redis = prev
+		client.Close()
+		listener.Close()
+	})
+	return f
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readRESPCommand(r)
+		if err != nil {
+			return
+		}
+		var reply string
+		f.mu.Lock()
+		switch strings.ToUpper(args[0]) {
+		case "GET":
+			if value, ok := f.data[args[1]]; ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(value), value)
+			} else {
+				reply = "$-1\r\n"
+			}
+		case "SET":
+			f.data[args[1]] = args[2]
+			reply = "+OK\r\n"
+		case "DEL":
+			deleted := 0
+			for _, key := range args[1:] {
+				if _, ok := f.data[key]; ok {
+					delete(f.data, key)
+					deleted++
+				}
+			}
+			reply = fmt.Sprintf(":%d\r\n", deleted)
+		case "PUBLISH":
+			reply = ":0\r\n"
+		default:
+			reply = "-ERR unknown command '" + args[0] + "'\r\n"
+		}
+		f.mu.Unlock()
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) exists(key string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.data[key]
+	return ok
+}
+
+// readRESPCommand 读取一条 RESP 数组格式的命令
+func readRESPCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	count, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "*")))
+	if err != nil || count <= 0 {
+		return nil, fmt.Errorf("命令格式错误: %q", line)
+	}
+	args := make([]string, count)
+	for i := range args {
+		header, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		size, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(header, "$")))
+		if err != nil {
+			return nil, err
+		}
+		buf := make([]byte, size+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		args[i] = string(buf[:size])
+	}
+	return args, nil
+}
+
+// startFakeGhost 模拟 gh-ost serve socket：status 返回 statusOutput，收到的命令写入返回的通道
+func startFakeGhost(t *testing.T, statusOutput string) (string, <-chan string) {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "ghost")
+	if err != nil {
+		t.Fatalf("创建临时目录失败: %v", err)
+	}
+	socketPath := filepath.Join(dir, "gh-ost.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("监听 socket 失败: %v", err)
+	}
+	t.Cleanup(func() {
+		listener.Close()
+		os.RemoveAll(dir)
+	})
+
+	commands := make(chan string, 100)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			line, _ := bufio.NewReader(conn).ReadString('\n')
+			command := strings.TrimSpace(line)
+			if command == "status" {
+				_, _ = io.WriteString(conn, statusOutput)
+			}
+			conn.Close()
+			select {
+			case commands <- command:
+			default:
+			}
+		}
+	}()
+	return socketPath, commands
+}
+
+func TestPollGhostStatus(t *testing.T) {
+	const (
+		migrating = "# chunk-size: 1000; max-lag-millis: 1500ms; dml-batch-size: 10; max-load: ; critical-load: ; nice-ratio: 0.000000\n" +
+			"# postpone-cut-over-flag-file: /tmp/gh-ost.test.t.postpone.flag\n" +
+			"Copy: 500/6000 8.3%; Applied: 0; Backlog: 0/1000; Time: 10s(total), 10s(copy); streamer: mysql-bin.000004:1000; Lag: 0.01s, HeartbeatLag: 0.01s, State: migrating; ETA: 1m50s\n"
+		postponed = "# chunk-size: 1000; max-lag-millis: 1500ms; dml-batch-size: 10; max-load: ; critical-load: ; nice-ratio: 0.000000\n" +
+			"# postpone-cut-over-flag-file: /tmp/gh-ost.test.t.postpone.flag [set]\n" +
+			"Copy: 6000/6000 100.0%; Applied: 5; Backlog: 0/1000; Time: 2m(total), 1m50s(copy); streamer: mysql-bin.000004:5000; Lag: 0.01s, HeartbeatLag: 0.01s, State: postponing cut-over; ETA: due\n"
+	)
+	conf := viper.New()
+	conf.Set("ghost.status_interval_seconds", 1)
+	prevConf, prevLogger := global.Conf, global.Logger
+	global.Conf, global.Logger = conf, &log.Logger{Logger: zap.NewNop()}
+	t.Cleanup(func() { global.Conf, global.Logger = prevConf, prevLogger })
+
+	testCases := []struct {
+		Name          string
+		Status        string
+		CutOverTime   *time.Time
+		ExpectCutOver bool
+	}{
+		{Name: "到达计划时间", Status: postponed, CutOverTime: ptrTime(time.Now().Add(-time.Minute)), ExpectCutOver: true},
+		{Name: "未到计划时间", Status: postponed, CutOverTime: ptrTime(time.Now().Add(time.Hour))},
+		{Name: "未计划cut-over", Status: postponed},
+		{Name: "尚未进入推迟状态", Status: migrating, CutOverTime: ptrTime(time.Now().Add(-time.Minute))},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			redisServer := startFakeRedis(t)
+			socketPath, commands := startFakeGhost(t, tc.Status)
+			e := &MySQLExecutor{Config: &DBConfig{OrderID: "order-" + tc.Name}}
+			cutOverKey := "ghost:cutover:" + e.Config.OrderID
+			if tc.CutOverTime != nil {
+				if err := utils.SetGhostCutOverTime(e.Config.OrderID, *tc.CutOverTime); err != nil {
+					t.Fatalf("保存计划 cut-over 时间失败: %v", err)
+				}
+			}
+
+			done := make(chan struct{})
+			output := make(chan string, 10)
+			stopped := make(chan struct{})
+			go func() {
+				e.pollGhostStatus(t.Context(), socketPath, done, output)
+				close(stopped)
+			}()
+
+			// 等待 unpostpone 命令，或至少两次状态查询
+			cutOver, statusQueries := false, 0
+			timeout := time.After(10 * time.Second)
+			for !cutOver && statusQueries < 2 {
+				select {
+				case command := <-commands:
+					switch command {
+					case "unpostpone":
+						cutOver = true
+					case "status":
+						statusQueries++
+					}
+				case <-timeout:
+					t.Fatalf("等待 gh-ost 命令超时")
+				}
+			}
+			if cutOver != tc.ExpectCutOver {
+				t.Fatalf("期望发送 cut-over=%v，实际 %v", tc.ExpectCutOver, cutOver)
+			}
+			if tc.ExpectCutOver {
+				select {
+				case msg := <-output:
+					if !strings.Contains(msg, "已发送 cut-over 命令") {
+						t.Errorf("执行日志错误: %q", msg)
+					}
+				case <-timeout:
+					t.Errorf("未记录 cut-over 执行日志")
+				}
+			}
+			close(done)
+			<-stopped
+
+			// 轮询结束后清理计划 cut-over 时间
+			if redisServer.exists(cutOverKey) {
+				t.Errorf("轮询结束后未删除计划 cut-over 时间")
+			}
+			if !redisServer.exists("ghost:progress:" + e.Config.OrderID) {
+				t.Errorf("未缓存 gh-ost 进度")
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,89 @@
+package executor
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-noah/pkg/secret"
+	"strings"
+)
+
+// 数据迁移的脱敏规则
+const (
+	MaskRuleNull    = "null"    // 置为 NULL
+	MaskRuleHash    = "hash"    // HMAC-SHA256 十六进制摘要（工单级密钥，同一工单内相同值脱敏结果相同，可用于关联）
+	MaskRulePhone   = "phone"   // 保留前 3 位和后 4 位，如 138****5678
+	MaskRuleEmail   = "email"   // 保留邮箱用户名首字符和域名，如 a***@example.com
+	MaskRulePartial = "partial" // 保留首尾各 1 个字符
+	MaskRuleFixed   = "fixed:"  // 替换为固定值，如 fixed:***
+)
+
+// IsValidMaskRule 是否为支持的脱敏规则
+func IsValidMaskRule(rule string) bool {
+	switch rule {
+	case MaskRuleNull, MaskRuleHash, MaskRulePhone, MaskRuleEmail, MaskRulePartial:
+		return true
+	}
+	return strings.HasPrefix(rule, MaskRuleFixed)
+}
+
+// decodeMaskKey 解密工单的哈希脱敏密钥
+func decodeMaskKey(maskSecret string) ([]byte, error) {
+	if maskSecret == "" {
+		return nil, errors.New("工单缺少哈希脱敏密钥，请重新提交工单")
+	}
+	encoded, err := secret.Decrypt(maskSecret)
+	if err != nil {
+		return nil, fmt.Errorf("解密哈希脱敏密钥失败: %w", err)
+	}
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("哈希脱敏密钥格式错误")
+	}
+	return key, nil
+}
+
+// maskValue 按脱敏规则转换字段值（NULL 保持为 NULL），hash 规则使用 key 计算 HMAC，避免通过穷举原值还原
+func maskValue(rule string, key []byte, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	text := formatExportValue(value)
+	switch {
+	case rule == MaskRuleNull:
+		return nil, nil
+	case rule == MaskRuleHash:
+		if len(key) == 0 {
+			return nil, errors.New("哈希脱敏缺少密钥")
+		}
+		mac := hmac.New(sha256.New, key)
+		mac.Write([]byte(text))
+		return hex.EncodeToString(mac.Sum(nil)), nil
+	case rule == MaskRulePhone:
+		return maskMiddle(text, 3, 4), nil
+	case rule == MaskRuleEmail:
+		at := strings.LastIndex(text, "@")
+		if at < 0 {
+			return maskMiddle(text, 1, 1), nil
+		}
+		return maskMiddle(text[:at], 1, 0) + text[at:], nil
+	case rule == MaskRulePartial:
+		return maskMiddle(text, 1, 1), nil
+	case strings.HasPrefix(rule, MaskRuleFixed):
+		return strings.TrimPrefix(rule, MaskRuleFixed), nil
+	default:
+		return nil, fmt.Errorf("不支持的脱敏规则: %s", rule)
+	}
+}
+
+// maskMiddle 保留前 keepHead 和后 keepTail 个字符，其余替换为 *（字符数不足时全部替换）
+func maskMiddle(text string, keepHead, keepTail int) string {
+	runes := []rune(text)
+	if len(runes) <= keepHead+keepTail {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:keepHead]) + strings.Repeat("*", len(runes)-keepHead-keepTail) + string(runes[len(runes)-keepTail:])
+}
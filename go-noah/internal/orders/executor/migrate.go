@@ -0,0 +1,448 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"go-noah/pkg/dbpool"
+	"strings"
+	"time"
+)
+
+// 数据迁移的主键冲突处理策略
+const (
+	ConflictSkip    = "skip"    // 保留目标表中已存在的行
+	ConflictReplace = "replace" // 使用源数据覆盖目标表中已存在的行
+	ConflictFail    = "fail"    // 出现主键冲突时停止迁移
+)
+
+// defaultMigrateBatchSize 默认每条 INSERT 写入的行数
+const defaultMigrateBatchSize = 500
+
+// MigrateConfig 数据迁移任务配置
+type MigrateConfig struct {
+	TargetInstanceID string            // 目标实例
+	TargetSchema     string            // 目标库（为空时使用源库）
+	TargetTable      string            // 目标表（为空时使用源表名）
+	ColumnMapping    map[string]string // 列映射（源列 -> 目标列，未列出的列同名复制，目标列为空表示不复制）
+	Masking          map[string]string // 脱敏规则（源列 -> 规则）
+	MaskSecret       string            // 哈希脱敏密钥（密文）
+	ConflictStrategy string            // 主键冲突处理策略（skip/replace/fail）
+	BatchSize        int               // 每条 INSERT 写入的行数（0使用默认值）
+	Resolver         InstanceResolver  // 目标实例配置
+}
+
+// ValidateMigrateSQL 校验数据迁移语句（SELECT * FROM 源表 [WHERE 过滤条件]，创建工单时调用）
+func ValidateMigrateSQL(sqltext, defaultSchema string) error {
+	_, err := buildTableScanPlan(sqltext, defaultSchema, false)
+	return err
+}
+
+// migrateColumn 迁移列（源列在查询结果中的位置和目标列名）
+type migrateColumn struct {
+	index  int
+	target string
+	mask   string
+}
+
+// migrateTarget 迁移目标表
+type migrateTarget struct {
+	db      *dbpool.Conn
+	schema  string
+	table   string
+	pk      string
+	columns []migrateColumn
+	pkIndex int    // 主键在目标列中的位置
+	maskKey []byte // 哈希脱敏密钥
+}
+
+// ExecuteMigrate 按主键分批将源表（或满足条件的部分行）复制到其他实例，
+// 支持列映射、脱敏和主键冲突处理，复制完成后逐批比对源表（转换后）和目标表的校验和
+func (e *MySQLExecutor) ExecuteMigrate(ctx context.Context) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+	fail := func(err error) (ReturnData, error) {
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	opts := e.Config.Migrate
+	if opts == nil || opts.Resolver == nil {
+		err := errors.New("数据迁移任务缺少迁移选项")
+		logMessage(err.Error())
+		return fail(err)
+	}
+	plan, err := buildTableScanPlan(e.Config.SQL, e.Config.Schema, false)
+	if err != nil {
+		logMessage(err.Error())
+		return fail(err)
+	}
+	chunkSize, sleep := getChunkSettings(e.Config)
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultMigrateBatchSize
+	}
+	strategy := opts.ConflictStrategy
+	if strategy == "" {
+		strategy = ConflictFail
+	}
+
+	logMessage(fmt.Sprintf("连接源实例 %s:%d...", e.Config.Hostname, e.Config.Port))
+	db, err := e.Connect()
+	if err != nil {
+		logMessage(fmt.Sprintf("连接失败: %s", err.Error()))
+		return fail(err)
+	}
+	defer db.Close()
+	logMessage("连接成功")
+
+	pk, err := getPrimaryKeyColumn(ctx, db, plan.Schema, plan.Table)
+	if err != nil {
+		logMessage(fmt.Sprintf("获取主键失败: %s", err.Error()))
+		return fail(err)
+	}
+	pkRef := quoteIdentifier(plan.Qualify) + "." + quoteIdentifier(pk)
+
+	target, err := e.prepareMigrateTarget(ctx, db, plan, pk)
+	if err != nil {
+		logMessage(fmt.Sprintf("准备目标表失败: %s", err.Error()))
+		return fail(err)
+	}
+	defer target.db.Close()
+
+	var total int64
+	if err := db.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE (%s)", plan.TableRef, plan.Where)).Scan(&total); err != nil {
+		logMessage(fmt.Sprintf("统计待迁移行数失败: %s", err.Error()))
+		return fail(err)
+	}
+	logMessage(fmt.Sprintf("迁移 %s.%s 到 %s.%s：主键 %s，每批读取 %d 行，每条 INSERT %d 行，冲突策略 %s，待迁移 %d 行",
+		plan.Schema, plan.Table, target.schema, target.table, pk, chunkSize, batchSize, strategy, total))
+	if len(opts.Masking) > 0 {
+		logMessage(fmt.Sprintf("脱敏列: %d 个", len(opts.Masking)))
+	}
+
+	var suffix string
+	switch strategy {
+	case ConflictSkip:
+		suffix = fmt.Sprintf(" ON DUPLICATE KEY UPDATE %s = %s", quoteIdentifier(target.pk), quoteIdentifier(target.pk))
+	case ConflictReplace:
+		// 使用 ON DUPLICATE KEY UPDATE 覆盖而不是 REPLACE INTO，避免删除行触发外键级联
+		var updates []string
+		for _, column := range target.columns {
+			updates = append(updates, fmt.Sprintf("%s = VALUES(%s)", quoteIdentifier(column.target), quoteIdentifier(column.target)))
+		}
+		suffix = " ON DUPLICATE KEY UPDATE " + strings.Join(updates, ", ")
+	case ConflictFail:
+	default:
+		err := fmt.Errorf("不支持的冲突处理策略: %s", strategy)
+		logMessage(err.Error())
+		return fail(err)
+	}
+
+	throttle := newThrottler(e.Config, db, logMessage)
+	defer throttle.Close()
+
+	startTime := time.Now()
+	var (
+		lastPK     string
+		chunkIndex int
+		copied     int64
+		skipped    int64
+	)
+	for {
+		if err := ctx.Err(); err != nil {
+			logMessage(fmt.Sprintf("迁移已中断，已复制 %d 行", copied))
+			data.AffectedRows = copied
+			return fail(err)
+		}
+		if err := throttle.Wait(ctx); err != nil {
+			logMessage(fmt.Sprintf("等待限流解除时中断，已复制 %d 行", copied))
+			data.AffectedRows = copied
+			return fail(err)
+		}
+		chunkIndex++
+
+		batch, rows, err := e.readMigrateBatch(ctx, db, plan, pk, pkRef, lastPK, chunkSize, target)
+		if err != nil {
+			logMessage(fmt.Sprintf("第%d批读取数据失败: %s", chunkIndex, err.Error()))
+			data.AffectedRows = copied
+			return fail(err)
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		columns := make([]string, len(target.columns))
+		for i, column := range target.columns {
+			columns[i] = column.target
+		}
+		affected, err := insertRows(ctx, target.db, target.schema, target.table, columns, rows, batchSize, suffix)
+		if err != nil {
+			logMessage(fmt.Sprintf("第%d批写入失败: %s", chunkIndex, err.Error()))
+			data.AffectedRows = copied
+			return fail(err)
+		}
+		n := int64(len(rows))
+		copied += n
+		// skip 策略下主键冲突的行影响行数为 0
+		if strategy == ConflictSkip {
+			skipped += n - affected
+		}
+
+		logMessage(fmt.Sprintf("第%d批迁移成功，%d 行，累计: %d/%d", chunkIndex, n, copied, total))
+		e.publishChunkProgress(chunkIndex, copied, total)
+
+		lastPK = batch.upper
+		if n < int64(chunkSize) {
+			break
+		}
+		if sleep > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(sleep):
+			}
+		}
+	}
+	if skipped > 0 {
+		logMessage(fmt.Sprintf("目标表中已存在 %d 行，按冲突策略保留目标表中的数据", skipped))
+	}
+	logMessage(fmt.Sprintf("复制完成，共 %d 批，%d 行，耗时: %s，开始校验...", chunkIndex, copied, time.Since(startTime)))
+
+	// 校验
+	result, err := e.verifyMigration(ctx, db, plan, pk, pkRef, chunkSize, target)
+	if err != nil {
+		logMessage(fmt.Sprintf("校验失败: %s", err.Error()))
+		data.AffectedRows = copied
+		return fail(err)
+	}
+	logMessage(fmt.Sprintf("校验完成：源表 %d 行，目标表缺失 %d 行，不一致 %d 行，校验和 源 %016x / 目标 %016x",
+		result.rows, result.missing, result.mismatched, result.sourceChecksum, result.targetChecksum))
+
+	executeCostTime := time.Since(startTime).String()
+	data.AffectedRows = copied
+	data.ExecuteCostTime = executeCostTime
+	if result.missing > 0 || (result.mismatched > 0 && strategy != ConflictSkip) {
+		err := fmt.Errorf("校验不一致：目标表缺失 %d 行，不一致 %d 行（迁移期间源表数据有变化时可重新执行）", result.missing, result.mismatched)
+		logMessage(err.Error())
+		return fail(err)
+	}
+	if result.mismatched > 0 {
+		logMessage(fmt.Sprintf("有 %d 行与目标表中已存在的数据不一致（冲突策略为 skip，保留目标表中的数据）", result.mismatched))
+	}
+	logMessage(fmt.Sprintf("数据迁移完成，耗时: %s", executeCostTime))
+	data.ExecuteLog = strings.Join(executeLog, "\n")
+	return data, nil
+}
+
+// prepareMigrateTarget 连接目标实例，按列映射确定写入的列并检查目标表
+// 目标表不存在且未映射列名时按源表结构创建
+func (e *MySQLExecutor) prepareMigrateTarget(ctx context.Context, source *dbpool.Conn, plan *tableScanPlan, pk string) (*migrateTarget, error) {
+	opts := e.Config.Migrate
+	target := &migrateTarget{schema: opts.TargetSchema, table: opts.TargetTable}
+	if target.schema == "" {
+		target.schema = plan.Schema
+	}
+	if target.table == "" {
+		target.table = plan.Table
+	}
+	sameInstance := opts.TargetInstanceID == "" || opts.TargetInstanceID == e.Config.InstanceID
+	if sameInstance && target.schema == plan.Schema && target.table == plan.Table {
+		return nil, fmt.Errorf("目标表不能与源表相同")
+	}
+
+	// 源表列
+	rows, err := source.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s.%s LIMIT 0", quoteIdentifier(plan.Schema), quoteIdentifier(plan.Table)))
+	if err != nil {
+		return nil, err
+	}
+	sourceColumns, err := rows.Columns()
+	rows.Close()
+	if err != nil {
+		return nil, err
+	}
+	renamed, err := target.mapColumns(sourceColumns, pk, opts.ColumnMapping, opts.Masking)
+	if err != nil {
+		return nil, err
+	}
+	if target.hasHashMask() {
+		if target.maskKey, err = decodeMaskKey(opts.MaskSecret); err != nil {
+			return nil, err
+		}
+	}
+
+	if target.db, err = e.connectInstance(ctx, opts.Resolver, opts.TargetInstanceID, target.schema); err != nil {
+		return nil, err
+	}
+	exists, err := tableExists(ctx, target.db, target.schema, target.table)
+	if err == nil && !exists {
+		if renamed {
+			err = fmt.Errorf("目标表 %s.%s 不存在（设置了列映射时需要预先创建目标表）", target.schema, target.table)
+		} else {
+			err = createTableLike(ctx, source, target.db, plan.Schema, plan.Table, target.schema, target.table)
+		}
+	}
+	if err != nil {
+		target.db.Close()
+		return nil, err
+	}
+
+	// 冲突处理和校验按主键进行，目标表主键需要与源表主键对应
+	targetPK, err := getPrimaryKeyColumn(ctx, target.db, target.schema, target.table)
+	if err == nil && !strings.EqualFold(targetPK, target.pk) {
+		err = fmt.Errorf("目标表主键 %s 与源表主键 %s（映射为 %s）不一致", targetPK, pk, target.pk)
+	}
+	if err != nil {
+		target.db.Close()
+		return nil, err
+	}
+	return target, nil
+}
+
+// mapColumns 按列映射和脱敏规则确定写入目标表的列，返回是否有列被重命名
+func (t *migrateTarget) mapColumns(sourceColumns []string, pk string, mapping, masking map[string]string) (bool, error) {
+	for name := range mapping {
+		if columnIndex(sourceColumns, name) < 0 {
+			return false, fmt.Errorf("列映射中的源列 %s 不存在", name)
+		}
+	}
+	for name := range masking {
+		if columnIndex(sourceColumns, name) < 0 {
+			return false, fmt.Errorf("脱敏规则中的源列 %s 不存在", name)
+		}
+		if strings.EqualFold(name, pk) {
+			return false, fmt.Errorf("主键列 %s 不能脱敏", pk)
+		}
+	}
+
+	renamed := false
+	t.pkIndex = -1
+	for i, column := range sourceColumns {
+		name := column
+		if mapped, ok := lookupColumn(mapping, column); ok {
+			name = mapped
+			renamed = true
+		}
+		if name == "" {
+			if strings.EqualFold(column, pk) {
+				return false, fmt.Errorf("主键列 %s 必须迁移", pk)
+			}
+			continue
+		}
+		if strings.EqualFold(column, pk) {
+			t.pk = name
+			t.pkIndex = len(t.columns)
+		}
+		mask, _ := lookupColumn(masking, column)
+		t.columns = append(t.columns, migrateColumn{index: i, target: name, mask: mask})
+	}
+	return renamed, nil
+}
+
+// hasHashMask 是否有列使用哈希脱敏
+func (t *migrateTarget) hasHashMask() bool {
+	for _, column := range t.columns {
+		if column.mask == MaskRuleHash {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupColumn 按列名查找映射（不区分大小写）
+func lookupColumn(mapping map[string]string, column string) (string, bool) {
+	for name, value := range mapping {
+		if strings.EqualFold(name, column) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// readMigrateBatch 读取下一批源数据，并按列映射和脱敏规则转换为目标表的行
+func (e *MySQLExecutor) readMigrateBatch(ctx context.Context, db *dbpool.Conn, plan *tableScanPlan, pk, pkRef, lastPK string, chunkSize int, target *migrateTarget) (*rowBatch, [][]interface{}, error) {
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, err
+	}
+	batch, err := selectRowBatch(ctx, tx, plan, pk, pkRef, lastPK, chunkSize, false)
+	tx.Rollback()
+	if err != nil {
+		return nil, nil, err
+	}
+	rows := make([][]interface{}, 0, len(batch.rows))
+	for _, row := range batch.rows {
+		values := make([]interface{}, len(target.columns))
+		for i, column := range target.columns {
+			values[i] = row[column.index]
+			if column.mask != "" {
+				if values[i], err = maskValue(column.mask, target.maskKey, values[i]); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+		rows = append(rows, values)
+	}
+	return batch, rows, nil
+}
+
+// migrateVerifyResult 迁移校验结果
+type migrateVerifyResult struct {
+	rows           int64
+	missing        int64
+	mismatched     int64
+	sourceChecksum uint64
+	targetChecksum uint64
+}
+
+// verifyMigration 按主键分批读取源表（按列映射和脱敏规则转换后）和目标表的对应行，逐行比对校验和
+func (e *MySQLExecutor) verifyMigration(ctx context.Context, db *dbpool.Conn, plan *tableScanPlan, pk, pkRef string, chunkSize int, target *migrateTarget) (*migrateVerifyResult, error) {
+	result := &migrateVerifyResult{}
+	columns := make([]string, len(target.columns))
+	for i, column := range target.columns {
+		columns[i] = column.target
+	}
+
+	var lastPK string
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		batch, rows, err := e.readMigrateBatch(ctx, db, plan, pk, pkRef, lastPK, chunkSize, target)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		// 目标表中本批主键对应的行
+		targetHashes, err := queryRowChecksums(ctx, target.db, target.schema, target.table, columns, target.pkIndex, batch.pks)
+		if err != nil {
+			return nil, err
+		}
+		for _, hash := range targetHashes {
+			result.targetChecksum ^= hash
+		}
+
+		for _, row := range rows {
+			hash := rowChecksum(row)
+			result.sourceChecksum ^= hash
+			result.rows++
+			targetHash, ok := targetHashes[formatExportValue(row[target.pkIndex])]
+			if !ok {
+				result.missing++
+			} else if targetHash != hash {
+				result.mismatched++
+			}
+		}
+
+		lastPK = batch.upper
+		if len(rows) < chunkSize {
+			break
+		}
+	}
+	return result, nil
+}
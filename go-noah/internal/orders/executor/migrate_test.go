@@ -0,0 +1,217 @@
+package executor
+
+import (
+	"fmt"
+	"go-noah/pkg/global"
+	"go-noah/pkg/secret"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestMapColumns(t *testing.T) {
+	source := []string{"id", "Name", "phone", "email", "remark"}
+	testCases := []struct {
+		Name          string
+		PK            string
+		Mapping       map[string]string
+		Masking       map[string]string
+		ExpectColumns string // 目标列（源列位置:目标列:脱敏规则）
+		ExpectPK      string
+		ExpectPKIndex int
+		ExpectRenamed bool
+		ExpectErr     string
+	}{
+		{
+			Name:          "同名复制",
+			PK:            "id",
+			ExpectColumns: "0:id: 1:Name: 2:phone: 3:email: 4:remark:",
+			ExpectPK:      "id",
+		},
+		{
+			Name:          "重命名和不复制的列（不区分大小写）",
+			PK:            "id",
+			Mapping:       map[string]string{"name": "user_name", "REMARK": ""},
+			ExpectColumns: "0:id: 1:user_name: 2:phone: 3:email:",
+			ExpectPK:      "id",
+			ExpectRenamed: true,
+		},
+		{
+			Name:          "重命名主键",
+			PK:            "id",
+			Mapping:       map[string]string{"id": "user_id"},
+			ExpectColumns: "0:user_id: 1:Name: 2:phone: 3:email: 4:remark:",
+			ExpectPK:      "user_id",
+			ExpectRenamed: true,
+		},
+		{Name: "映射的源列不存在", PK: "id", Mapping: map[string]string{"id2": "x"}, ExpectErr: "源列 id2 不存在"},
+		{
+			Name:          "主键不在第一列",
+			PK:            "email",
+			Mapping:       map[string]string{"id": ""},
+			ExpectColumns: "1:Name: 2:phone: 3:email: 4:remark:",
+			ExpectPK:      "email",
+			ExpectPKIndex: 2,
+			ExpectRenamed: true,
+		},
+		{
+			Name:          "脱敏规则",
+			PK:            "id",
+			Masking:       map[string]string{"PHONE": MaskRulePhone, "email": MaskRuleHash},
+			ExpectColumns: "0:id: 1:Name: 2:phone:phone 3:email:hash 4:remark:",
+			ExpectPK:      "id",
+		},
+		{Name: "主键不复制", PK: "id", Mapping: map[string]string{"id": ""}, ExpectErr: "必须迁移"},
+		{Name: "主键脱敏", PK: "id", Masking: map[string]string{"ID": MaskRuleNull}, ExpectErr: "不能脱敏"},
+		{Name: "脱敏列不存在", PK: "id", Masking: map[string]string{"mobile": MaskRulePhone}, ExpectErr: "源列 mobile 不存在"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			target := &migrateTarget{}
+			renamed, err := target.mapColumns(source, tc.PK, tc.Mapping, tc.Masking)
+			if tc.ExpectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tc.ExpectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("列映射失败: %v", err)
+			}
+			var columns []string
+			for _, column := range target.columns {
+				columns = append(columns, fmt.Sprintf("%d:%s:%s", column.index, column.target, column.mask))
+			}
+			if got := strings.Join(columns, " "); got != tc.ExpectColumns {
+				t.Errorf("期望目标列 %q，实际 %q", tc.ExpectColumns, got)
+			}
+			if target.pk != tc.ExpectPK || target.pkIndex != tc.ExpectPKIndex || renamed != tc.ExpectRenamed {
+				t.Errorf("期望主键 %s（位置 %d，重命名 %v），实际 %s（位置 %d，重命名 %v）",
+					tc.ExpectPK, tc.ExpectPKIndex, tc.ExpectRenamed, target.pk, target.pkIndex, renamed)
+			}
+		})
+	}
+}
+
+func TestMaskValue(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	testCases := []struct {
+		Name   string
+		Rule   string
+		Value  interface{}
+		Expect interface{}
+	}{
+		{Name: "NULL保持为NULL", Rule: MaskRulePhone, Value: nil, Expect: nil},
+		{Name: "置为NULL", Rule: MaskRuleNull, Value: "abc", Expect: nil},
+		{Name: "手机号", Rule: MaskRulePhone, Value: "13812345678", Expect: "138****5678"},
+		{Name: "手机号字节", Rule: MaskRulePhone, Value: []byte("13812345678"), Expect: "138****5678"},
+		{Name: "手机号过短", Rule: MaskRulePhone, Value: "1234567", Expect: "*******"},
+		{Name: "邮箱", Rule: MaskRuleEmail, Value: "alice@example.com", Expect: "a****@example.com"},
+		{Name: "无@的邮箱", Rule: MaskRuleEmail, Value: "alice", Expect: "a***e"},
+		{Name: "部分保留中文", Rule: MaskRulePartial, Value: "张三丰", Expect: "张*丰"},
+		{Name: "部分保留单字符", Rule: MaskRulePartial, Value: "a", Expect: "*"},
+		{Name: "固定值", Rule: MaskRuleFixed + "***", Value: 42, Expect: "***"},
+		{Name: "固定空值", Rule: MaskRuleFixed, Value: "abc", Expect: ""},
+		{Name: "哈希", Rule: MaskRuleHash, Value: "abc", Expect: "a60c859a6827c5ea576a48d8d368672fbfe4667c6a927428284a0cb3859cc1d6"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			got, err := maskValue(tc.Rule, key, tc.Value)
+			if err != nil {
+				t.Fatalf("脱敏失败: %v", err)
+			}
+			if got != tc.Expect {
+				t.Errorf("期望 %v，实际 %v", tc.Expect, got)
+			}
+		})
+	}
+
+	t.Run("哈希结果与密钥相关", func(t *testing.T) {
+		a, _ := maskValue(MaskRuleHash, key, "abc")
+		b, _ := maskValue(MaskRuleHash, key, []byte("abc"))
+		c, _ := maskValue(MaskRuleHash, []byte("another-key"), "abc")
+		if a != b {
+			t.Errorf("相同值和密钥的哈希结果不同: %v / %v", a, b)
+		}
+		if a == c {
+			t.Errorf("不同密钥的哈希结果相同")
+		}
+	})
+	t.Run("哈希缺少密钥", func(t *testing.T) {
+		if _, err := maskValue(MaskRuleHash, nil, "abc"); err == nil {
+			t.Errorf("期望返回错误")
+		}
+	})
+	t.Run("不支持的规则", func(t *testing.T) {
+		if _, err := maskValue("reverse", key, "abc"); err == nil {
+			t.Errorf("期望返回错误")
+		}
+	})
+}
+
+func TestIsValidMaskRule(t *testing.T) {
+	for rule, expect := range map[string]bool{
+		MaskRuleNull: true, MaskRuleHash: true, MaskRulePhone: true, MaskRuleEmail: true, MaskRulePartial: true,
+		"fixed:***": true, "fixed:": true, "sha1": false, "": false, "FIXED:x": false,
+	} {
+		if got := IsValidMaskRule(rule); got != expect {
+			t.Errorf("规则 %q 期望 %v，实际 %v", rule, expect, got)
+		}
+	}
+}
+
+func TestDecodeMaskKey(t *testing.T) {
+	masterKey, err := secret.GenerateKey()
+	if err != nil {
+		t.Fatalf("生成主密钥失败: %v", err)
+	}
+	conf := viper.New()
+	conf.Set("secret.master_key", masterKey)
+	prev := global.Conf
+	global.Conf = conf
+	t.Cleanup(func() { global.Conf = prev })
+
+	maskKey, _ := secret.GenerateKey()
+	encrypted, err := secret.Encrypt(maskKey)
+	if err != nil {
+		t.Fatalf("加密脱敏密钥失败: %v", err)
+	}
+	key, err := decodeMaskKey(encrypted)
+	if err != nil || len(key) != 32 {
+		t.Fatalf("解密脱敏密钥失败: %d, %v", len(key), err)
+	}
+	if _, err := decodeMaskKey(""); err == nil {
+		t.Errorf("缺少密钥时期望返回错误")
+	}
+	if _, err := decodeMaskKey("not-base64!"); err == nil {
+		t.Errorf("密钥格式错误时期望返回错误")
+	}
+}
+
+func TestRowChecksum(t *testing.T) {
+	ts := time.Date(2024, 6, 3, 10, 30, 0, 0, time.UTC)
+	testCases := []struct {
+		Name  string
+		A, B  []interface{}
+		Equal bool
+	}{
+		{Name: "相同的行", A: []interface{}{1, "a", ts}, B: []interface{}{1, "a", ts}, Equal: true},
+		{Name: "字节与字符串", A: []interface{}{[]byte("1"), []byte("a")}, B: []interface{}{"1", "a"}, Equal: true},
+		{Name: "整数与文本", A: []interface{}{int64(1)}, B: []interface{}{"1"}, Equal: true},
+		{Name: "时间与文本", A: []interface{}{ts}, B: []interface{}{"2024-06-03 10:30:00"}, Equal: true},
+		{Name: "NULL与空字符串", A: []interface{}{nil}, B: []interface{}{""}},
+		{Name: "字段边界", A: []interface{}{"ab", "c"}, B: []interface{}{"a", "bc"}},
+		{Name: "字段顺序", A: []interface{}{"a", "b"}, B: []interface{}{"b", "a"}},
+		{Name: "值不同", A: []interface{}{1, "a"}, B: []interface{}{1, "b"}},
+		{Name: "列数不同", A: []interface{}{"a"}, B: []interface{}{"a", nil}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if equal := rowChecksum(tc.A) == rowChecksum(tc.B); equal != tc.Equal {
+				t.Errorf("期望校验和相同=%v，实际 %v", tc.Equal, equal)
+			}
+		})
+	}
+}
@@ -7,10 +7,13 @@ import (
 	"fmt"
 	"go-noah/internal/inspect/parser"
 	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/pkg/dbpool"
 	"go-noah/pkg/global"
+	"go-noah/pkg/lease"
+	"go-noah/pkg/secret"
 	"go-noah/pkg/utils"
+	"os"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -29,76 +32,156 @@ func NewMySQLExecutor(config *DBConfig) *MySQLExecutor {
 }
 
 // Run 执行SQL
-func (e *MySQLExecutor) Run() (ReturnData, error) {
+func (e *MySQLExecutor) Run(ctx context.Context) (ReturnData, error) {
 	switch e.Config.SQLType {
 	case "DDL":
-		return e.ExecuteDDL()
+		return e.ExecuteDDL(ctx)
 	case "DML":
-		return e.ExecuteDML()
+		return e.ExecuteDML(ctx)
 	case "EXPORT":
-		return e.ExecuteExport()
+		return e.ExecuteExport(ctx)
+	case "ARCHIVE":
+		return e.ExecuteArchive(ctx)
+	case "MIGRATE":
+		return e.ExecuteMigrate(ctx)
 	default:
 		return ReturnData{Error: fmt.Sprintf("不支持的SQL类型: %s", e.Config.SQLType)}, fmt.Errorf("不支持的SQL类型: %s", e.Config.SQLType)
 	}
 }
 
-// Connect 连接数据库
-func (e *MySQLExecutor) Connect() (*sql.DB, error) {
+// Connect 从实例连接池获取连接（独占，使用完成后需要 Close 归还）
+func (e *MySQLExecutor) Connect() (*dbpool.Conn, error) {
+	return e.connect(dbpool.RoleExecutor, e.Config.Schema)
+}
+
+// ControlConnect 从监控、终止专用的连接池获取未选择库的连接，不与执行连接抢占
+func (e *MySQLExecutor) ControlConnect() (*dbpool.Conn, error) {
+	return e.connect(dbpool.RoleControl, "")
+}
+
+func (e *MySQLExecutor) connect(role dbpool.Role, database string) (*dbpool.Conn, error) {
+	password, err := secret.Decrypt(e.Config.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密实例密码失败: %w", err)
+	}
 	config := mysql.Config{
 		User:                 e.Config.UserName,
-		Passwd:               e.Config.Password,
+		Passwd:               password,
 		Addr:                 fmt.Sprintf("%s:%d", e.Config.Hostname, e.Config.Port),
 		Net:                  "tcp",
-		DBName:               e.Config.Schema,
 		AllowNativePasswords: true,
 		Timeout:              10 * time.Second,
 		ReadTimeout:          300 * time.Second,
 		WriteTimeout:         300 * time.Second,
 	}
+	if err := e.Config.ConnOptions.ApplyMySQL(&config); err != nil {
+		return nil, err
+	}
 
-	if e.Config.Charset != "" {
-		config.Params = map[string]string{"charset": e.Config.Charset}
+	spec := dbpool.Spec{
+		InstanceID: e.Config.InstanceID,
+		Role:       role,
+		DSN:        config.FormatDSN(),
+		Database:   database,
+	}
+	if e.Config.Charset != "" && role == dbpool.RoleExecutor {
+		spec.Params = map[string]string{"charset": e.Config.Charset}
 	}
+	return dbpool.Get(context.Background(), spec)
+}
 
-	db, err := sql.Open("mysql", config.FormatDSN())
-	if err != nil {
-		return nil, err
+// newLogger 创建执行日志记录函数（同时推送到 WebSocket）
+func (e *MySQLExecutor) newLogger(executeLog *[]string) func(string) {
+	return func(msg string) {
+		timestamp := time.Now().Format("2006-01-02 15:04:05")
+		logMsg := fmt.Sprintf("[%s] %s", timestamp, msg)
+		*executeLog = append(*executeLog, logMsg)
+		// 发布消息到 Redis（用于 WebSocket 推送）
+		if e.Config.OrderID != "" {
+			_ = utils.PublishMessageToChannel(e.Config.OrderID, logMsg, "")
+		}
 	}
+}
 
-	db.SetMaxOpenConns(1)
-	db.SetMaxIdleConns(1)
-	db.SetConnMaxLifetime(5 * time.Minute)
+// ErrAffectedRowsExceeded 影响行数超过上限（周期工单的防护），超出的变更已回滚
+var ErrAffectedRowsExceeded = errors.New("影响行数超过上限")
 
-	// 测试连接
-	if err := db.Ping(); err != nil {
-		db.Close()
-		return nil, err
+// checkAffectedRows 影响行数超过上限时返回错误（MaxAffectedRows 为 0 时不限制）
+func (e *MySQLExecutor) checkAffectedRows(rows int64) error {
+	if e.Config.MaxAffectedRows > 0 && rows > e.Config.MaxAffectedRows {
+		return fmt.Errorf("%w（%d > %d）", ErrAffectedRowsExceeded, rows, e.Config.MaxAffectedRows)
 	}
+	return nil
+}
 
-	return db, nil
+// saveCheckpoint 记录任务执行检查点（供服务重启后核对中断任务的执行结果），返回的函数在执行结束时删除检查点
+func (e *MySQLExecutor) saveCheckpoint(connectionID int64, binlogFile string, binlogPos int64, chunked bool) func() {
+	if e.Config.TaskID == "" {
+		return func() {}
+	}
+	checkpoint := &utils.TaskCheckpoint{
+		Node:         lease.NodeName(),
+		ConnectionID: connectionID,
+		Chunked:      chunked,
+		BinlogFile:   binlogFile,
+		BinlogPos:    binlogPos,
+		StartedAt:    time.Now(),
+	}
+	if err := utils.SetTaskCheckpoint(e.Config.TaskID, checkpoint); err != nil {
+		global.Logger.Warn("Failed to save task checkpoint", zap.Error(err), zap.String("task_id", e.Config.TaskID))
+		return func() {}
+	}
+	return func() { utils.DeleteTaskCheckpoint(e.Config.TaskID) }
 }
 
 // ExecuteDDL 执行DDL语句
-func (e *MySQLExecutor) ExecuteDDL() (ReturnData, error) {
+func (e *MySQLExecutor) ExecuteDDL(ctx context.Context) (ReturnData, error) {
 	// 解析SQL类型，判断是否需要使用 gh-ost
 	sqlType, err := parser.GetSqlStatement(e.Config.SQL)
 	if err != nil {
 		return ReturnData{Error: err.Error()}, err
 	}
 
+	// 记录执行前的表结构，执行成功后生成反向DDL
+	snapshot := e.captureDDLSnapshot()
+	data, err := e.executeDDLStatement(ctx, sqlType)
+	if err != nil || snapshot == nil {
+		return data, err
+	}
+
+	var executeLog []string
+	logMessage := e.newLogger(&executeLog)
+	rollbackSQL, notes, rollbackErr := e.generateReverseDDL(snapshot)
+	for _, note := range notes {
+		logMessage(note)
+	}
+	if rollbackErr != nil {
+		logMessage(fmt.Sprintf("生成反向DDL失败: %s", rollbackErr.Error()))
+	} else if rollbackSQL != "" {
+		logMessage("已生成反向DDL")
+		data.RollbackSQL = rollbackSQL
+	}
+	if len(executeLog) > 0 {
+		data.ExecuteLog = strings.TrimPrefix(data.ExecuteLog+"\n"+strings.Join(executeLog, "\n"), "\n")
+	}
+	return data, nil
+}
+
+// executeDDLStatement 按语句类型执行DDL
+func (e *MySQLExecutor) executeDDLStatement(ctx context.Context, sqlType string) (ReturnData, error) {
 	switch sqlType {
 	case "AlterTable":
-		// ALTER TABLE 使用 gh-ost 在线执行
-		return e.ExecuteDDLWithGhost()
+		// ALTER TABLE 按配置选择在线DDL引擎（gh-ost/pt-osc/原生ALTER）执行，TiDB 使用原生DDL并跟踪 DDL Job
+		return e.ExecuteAlterTable(ctx)
 	case "CreateDatabase", "CreateTable", "CreateView":
 		// CREATE 语句直接执行
-		return e.ExecuteOnlineDDL()
+		return e.ExecuteOnlineDDL(ctx)
 	case "DropTable", "DropIndex":
 		// DROP 语句直接执行
-		return e.ExecuteOnlineDDL()
+		return e.ExecuteOnlineDDL(ctx)
 	case "TruncateTable":
 		// TRUNCATE 语句直接执行
-		return e.ExecuteOnlineDDL()
+		return e.ExecuteOnlineDDL(ctx)
 	case "RenameTable":
 		// RENAME TABLE 不支持，建议使用 ALTER TABLE ... RENAME
 		return ReturnData{Error: "请更正为alter table ... rename语法"}, errors.New("请更正为alter table ... rename语法")
@@ -114,7 +197,7 @@ func (e *MySQLExecutor) ExecuteDDL() (ReturnData, error) {
 }
 
 // ExecuteOnlineDDL 执行Online DDL语句（直接执行）
-func (e *MySQLExecutor) ExecuteOnlineDDL() (ReturnData, error) {
+func (e *MySQLExecutor) ExecuteOnlineDDL(ctx context.Context) (ReturnData, error) {
 	var data ReturnData
 	var executeLog []string
 
@@ -142,7 +225,7 @@ func (e *MySQLExecutor) ExecuteOnlineDDL() (ReturnData, error) {
 
 	// 获取连接ID
 	var connectionID int64
-	if err := db.QueryRow("SELECT CONNECTION_ID()").Scan(&connectionID); err != nil {
+	if err := db.QueryRowContext(ctx, "SELECT CONNECTION_ID()").Scan(&connectionID); err != nil {
 		logMessage(fmt.Sprintf("获取Connection ID失败: %s", err.Error()))
 		data.ExecuteLog = strings.Join(executeLog, "\n")
 		data.Error = err.Error()
@@ -150,6 +233,10 @@ func (e *MySQLExecutor) ExecuteOnlineDDL() (ReturnData, error) {
 	}
 	logMessage(fmt.Sprintf("Connection ID: %d", connectionID))
 
+	// 记录执行检查点（binlog 不可用时只记录连接ID）
+	binlogFile, binlogPos, _ := mysqlpkg.GetBinlogPos(db)
+	defer e.saveCheckpoint(connectionID, binlogFile, binlogPos, false)()
+
 	// 启动 PROCESSLIST 监控（在单独的 goroutine 中）
 	var ch1 chan int64
 	if e.Config.OrderID != "" {
@@ -163,6 +250,16 @@ func (e *MySQLExecutor) ExecuteOnlineDDL() (ReturnData, error) {
 		}()
 	}
 
+	// 执行前检查限流条件
+	throttle := newThrottler(e.Config, db, logMessage)
+	defer throttle.Close()
+	if err := throttle.Wait(ctx); err != nil {
+		logMessage(fmt.Sprintf("等待限流解除时中断: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
 	// 执行SQL
 	logMessage(fmt.Sprintf("执行SQL: %s", truncateSQL(e.Config.SQL, 200)))
 	startTime := time.Now()
@@ -175,9 +272,6 @@ func (e *MySQLExecutor) ExecuteOnlineDDL() (ReturnData, error) {
 		}
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
-	defer cancel()
-
 	result, err := db.ExecContext(ctx, e.Config.SQL)
 
 	if err != nil {
@@ -199,7 +293,7 @@ func (e *MySQLExecutor) ExecuteOnlineDDL() (ReturnData, error) {
 }
 
 // ExecuteDDLWithGhost 使用 gh-ost 执行 ALTER TABLE 语句
-func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
+func (e *MySQLExecutor) ExecuteDDLWithGhost(ctx context.Context) (ReturnData, error) {
 	var data ReturnData
 	var executeLog []string
 
@@ -239,45 +333,12 @@ func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
 		ghostArgs = []string{} // 使用默认参数
 	}
 
-	// 移除SQL语句前后的所有空白字符
-	newSQL := strings.TrimSpace(e.Config.SQL)
-	logMessage("移除SQL语句前后的所有空白字符，包括空格、制表符、换行符等")
-
-	// 获取表名
-	fullTableName, err := parser.GetTableNameFromAlterStatement(e.Config.SQL)
+	// 解析 ALTER 语句，提取库名、表名和变更子句
+	databaseName, tableName, alterClause, err := parseAlterStatement(e.Config.SQL, e.Config.Schema)
 	if err != nil {
-		return logErrorAndReturn(err, "解析SQL提取表名失败，错误：")
-	}
-	logMessage("从SQL语句中提取表名成功")
-
-	// 处理表名：如果包含 schema.table 格式，需要分离
-	var databaseName, tableName string
-	if strings.Contains(fullTableName, ".") {
-		parts := strings.SplitN(fullTableName, ".", 2)
-		databaseName = strings.Trim(parts[0], "`")
-		tableName = strings.Trim(parts[1], "`")
-	} else {
-		databaseName = e.Config.Schema
-		tableName = strings.Trim(fullTableName, "`")
+		return logErrorAndReturn(err, "解析ALTER语句失败，错误：")
 	}
-
-	// 正则匹配提取 ALTER 子句
-	syntax := `(?i)^ALTER(\s+)TABLE(\s+)([\S]*)(\s+)(ADD|CHANGE|RENAME|MODIFY|DROP|ENGINE|CONVERT)(\s*)([\S\s]*)`
-	re, err := regexp.Compile(syntax)
-	if err != nil {
-		return logErrorAndReturn(err, "正则匹配SQL语句失败，错误：")
-	}
-	match := re.FindStringSubmatch(newSQL)
-	if len(match) < 5 {
-		return logErrorAndReturn(errors.New("正则匹配失败"), "正则匹配SQL语句失败")
-	}
-	logMessage("正则匹配SQL语句成功")
-
-	// 将反引号处理为空，将双引号处理成单引号
-	alterClause := strings.Join(match[5:], "")
-	alterClause = strings.ReplaceAll(alterClause, "`", "")
-	alterClause = strings.ReplaceAll(alterClause, "\"", "'")
-	logMessage("将反引号处理为空，将双引号处理成单引号")
+	logMessage(fmt.Sprintf("解析ALTER语句成功，表: %s.%s", databaseName, tableName))
 
 	// 生成 gh-ost 命令
 	logMessage("生成gh-ost执行命令")
@@ -294,16 +355,26 @@ func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
 		}
 	}
 
+	password, err := secret.Decrypt(e.Config.Password)
+	if err != nil {
+		return logErrorAndReturn(err, "解密实例密码失败，错误：")
+	}
 	ghostCMDParts := []string{
 		ghostPath,
 		strings.Join(ghostArgs, " "),
-		fmt.Sprintf("--user=\"%s\" --password=\"%s\"", e.Config.UserName, e.Config.Password),
+		fmt.Sprintf("--user=\"%s\" --password=\"%s\"", e.Config.UserName, password),
 		fmt.Sprintf("--host=\"%s\" --port=%d", e.Config.Hostname, e.Config.Port),
 		fmt.Sprintf("--database=%s --table=%s", databaseName, tableName),
 		fmt.Sprintf("--alter=\"%s\" --execute", alterClause),
 		fmt.Sprintf("--serve-socket-file=%s", socketPath),
 	}
 
+	// 实例限流配置
+	if throttleArgs := ghostThrottleArgs(e.Config); len(throttleArgs) > 0 {
+		ghostCMDParts = append(ghostCMDParts, throttleArgs...)
+		logMessage(fmt.Sprintf("已添加实例限流参数: %s", strings.Join(throttleArgs, " ")))
+	}
+
 	// 如果是阿里云 RDS，添加特殊参数
 	if strings.Contains(e.Config.Hostname, "rds.aliyuncs.com") {
 		ghostCMDParts = append(ghostCMDParts, "--aliyun-rds=true")
@@ -328,17 +399,28 @@ func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
 		)
 	}
 
+	// 推迟 cut-over：创建标记文件，待手动或到达计划时间后再执行 cut-over
+	if e.Config.GhostPostponeCutOver {
+		flagFile := utils.GetGhostPostponeFlagPath(databaseName, tableName)
+		if err := os.WriteFile(flagFile, nil, 0o644); err != nil {
+			return logErrorAndReturn(err, "创建推迟 cut-over 标记文件失败，错误：")
+		}
+		defer os.Remove(flagFile)
+		ghostCMDParts = append(ghostCMDParts, fmt.Sprintf("--postpone-cut-over-flag-file=%s", flagFile))
+		logMessage("已添加 --postpone-cut-over-flag-file 参数：数据同步完成后等待手动或计划时间执行 cut-over")
+	}
+
 	ghostCMD := strings.Join(ghostCMDParts, " ")
 
 	startTime := time.Now()
 
 	// 打印命令（掩码 password）
-	re = regexp.MustCompile(`--password="([^"]*)"`)
+	re := regexp.MustCompile(`--password="([^"]*)"`)
 	printGhostCMD := re.ReplaceAllString(ghostCMD, `--password="..."`)
 	logMessage(fmt.Sprintf("执行gh-ost命令：%s", printGhostCMD))
 
-	// 执行 gh-ost 命令
-	ctx, cancel := context.WithCancel(context.Background())
+	// 执行 gh-ost 命令（取消任务时会终止 gh-ost 进程）
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	ch := make(chan string, 100) // 使用缓冲通道，避免阻塞
@@ -361,8 +443,6 @@ func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
 				trimmedMsg := strings.TrimRight(msg, "\n")
 				if trimmedMsg != "" {
 					logMessage(trimmedMsg)
-					// 解析进度信息并推送
-					e.parseAndPublishGhostProgress(trimmedMsg)
 				}
 			}
 		}
@@ -373,8 +453,18 @@ func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
 		done <- utils.Command(ctx, ch, ghostCMD)
 	}()
 
+	// 通过 serve socket 轮询 gh-ost 状态并推送进度
+	pollDone := make(chan struct{})
+	pollExited := make(chan struct{})
+	go func() {
+		defer close(pollExited)
+		e.pollGhostStatus(ctx, socketPath, pollDone, ch)
+	}()
+
 	// 等待命令执行完成
 	commandError = <-done
+	close(pollDone)
+	<-pollExited
 	// 命令执行完成，关闭 channel 让读取 goroutine 退出
 	close(ch)
 	// 等待读取 goroutine 处理完剩余输出
@@ -397,7 +487,7 @@ func (e *MySQLExecutor) ExecuteDDLWithGhost() (ReturnData, error) {
 }
 
 // ExecuteDML 执行DML语句
-func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
+func (e *MySQLExecutor) ExecuteDML(ctx context.Context) (ReturnData, error) {
 	var data ReturnData
 	var executeLog []string
 
@@ -411,6 +501,15 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 		}
 	}
 
+	// 分批执行：能改写为主键范围批次的语句走分批逻辑，否则按普通方式执行
+	if e.Config.ChunkedDML {
+		plan, err := buildChunkPlan(e.Config.SQL, e.Config.Schema)
+		if err == nil {
+			return e.ExecuteChunkedDML(ctx, plan)
+		}
+		logMessage(fmt.Sprintf("无法分批执行（%s），按普通方式执行", err.Error()))
+	}
+
 	// 连接数据库
 	logMessage(fmt.Sprintf("连接数据库 %s:%d...", e.Config.Hostname, e.Config.Port))
 	db, err := e.Connect()
@@ -445,6 +544,24 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 	} else {
 		logMessage(fmt.Sprintf("Start Binlog File: %s, Position: %d", startFile, startPosition))
 	}
+	defer e.saveCheckpoint(connectionID, startFile, startPosition, false)()
+
+	// binlog 不可用时改为前镜像备份（需在开启事务前获取表结构）
+	useBinlog := startFile != ""
+	if useBinlog {
+		if err := checkBinlogUsable(ctx, db, e.Config.DBType); err != nil {
+			logMessage(fmt.Sprintf("无法解析binlog生成回滚SQL: %s", err.Error()))
+			useBinlog = false
+		}
+	}
+	var preImage *preImageBackup
+	if !useBinlog {
+		if preImage, err = newPreImageBackup(ctx, db, e.Config.SQL, e.Config.Schema); err != nil {
+			logMessage(fmt.Sprintf("无法使用前镜像备份（%s），不生成回滚SQL", err.Error()))
+		} else {
+			logMessage("使用前镜像备份生成回滚SQL")
+		}
+	}
 
 	// 启动 PROCESSLIST 监控（在单独的 goroutine 中）
 	var ch1 chan int64
@@ -459,9 +576,19 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 		}()
 	}
 
+	// 执行前检查限流条件
+	throttle := newThrottler(e.Config, db, logMessage)
+	defer throttle.Close()
+	if err := throttle.Wait(ctx); err != nil {
+		logMessage(fmt.Sprintf("等待限流解除时中断: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
 	// 开启事务
 	logMessage("开启事务...")
-	tx, err := db.Begin()
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		logMessage(fmt.Sprintf("开启事务失败: %s", err.Error()))
 		data.ExecuteLog = strings.Join(executeLog, "\n")
@@ -469,6 +596,28 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 		return data, err
 	}
 
+	// 前镜像备份：在同一事务中锁定并查询受影响的行
+	var preImageSQL string
+	var preImageCost time.Duration
+	if preImage != nil {
+		backupStartTime := time.Now()
+		var rows int
+		preImageSQL, rows, err = preImage.Capture(ctx, tx, "")
+		preImageCost = time.Since(backupStartTime)
+		if errors.Is(err, errPreImageTooLarge) {
+			logMessage(fmt.Sprintf("前镜像备份失败: %s，不生成回滚SQL", err.Error()))
+			preImage = nil
+		} else if err != nil {
+			tx.Rollback()
+			logMessage(fmt.Sprintf("前镜像备份失败，已回滚: %s", err.Error()))
+			data.ExecuteLog = strings.Join(executeLog, "\n")
+			data.Error = err.Error()
+			return data, err
+		} else {
+			logMessage(fmt.Sprintf("前镜像备份 %d 行，耗时: %s", rows, preImageCost.String()))
+		}
+	}
+
 	// 执行SQL
 	logMessage(fmt.Sprintf("执行SQL: %s", truncateSQL(e.Config.SQL, 200)))
 	startTime := time.Now()
@@ -481,7 +630,7 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 		}
 	}
 
-	result, err := tx.Exec(e.Config.SQL)
+	result, err := tx.ExecContext(ctx, e.Config.SQL)
 	if err != nil {
 		tx.Rollback()
 		logMessage(fmt.Sprintf("执行失败，已回滚: %s", err.Error()))
@@ -490,6 +639,16 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 		return data, err
 	}
 
+	// 影响行数超过上限时回滚，不提交
+	rows, _ := result.RowsAffected()
+	if err := e.checkAffectedRows(rows); err != nil {
+		tx.Rollback()
+		logMessage(fmt.Sprintf("执行失败，已回滚: %s", err.Error()))
+		data.ExecuteLog = strings.Join(executeLog, "\n")
+		data.Error = err.Error()
+		return data, err
+	}
+
 	// 提交事务
 	if err := tx.Commit(); err != nil {
 		logMessage(fmt.Sprintf("提交事务失败: %s", err.Error()))
@@ -506,9 +665,16 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 	data.AffectedRows = affectedRows
 	data.ExecuteCostTime = executeCostTime
 
-	// 如果影响行数大于0，且成功获取了binlog position，生成回滚SQL
+	// 如果影响行数大于0，使用前镜像或解析binlog生成回滚SQL
 	var rollbackSQL, backupCostTime string
-	if affectedRows > 0 && startFile != "" {
+	backupStrategy := BackupStrategyNone
+	if preImage != nil {
+		if affectedRows > 0 && preImageSQL != "" {
+			rollbackSQL = preImageSQL
+			backupCostTime = preImageCost.String()
+			backupStrategy = BackupStrategyPreImage
+		}
+	} else if affectedRows > 0 && useBinlog {
 		// 获取执行后的binlog position
 		endFile, endPosition, err := mysqlpkg.GetBinlogPos(db)
 		if err != nil {
@@ -525,6 +691,8 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 					UserName: e.Config.UserName,
 					Password: e.Config.Password,
 					Schema:   e.Config.Schema,
+
+					ConnOptions: e.Config.ConnOptions,
 				},
 				ConnectionID:  connectionID,
 				StartFile:     startFile,
@@ -537,6 +705,7 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 				logMessage(fmt.Sprintf("生成回滚SQL失败: %s", err.Error()))
 			} else {
 				backupCostTime = time.Since(backupStartTime).String()
+				backupStrategy = BackupStrategyBinlog
 				logMessage(fmt.Sprintf("生成回滚SQL成功，耗时: %s", backupCostTime))
 			}
 		}
@@ -544,12 +713,13 @@ func (e *MySQLExecutor) ExecuteDML() (ReturnData, error) {
 
 	data.RollbackSQL = rollbackSQL
 	data.BackupCostTime = backupCostTime
+	data.BackupStrategy = backupStrategy
 	data.ExecuteLog = strings.Join(executeLog, "\n")
 	return data, nil
 }
 
 // ExecuteExport 执行数据导出
-func (e *MySQLExecutor) ExecuteExport() (ReturnData, error) {
+func (e *MySQLExecutor) ExecuteExport(ctx context.Context) (ReturnData, error) {
 	var data ReturnData
 	var executeLog []string
 
@@ -579,7 +749,7 @@ func (e *MySQLExecutor) ExecuteExport() (ReturnData, error) {
 	logMessage(fmt.Sprintf("执行查询: %s", truncateSQL(e.Config.SQL, 200)))
 	startTime := time.Now()
 
-	rows, err := db.Query(e.Config.SQL)
+	rows, err := db.QueryContext(ctx, e.Config.SQL)
 	if err != nil {
 		logMessage(fmt.Sprintf("查询失败: %s", err.Error()))
 		data.ExecuteLog = strings.Join(executeLog, "\n")
@@ -588,26 +758,19 @@ func (e *MySQLExecutor) ExecuteExport() (ReturnData, error) {
 	}
 	defer rows.Close()
 
-	// 获取列信息
-	columns, err := rows.Columns()
+	// 流式写入导出文件
+	exportFile, err := exportToFile(rows, e.Config, logMessage)
 	if err != nil {
-		logMessage(fmt.Sprintf("获取列信息失败: %s", err.Error()))
+		logMessage(fmt.Sprintf("导出失败: %s", err.Error()))
 		data.ExecuteLog = strings.Join(executeLog, "\n")
 		data.Error = err.Error()
 		return data, err
 	}
 
-	// 统计行数
-	var rowCount int64
-	for rows.Next() {
-		rowCount++
-	}
-
 	executeCostTime := time.Since(startTime).String()
-	logMessage(fmt.Sprintf("查询成功，列数: %d，行数: %d，耗时: %s", len(columns), rowCount, executeCostTime))
+	logMessage(fmt.Sprintf("导出成功，文件: %s，大小: %d 字节，行数: %d，耗时: %s", exportFile.FileName, exportFile.FileSize, exportFile.ExportRows, executeCostTime))
 
-	// TODO: 实际导出文件逻辑
-	data.ExportRows = rowCount
+	data.ExportFile = exportFile
 	data.ExecuteCostTime = executeCostTime
 	data.ExecuteLog = strings.Join(executeLog, "\n")
 	return data, nil
@@ -619,14 +782,22 @@ func (e *MySQLExecutor) ExecuteExport() (ReturnData, error) {
 // connectionID: 连接ID（需要监控的连接）
 // ch: 控制通道（当channel关闭时，停止监控）
 func (e *MySQLExecutor) GetProcesslist(orderID string, connectionID int64, ch <-chan int64) {
-	// 创建新的数据库连接
-	monitorDB, err := e.Connect()
+	// 使用监控专用连接池，执行连接数达到上限时仍可监控
+	monitorDB, err := e.ControlConnect()
 	if err != nil {
 		global.Logger.Error("Failed to create monitor connection", zap.Error(err), zap.String("order_id", orderID))
 		return
 	}
 	defer monitorDB.Close()
 
+	// 记录任务对应的连接ID（用于取消任务时 KILL QUERY）
+	if e.Config.TaskID != "" {
+		if err := utils.SetTaskConnectionID(e.Config.TaskID, connectionID); err != nil {
+			global.Logger.Warn("Failed to save task connection id", zap.Error(err), zap.String("task_id", e.Config.TaskID))
+		}
+		defer utils.DeleteTaskConnectionID(e.Config.TaskID)
+	}
+
 	// 构造查询SQL
 	querySQL := fmt.Sprintf("SELECT * FROM INFORMATION_SCHEMA.PROCESSLIST WHERE ID=%d", connectionID)
 
@@ -648,7 +819,7 @@ func (e *MySQLExecutor) GetProcesslist(orderID string, connectionID int64, ch <-
 		}
 
 		// 执行查询
-		rows, err := monitorDB.Query(querySQL)
+		rows, err := monitorDB.QueryContext(context.Background(), querySQL)
 		if err != nil {
 			global.Logger.Error("Failed to get processlist", zap.Error(err), zap.String("order_id", orderID), zap.Int64("connection_id", connectionID))
 			break
@@ -709,104 +880,16 @@ func (e *MySQLExecutor) GetProcesslist(orderID string, connectionID int64, ch <-
 	global.Logger.Debug("Processlist monitor stopped", zap.String("order_id", orderID), zap.Int64("connection_id", connectionID))
 }
 
-// parseAndPublishGhostProgress 解析 gh-ost 输出中的进度信息并推送
-// gh-ost 输出示例：
-// - "Copy: 12345/1000000 1.23%"
-// - "Progress: 1.23% ETA: 2h30m"
-// - "Rows: 12345/1000000 (1.23%)"
-func (e *MySQLExecutor) parseAndPublishGhostProgress(line string) {
-	if e.Config.OrderID == "" {
-		return
+// KillQuery 终止指定连接上正在执行的语句（用于取消任务）
+func KillQuery(config *DBConfig, connectionID int64) error {
+	db, err := NewMySQLExecutor(config).ControlConnect()
+	if err != nil {
+		return err
 	}
+	defer db.Close()
 
-	// 定义多种进度匹配模式
-	patterns := []struct {
-		regex   *regexp.Regexp
-		getData func([]string) map[string]interface{}
-	}{
-		// 匹配 "Copy: 12345/1000000 1.23%" 格式
-		{
-			regex: regexp.MustCompile(`(?i)Copy:\s*(\d+)/(\d+)\s+([\d.]+)%`),
-			getData: func(matches []string) map[string]interface{} {
-				current, _ := strconv.ParseInt(matches[1], 10, 64)
-				total, _ := strconv.ParseInt(matches[2], 10, 64)
-				percent, _ := strconv.ParseFloat(matches[3], 64)
-				return map[string]interface{}{
-					"current":   current,
-					"total":     total,
-					"percent":   percent,
-					"operation": "copy",
-				}
-			},
-		},
-		// 匹配 "Progress: 1.23% ETA: 2h30m" 格式
-		{
-			regex: regexp.MustCompile(`(?i)Progress:\s*([\d.]+)%\s+ETA:\s*([\w]+)`),
-			getData: func(matches []string) map[string]interface{} {
-				percent, _ := strconv.ParseFloat(matches[1], 64)
-				return map[string]interface{}{
-					"percent":   percent,
-					"eta":       matches[2],
-					"operation": "progress",
-				}
-			},
-		},
-		// 匹配 "Rows: 12345/1000000 (1.23%)" 格式
-		{
-			regex: regexp.MustCompile(`(?i)Rows:\s*(\d+)/(\d+)\s+\(([\d.]+)%\)`),
-			getData: func(matches []string) map[string]interface{} {
-				current, _ := strconv.ParseInt(matches[1], 10, 64)
-				total, _ := strconv.ParseInt(matches[2], 10, 64)
-				percent, _ := strconv.ParseFloat(matches[3], 64)
-				return map[string]interface{}{
-					"current":   current,
-					"total":     total,
-					"percent":   percent,
-					"operation": "rows",
-				}
-			},
-		},
-		// 匹配包含百分比的通用格式，如 "1.23%" 或 "23.45%"
-		{
-			regex: regexp.MustCompile(`(?i)(\d+\.?\d*)\s*%`),
-			getData: func(matches []string) map[string]interface{} {
-				percent, _ := strconv.ParseFloat(matches[1], 64)
-				// 只处理 0-100 之间的百分比
-				if percent >= 0 && percent <= 100 {
-					return map[string]interface{}{
-						"percent":   percent,
-						"operation": "general",
-					}
-				}
-				return nil
-			},
-		},
-	}
-
-	// 尝试匹配各种格式
-	for _, pattern := range patterns {
-		matches := pattern.regex.FindStringSubmatch(line)
-		if len(matches) > 1 {
-			progressData := pattern.getData(matches)
-			if progressData != nil {
-				// 推送进度信息到 WebSocket（类型为 "ghost-progress"）
-				if err := utils.PublishMessageToChannel(e.Config.OrderID, progressData, "ghost-progress"); err != nil {
-					global.Logger.Error("Failed to publish ghost progress", zap.String("order_id", e.Config.OrderID), zap.Error(err))
-				}
-
-				// 保存进度到 Redis 缓存
-				if err := utils.SaveGhostProgressToRedis(e.Config.OrderID, progressData); err != nil {
-					global.Logger.Warn("Failed to save ghost progress to Redis cache",
-						zap.String("order_id", e.Config.OrderID),
-						zap.Error(err),
-					)
-				}
-
-				// 匹配第一个成功的模式后退出
-				break
-			}
-		}
-	}
+	_, err = db.ExecContext(context.Background(), fmt.Sprintf("KILL QUERY %d", connectionID))
+	return err
 }
 
 // truncateSQL 截断SQL用于日志显示
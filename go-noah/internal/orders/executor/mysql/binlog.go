@@ -5,13 +5,16 @@ import (
 	"database/sql"
 	"database/sql/driver"
 	"fmt"
+	"go-noah/pkg/dbconn"
 	"go-noah/pkg/global"
+	"go-noah/pkg/secret"
 	"go-noah/pkg/utils"
 	"strings"
 	"time"
 
 	mysqlpkg "github.com/go-mysql-org/go-mysql/mysql"
 	"github.com/go-mysql-org/go-mysql/replication"
+	gomysql "github.com/go-sql-driver/mysql"
 	"github.com/pingcap/tidb/pkg/parser"
 	"github.com/pingcap/tidb/pkg/parser/ast"
 	"go.uber.org/zap"
@@ -24,6 +27,8 @@ type BinlogConfig struct {
 	UserName string
 	Password string
 	Schema   string
+
+	ConnOptions dbconn.Options // 连接选项（SSH 跳板机、TLS）
 }
 
 // Binlog binlog解析器
@@ -104,14 +109,9 @@ func getNonGeneratedCols(stmt *ast.CreateTableStmt) []int {
 
 // Run 解析binlog生成回滚SQL
 func (b *Binlog) Run() (string, error) {
-	cfg := replication.BinlogSyncerConfig{
-		ServerID:   20231108 + uint32(uint32(time.Now().Unix())%10000),
-		Flavor:     "mysql",
-		Host:       b.Config.Hostname,
-		Port:       uint16(b.Config.Port),
-		User:       b.Config.UserName,
-		Password:   b.Config.Password,
-		UseDecimal: true,
+	cfg, err := b.Config.syncerConfig(20231108 + uint32(uint32(time.Now().Unix())%10000))
+	if err != nil {
+		return "", err
 	}
 	syncer := replication.NewBinlogSyncer(cfg)
 	defer syncer.Close()
@@ -557,14 +557,127 @@ func (b *Binlog) generateDeleteSql(e *replication.RowsEvent, stmt *ast.CreateTab
 	return strings.Join(rbsqls, ";\r\n"), nil
 }
 
+// ThreadActivity 指定连接在 binlog 区间内已提交的变更
+type ThreadActivity struct {
+	Transactions int      // 已提交的包含变更的事务数
+	Statements   []string // 事务外的语句（DDL）
+}
+
+// ScanThread 扫描 binlog 区间内指定连接（ConnectionID）已提交的事务和DDL，用于核对执行中断的任务是否已生效
+// binlog 中只包含已提交的事务，连接断开时未提交的事务已回滚
+func (b *Binlog) ScanThread(ctx context.Context) (*ThreadActivity, error) {
+	activity := &ThreadActivity{}
+	startPosition := mysqlpkg.Position{Name: b.StartFile, Pos: uint32(b.StartPosition)}
+	stopPosition := mysqlpkg.Position{Name: b.EndFile, Pos: uint32(b.EndPosition)}
+	if startPosition.Compare(stopPosition) > -1 {
+		return activity, nil
+	}
+
+	cfg, err := b.Config.syncerConfig(20231108 + uint32(uint32(time.Now().Unix())%10000))
+	if err != nil {
+		return nil, err
+	}
+	syncer := replication.NewBinlogSyncer(cfg)
+	defer syncer.Close()
+
+	streamer, err := syncer.StartSync(startPosition)
+	if err != nil {
+		return nil, fmt.Errorf("启动binlog同步失败: %w", err)
+	}
+
+	currentPosition := startPosition
+	var currentThreadID uint32
+	inTx, txChanged := false, false
+	for currentPosition.Compare(stopPosition) == -1 {
+		e, err := streamer.GetEvent(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取binlog事件失败: %w", err)
+		}
+		if e.Header.LogPos > 0 {
+			currentPosition.Pos = e.Header.LogPos
+		}
+		if event, ok := e.Event.(*replication.RotateEvent); ok && e.Header.EventType == replication.ROTATE_EVENT {
+			currentPosition = mysqlpkg.Position{Name: string(event.NextLogName), Pos: uint32(event.Position)}
+			continue
+		}
+		if currentPosition.Compare(startPosition) < 1 {
+			continue
+		}
+
+		switch event := e.Event.(type) {
+		case *replication.QueryEvent:
+			currentThreadID = event.SlaveProxyID
+			query := strings.TrimSpace(string(event.Query))
+			switch {
+			case strings.EqualFold(query, "BEGIN"):
+				inTx, txChanged = true, false
+			case strings.EqualFold(query, "COMMIT"):
+				// 非事务引擎以 COMMIT 语句结束
+				if txChanged && b.ConnectionID == int64(currentThreadID) {
+					activity.Transactions++
+				}
+				inTx, txChanged = false, false
+			case b.ConnectionID != int64(currentThreadID):
+			case inTx:
+				// STATEMENT 格式的 DML
+				txChanged = true
+			default:
+				activity.Statements = append(activity.Statements, query)
+			}
+		case *replication.RowsEvent:
+			if b.ConnectionID == int64(currentThreadID) {
+				txChanged = true
+			}
+		case *replication.XIDEvent:
+			if txChanged && b.ConnectionID == int64(currentThreadID) {
+				activity.Transactions++
+			}
+			inTx, txChanged = false, false
+		}
+	}
+	return activity, nil
+}
+
+// syncerConfig 生成 binlog 同步器配置（经过 SSH 跳板机时通过隧道连接）
+func (c *BinlogConfig) syncerConfig(serverID uint32) (replication.BinlogSyncerConfig, error) {
+	password, err := secret.Decrypt(c.Password)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, fmt.Errorf("解密实例密码失败: %w", err)
+	}
+	tlsConfig, err := c.ConnOptions.TLSConfig(c.Hostname)
+	if err != nil {
+		return replication.BinlogSyncerConfig{}, err
+	}
+	return replication.BinlogSyncerConfig{
+		ServerID:   serverID,
+		Flavor:     "mysql",
+		Host:       c.Hostname,
+		Port:       uint16(c.Port),
+		User:       c.UserName,
+		Password:   password,
+		UseDecimal: true,
+		TLSConfig:  tlsConfig,
+		Dialer:     c.ConnOptions.DialContext,
+	}, nil
+}
+
 // connect 连接数据库
 func (b *Binlog) connect() (*sql.DB, error) {
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s?charset=utf8mb4&parseTime=True&loc=Local",
-		b.Config.UserName,
-		b.Config.Password,
-		b.Config.Hostname,
-		b.Config.Port,
-		b.Config.Schema,
-	)
-	return sql.Open("mysql", dsn)
+	password, err := secret.Decrypt(b.Config.Password)
+	if err != nil {
+		return nil, fmt.Errorf("解密实例密码失败: %w", err)
+	}
+	config := gomysql.NewConfig()
+	config.User = b.Config.UserName
+	config.Passwd = password
+	config.Net = "tcp"
+	config.Addr = fmt.Sprintf("%s:%d", b.Config.Hostname, b.Config.Port)
+	config.DBName = b.Config.Schema
+	config.ParseTime = true
+	config.Loc = time.Local
+	config.Params = map[string]string{"charset": "utf8mb4"}
+	if err := b.Config.ConnOptions.ApplyMySQL(config); err != nil {
+		return nil, err
+	}
+	return sql.Open("mysql", config.FormatDSN())
 }
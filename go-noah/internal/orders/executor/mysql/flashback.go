@@ -0,0 +1,400 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	mysqlpkg "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// 闪回事件类型
+const (
+	FlashbackEventInsert = "INSERT"
+	FlashbackEventUpdate = "UPDATE"
+	FlashbackEventDelete = "DELETE"
+)
+
+// FlashbackOptions 闪回参数
+// 起始位置和起始时间至少指定一个；未指定结束位置时使用开始解析时的 SHOW MASTER STATUS
+type FlashbackOptions struct {
+	StartFile     string
+	StartPosition int64
+	StopFile      string
+	StopPosition  int64
+	StartTime     time.Time
+	StopTime      time.Time
+	Schemas       []string // 库名过滤（为空不过滤）
+	Tables        []string // 表名过滤，支持 table 或 schema.table（为空不过滤）
+	EventTypes    []string // 事件类型过滤 INSERT/UPDATE/DELETE（为空不过滤）
+}
+
+// FlashbackResult 闪回结果统计
+type FlashbackResult struct {
+	StartFile     string `json:"start_file"`
+	StartPosition int64  `json:"start_position"`
+	StopFile      string `json:"stop_file"`
+	StopPosition  int64  `json:"stop_position"`
+	InsertEvents  int64  `json:"insert_events"`
+	UpdateEvents  int64  `json:"update_events"`
+	DeleteEvents  int64  `json:"delete_events"`
+	Bytes         int64  `json:"bytes"`
+}
+
+// Flashback binlog闪回：解析指定范围内的行事件，按逆序输出反向SQL
+// 与工单执行后的回滚SQL生成不同，不按连接ID过滤，用于恢复平台之外的数据变更
+type Flashback struct {
+	binlog     *Binlog
+	options    *FlashbackOptions
+	schemas    map[string]bool
+	tables     map[string]bool
+	eventTypes map[string]bool
+	tableStmts map[string]*ast.CreateTableStmt
+}
+
+// NewFlashback 创建闪回解析器
+func NewFlashback(config *BinlogConfig, options *FlashbackOptions) *Flashback {
+	return &Flashback{
+		binlog:     &Binlog{Config: config},
+		options:    options,
+		schemas:    toLowerSet(options.Schemas),
+		tables:     toLowerSet(options.Tables),
+		eventTypes: toUpperSet(options.EventTypes),
+		tableStmts: make(map[string]*ast.CreateTableStmt),
+	}
+}
+
+// Run 解析binlog并将反向SQL写入 w
+// 反向SQL需要按事件逆序输出，解析过程中先写入临时文件，结束后逆序拷贝，避免大范围闪回占用内存
+func (f *Flashback) Run(ctx context.Context, w io.Writer) (*FlashbackResult, error) {
+	if err := f.resolvePositions(ctx); err != nil {
+		return nil, err
+	}
+	result := &FlashbackResult{
+		StartFile:     f.options.StartFile,
+		StartPosition: f.options.StartPosition,
+		StopFile:      f.options.StopFile,
+		StopPosition:  f.options.StopPosition,
+	}
+
+	tmp, err := os.CreateTemp("", "flashback-*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("创建临时文件失败: %w", err)
+	}
+	defer func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}()
+
+	// 每个事件的反向SQL在临时文件中的位置
+	type segment struct {
+		offset int64
+		length int64
+	}
+	var segments []segment
+	var offset int64
+
+	syncer, err := f.newSyncer()
+	if err != nil {
+		return nil, err
+	}
+	defer syncer.Close()
+
+	startPosition := mysqlpkg.Position{Name: f.options.StartFile, Pos: uint32(f.options.StartPosition)}
+	stopPosition := mysqlpkg.Position{Name: f.options.StopFile, Pos: uint32(f.options.StopPosition)}
+	streamer, err := syncer.StartSync(startPosition)
+	if err != nil {
+		return nil, fmt.Errorf("启动binlog同步失败: %w", err)
+	}
+
+	currentPosition := startPosition
+	for {
+		e, err := streamer.GetEvent(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("获取binlog事件失败: %w", err)
+		}
+
+		if e.Header.LogPos > 0 {
+			currentPosition.Pos = e.Header.LogPos
+		}
+		if e.Header.EventType == replication.ROTATE_EVENT {
+			if event, ok := e.Event.(*replication.RotateEvent); ok {
+				currentPosition = mysqlpkg.Position{
+					Name: string(event.NextLogName),
+					Pos:  uint32(event.Position),
+				}
+			}
+			continue
+		}
+
+		if currentPosition.Compare(startPosition) == -1 {
+			continue
+		}
+		if currentPosition.Compare(stopPosition) > -1 {
+			break
+		}
+		if e.Header.Timestamp > 0 {
+			if !f.options.StartTime.IsZero() && int64(e.Header.Timestamp) < f.options.StartTime.Unix() {
+				continue
+			}
+			if !f.options.StopTime.IsZero() && int64(e.Header.Timestamp) > f.options.StopTime.Unix() {
+				break
+			}
+		}
+
+		event, ok := e.Event.(*replication.RowsEvent)
+		if !ok {
+			continue
+		}
+		eventType := rowsEventType(e.Header.EventType)
+		if eventType == "" || !f.match(string(event.Table.Schema), string(event.Table.Table), eventType) {
+			continue
+		}
+
+		rollbackSQL, err := f.generate(event, eventType)
+		if err != nil {
+			return nil, err
+		}
+		if rollbackSQL == "" {
+			continue
+		}
+		switch eventType {
+		case FlashbackEventInsert:
+			result.InsertEvents++
+		case FlashbackEventUpdate:
+			result.UpdateEvents++
+		case FlashbackEventDelete:
+			result.DeleteEvents++
+		}
+
+		content := fmt.Sprintf("-- %s %s:%d %s `%s`.`%s`\n%s;\n",
+			time.Unix(int64(e.Header.Timestamp), 0).Format("2006-01-02 15:04:05"),
+			currentPosition.Name, e.Header.LogPos, eventType, event.Table.Schema, event.Table.Table,
+			strings.ReplaceAll(rollbackSQL, ";\r\n", ";\n"))
+		n, err := tmp.WriteString(content)
+		if err != nil {
+			return nil, fmt.Errorf("写入临时文件失败: %w", err)
+		}
+		segments = append(segments, segment{offset: offset, length: int64(n)})
+		offset += int64(n)
+	}
+
+	// 逆序输出：后发生的变更先回滚
+	for i := len(segments) - 1; i >= 0; i-- {
+		n, err := io.Copy(w, io.NewSectionReader(tmp, segments[i].offset, segments[i].length))
+		if err != nil {
+			return nil, fmt.Errorf("写入闪回SQL失败: %w", err)
+		}
+		result.Bytes += n
+	}
+	return result, nil
+}
+
+// generate 复用回滚SQL生成逻辑：INSERT -> DELETE，DELETE -> INSERT，UPDATE -> 反向UPDATE
+func (f *Flashback) generate(event *replication.RowsEvent, eventType string) (string, error) {
+	tableName := fmt.Sprintf("`%s`.`%s`", event.Table.Schema, event.Table.Table)
+	stmt, ok := f.tableStmts[tableName]
+	if !ok {
+		var err error
+		stmt, err = f.binlog.parserTableStmt(tableName)
+		if err != nil {
+			return "", err
+		}
+		f.tableStmts[tableName] = stmt
+	}
+
+	switch eventType {
+	case FlashbackEventInsert:
+		return f.binlog.generateDeleteSql(event, stmt)
+	case FlashbackEventDelete:
+		return f.binlog.generateInsertSql(event, stmt)
+	default:
+		return f.binlog.generateUpdateSql(event, stmt)
+	}
+}
+
+// match 判断事件是否满足过滤条件
+func (f *Flashback) match(schema, table, eventType string) bool {
+	if len(f.eventTypes) > 0 && !f.eventTypes[eventType] {
+		return false
+	}
+	if len(f.schemas) > 0 && !f.schemas[strings.ToLower(schema)] {
+		return false
+	}
+	if len(f.tables) > 0 && !f.tables[strings.ToLower(table)] && !f.tables[strings.ToLower(schema+"."+table)] {
+		return false
+	}
+	return true
+}
+
+// resolvePositions 补全起止位置：
+// 未指定结束位置时使用当前 SHOW MASTER STATUS；未指定起始位置时根据起始时间定位binlog文件
+func (f *Flashback) resolvePositions(ctx context.Context) error {
+	if f.options.StartFile == "" && f.options.StartTime.IsZero() {
+		return fmt.Errorf("请指定起始binlog位置或起始时间")
+	}
+
+	db, err := f.binlog.connect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if f.options.StopFile == "" || f.options.StopPosition <= 0 {
+		file, position, err := GetBinlogPos(db)
+		if err != nil {
+			return err
+		}
+		switch {
+		case f.options.StopFile == "" || f.options.StopFile == file:
+			f.options.StopFile, f.options.StopPosition = file, position
+		default:
+			// 只指定了已归档的结束文件时解析到该文件末尾
+			f.options.StopPosition = 1<<32 - 1
+		}
+	}
+
+	if f.options.StartFile == "" {
+		file, err := f.locateBinlogFile(ctx, db, f.options.StartTime)
+		if err != nil {
+			return err
+		}
+		f.options.StartFile = file
+	}
+	if f.options.StartPosition < 4 {
+		f.options.StartPosition = 4
+	}
+	return nil
+}
+
+// locateBinlogFile 二分查找首个事件时间不晚于 t 的最后一个binlog文件
+func (f *Flashback) locateBinlogFile(ctx context.Context, db *sql.DB, t time.Time) (string, error) {
+	files, err := getBinaryLogs(db)
+	if err != nil {
+		return "", err
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("未找到binlog文件，请检查MySQL是否开启了binlog")
+	}
+
+	var searchErr error
+	idx := sort.Search(len(files), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+		ts, err := f.firstEventTime(ctx, files[i])
+		if err != nil {
+			searchErr = err
+			return true
+		}
+		return ts.After(t)
+	})
+	if searchErr != nil {
+		return "", searchErr
+	}
+	if idx == 0 {
+		return files[0], nil
+	}
+	return files[idx-1], nil
+}
+
+// firstEventTime 获取binlog文件首个事件（FORMAT_DESCRIPTION_EVENT）的时间
+func (f *Flashback) firstEventTime(ctx context.Context, file string) (time.Time, error) {
+	syncer, err := f.newSyncer()
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer syncer.Close()
+
+	streamer, err := syncer.StartSync(mysqlpkg.Position{Name: file, Pos: 4})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("启动binlog同步失败: %w", err)
+	}
+	for {
+		e, err := streamer.GetEvent(ctx)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("获取binlog事件失败: %w", err)
+		}
+		if e.Header.EventType == replication.FORMAT_DESCRIPTION_EVENT {
+			return time.Unix(int64(e.Header.Timestamp), 0), nil
+		}
+	}
+}
+
+// newSyncer 创建binlog同步器
+func (f *Flashback) newSyncer() (*replication.BinlogSyncer, error) {
+	cfg, err := f.binlog.Config.syncerConfig(20231108 + uint32(time.Now().UnixNano()%10000))
+	if err != nil {
+		return nil, err
+	}
+	return replication.NewBinlogSyncer(cfg), nil
+}
+
+// getBinaryLogs 获取binlog文件列表
+func getBinaryLogs(db *sql.DB) ([]string, error) {
+	rows, err := db.Query("SHOW BINARY LOGS")
+	if err != nil {
+		return nil, fmt.Errorf("获取binlog文件列表失败: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for rows.Next() {
+		values := make([]sql.RawBytes, len(columns))
+		scanArgs := make([]interface{}, len(columns))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		files = append(files, string(values[0]))
+	}
+	return files, rows.Err()
+}
+
+// rowsEventType 行事件对应的闪回事件类型
+func rowsEventType(t replication.EventType) string {
+	switch t {
+	case replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		return FlashbackEventInsert
+	case replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		return FlashbackEventUpdate
+	case replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		return FlashbackEventDelete
+	default:
+		return ""
+	}
+}
+
+func toLowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			set[strings.ToLower(v)] = true
+		}
+	}
+	return set
+}
+
+func toUpperSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			set[strings.ToUpper(v)] = true
+		}
+	}
+	return set
+}
@@ -1,6 +1,7 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"database/sql/driver"
 	"encoding/hex"
@@ -17,10 +18,16 @@ import (
 	"github.com/shopspring/decimal"
 )
 
+// Queryer 可执行查询的连接（*sql.DB 或 *sql.Conn）
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
 // GetConnectionID 获取MySQL连接ID
-func GetConnectionID(db *sql.DB) (int64, error) {
+func GetConnectionID(db Queryer) (int64, error) {
 	var connectionID int64
-	err := db.QueryRow("SELECT CONNECTION_ID()").Scan(&connectionID)
+	err := db.QueryRowContext(context.Background(), "SELECT CONNECTION_ID()").Scan(&connectionID)
 	if err != nil {
 		return 0, fmt.Errorf("获取Connection ID失败: %w", err)
 	}
@@ -28,9 +35,9 @@ func GetConnectionID(db *sql.DB) (int64, error) {
 }
 
 // GetBinlogPos 获取MySQL binlog position
-func GetBinlogPos(db *sql.DB) (file string, position int64, err error) {
+func GetBinlogPos(db Queryer) (file string, position int64, err error) {
 	// 使用 Query 获取所有列，然后通过列名提取需要的值（兼容不同 MySQL 版本的列数差异）
-	rows, err := db.Query("SHOW MASTER STATUS")
+	rows, err := db.QueryContext(context.Background(), "SHOW MASTER STATUS")
 	if err != nil {
 		return "", 0, fmt.Errorf("获取MySQL position失败: %w", err)
 	}
@@ -119,6 +126,15 @@ func isUnsigned(flag uint) bool {
 	return mysql.HasUnsignedFlag(flag)
 }
 
+// InterpolateParams 参数插值，生成可直接执行的SQL（非UTF-8的二进制数据使用十六进制表示）
+func InterpolateParams(query string, args []driver.Value) (string, error) {
+	buf, err := interpolateParams(query, args, true)
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
+
 // interpolateParams 参数插值，将SQL中的?替换为实际值
 func interpolateParams(query string, args []driver.Value, hexBlob bool) ([]byte, error) {
 	if strings.Count(query, "?") != len(args) {
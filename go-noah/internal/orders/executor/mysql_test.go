@@ -0,0 +1,33 @@
+package executor
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckAffectedRows(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Max     int64
+		Rows    int64
+		WantErr bool
+	}{
+		{Name: "不限制", Max: 0, Rows: 1 << 40},
+		{Name: "未达到上限", Max: 100, Rows: 99},
+		{Name: "等于上限", Max: 100, Rows: 100},
+		{Name: "超过上限", Max: 100, Rows: 101, WantErr: true},
+		{Name: "上限为1", Max: 1, Rows: 2, WantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			e := &MySQLExecutor{Config: &DBConfig{MaxAffectedRows: tc.Max}}
+			err := e.checkAffectedRows(tc.Rows)
+			if tc.WantErr != errors.Is(err, ErrAffectedRowsExceeded) {
+				t.Errorf("期望超过上限=%v，实际 %v", tc.WantErr, err)
+			}
+			if !tc.WantErr && err != nil {
+				t.Errorf("期望无错误，实际 %v", err)
+			}
+		})
+	}
+}
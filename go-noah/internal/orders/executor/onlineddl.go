@@ -0,0 +1,415 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-noah/internal/inspect/parser"
+	"go-noah/pkg/global"
+	"go-noah/pkg/secret"
+	"go-noah/pkg/utils"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// 在线DDL引擎
+const (
+	DDLEngineGhost  = "gh-ost" // gh-ost（默认）
+	DDLEnginePtOSC  = "pt-osc" // pt-online-schema-change，支持外键和触发器
+	DDLEngineNative = "native" // 原生 ALTER（按版本使用 ALGORITHM=INSTANT/INPLACE）
+	DDLEngineAuto   = "auto"   // 自动选择：有外键或触发器时使用 pt-osc，否则使用 gh-ost
+)
+
+// IsValidDDLEngine 判断在线DDL引擎是否合法（空表示使用默认值）
+func IsValidDDLEngine(engine string) bool {
+	switch engine {
+	case "", DDLEngineGhost, DDLEnginePtOSC, DDLEngineNative, DDLEngineAuto:
+		return true
+	}
+	return false
+}
+
+// onlineDDLStrategy 在线DDL执行策略
+type onlineDDLStrategy interface {
+	Name() string
+	Execute(ctx context.Context) (ReturnData, error)
+}
+
+type ghostStrategy struct{ e *MySQLExecutor }
+
+func (s ghostStrategy) Name() string { return DDLEngineGhost }
+func (s ghostStrategy) Execute(ctx context.Context) (ReturnData, error) {
+	return s.e.ExecuteDDLWithGhost(ctx)
+}
+
+type ptOSCStrategy struct{ e *MySQLExecutor }
+
+func (s ptOSCStrategy) Name() string { return DDLEnginePtOSC }
+func (s ptOSCStrategy) Execute(ctx context.Context) (ReturnData, error) {
+	return s.e.ExecuteDDLWithPtOSC(ctx)
+}
+
+type nativeAlterStrategy struct{ e *MySQLExecutor }
+
+func (s nativeAlterStrategy) Name() string { return DDLEngineNative }
+func (s nativeAlterStrategy) Execute(ctx context.Context) (ReturnData, error) {
+	return s.e.ExecuteNativeAlter(ctx)
+}
+
+// ExecuteAlterTable 按配置选择在线DDL引擎执行 ALTER TABLE
+func (e *MySQLExecutor) ExecuteAlterTable(ctx context.Context) (ReturnData, error) {
+	strategy, reason, err := e.chooseOnlineDDLStrategy(ctx)
+	if err != nil {
+		return ReturnData{Error: err.Error()}, err
+	}
+
+	note := fmt.Sprintf("[%s] 在线DDL引擎: %s（%s）", time.Now().Format("2006-01-02 15:04:05"), strategy.Name(), reason)
+	if e.Config.OrderID != "" {
+		_ = utils.PublishMessageToChannel(e.Config.OrderID, note, "")
+	}
+	global.Logger.Info("选择在线DDL引擎",
+		zap.String("order_id", e.Config.OrderID),
+		zap.String("task_id", e.Config.TaskID),
+		zap.String("engine", strategy.Name()),
+		zap.String("reason", reason),
+	)
+
+	data, err := strategy.Execute(ctx)
+	data.ExecuteLog = note + "\n" + data.ExecuteLog
+	return data, err
+}
+
+// chooseOnlineDDLStrategy 选择在线DDL引擎（工单指定优先，其次实例配置，默认 gh-ost）
+func (e *MySQLExecutor) chooseOnlineDDLStrategy(ctx context.Context) (onlineDDLStrategy, string, error) {
+	// TiDB 的 DDL 本身即为在线变更，外部工具不适用
+	if e.Config.DBType == "TiDB" {
+		return tidbDDLStrategy{e}, "TiDB 原生在线DDL", nil
+	}
+
+	// gh-ost/pt-osc 由外部进程直接连接数据库，无法使用实例的 SSH 跳板机和 TLS 配置
+	if e.Config.ConnOptions.SSH != nil || e.Config.ConnOptions.TLS != nil {
+		switch e.Config.DDLEngine {
+		case "", DDLEngineAuto:
+			return nativeAlterStrategy{e}, "实例配置了SSH跳板机或TLS，外部工具无法连接", nil
+		case DDLEngineGhost, DDLEnginePtOSC:
+			return nil, "", fmt.Errorf("实例配置了SSH跳板机或TLS，不支持使用 %s 执行", e.Config.DDLEngine)
+		}
+	}
+
+	switch e.Config.DDLEngine {
+	case "", DDLEngineGhost:
+		return ghostStrategy{e}, "默认引擎", nil
+	case DDLEnginePtOSC:
+		return ptOSCStrategy{e}, "工单或实例指定", nil
+	case DDLEngineNative:
+		return nativeAlterStrategy{e}, "工单或实例指定", nil
+	case DDLEngineAuto:
+		databaseName, tableName, _, err := parseAlterStatement(e.Config.SQL, e.Config.Schema)
+		if err != nil {
+			return nil, "", err
+		}
+		db, err := e.Connect()
+		if err != nil {
+			return nil, "", err
+		}
+		defer db.Close()
+
+		var foreignKeys, triggers int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.KEY_COLUMN_USAGE
+			WHERE REFERENCED_TABLE_NAME IS NOT NULL
+			AND ((TABLE_SCHEMA = ? AND TABLE_NAME = ?) OR (REFERENCED_TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME = ?))`,
+			databaseName, tableName, databaseName, tableName).Scan(&foreignKeys); err != nil {
+			return nil, "", fmt.Errorf("检查外键失败: %s", err.Error())
+		}
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM information_schema.TRIGGERS
+			WHERE EVENT_OBJECT_SCHEMA = ? AND EVENT_OBJECT_TABLE = ?`, databaseName, tableName).Scan(&triggers); err != nil {
+			return nil, "", fmt.Errorf("检查触发器失败: %s", err.Error())
+		}
+		if foreignKeys > 0 || triggers > 0 {
+			return ptOSCStrategy{e}, fmt.Sprintf("自动选择，表存在外键 %d 个、触发器 %d 个", foreignKeys, triggers), nil
+		}
+		return ghostStrategy{e}, "自动选择，表无外键和触发器", nil
+	default:
+		return nil, "", fmt.Errorf("不支持的在线DDL引擎: %s", e.Config.DDLEngine)
+	}
+}
+
+// parseAlterStatement 解析 ALTER TABLE 语句，返回库名、表名以及去掉 ALTER TABLE 前缀后的变更子句
+// 变更子句中的反引号会被去除、双引号替换为单引号，便于作为 gh-ost/pt-osc 的 --alter 参数
+func parseAlterStatement(sqltext, defaultSchema string) (string, string, string, error) {
+	fullTableName, err := parser.GetTableNameFromAlterStatement(sqltext)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	// 处理表名：如果包含 schema.table 格式，需要分离
+	var databaseName, tableName string
+	if strings.Contains(fullTableName, ".") {
+		parts := strings.SplitN(fullTableName, ".", 2)
+		databaseName = strings.Trim(parts[0], "`")
+		tableName = strings.Trim(parts[1], "`")
+	} else {
+		databaseName = defaultSchema
+		tableName = strings.Trim(fullTableName, "`")
+	}
+
+	match := alterClauseRegex.FindStringSubmatch(strings.TrimSpace(sqltext))
+	if len(match) < 5 {
+		return "", "", "", errors.New("正则匹配SQL语句失败")
+	}
+
+	alterClause := strings.Join(match[5:], "")
+	alterClause = strings.ReplaceAll(alterClause, "`", "")
+	alterClause = strings.ReplaceAll(alterClause, "\"", "'")
+	return databaseName, tableName, alterClause, nil
+}
+
+var (
+	alterClauseRegex   = regexp.MustCompile(`(?i)^ALTER(\s+)TABLE(\s+)([\S]*)(\s+)(ADD|CHANGE|RENAME|MODIFY|DROP|ENGINE|CONVERT)(\s*)([\S\s]*)`)
+	alterAlgorithmRe   = regexp.MustCompile(`(?i)\bALGORITHM\s*=`)
+	ptOSCProgressRegex = regexp.MustCompile(`Copying .*?:\s+(\d+)%\s+(\S+)\s+remain`)
+	mysqlVersionRegex  = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+)
+
+// ExecuteNativeAlter 使用原生 ALTER TABLE 执行，按服务器版本依次尝试 ALGORITHM=INSTANT、INPLACE
+// 服务器不支持指定算法时回退到下一个算法；全部不支持时报错，避免隐式走 COPY 锁表
+func (e *MySQLExecutor) ExecuteNativeAlter(ctx context.Context) (ReturnData, error) {
+	algorithms := []string{""}
+	if !alterAlgorithmRe.MatchString(e.Config.SQL) && e.Config.DBType != "TiDB" {
+		db, err := e.Connect()
+		if err != nil {
+			return ReturnData{Error: err.Error()}, err
+		}
+		var version string
+		err = db.QueryRowContext(ctx, "SELECT VERSION()").Scan(&version)
+		db.Close()
+		if err != nil {
+			return ReturnData{Error: err.Error()}, err
+		}
+		algorithms = nativeAlterAlgorithms(version)
+	}
+
+	var logs []string
+	for i, algorithm := range algorithms {
+		config := *e.Config
+		config.SQL = withAlterAlgorithm(e.Config.SQL, algorithm)
+		data, err := (&MySQLExecutor{Config: &config}).ExecuteOnlineDDL(ctx)
+		logs = append(logs, data.ExecuteLog)
+		if err != nil && isAlterAlgorithmNotSupported(err) && i < len(algorithms)-1 {
+			logs = append(logs, fmt.Sprintf("[%s] 当前变更不支持 ALGORITHM=%s，尝试 ALGORITHM=%s",
+				time.Now().Format("2006-01-02 15:04:05"), algorithm, algorithms[i+1]))
+			continue
+		}
+		if err != nil && isAlterAlgorithmNotSupported(err) {
+			err = fmt.Errorf("当前变更不支持在线算法，请使用 gh-ost 或 pt-osc 执行: %w", err)
+			data.Error = err.Error()
+		}
+		data.ExecuteLog = strings.Join(logs, "\n")
+		return data, err
+	}
+	return ReturnData{}, nil
+}
+
+// nativeAlterAlgorithms 根据服务器版本返回依次尝试的 ALTER 算法
+func nativeAlterAlgorithms(version string) []string {
+	match := mysqlVersionRegex.FindStringSubmatch(version)
+	if len(match) < 4 || strings.Contains(version, "TiDB") {
+		return []string{""}
+	}
+	major, _ := strconv.Atoi(match[1])
+	minor, _ := strconv.Atoi(match[2])
+	patch, _ := strconv.Atoi(match[3])
+	v := major*10000 + minor*100 + patch
+
+	if strings.Contains(strings.ToLower(version), "mariadb") {
+		switch {
+		case v >= 100302:
+			return []string{"INSTANT", "INPLACE"}
+		case v >= 100000:
+			return []string{"INPLACE"}
+		}
+		return []string{""}
+	}
+	switch {
+	case v >= 80012:
+		return []string{"INSTANT", "INPLACE"}
+	case v >= 50600:
+		return []string{"INPLACE"}
+	}
+	return []string{""}
+}
+
+// withAlterAlgorithm 为 ALTER 语句追加 ALGORITHM 子句（INPLACE 同时要求 LOCK=NONE）
+func withAlterAlgorithm(sqltext, algorithm string) string {
+	if algorithm == "" {
+		return sqltext
+	}
+	sqltext = strings.TrimRight(strings.TrimSpace(sqltext), ";")
+	if algorithm == "INPLACE" {
+		return sqltext + ", ALGORITHM=INPLACE, LOCK=NONE"
+	}
+	return sqltext + ", ALGORITHM=" + algorithm
+}
+
+// isAlterAlgorithmNotSupported 判断是否为不支持指定 ALTER 算法/锁级别的错误
+func isAlterAlgorithmNotSupported(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return false
+	}
+	// 1845: ER_ALTER_OPERATION_NOT_SUPPORTED, 1846: ER_ALTER_OPERATION_NOT_SUPPORTED_REASON
+	return mysqlErr.Number == 1845 || mysqlErr.Number == 1846
+}
+
+// ExecuteDDLWithPtOSC 使用 pt-online-schema-change 执行 ALTER TABLE 语句
+func (e *MySQLExecutor) ExecuteDDLWithPtOSC(ctx context.Context) (ReturnData, error) {
+	var data ReturnData
+	var executeLog []string
+
+	// 日志复用 "ghost" 消息类型，前端在同一面板展示
+	logMessage := func(msg string) {
+		timestamp := time.Now().Format("2006-01-02 15:04:05")
+		logMsg := fmt.Sprintf("[%s] %s\n", timestamp, msg)
+		executeLog = append(executeLog, logMsg)
+		if e.Config.OrderID != "" {
+			if err := utils.PublishMessageToChannel(e.Config.OrderID, logMsg, "ghost"); err != nil {
+				global.Logger.Error("Failed to publish pt-osc message to Redis", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+			}
+		}
+	}
+
+	logErrorAndReturn := func(err error, errMsg string) (ReturnData, error) {
+		logMessage(errMsg + err.Error())
+		data.ExecuteLog = strings.Join(executeLog, "")
+		data.Error = err.Error()
+		return data, err
+	}
+
+	if global.Conf == nil {
+		return logErrorAndReturn(errors.New("配置未初始化"), "pt-osc 配置未初始化，错误：")
+	}
+	ptoscPath := global.Conf.GetString("ptosc.path")
+	if ptoscPath == "" {
+		return logErrorAndReturn(errors.New("pt-online-schema-change 路径未配置"), "pt-osc 路径未配置，错误：")
+	}
+	ptoscArgs := global.Conf.GetStringSlice("ptosc.args")
+
+	databaseName, tableName, alterClause, err := parseAlterStatement(e.Config.SQL, e.Config.Schema)
+	if err != nil {
+		return logErrorAndReturn(err, "解析ALTER语句失败，错误：")
+	}
+	logMessage(fmt.Sprintf("解析ALTER语句成功，表: %s.%s", databaseName, tableName))
+
+	password, err := secret.Decrypt(e.Config.Password)
+	if err != nil {
+		return logErrorAndReturn(err, "解密实例密码失败，错误：")
+	}
+	cmdParts := []string{shellQuote(ptoscPath)}
+	for _, arg := range ptoscArgs {
+		cmdParts = append(cmdParts, shellQuote(arg))
+	}
+	cmdParts = append(cmdParts,
+		"--alter", shellQuote(alterClause),
+		"--user", shellQuote(e.Config.UserName),
+		"--password", shellQuote(password),
+	)
+
+	// 实例限流配置
+	if e.Config.ThrottleMaxReplicaLag > 0 {
+		cmdParts = append(cmdParts, "--max-lag", strconv.Itoa(e.Config.ThrottleMaxReplicaLag))
+	}
+	if e.Config.ThrottleMaxThreadsRunning > 0 {
+		cmdParts = append(cmdParts, "--max-load", shellQuote(fmt.Sprintf("Threads_running=%d", e.Config.ThrottleMaxThreadsRunning)))
+	}
+	if strings.TrimSpace(e.Config.ThrottleQuery) != "" {
+		logMessage("pt-osc 不支持自定义限流查询，已忽略实例的限流查询配置")
+	}
+
+	if !e.Config.GhostOkToDropTable {
+		cmdParts = append(cmdParts, "--no-drop-old-table")
+		logMessage("已添加 --no-drop-old-table 参数：操作成功后保留旧表")
+	}
+
+	dsn := fmt.Sprintf("h=%s,P=%d,D=%s,t=%s", e.Config.Hostname, e.Config.Port, databaseName, tableName)
+	cmdParts = append(cmdParts, shellQuote(dsn), "--execute")
+	ptoscCMD := strings.Join(cmdParts, " ")
+
+	// 打印命令（掩码 password）
+	printCMD := strings.Replace(ptoscCMD, "--password "+shellQuote(password), "--password '...'", 1)
+	logMessage(fmt.Sprintf("执行pt-osc命令：%s", printCMD))
+
+	startTime := time.Now()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	ch := make(chan string, 100)
+	done := make(chan error, 1)
+	go func() {
+		done <- utils.Command(ctx, ch, ptoscCMD)
+		close(ch)
+	}()
+	for msg := range ch {
+		if trimmed := strings.TrimRight(msg, "\n"); trimmed != "" {
+			logMessage(trimmed)
+			e.parseAndPublishPtOSCProgress(trimmed)
+		}
+	}
+
+	if err := <-done; err != nil {
+		if ctx.Err() != nil {
+			logMessage("pt-osc 进程已被终止，请检查并清理残留的触发器（pt_osc_*）和 _new 表")
+		}
+		return logErrorAndReturn(err, "执行失败，错误：")
+	}
+
+	logMessage("pt-osc命令执行成功")
+	data.ExecuteLog = strings.Join(executeLog, "")
+	data.ExecuteCostTime = time.Since(startTime).String()
+	return data, nil
+}
+
+// parseAndPublishPtOSCProgress 解析 pt-osc 进度输出（如 "Copying `db`.`t`:  45% 00:30 remain"），
+// 以 ghost-progress 消息格式推送
+func (e *MySQLExecutor) parseAndPublishPtOSCProgress(line string) {
+	if e.Config.OrderID == "" {
+		return
+	}
+
+	var progressData map[string]interface{}
+	if matches := ptOSCProgressRegex.FindStringSubmatch(line); len(matches) > 2 {
+		percent, err := strconv.ParseFloat(matches[1], 64)
+		if err != nil {
+			return
+		}
+		progressData = map[string]interface{}{
+			"percent":   percent,
+			"eta":       matches[2],
+			"operation": "pt-osc",
+		}
+	} else if strings.Contains(line, "Copied rows OK") {
+		progressData = map[string]interface{}{
+			"percent":   100.0,
+			"eta":       "0s",
+			"operation": "pt-osc",
+		}
+	} else {
+		return
+	}
+
+	if err := utils.PublishMessageToChannel(e.Config.OrderID, progressData, "ghost-progress"); err != nil {
+		global.Logger.Error("Failed to publish pt-osc progress", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+	if err := utils.SaveGhostProgressToRedis(e.Config.OrderID, progressData); err != nil {
+		global.Logger.Warn("Failed to save pt-osc progress to Redis cache", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+}
+
+// shellQuote 使用单引号转义 shell 参数
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
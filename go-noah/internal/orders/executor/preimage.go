@@ -0,0 +1,313 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"go-noah/internal/inspect/parser"
+	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/global"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// 回滚SQL生成方式
+const (
+	BackupStrategyBinlog   = "binlog"    // 解析 ROW 格式 binlog
+	BackupStrategyPreImage = "pre_image" // 执行前查询受影响的行（前镜像）
+	BackupStrategyNone     = "none"      // 未生成回滚SQL
+)
+
+// defaultPreImageMaxRows 前镜像备份最多备份的行数，超过时不生成回滚SQL
+const defaultPreImageMaxRows = 100000
+
+// errPreImageTooLarge 受影响行数超过前镜像备份上限
+var errPreImageTooLarge = errors.New("受影响行数超过前镜像备份上限")
+
+// dmlTarget 单表 UPDATE/DELETE 的目标表及条件
+type dmlTarget struct {
+	Schema   string
+	Table    string
+	TableRef string // 表引用（含别名）
+	Qualify  string // 列限定名（别名或表名）
+	Where    string // WHERE 条件（可能为空）
+	Order    string // ORDER BY（可能为空）
+	Limit    string // LIMIT（可能为空）
+	IsUpdate bool
+	SetCols  []string // UPDATE 修改的列
+}
+
+// buildDMLTarget 解析单表 UPDATE/DELETE，其他语句返回 nil
+func buildDMLTarget(sqltext, defaultSchema string) (*dmlTarget, error) {
+	audit, _, err := parser.ParseSQL(sqltext)
+	if err != nil {
+		return nil, fmt.Errorf("SQL解析错误: %s", err.Error())
+	}
+	if len(audit.TiStmt) != 1 {
+		return nil, fmt.Errorf("仅支持单条语句")
+	}
+
+	var (
+		refs   *ast.TableRefsClause
+		where  ast.ExprNode
+		order  *ast.OrderByClause
+		limit  *ast.Limit
+		target = &dmlTarget{}
+	)
+	switch stmt := audit.TiStmt[0].(type) {
+	case *ast.UpdateStmt:
+		if stmt.MultipleTable || stmt.With != nil {
+			return nil, fmt.Errorf("不支持多表或CTE语句")
+		}
+		refs, where, order, limit = stmt.TableRefs, stmt.Where, stmt.Order, stmt.Limit
+		target.IsUpdate = true
+		for _, assign := range stmt.List {
+			target.SetCols = append(target.SetCols, assign.Column.Name.L)
+		}
+	case *ast.DeleteStmt:
+		if stmt.IsMultiTable || stmt.With != nil {
+			return nil, fmt.Errorf("不支持多表或CTE语句")
+		}
+		refs, where, order, limit = stmt.TableRefs, stmt.Where, stmt.Order, stmt.Limit
+	default:
+		return nil, nil
+	}
+
+	if refs == nil || refs.TableRefs == nil || refs.TableRefs.Right != nil {
+		return nil, fmt.Errorf("不支持多表语句")
+	}
+	source, ok := refs.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil, fmt.Errorf("不支持多表语句")
+	}
+	table, ok := source.Source.(*ast.TableName)
+	if !ok {
+		return nil, fmt.Errorf("不支持多表语句")
+	}
+
+	target.Schema = table.Schema.O
+	if target.Schema == "" {
+		target.Schema = defaultSchema
+	}
+	target.Table = table.Name.O
+	target.Qualify = table.Name.O
+	if source.AsName.O != "" {
+		target.Qualify = source.AsName.O
+	}
+	if target.TableRef, err = restoreNode(source); err != nil {
+		return nil, err
+	}
+	if where != nil {
+		if target.Where, err = restoreNode(where); err != nil {
+			return nil, err
+		}
+	}
+	if order != nil {
+		if target.Order, err = restoreNode(order); err != nil {
+			return nil, err
+		}
+	}
+	if limit != nil {
+		if target.Limit, err = restoreNode(limit); err != nil {
+			return nil, err
+		}
+	}
+	return target, nil
+}
+
+// checkBinlogUsable 检查是否可以通过解析 binlog 生成回滚SQL（要求 ROW 格式且 binlog_row_image=FULL）
+func checkBinlogUsable(ctx context.Context, db *dbpool.Conn, dbType string) error {
+	if dbType == "TiDB" {
+		return fmt.Errorf("TiDB 不支持解析 binlog 生成回滚SQL")
+	}
+	var format, rowImage string
+	if err := db.QueryRowContext(ctx, "SELECT @@session.binlog_format, @@session.binlog_row_image").Scan(&format, &rowImage); err != nil {
+		return fmt.Errorf("获取binlog格式失败: %s", err.Error())
+	}
+	if !strings.EqualFold(format, "ROW") {
+		return fmt.Errorf("binlog_format=%s，不是 ROW 格式", format)
+	}
+	if !strings.EqualFold(rowImage, "FULL") {
+		return fmt.Errorf("binlog_row_image=%s，不是 FULL", rowImage)
+	}
+	return nil
+}
+
+// preImageBackup 前镜像备份：在执行 UPDATE/DELETE 的事务中先查询（并锁定）受影响的行，据此生成回滚SQL
+type preImageBackup struct {
+	target  *dmlTarget
+	columns []string // 非生成列
+	pk      []string // 主键列
+	maxRows int
+}
+
+// newPreImageBackup 创建前镜像备份，需要在开启事务前调用
+func newPreImageBackup(ctx context.Context, db *dbpool.Conn, sqltext, defaultSchema string) (*preImageBackup, error) {
+	target, err := buildDMLTarget(sqltext, defaultSchema)
+	if err != nil {
+		return nil, err
+	}
+	if target == nil {
+		return nil, fmt.Errorf("仅支持 UPDATE/DELETE 语句")
+	}
+
+	rows, err := db.QueryContext(ctx, `SELECT COLUMN_NAME, COLUMN_KEY, EXTRA FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION`, target.Schema, target.Table)
+	if err != nil {
+		return nil, fmt.Errorf("获取表结构失败: %s", err.Error())
+	}
+	defer rows.Close()
+
+	backup := &preImageBackup{target: target, maxRows: defaultPreImageMaxRows}
+	for rows.Next() {
+		var column, key, extra string
+		if err := rows.Scan(&column, &key, &extra); err != nil {
+			return nil, err
+		}
+		if strings.Contains(strings.ToUpper(extra), "GENERATED") {
+			continue
+		}
+		backup.columns = append(backup.columns, column)
+		if key == "PRI" {
+			backup.pk = append(backup.pk, column)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(backup.columns) == 0 {
+		return nil, fmt.Errorf("表 %s.%s 不存在", target.Schema, target.Table)
+	}
+	if target.IsUpdate {
+		if len(backup.pk) == 0 {
+			return nil, fmt.Errorf("表 %s.%s 没有主键", target.Schema, target.Table)
+		}
+		for _, col := range target.SetCols {
+			for _, pk := range backup.pk {
+				if strings.EqualFold(col, pk) {
+					return nil, fmt.Errorf("语句修改了主键列 %s", pk)
+				}
+			}
+		}
+	}
+	if global.Conf != nil {
+		if v := global.Conf.GetInt("pre_image.max_rows"); v > 0 {
+			backup.maxRows = v
+		}
+	}
+	return backup, nil
+}
+
+// Capture 在事务中锁定并查询受影响的行，生成回滚SQL
+// where 为空时使用语句本身的条件（分批执行时传入批次条件）
+func (p *preImageBackup) Capture(ctx context.Context, tx *sql.Tx, where string, args ...interface{}) (string, int, error) {
+	t := p.target
+	columns := make([]string, len(p.columns))
+	for i, col := range p.columns {
+		columns[i] = quoteIdentifier(t.Qualify) + "." + quoteIdentifier(col)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s", strings.Join(columns, ", "), t.TableRef)
+	if where == "" {
+		where = t.Where
+	}
+	if where != "" {
+		query += " WHERE " + where
+	}
+	if t.Order != "" {
+		query += " " + t.Order
+	}
+	if t.Limit != "" {
+		query += " " + t.Limit
+	} else {
+		query += fmt.Sprintf(" LIMIT %d", p.maxRows+1)
+	}
+	query += " FOR UPDATE"
+
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+
+	var (
+		sb    strings.Builder
+		count int
+	)
+	for rows.Next() {
+		count++
+		if count > p.maxRows {
+			return "", count, fmt.Errorf("%w %d", errPreImageTooLarge, p.maxRows)
+		}
+		values := make([]sql.RawBytes, len(p.columns))
+		dest := make([]interface{}, len(p.columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return "", count, err
+		}
+		row := make(map[string]driver.Value, len(p.columns))
+		for i, value := range values {
+			if value != nil {
+				row[p.columns[i]] = append([]byte{}, value...)
+			} else {
+				row[p.columns[i]] = nil
+			}
+		}
+		stmt, err := p.rollbackStatement(row)
+		if err != nil {
+			return "", count, err
+		}
+		sb.WriteString(stmt)
+		sb.WriteString(";\n")
+	}
+	if err := rows.Err(); err != nil {
+		return "", count, err
+	}
+	return sb.String(), count, nil
+}
+
+// rollbackStatement 根据单行前镜像生成回滚语句：DELETE 回滚为 INSERT，UPDATE 回滚为按主键恢复所有列
+func (p *preImageBackup) rollbackStatement(row map[string]driver.Value) (string, error) {
+	t := p.target
+	table := quoteIdentifier(t.Schema) + "." + quoteIdentifier(t.Table)
+	var (
+		query string
+		args  []driver.Value
+	)
+	if !t.IsUpdate {
+		columns := make([]string, len(p.columns))
+		for i, col := range p.columns {
+			columns[i] = quoteIdentifier(col)
+			args = append(args, row[col])
+		}
+		query = fmt.Sprintf("INSERT INTO %s(%s) VALUES(%s)", table, strings.Join(columns, ","),
+			strings.TrimSuffix(strings.Repeat("?,", len(columns)), ","))
+	} else {
+		var sets, conds []string
+		isPK := make(map[string]bool, len(p.pk))
+		for _, pk := range p.pk {
+			isPK[pk] = true
+		}
+		for _, col := range p.columns {
+			if isPK[col] {
+				continue
+			}
+			sets = append(sets, quoteIdentifier(col)+"=?")
+			args = append(args, row[col])
+		}
+		if len(sets) == 0 {
+			return "", fmt.Errorf("表 %s 只有主键列", table)
+		}
+		for _, pk := range p.pk {
+			conds = append(conds, quoteIdentifier(pk)+"=?")
+			args = append(args, row[pk])
+		}
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", table, strings.Join(sets, ","), strings.Join(conds, " AND "))
+	}
+	return mysqlpkg.InterpolateParams(query, args)
+}
@@ -0,0 +1,412 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"go-noah/internal/inspect/dao"
+	"go-noah/internal/inspect/parser"
+	"go-noah/pkg/kv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// errNoSuchTable MySQL 错误码 ER_NO_SUCH_TABLE
+const errNoSuchTable = 1146
+
+// ddlTable DDL涉及的表及其执行前的表结构
+type ddlTable struct {
+	schema string
+	table  string
+	before *ast.CreateTableStmt // 执行前的表结构，表不存在时为 nil
+	query  string               // 执行前的 SHOW CREATE TABLE 原文
+	err    error                // 获取执行前的表结构失败（表不存在除外），此时不生成反向DDL
+}
+
+// ddlSnapshot 记录DDL执行前的表结构，执行成功后与执行后的表结构对比生成反向DDL
+type ddlSnapshot struct {
+	stmt   ast.StmtNode
+	tables []*ddlTable
+}
+
+// captureDDLSnapshot 解析DDL并获取执行前的表结构，不支持生成反向DDL的语句返回 nil
+func (e *MySQLExecutor) captureDDLSnapshot() *ddlSnapshot {
+	audit, _, err := parser.ParseSQL(e.Config.SQL)
+	if err != nil || len(audit.TiStmt) != 1 {
+		return nil
+	}
+	snapshot := &ddlSnapshot{stmt: audit.TiStmt[0]}
+
+	var names []*ast.TableName
+	switch stmt := snapshot.stmt.(type) {
+	case *ast.CreateTableStmt:
+		names = append(names, stmt.Table)
+	case *ast.AlterTableStmt:
+		names = append(names, stmt.Table)
+	case *ast.DropIndexStmt:
+		names = append(names, stmt.Table)
+	case *ast.DropTableStmt:
+		if stmt.IsView {
+			return nil
+		}
+		names = append(names, stmt.Tables...)
+	case *ast.CreateViewStmt:
+		names = append(names, stmt.ViewName)
+	default:
+		return nil
+	}
+
+	for _, name := range names {
+		t := &ddlTable{schema: e.Config.Schema, table: name.Name.O}
+		if name.Schema.O != "" {
+			t.schema = name.Schema.O
+		}
+		before, query, err := e.showCreateTable(t.schema, t.table)
+		t.setBefore(before, query, err)
+		snapshot.tables = append(snapshot.tables, t)
+	}
+	return snapshot
+}
+
+// setBefore 记录执行前的表结构，只有表不存在（1146）才视为执行前不存在
+// 权限不足、连接断开、解析失败等其他错误无法判断表是否存在，记录错误后不生成反向DDL
+func (t *ddlTable) setBefore(before *ast.CreateTableStmt, query string, err error) {
+	var mysqlErr *mysql.MySQLError
+	switch {
+	case err == nil:
+		t.before, t.query = before, query
+	case errors.As(err, &mysqlErr) && mysqlErr.Number == errNoSuchTable:
+	default:
+		t.err = err
+	}
+}
+
+// showCreateTable 获取表结构（复用审核模块的 dao.ShowCreateTable），表不存在或解析失败时返回错误
+func (e *MySQLExecutor) showCreateTable(schema, table string) (*ast.CreateTableStmt, string, error) {
+	db := &dao.DB{
+		InstanceID:  e.Config.InstanceID,
+		User:        e.Config.UserName,
+		Password:    e.Config.Password,
+		Host:        e.Config.Hostname,
+		Port:        e.Config.Port,
+		Database:    schema,
+		ConnOptions: e.Config.ConnOptions,
+	}
+	data, err := dao.ShowCreateTable(table, db, kv.NewKVCache(e.Config.TaskID))
+	if err != nil {
+		return nil, "", err
+	}
+	audit, ok := data.(*parser.Audit)
+	if !ok || len(audit.TiStmt) == 0 {
+		return nil, "", fmt.Errorf("表`%s`结构解析失败", table)
+	}
+	// 视图返回 CreateViewStmt，此处只关心是否存在
+	createTable, _ := audit.TiStmt[0].(*ast.CreateTableStmt)
+	return createTable, audit.Query, nil
+}
+
+// generateReverseDDL 对比执行前后的表结构生成反向DDL
+// 返回 (回滚SQL, 说明信息)，无法生成时回滚SQL为空
+func (e *MySQLExecutor) generateReverseDDL(snapshot *ddlSnapshot) (string, []string, error) {
+	var rollbacks, notes []string
+	for _, t := range snapshot.tables {
+		if t.err != nil {
+			notes = append(notes, fmt.Sprintf("获取表`%s`执行前的表结构失败，不生成反向DDL: %s", t.table, t.err.Error()))
+		}
+	}
+
+	switch stmt := snapshot.stmt.(type) {
+	case *ast.CreateTableStmt:
+		t := snapshot.tables[0]
+		if t.err != nil {
+			break
+		}
+		if t.query != "" {
+			notes = append(notes, fmt.Sprintf("表`%s`执行前已存在，不生成反向DDL", t.table))
+			break
+		}
+		rollbacks = append(rollbacks, fmt.Sprintf("DROP TABLE %s;", qualifiedTable(t.schema, t.table)))
+
+	case *ast.CreateViewStmt:
+		t := snapshot.tables[0]
+		if t.err != nil {
+			break
+		}
+		if t.query != "" {
+			notes = append(notes, fmt.Sprintf("视图`%s`执行前已存在，不生成反向DDL", t.table))
+			break
+		}
+		rollbacks = append(rollbacks, fmt.Sprintf("DROP VIEW %s;", qualifiedTable(t.schema, t.table)))
+
+	case *ast.DropTableStmt:
+		// 只能恢复表结构，数据需要通过备份恢复
+		for _, t := range snapshot.tables {
+			if t.before == nil {
+				continue
+			}
+			rollbacks = append(rollbacks, fmt.Sprintf("-- 表`%s`仅恢复表结构，数据无法通过回滚SQL恢复\n%s;", t.table, t.query))
+		}
+
+	case *ast.DropIndexStmt, *ast.AlterTableStmt:
+		t := snapshot.tables[0]
+		if t.err != nil {
+			break
+		}
+		if t.before == nil {
+			return "", nil, fmt.Errorf("未获取到表`%s`执行前的表结构", t.table)
+		}
+		// ALTER TABLE ... RENAME 后使用新表名获取执行后的表结构
+		afterSchema, afterTable := t.schema, t.table
+		renames := make(map[string]string)
+		if alter, ok := stmt.(*ast.AlterTableStmt); ok {
+			for _, spec := range alter.Specs {
+				switch spec.Tp {
+				case ast.AlterTableRenameTable:
+					afterTable = spec.NewTable.Name.O
+					if spec.NewTable.Schema.O != "" {
+						afterSchema = spec.NewTable.Schema.O
+					}
+				case ast.AlterTableChangeColumn:
+					if len(spec.NewColumns) > 0 && spec.OldColumnName != nil {
+						renames[spec.NewColumns[0].Name.Name.L] = spec.OldColumnName.Name.L
+					}
+				case ast.AlterTableRenameColumn:
+					renames[spec.NewColumnName.Name.L] = spec.OldColumnName.Name.L
+				}
+			}
+		}
+		after, _, err := e.showCreateTable(afterSchema, afterTable)
+		if err != nil {
+			return "", nil, fmt.Errorf("获取表`%s`执行后的表结构失败: %s", afterTable, err.Error())
+		}
+
+		clauses, diffNotes, err := diffCreateTable(t.before, after, renames)
+		if err != nil {
+			return "", nil, err
+		}
+		notes = append(notes, diffNotes...)
+		if afterSchema != t.schema || afterTable != t.table {
+			clauses = append(clauses, fmt.Sprintf("RENAME TO %s", qualifiedTable(t.schema, t.table)))
+		}
+		if len(clauses) == 0 {
+			notes = append(notes, "表结构无变化，不生成反向DDL")
+			break
+		}
+		rollbacks = append(rollbacks, fmt.Sprintf("ALTER TABLE %s %s;", qualifiedTable(afterSchema, afterTable), strings.Join(clauses, ", ")))
+	}
+
+	return strings.Join(rollbacks, "\n"), notes, nil
+}
+
+// diffCreateTable 对比执行前后的表结构，生成将 after 恢复为 before 的 ALTER 子句
+// renames 为执行时重命名的列（新列名 -> 原列名，小写）
+func diffCreateTable(before, after *ast.CreateTableStmt, renames map[string]string) ([]string, []string, error) {
+	var drops, columns, adds, options, notes []string
+
+	// 索引与约束：先删除新增或变更的，最后重新添加原有的
+	beforeCons, err := constraintDefinitions(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterCons, err := constraintDefinitions(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, c := range after.Constraints {
+		key := constraintKey(c)
+		if def, ok := beforeCons[key]; !ok || def != afterCons[key] {
+			drops = append(drops, dropConstraintClause(c))
+		}
+	}
+	for _, c := range before.Constraints {
+		key := constraintKey(c)
+		if def, ok := afterCons[key]; !ok || def != beforeCons[key] {
+			adds = append(adds, "ADD "+beforeCons[key])
+		}
+	}
+
+	// 列
+	beforeCols, beforePrev, err := columnDefinitions(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterCols, afterPrev, err := columnDefinitions(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 执行后仍存在的原列（含被重命名的列），其余原列需要重新添加
+	kept := make(map[string]bool)
+	for _, col := range after.Cols {
+		name := col.Name.Name.L
+		if oldName, ok := renames[name]; ok && oldName != name {
+			if _, ok := beforeCols[oldName]; ok {
+				kept[oldName] = true
+				continue
+			}
+		}
+		if _, ok := beforeCols[name]; ok {
+			kept[name] = true
+		}
+	}
+	// CHANGE/MODIFY 的位置不能引用同一语句中重新添加的列，此时不调整位置
+	position := func(prev string) string {
+		if prev != "" && !kept[strings.ToLower(prev)] {
+			return ""
+		}
+		return columnPosition(prev)
+	}
+
+	for _, col := range after.Cols {
+		name := col.Name.Name.L
+		if oldName, ok := renames[name]; ok && oldName != name && kept[oldName] {
+			columns = append(columns, fmt.Sprintf("CHANGE COLUMN %s %s%s", quoteIdentifier(col.Name.Name.O), beforeCols[oldName], position(beforePrev[oldName])))
+			continue
+		}
+		if !kept[name] {
+			columns = append(columns, fmt.Sprintf("DROP COLUMN %s", quoteIdentifier(col.Name.Name.O)))
+			continue
+		}
+		// 前一列被重命名时按原列名比较位置
+		prev := strings.ToLower(afterPrev[name])
+		if oldName, ok := renames[prev]; ok && kept[oldName] {
+			prev = oldName
+		}
+		if beforeCols[name] != afterCols[name] || !strings.EqualFold(beforePrev[name], prev) {
+			columns = append(columns, fmt.Sprintf("MODIFY COLUMN %s%s", beforeCols[name], position(beforePrev[name])))
+		}
+	}
+	// 重新添加的列按原顺序放在最后，位置可以引用已恢复的列名
+	for _, col := range before.Cols {
+		name := col.Name.Name.L
+		if kept[name] {
+			continue
+		}
+		columns = append(columns, fmt.Sprintf("ADD COLUMN %s%s", beforeCols[name], columnPosition(beforePrev[name])))
+		notes = append(notes, fmt.Sprintf("列`%s`被删除，反向DDL只能恢复列定义，数据无法恢复", col.Name.Name.O))
+	}
+
+	// 表选项（忽略自增值等运行时选项）
+	beforeOpts, err := tableOptionDefinitions(before)
+	if err != nil {
+		return nil, nil, err
+	}
+	afterOpts, err := tableOptionDefinitions(after)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, opt := range before.Options {
+		if def, ok := beforeOpts[opt.Tp]; ok && def != afterOpts[opt.Tp] {
+			options = append(options, def)
+		}
+	}
+	if _, ok := beforeOpts[ast.TableOptionComment]; !ok {
+		if _, ok := afterOpts[ast.TableOptionComment]; ok {
+			options = append(options, "COMMENT = ''")
+		}
+	}
+
+	// 分区变更无法可靠地反向生成，仅提示
+	if partitionDefinition(before) != partitionDefinition(after) {
+		notes = append(notes, "分区定义发生变化，反向DDL不包含分区变更，请手动处理")
+	}
+
+	clauses := append(drops, columns...)
+	clauses = append(clauses, adds...)
+	clauses = append(clauses, options...)
+	return clauses, notes, nil
+}
+
+// columnDefinitions 返回 列名(小写) -> 列定义 以及 列名 -> 前一列列名（第一列为空）
+func columnDefinitions(stmt *ast.CreateTableStmt) (map[string]string, map[string]string, error) {
+	defs := make(map[string]string, len(stmt.Cols))
+	prev := make(map[string]string, len(stmt.Cols))
+	last := ""
+	for _, col := range stmt.Cols {
+		def, err := restoreNode(col)
+		if err != nil {
+			return nil, nil, err
+		}
+		defs[col.Name.Name.L] = def
+		prev[col.Name.Name.L] = last
+		last = col.Name.Name.O
+	}
+	return defs, prev, nil
+}
+
+// columnPosition 生成列位置子句
+func columnPosition(prev string) string {
+	if prev == "" {
+		return " FIRST"
+	}
+	return " AFTER " + quoteIdentifier(prev)
+}
+
+// constraintKey 索引与约束的唯一标识
+func constraintKey(c *ast.Constraint) string {
+	if c.Tp == ast.ConstraintPrimaryKey {
+		return "primary"
+	}
+	return fmt.Sprintf("%d:%s", c.Tp, strings.ToLower(c.Name))
+}
+
+// constraintDefinitions 返回 约束标识 -> 约束定义
+func constraintDefinitions(stmt *ast.CreateTableStmt) (map[string]string, error) {
+	defs := make(map[string]string, len(stmt.Constraints))
+	for _, c := range stmt.Constraints {
+		def, err := restoreNode(c)
+		if err != nil {
+			return nil, err
+		}
+		defs[constraintKey(c)] = def
+	}
+	return defs, nil
+}
+
+// dropConstraintClause 生成删除索引或约束的子句
+func dropConstraintClause(c *ast.Constraint) string {
+	switch c.Tp {
+	case ast.ConstraintPrimaryKey:
+		return "DROP PRIMARY KEY"
+	case ast.ConstraintForeignKey:
+		return fmt.Sprintf("DROP FOREIGN KEY %s", quoteIdentifier(c.Name))
+	case ast.ConstraintCheck:
+		return fmt.Sprintf("DROP CHECK %s", quoteIdentifier(c.Name))
+	default:
+		return fmt.Sprintf("DROP INDEX %s", quoteIdentifier(c.Name))
+	}
+}
+
+// tableOptionDefinitions 返回 表选项类型 -> 选项定义（忽略自增值等运行时选项）
+func tableOptionDefinitions(stmt *ast.CreateTableStmt) (map[ast.TableOptionType]string, error) {
+	defs := make(map[ast.TableOptionType]string, len(stmt.Options))
+	for _, opt := range stmt.Options {
+		switch opt.Tp {
+		case ast.TableOptionAutoIncrement, ast.TableOptionAutoIdCache, ast.TableOptionAutoRandomBase:
+			continue
+		}
+		def, err := restoreNode(opt)
+		if err != nil {
+			return nil, err
+		}
+		defs[opt.Tp] = def
+	}
+	return defs, nil
+}
+
+// partitionDefinition 返回分区定义，未分区时为空
+func partitionDefinition(stmt *ast.CreateTableStmt) string {
+	if stmt.Partition == nil {
+		return ""
+	}
+	def, _ := restoreNode(stmt.Partition)
+	return def
+}
+
+// qualifiedTable 生成 `schema`.`table` 形式的表名
+func qualifiedTable(schema, table string) string {
+	if schema == "" {
+		return quoteIdentifier(table)
+	}
+	return quoteIdentifier(schema) + "." + quoteIdentifier(table)
+}
@@ -0,0 +1,163 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"go-noah/internal/inspect/parser"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// parseCreateTable 解析 CREATE TABLE 语句
+func parseCreateTable(t *testing.T, sqltext string) *ast.CreateTableStmt {
+	t.Helper()
+	audit, _, err := parser.ParseSQL(sqltext)
+	if err != nil {
+		t.Fatalf("SQL解析错误: %v", err)
+	}
+	stmt, ok := audit.TiStmt[0].(*ast.CreateTableStmt)
+	if !ok {
+		t.Fatalf("不是 CREATE TABLE 语句: %s", sqltext)
+	}
+	return stmt
+}
+
+func TestDiffCreateTable(t *testing.T) {
+	const base = "CREATE TABLE t (id INT NOT NULL, name VARCHAR(32) NOT NULL, age INT, PRIMARY KEY (id), KEY idx_name (name)) ENGINE=InnoDB COMMENT='用户'"
+	testCases := []struct {
+		Name        string
+		Before      string
+		After       string
+		Renames     map[string]string
+		Expect      []string
+		ExpectNotes []string // 期望说明信息包含的内容
+	}{
+		{
+			Name:   "表结构无变化",
+			Before: base,
+			After:  base,
+			Expect: nil,
+		},
+		{
+			Name:   "新增列",
+			Before: base,
+			After:  "CREATE TABLE t (id INT NOT NULL, name VARCHAR(32) NOT NULL, age INT, email VARCHAR(64), PRIMARY KEY (id), KEY idx_name (name)) ENGINE=InnoDB COMMENT='用户'",
+			Expect: []string{"DROP COLUMN `email`"},
+		},
+		{
+			Name:        "删除列",
+			Before:      base,
+			After:       "CREATE TABLE t (id INT NOT NULL, name VARCHAR(32) NOT NULL, PRIMARY KEY (id), KEY idx_name (name)) ENGINE=InnoDB COMMENT='用户'",
+			Expect:      []string{"ADD COLUMN `age` INT AFTER `name`"},
+			ExpectNotes: []string{"列`age`被删除"},
+		},
+		{
+			Name:   "修改列类型",
+			Before: base,
+			After:  "CREATE TABLE t (id INT NOT NULL, name VARCHAR(64) NOT NULL, age INT, PRIMARY KEY (id), KEY idx_name (name)) ENGINE=InnoDB COMMENT='用户'",
+			Expect: []string{"MODIFY COLUMN `name` VARCHAR(32) NOT NULL AFTER `id`"},
+		},
+		{
+			Name:    "重命名列",
+			Before:  base,
+			After:   "CREATE TABLE t (id INT NOT NULL, user_name VARCHAR(32) NOT NULL, age INT, PRIMARY KEY (id), KEY idx_name (user_name)) ENGINE=InnoDB COMMENT='用户'",
+			Renames: map[string]string{"user_name": "name"},
+			Expect: []string{
+				"DROP INDEX `idx_name`",
+				"CHANGE COLUMN `user_name` `name` VARCHAR(32) NOT NULL AFTER `id`",
+				"ADD INDEX `idx_name`(`name`)",
+			},
+		},
+		{
+			Name:   "新增和删除索引",
+			Before: base,
+			After:  "CREATE TABLE t (id INT NOT NULL, name VARCHAR(32) NOT NULL, age INT, PRIMARY KEY (id), UNIQUE KEY uk_age (age)) ENGINE=InnoDB COMMENT='用户'",
+			Expect: []string{"DROP INDEX `uk_age`", "ADD INDEX `idx_name`(`name`)"},
+		},
+		{
+			Name:   "修改表注释",
+			Before: base,
+			After:  "CREATE TABLE t (id INT NOT NULL, name VARCHAR(32) NOT NULL, age INT, PRIMARY KEY (id), KEY idx_name (name)) ENGINE=InnoDB COMMENT='用户表'",
+			Expect: []string{"COMMENT = '用户'"},
+		},
+		{
+			Name:   "新增表注释",
+			Before: "CREATE TABLE t (id INT NOT NULL, PRIMARY KEY (id)) ENGINE=InnoDB",
+			After:  "CREATE TABLE t (id INT NOT NULL, PRIMARY KEY (id)) ENGINE=InnoDB COMMENT='用户'",
+			Expect: []string{"COMMENT = ''"},
+		},
+		{
+			Name:        "分区变化",
+			Before:      "CREATE TABLE t (id INT NOT NULL, PRIMARY KEY (id)) ENGINE=InnoDB",
+			After:       "CREATE TABLE t (id INT NOT NULL, PRIMARY KEY (id)) ENGINE=InnoDB PARTITION BY HASH (id) PARTITIONS 4",
+			Expect:      nil,
+			ExpectNotes: []string{"分区定义发生变化"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			clauses, notes, err := diffCreateTable(parseCreateTable(t, tc.Before), parseCreateTable(t, tc.After), tc.Renames)
+			if err != nil {
+				t.Fatalf("生成反向DDL失败: %v", err)
+			}
+			if fmt.Sprintf("%q", clauses) != fmt.Sprintf("%q", tc.Expect) {
+				t.Errorf("期望 %q\n实际 %q", tc.Expect, clauses)
+			}
+			for _, expect := range tc.ExpectNotes {
+				if !strings.Contains(strings.Join(notes, "\n"), expect) {
+					t.Errorf("说明信息 %q 未包含 %q", notes, expect)
+				}
+			}
+		})
+	}
+}
+
+func TestGenerateReverseDDLSnapshot(t *testing.T) {
+	const existing = "CREATE TABLE `t` (`id` int NOT NULL, PRIMARY KEY (`id`))"
+	testCases := []struct {
+		Name        string
+		SQL         string
+		Before      string
+		Err         error
+		Expect      string
+		ExpectNotes string // 期望说明信息包含的内容
+	}{
+		{Name: "表不存在", SQL: "CREATE TABLE t (id INT)", Err: &mysql.MySQLError{Number: 1146, Message: "Table 't' doesn't exist"}, Expect: "DROP TABLE `test`.`t`;"},
+		{Name: "表已存在", SQL: "CREATE TABLE IF NOT EXISTS t (id INT)", Before: existing, ExpectNotes: "执行前已存在"},
+		{Name: "权限不足", SQL: "CREATE TABLE IF NOT EXISTS t (id INT)", Err: &mysql.MySQLError{Number: 1142, Message: "SHOW command denied"}, ExpectNotes: "SHOW command denied"},
+		{Name: "连接断开", SQL: "CREATE TABLE t (id INT)", Err: errors.New("invalid connection"), ExpectNotes: "invalid connection"},
+		{Name: "创建视图时解析失败", SQL: "CREATE VIEW v AS SELECT 1", Err: fmt.Errorf("表`v`结构解析失败"), ExpectNotes: "结构解析失败"},
+		{Name: "修改表时获取失败", SQL: "ALTER TABLE t ADD COLUMN c INT", Err: errors.New("invalid connection"), ExpectNotes: "invalid connection"},
+		{Name: "删除表时获取失败", SQL: "DROP TABLE t", Err: errors.New("invalid connection"), ExpectNotes: "invalid connection"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			audit, _, err := parser.ParseSQL(tc.SQL)
+			if err != nil {
+				t.Fatalf("SQL解析错误: %v", err)
+			}
+			table := &ddlTable{schema: "test", table: "t"}
+			var before *ast.CreateTableStmt
+			if tc.Before != "" {
+				before = parseCreateTable(t, tc.Before)
+			}
+			table.setBefore(before, tc.Before, tc.Err)
+			snapshot := &ddlSnapshot{stmt: audit.TiStmt[0], tables: []*ddlTable{table}}
+
+			rollback, notes, err := (&MySQLExecutor{Config: &DBConfig{}}).generateReverseDDL(snapshot)
+			if err != nil {
+				t.Fatalf("生成反向DDL失败: %v", err)
+			}
+			if rollback != tc.Expect {
+				t.Errorf("期望 %q，实际 %q", tc.Expect, rollback)
+			}
+			if tc.ExpectNotes != "" && !strings.Contains(strings.Join(notes, "\n"), tc.ExpectNotes) {
+				t.Errorf("期望说明信息包含 %q，实际 %q", tc.ExpectNotes, notes)
+			}
+		})
+	}
+}
@@ -0,0 +1,301 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-noah/internal/inspect/parser"
+	"go-noah/pkg/dbpool"
+	"hash/fnv"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/tidb/pkg/parser/ast"
+)
+
+// tableScanPlan 按主键分批读取单表的计划（归档、数据迁移共用，由 SELECT * FROM 表 WHERE 条件 解析而来）
+type tableScanPlan struct {
+	Schema   string // 库名
+	Table    string // 表名
+	TableRef string // 表引用（含别名）
+	Qualify  string // 主键列限定名（别名或表名）
+	Where    string // 过滤条件（没有 WHERE 时为 1）
+}
+
+// buildTableScanPlan 解析 SELECT 语句，仅支持不含 JOIN/GROUP BY/ORDER BY/LIMIT 的单表 SELECT *
+func buildTableScanPlan(sqltext, defaultSchema string, requireWhere bool) (*tableScanPlan, error) {
+	audit, _, err := parser.ParseSQL(sqltext)
+	if err != nil {
+		return nil, fmt.Errorf("SQL解析错误: %s", err.Error())
+	}
+	if len(audit.TiStmt) != 1 {
+		return nil, fmt.Errorf("每个任务仅支持单条语句")
+	}
+	stmt, ok := audit.TiStmt[0].(*ast.SelectStmt)
+	if !ok || stmt.Kind != ast.SelectStmtKindSelect {
+		return nil, fmt.Errorf("语句格式为 SELECT * FROM 表名 WHERE 条件")
+	}
+	if stmt.Distinct || stmt.With != nil || stmt.GroupBy != nil || stmt.Having != nil ||
+		stmt.OrderBy != nil || stmt.Limit != nil || stmt.LockInfo != nil || stmt.SelectIntoOpt != nil {
+		return nil, fmt.Errorf("语句不支持 DISTINCT、CTE、GROUP BY、ORDER BY、LIMIT 或锁定子句")
+	}
+	if stmt.Fields == nil || len(stmt.Fields.Fields) != 1 || stmt.Fields.Fields[0].WildCard == nil {
+		return nil, fmt.Errorf("语句必须使用 SELECT * 读取整行数据")
+	}
+	if stmt.Where == nil && requireWhere {
+		return nil, fmt.Errorf("语句必须包含WHERE条件")
+	}
+	if stmt.From == nil || stmt.From.TableRefs == nil || stmt.From.TableRefs.Right != nil {
+		return nil, fmt.Errorf("语句仅支持单表")
+	}
+	source, ok := stmt.From.TableRefs.Left.(*ast.TableSource)
+	if !ok {
+		return nil, fmt.Errorf("语句仅支持单表")
+	}
+	table, ok := source.Source.(*ast.TableName)
+	if !ok {
+		return nil, fmt.Errorf("语句仅支持单表")
+	}
+
+	plan := &tableScanPlan{
+		Schema:  table.Schema.O,
+		Table:   table.Name.O,
+		Qualify: table.Name.O,
+		Where:   "1",
+	}
+	if plan.Schema == "" {
+		plan.Schema = defaultSchema
+	}
+	if source.AsName.O != "" {
+		plan.Qualify = source.AsName.O
+	}
+	if plan.TableRef, err = restoreNode(source); err != nil {
+		return nil, err
+	}
+	if stmt.Where != nil {
+		if plan.Where, err = restoreNode(stmt.Where); err != nil {
+			return nil, err
+		}
+	}
+	return plan, nil
+}
+
+// rowBatch 按主键顺序读取的一批行
+type rowBatch struct {
+	columns []string
+	rows    [][]interface{}
+	pks     []interface{}
+	upper   string // 本批主键上界
+}
+
+// selectRowBatch 读取主键大于 lastPK 的下一批行，lock 为 true 时锁定读取的行
+func selectRowBatch(ctx context.Context, tx *sql.Tx, plan *tableScanPlan, pk, pkRef, lastPK string, chunkSize int, lock bool) (*rowBatch, error) {
+	cond := fmt.Sprintf("(%s)", plan.Where)
+	var args []interface{}
+	if lastPK != "" {
+		cond += fmt.Sprintf(" AND %s > ?", pkRef)
+		args = append(args, pkArg(lastPK))
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE %s ORDER BY %s LIMIT %d", plan.TableRef, cond, pkRef, chunkSize)
+	if lock {
+		query += " FOR UPDATE"
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	batch := &rowBatch{}
+	if batch.columns, err = rows.Columns(); err != nil {
+		return nil, err
+	}
+	pkIndex := columnIndex(batch.columns, pk)
+	if pkIndex < 0 {
+		return nil, fmt.Errorf("查询结果中没有主键列 %s", pk)
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(batch.columns))
+		scanArgs := make([]interface{}, len(values))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		batch.rows = append(batch.rows, values)
+		batch.pks = append(batch.pks, values[pkIndex])
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(batch.pks) > 0 {
+		batch.upper = formatExportValue(batch.pks[len(batch.pks)-1])
+	}
+	return batch, nil
+}
+
+// columnIndex 查找列的位置（不区分大小写），不存在时返回 -1
+func columnIndex(columns []string, name string) int {
+	for i, column := range columns {
+		if strings.EqualFold(column, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// pkArg 主键值转换为查询参数（整数主键按整数比较，避免大整数转换为浮点数丢失精度）
+func pkArg(value string) interface{} {
+	if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return v
+	}
+	return value
+}
+
+// placeholders 生成 n 个以逗号分隔的占位符
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?,", n), ",")
+}
+
+// maxInsertPlaceholders 单条 INSERT 语句的最大占位符数量（MySQL 上限为 65535）
+const maxInsertPlaceholders = 60000
+
+// insertRows 批量写入行，每条 INSERT 最多 batchSize 行（0 为不限制，同时受占位符数量上限约束），
+// suffix 为冲突处理子句，返回影响行数
+func insertRows(ctx context.Context, db *dbpool.Conn, schema, table string, columns []string, rows [][]interface{}, batchSize int, suffix string) (int64, error) {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	perInsert := maxInsertPlaceholders / len(columns)
+	if batchSize > 0 && batchSize < perInsert {
+		perInsert = batchSize
+	}
+	if perInsert < 1 {
+		perInsert = 1
+	}
+	rowPlaceholder := "(" + placeholders(len(columns)) + ")"
+	prefix := fmt.Sprintf("INSERT INTO %s.%s (%s) VALUES ", quoteIdentifier(schema), quoteIdentifier(table), strings.Join(quoted, ","))
+
+	var affected int64
+	for start := 0; start < len(rows); start += perInsert {
+		end := min(start+perInsert, len(rows))
+		values := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, row := range rows[start:end] {
+			values = append(values, rowPlaceholder)
+			args = append(args, row...)
+		}
+		result, err := db.ExecContext(ctx, prefix+strings.Join(values, ",")+suffix, args...)
+		if err != nil {
+			return affected, err
+		}
+		n, _ := result.RowsAffected()
+		affected += n
+	}
+	return affected, nil
+}
+
+// connectInstance 连接目标实例（instanceID 为空或与源实例相同时使用源实例的连接配置）
+func (e *MySQLExecutor) connectInstance(ctx context.Context, resolver InstanceResolver, instanceID, schema string) (*dbpool.Conn, error) {
+	var target DBConfig
+	if instanceID == "" || instanceID == e.Config.InstanceID {
+		target = *e.Config
+	} else {
+		config, err := resolver.TargetConfig(ctx, instanceID)
+		if err != nil {
+			return nil, fmt.Errorf("获取目标实例配置失败: %w", err)
+		}
+		target = *config
+	}
+	target.Schema = schema
+	target.Archive, target.Migrate = nil, nil
+	db, err := NewMySQLExecutor(&target).Connect()
+	if err != nil {
+		return nil, fmt.Errorf("连接目标实例失败: %w", err)
+	}
+	return db, nil
+}
+
+// autoIncrementPattern 建表语句中的 AUTO_INCREMENT 起始值
+var autoIncrementPattern = regexp.MustCompile(`\s+AUTO_INCREMENT=\d+`)
+
+// tableExists 判断表是否存在
+func tableExists(ctx context.Context, db *dbpool.Conn, schema, table string) (bool, error) {
+	var count int
+	if err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?",
+		schema, table).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// createTableLike 目标表不存在时按源表结构创建（源表和目标表可以在不同实例）
+func createTableLike(ctx context.Context, source, target *dbpool.Conn, srcSchema, srcTable, dstSchema, dstTable string) error {
+	exists, err := tableExists(ctx, target, dstSchema, dstTable)
+	if err != nil || exists {
+		return err
+	}
+	var name, createSQL string
+	if err := source.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE TABLE %s.%s",
+		quoteIdentifier(srcSchema), quoteIdentifier(srcTable))).Scan(&name, &createSQL); err != nil {
+		return fmt.Errorf("获取源表结构失败: %w", err)
+	}
+	// 替换表名并去掉 AUTO_INCREMENT 起始值
+	pos := strings.Index(createSQL, " (")
+	if pos < 0 {
+		return fmt.Errorf("无法解析源表结构")
+	}
+	createSQL = fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s.%s%s",
+		quoteIdentifier(dstSchema), quoteIdentifier(dstTable), autoIncrementPattern.ReplaceAllString(createSQL[pos:], ""))
+	if _, err := target.ExecContext(ctx, createSQL); err != nil {
+		return fmt.Errorf("创建目标表失败: %w", err)
+	}
+	return nil
+}
+
+// rowChecksum 行校验和（按字段文本计算，NULL 与空字符串区分）
+func rowChecksum(values []interface{}) uint64 {
+	h := fnv.New64a()
+	for _, v := range values {
+		if v == nil {
+			h.Write([]byte{0})
+		} else {
+			h.Write([]byte{1})
+			h.Write([]byte(formatExportValue(v)))
+		}
+		h.Write([]byte{0x1f})
+	}
+	return h.Sum64()
+}
+
+// queryRowChecksums 按主键查询表中对应的行并计算行校验和（主键文本 -> 校验和），columns 为参与校验的列，pkIndex 为主键在其中的位置
+func queryRowChecksums(ctx context.Context, db *dbpool.Conn, schema, table string, columns []string, pkIndex int, pks []interface{}) (map[string]uint64, error) {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+	}
+	query := fmt.Sprintf("SELECT %s FROM %s.%s WHERE %s IN (%s)", strings.Join(quoted, ","),
+		quoteIdentifier(schema), quoteIdentifier(table), quoted[pkIndex], placeholders(len(pks)))
+	rows, err := db.QueryContext(ctx, query, pks...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	checksums := make(map[string]uint64, len(pks))
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		scanArgs := make([]interface{}, len(values))
+		for i := range values {
+			scanArgs[i] = &values[i]
+		}
+		if err := rows.Scan(scanArgs...); err != nil {
+			return nil, err
+		}
+		checksums[formatExportValue(values[pkIndex])] = rowChecksum(values)
+	}
+	return checksums, rows.Err()
+}
@@ -0,0 +1,298 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/global"
+	"go-noah/pkg/secret"
+	"go-noah/pkg/utils"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+const (
+	// throttleCheckInterval 限流状态下的检查间隔
+	throttleCheckInterval = 2 * time.Second
+)
+
+// throttler 执行限流器：在语句或批次之间检查从库延迟、Threads_running 以及自定义限流查询，
+// 超过阈值时暂停执行，恢复后自动继续
+type throttler struct {
+	config     *DBConfig
+	db         *dbpool.Conn
+	logMessage func(string)
+
+	replicas        map[string]*sql.DB // 从库连接（host:port -> db）
+	replicaResolved bool
+	lastErr         string
+}
+
+// newThrottler 创建限流器，实例未配置任何限流参数时返回 nil
+func newThrottler(config *DBConfig, db *dbpool.Conn, logMessage func(string)) *throttler {
+	if config.ThrottleMaxReplicaLag <= 0 && config.ThrottleMaxThreadsRunning <= 0 && strings.TrimSpace(config.ThrottleQuery) == "" {
+		return nil
+	}
+	return &throttler{config: config, db: db, logMessage: logMessage, replicas: make(map[string]*sql.DB)}
+}
+
+// Close 关闭从库连接
+func (t *throttler) Close() {
+	if t == nil {
+		return
+	}
+	for _, db := range t.replicas {
+		db.Close()
+	}
+}
+
+// Wait 检查限流条件，超过阈值时阻塞直到恢复或 ctx 被取消
+func (t *throttler) Wait(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+	throttled := false
+	throttledAt := time.Now()
+	for {
+		reason := t.check(ctx)
+		if reason == "" {
+			if throttled {
+				t.logMessage(fmt.Sprintf("限流解除，继续执行（暂停 %s）", time.Since(throttledAt).Round(time.Second)))
+				t.publish(false, "")
+			}
+			return nil
+		}
+		if !throttled {
+			throttled = true
+			throttledAt = time.Now()
+			t.logMessage(fmt.Sprintf("触发限流，暂停执行: %s", reason))
+			t.publish(true, reason)
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(throttleCheckInterval):
+		}
+	}
+}
+
+// check 返回限流原因，为空表示无需限流
+// 检查本身出错（如从库不可达）时仅记录日志，不阻塞执行
+func (t *throttler) check(ctx context.Context) string {
+	if t.config.ThrottleMaxThreadsRunning > 0 {
+		var name string
+		var value int64
+		err := t.db.QueryRowContext(ctx, "SHOW GLOBAL STATUS LIKE 'Threads_running'").Scan(&name, &value)
+		if err != nil {
+			t.warn(fmt.Sprintf("获取Threads_running失败: %s", err.Error()))
+		} else if value > int64(t.config.ThrottleMaxThreadsRunning) {
+			return fmt.Sprintf("Threads_running=%d 超过阈值 %d", value, t.config.ThrottleMaxThreadsRunning)
+		}
+	}
+
+	if query := strings.TrimSpace(t.config.ThrottleQuery); query != "" {
+		var value sql.NullInt64
+		err := t.db.QueryRowContext(ctx, query).Scan(&value)
+		if err != nil && err != sql.ErrNoRows {
+			t.warn(fmt.Sprintf("执行限流查询失败: %s", err.Error()))
+		} else if value.Valid && value.Int64 > 0 {
+			return fmt.Sprintf("限流查询返回 %d", value.Int64)
+		}
+	}
+
+	if t.config.ThrottleMaxReplicaLag > 0 {
+		t.resolveReplicas(ctx)
+		for addr, replica := range t.replicas {
+			lag, err := getReplicaLag(ctx, replica)
+			if err != nil {
+				t.warn(fmt.Sprintf("获取从库 %s 延迟失败: %s", addr, err.Error()))
+				continue
+			}
+			if !lag.Valid {
+				return fmt.Sprintf("从库 %s 复制未运行", addr)
+			}
+			if lag.Int64 > int64(t.config.ThrottleMaxReplicaLag) {
+				return fmt.Sprintf("从库 %s 延迟 %ds 超过阈值 %ds", addr, lag.Int64, t.config.ThrottleMaxReplicaLag)
+			}
+		}
+	}
+	return ""
+}
+
+// warn 记录检查异常（相同错误只记录一次）
+func (t *throttler) warn(msg string) {
+	if msg == t.lastErr {
+		return
+	}
+	t.lastErr = msg
+	t.logMessage(msg)
+}
+
+// publish 推送限流状态（类型为 "throttled"）
+func (t *throttler) publish(throttled bool, reason string) {
+	if t.config.OrderID == "" {
+		return
+	}
+	data := map[string]interface{}{
+		"task_id":   t.config.TaskID,
+		"throttled": throttled,
+		"reason":    reason,
+	}
+	if err := utils.PublishMessageToChannel(t.config.OrderID, data, "throttled"); err != nil {
+		global.Logger.Error("Failed to publish throttled message", zap.String("order_id", t.config.OrderID), zap.Error(err))
+	}
+}
+
+// resolveReplicas 发现主库下挂的从库并建立连接（只在首次检查时执行）
+// 优先使用 SHOW SLAVE HOSTS（需要从库配置 report_host），否则从 Binlog Dump 线程获取从库地址，端口沿用主库端口
+func (t *throttler) resolveReplicas(ctx context.Context) {
+	if t.replicaResolved {
+		return
+	}
+	t.replicaResolved = true
+
+	addrs := make(map[string]struct{})
+	if rows, err := t.db.QueryContext(ctx, "SHOW SLAVE HOSTS"); err == nil {
+		columns, _ := rows.Columns()
+		for rows.Next() {
+			record := scanStringMap(rows, columns)
+			if host := record["Host"]; host != "" {
+				port := record["Port"]
+				if port == "" || port == "0" {
+					port = strconv.Itoa(t.config.Port)
+				}
+				addrs[net.JoinHostPort(host, port)] = struct{}{}
+			}
+		}
+		rows.Close()
+	}
+	if len(addrs) == 0 {
+		rows, err := t.db.QueryContext(ctx, "SELECT HOST FROM information_schema.PROCESSLIST WHERE COMMAND LIKE 'Binlog Dump%'")
+		if err != nil {
+			t.warn(fmt.Sprintf("发现从库失败: %s", err.Error()))
+			return
+		}
+		for rows.Next() {
+			var host string
+			if err := rows.Scan(&host); err != nil {
+				continue
+			}
+			if h, _, err := net.SplitHostPort(host); err == nil {
+				host = h
+			}
+			if host != "" {
+				addrs[net.JoinHostPort(host, strconv.Itoa(t.config.Port))] = struct{}{}
+			}
+		}
+		rows.Close()
+	}
+	if len(addrs) == 0 {
+		t.logMessage("未发现从库，跳过从库延迟检查")
+		return
+	}
+
+	password, err := secret.Decrypt(t.config.Password)
+	if err != nil {
+		t.warn(fmt.Sprintf("解密实例密码失败，跳过从库延迟检查: %s", err.Error()))
+		return
+	}
+	for addr := range addrs {
+		cfg := mysql.Config{
+			User:                 t.config.UserName,
+			Passwd:               password,
+			Addr:                 addr,
+			Net:                  "tcp",
+			AllowNativePasswords: true,
+			Timeout:              5 * time.Second,
+			ReadTimeout:          10 * time.Second,
+		}
+		// 从库与主库通过相同的跳板机和 TLS 配置访问
+		if err := t.config.ConnOptions.ApplyMySQL(&cfg); err != nil {
+			t.warn(fmt.Sprintf("连接从库 %s 失败: %s", addr, err.Error()))
+			continue
+		}
+		db, err := sql.Open("mysql", cfg.FormatDSN())
+		if err != nil {
+			t.warn(fmt.Sprintf("连接从库 %s 失败: %s", addr, err.Error()))
+			continue
+		}
+		db.SetMaxOpenConns(1)
+		t.replicas[addr] = db
+	}
+	t.logMessage(fmt.Sprintf("从库延迟检查已启用，从库: %d 个，阈值: %ds", len(t.replicas), t.config.ThrottleMaxReplicaLag))
+}
+
+// getReplicaLag 获取从库复制延迟（秒），复制线程未运行时返回 NULL
+func getReplicaLag(ctx context.Context, db *sql.DB) (sql.NullInt64, error) {
+	var lag sql.NullInt64
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		// 低版本不支持 SHOW REPLICA STATUS
+		rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS")
+		if err != nil {
+			return lag, err
+		}
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return lag, err
+	}
+	if !rows.Next() {
+		return lag, fmt.Errorf("不是从库")
+	}
+	record := scanStringMap(rows, columns)
+	value, ok := record["Seconds_Behind_Source"]
+	if !ok {
+		value = record["Seconds_Behind_Master"]
+	}
+	if value == "" {
+		return lag, nil
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return lag, err
+	}
+	return sql.NullInt64{Int64: n, Valid: true}, nil
+}
+
+// scanStringMap 将当前行扫描为 列名 -> 字符串 的映射（NULL 为空字符串）
+func scanStringMap(rows *sql.Rows, columns []string) map[string]string {
+	values := make([]sql.NullString, len(columns))
+	scanArgs := make([]interface{}, len(columns))
+	for i := range values {
+		scanArgs[i] = &values[i]
+	}
+	record := make(map[string]string, len(columns))
+	if err := rows.Scan(scanArgs...); err != nil {
+		return record
+	}
+	for i, column := range columns {
+		record[column] = values[i].String
+	}
+	return record
+}
+
+// ghostThrottleArgs 将实例限流配置转换为 gh-ost 参数（已按 shell 转义）
+func ghostThrottleArgs(config *DBConfig) []string {
+	var args []string
+	if config.ThrottleMaxReplicaLag > 0 {
+		args = append(args, fmt.Sprintf("--max-lag-millis=%d", config.ThrottleMaxReplicaLag*1000))
+	}
+	if config.ThrottleMaxThreadsRunning > 0 {
+		args = append(args, fmt.Sprintf("--max-load=Threads_running=%d", config.ThrottleMaxThreadsRunning))
+	}
+	if query := strings.TrimSpace(config.ThrottleQuery); query != "" {
+		// 命令通过 bash -c 执行，自定义查询需要整体单引号转义，避免 $()、反引号等被 shell 解释
+		args = append(args, "--throttle-query="+shellQuote(query))
+	}
+	return args
+}
@@ -0,0 +1,41 @@
+package executor
+
+import (
+	"os/exec"
+	"testing"
+)
+
+func TestGhostThrottleArgs(t *testing.T) {
+	bash, err := exec.LookPath("bash")
+	if err != nil {
+		t.Skip("未找到 bash")
+	}
+	testCases := []struct {
+		Name  string
+		Query string
+	}{
+		{Name: "普通查询", Query: "SELECT COUNT(*) > 100 FROM information_schema.PROCESSLIST"},
+		{Name: "双引号", Query: `SELECT "a" = "a"`},
+		{Name: "单引号", Query: "SELECT 'it''s' = 'x'"},
+		{Name: "命令替换", Query: "SELECT $(touch /tmp/noah-throttle-injected) 1"},
+		{Name: "反引号", Query: "SELECT `id` FROM t WHERE `touch /tmp/noah-throttle-injected`"},
+		{Name: "反斜杠和变量", Query: `SELECT '\n' = "$HOME\"; echo injected"`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			args := ghostThrottleArgs(&DBConfig{ThrottleQuery: tc.Query})
+			if len(args) != 1 {
+				t.Fatalf("期望 1 个参数，实际 %q", args)
+			}
+			// 与 gh-ost 命令相同通过 bash -c 执行，shell 解析后的参数应与原查询一致
+			output, err := exec.Command(bash, "-c", "printf '%s' "+args[0]).Output()
+			if err != nil {
+				t.Fatalf("执行失败: %v", err)
+			}
+			expect := "--throttle-query=" + tc.Query
+			if got := string(output); got != expect {
+				t.Errorf("期望 %q，实际 %q", expect, got)
+			}
+		})
+	}
+}
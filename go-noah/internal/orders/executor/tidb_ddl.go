@@ -0,0 +1,316 @@
+package executor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"go-noah/pkg/dbpool"
+	"go-noah/pkg/global"
+	"go-noah/pkg/utils"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// DDLEngineTiDB TiDB 原生在线DDL（直接执行，通过 ADMIN SHOW DDL JOBS 跟踪进度）
+const DDLEngineTiDB = "tidb"
+
+// TiDB DDL Job 控制操作
+const (
+	TiDBDDLActionCancel = "CANCEL"
+	TiDBDDLActionPause  = "PAUSE"
+	TiDBDDLActionResume = "RESUME"
+)
+
+const defaultTiDBDDLPollInterval = 3 * time.Second
+
+type tidbDDLStrategy struct{ e *MySQLExecutor }
+
+func (s tidbDDLStrategy) Name() string { return DDLEngineTiDB }
+func (s tidbDDLStrategy) Execute(ctx context.Context) (ReturnData, error) {
+	return s.e.ExecuteTiDBDDL(ctx)
+}
+
+// tidbDDLJob ADMIN SHOW DDL JOBS 的一行
+type tidbDDLJob struct {
+	JobID       int64
+	DBName      string
+	TableName   string
+	JobType     string
+	SchemaState string
+	RowCount    int64
+	State       string
+}
+
+// getTiDBDDLPollInterval 获取 DDL Job 进度轮询间隔
+func getTiDBDDLPollInterval() time.Duration {
+	if global.Conf != nil {
+		if seconds := global.Conf.GetInt("tidb_ddl.poll_interval_seconds"); seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+	return defaultTiDBDDLPollInterval
+}
+
+// ExecuteTiDBDDL 在 TiDB 上直接执行 ALTER TABLE，并通过 ADMIN SHOW DDL JOBS 跟踪 Job 状态和行数
+func (e *MySQLExecutor) ExecuteTiDBDDL(ctx context.Context) (ReturnData, error) {
+	databaseName, tableName, _, err := parseAlterStatement(e.Config.SQL, e.Config.Schema)
+	if err != nil {
+		return ReturnData{Error: err.Error()}, err
+	}
+
+	// 监控使用独立连接（执行连接会阻塞到 DDL 完成）
+	monitor, err := e.ControlConnect()
+	if err != nil {
+		return ReturnData{Error: err.Error()}, err
+	}
+	defer monitor.Close()
+
+	// 记录执行前最新的 Job ID，之后出现的目标表 Job 即为本次 DDL
+	var baseline int64
+	if jobs, err := showTiDBDDLJobs(ctx, monitor, 1); err == nil && len(jobs) > 0 {
+		baseline = jobs[0].JobID
+	}
+	var totalRows int64
+	_ = monitor.QueryRowContext(ctx, `SELECT COALESCE(TABLE_ROWS, 0) FROM information_schema.TABLES
+		WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?`, databaseName, tableName).Scan(&totalRows)
+
+	type result struct {
+		data ReturnData
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, err := e.ExecuteOnlineDDL(ctx)
+		done <- result{data, err}
+	}()
+
+	var (
+		executeLog []string
+		job        *tidbDDLJob
+		lastState  string
+	)
+	logMessage := e.newLogger(&executeLog)
+	poll := func() {
+		jobs, err := showTiDBDDLJobs(context.Background(), monitor, 20)
+		if err != nil {
+			global.Logger.Warn("查询 TiDB DDL Job 失败", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+			return
+		}
+		current := findTiDBDDLJob(jobs, baseline, databaseName, tableName, job)
+		if current == nil {
+			return
+		}
+		if job == nil {
+			logMessage(fmt.Sprintf("TiDB DDL Job ID: %d，类型: %s", current.JobID, current.JobType))
+			if e.Config.OrderID != "" {
+				if err := utils.SetTiDBDDLJobID(e.Config.OrderID, current.JobID); err != nil {
+					global.Logger.Warn("保存 TiDB DDL Job ID 失败", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+				}
+			}
+		}
+		job = current
+		if state := job.State + "/" + job.SchemaState; state != lastState {
+			logMessage(fmt.Sprintf("DDL Job 状态: %s，Schema 状态: %s，已处理行数: %d", job.State, job.SchemaState, job.RowCount))
+			lastState = state
+		}
+		e.publishTiDBDDLProgress(job, totalRows)
+	}
+	defer func() {
+		if e.Config.OrderID != "" {
+			utils.DeleteTiDBDDLJobID(e.Config.OrderID)
+		}
+	}()
+
+	// 断开执行连接不会取消 TiDB 中的 DDL Job，任务取消时需要显式取消 Job
+	cancelRequested, cancelled := false, false
+	cancelJob := func() {
+		if !cancelRequested || cancelled || job == nil {
+			return
+		}
+		cancelled = true
+		if err := controlTiDBDDLJob(context.Background(), monitor, job.JobID, TiDBDDLActionCancel); err != nil {
+			logMessage(fmt.Sprintf("取消 DDL Job %d 失败: %s", job.JobID, err.Error()))
+		} else {
+			logMessage(fmt.Sprintf("已取消 DDL Job %d", job.JobID))
+		}
+	}
+
+	ticker := time.NewTicker(getTiDBDDLPollInterval())
+	defer ticker.Stop()
+	ctxDone := ctx.Done()
+	for {
+		select {
+		case r := <-done:
+			poll()
+			if ctx.Err() != nil {
+				cancelRequested = true
+				cancelJob()
+			}
+			r.data.ExecuteLog = mergeExecuteLog(r.data.ExecuteLog, executeLog)
+			return r.data, r.err
+		case <-ctxDone:
+			cancelRequested = true
+			ctxDone = nil
+			poll()
+			cancelJob()
+		case <-ticker.C:
+			poll()
+			cancelJob()
+		}
+	}
+}
+
+// mergeExecuteLog 将 DDL Job 跟踪日志追加到执行日志后
+func mergeExecuteLog(executeLog string, logs []string) string {
+	if len(logs) == 0 {
+		return executeLog
+	}
+	return strings.TrimPrefix(executeLog+"\n"+strings.Join(logs, "\n"), "\n")
+}
+
+// findTiDBDDLJob 在 Job 列表中查找本次执行的 DDL Job（已知 Job ID 时按 ID 查找）
+func findTiDBDDLJob(jobs []tidbDDLJob, baseline int64, databaseName, tableName string, known *tidbDDLJob) *tidbDDLJob {
+	var found *tidbDDLJob
+	for i := range jobs {
+		job := &jobs[i]
+		if known != nil {
+			if job.JobID == known.JobID {
+				return job
+			}
+			continue
+		}
+		if job.JobID <= baseline || !strings.EqualFold(job.DBName, databaseName) || !strings.EqualFold(job.TableName, tableName) {
+			continue
+		}
+		if found == nil || job.JobID < found.JobID {
+			found = job
+		}
+	}
+	return found
+}
+
+// publishTiDBDDLProgress 推送 TiDB DDL Job 进度（复用 ghost-progress 消息类型，便于前端展示）
+func (e *MySQLExecutor) publishTiDBDDLProgress(job *tidbDDLJob, totalRows int64) {
+	if e.Config.OrderID == "" {
+		return
+	}
+	percent := 0.0
+	if strings.EqualFold(job.State, "synced") || strings.EqualFold(job.State, "done") {
+		percent = 100
+	} else if totalRows > 0 {
+		percent = float64(job.RowCount) * 100 / float64(totalRows)
+		if percent > 99 {
+			percent = 99
+		}
+	}
+	progressData := map[string]interface{}{
+		"current":      job.RowCount,
+		"total":        totalRows,
+		"percent":      percent,
+		"operation":    "tidb-ddl",
+		"job_id":       job.JobID,
+		"job_type":     job.JobType,
+		"schema_state": job.SchemaState,
+		"state":        job.State,
+	}
+	if err := utils.PublishMessageToChannel(e.Config.OrderID, progressData, "ghost-progress"); err != nil {
+		global.Logger.Error("Failed to publish TiDB DDL progress", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+	if err := utils.SaveGhostProgressToRedis(e.Config.OrderID, progressData); err != nil {
+		global.Logger.Warn("Failed to save TiDB DDL progress to Redis cache", zap.String("order_id", e.Config.OrderID), zap.Error(err))
+	}
+}
+
+// showTiDBDDLJobs 查询最近的 DDL Job（按列名读取，兼容不同 TiDB 版本的列差异）
+func showTiDBDDLJobs(ctx context.Context, db *dbpool.Conn, limit int) ([]tidbDDLJob, error) {
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("ADMIN SHOW DDL JOBS %d", limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var jobs []tidbDDLJob
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		var job tidbDDLJob
+		for i, column := range columns {
+			value := values[i].String
+			switch strings.ToUpper(column) {
+			case "JOB_ID":
+				job.JobID, _ = strconv.ParseInt(value, 10, 64)
+			case "DB_NAME":
+				job.DBName = value
+			case "TABLE_NAME":
+				job.TableName = value
+			case "JOB_TYPE":
+				job.JobType = value
+			case "SCHEMA_STATE":
+				job.SchemaState = value
+			case "ROW_COUNT":
+				job.RowCount, _ = strconv.ParseInt(value, 10, 64)
+			case "STATE":
+				job.State = value
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// controlTiDBDDLJob 执行 ADMIN CANCEL/PAUSE/RESUME DDL JOBS
+func controlTiDBDDLJob(ctx context.Context, db *dbpool.Conn, jobID int64, action string) error {
+	switch action {
+	case TiDBDDLActionCancel, TiDBDDLActionPause, TiDBDDLActionResume:
+	default:
+		return fmt.Errorf("不支持的 DDL Job 操作: %s", action)
+	}
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("ADMIN %s DDL JOBS %d", action, jobID))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// 返回结果为 (JOB_ID, RESULT)，RESULT 不为 successful 时表示操作失败
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		values := make([]sql.NullString, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		if len(values) > 1 && !strings.EqualFold(values[1].String, "successful") {
+			return fmt.Errorf("DDL Job %d: %s", jobID, values[1].String)
+		}
+	}
+	return rows.Err()
+}
+
+// ControlTiDBDDLJob 连接 TiDB 对 DDL Job 执行取消、暂停或恢复（暂停/恢复需要 TiDB v7.2 及以上版本）
+func ControlTiDBDDLJob(ctx context.Context, config *DBConfig, jobID int64, action string) error {
+	db, err := (&MySQLExecutor{Config: config}).ControlConnect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	return controlTiDBDDLJob(ctx, db, jobID, action)
+}
@@ -1,7 +1,13 @@
 package executor
 
+import (
+	"context"
+	"go-noah/pkg/dbconn"
+)
+
 // DBConfig 数据库配置
 type DBConfig struct {
+	InstanceID         string // 实例ID（用于复用实例连接池）
 	Hostname           string // 主机名
 	Port               int    // 端口
 	Charset            string // 字符集
@@ -9,23 +15,43 @@ type DBConfig struct {
 	Password           string // 密码
 	Schema             string // 数据库
 	DBType             string // 数据库类型（MySQL/TiDB/ClickHouse）
-	SQLType            string // SQL类型（DDL/DML/EXPORT）
+	SQLType            string // SQL类型（DDL/DML/EXPORT/ARCHIVE/MIGRATE）
 	SQL                string // SQL语句
 	OrderID            string // 工单ID
 	TaskID             string // 任务ID
 	ExportFileFormat   string // 导出文件格式
 	GhostOkToDropTable bool   // gh-ost执行成功后自动删除旧表
+	EncryptionKey      string // 导出文件加密密钥（工单级别，密文，导出时解密）
+	DDLEngine          string // 在线DDL引擎（gh-ost/pt-osc/native/auto）
+	ChunkedDML         bool   // DML按主键分批执行
+	ChunkSize          int    // 分批执行每批行数（0使用系统默认）
+	ChunkSleepMs       int    // 分批执行批次间隔毫秒（0使用系统默认）
+	MaxAffectedRows    int64  // DML影响行数上限（超过时回滚，0为不限制）
+
+	ThrottleMaxReplicaLag     int    // 限流：最大从库延迟（秒）
+	ThrottleMaxThreadsRunning int    // 限流：最大 Threads_running
+	ThrottleQuery             string // 限流：自定义查询（返回值大于0时限流）
+	GhostPostponeCutOver      bool   // gh-ost推迟cut-over（数据同步完成后等待手动或计划时间执行）
+
+	ConnOptions dbconn.Options // 连接选项（SSH 跳板机、TLS）
+
+	Archive *ArchiveConfig // 归档任务配置（仅 ARCHIVE 任务）
+	Migrate *MigrateConfig // 数据迁移任务配置（仅 MIGRATE 任务）
 }
 
-// ExportFile 导出文件信息
+// ExportFile 导出文件信息（保存在任务结果中）
+// 解压密码不写入任务结果，加密保存在工单中，仅申请人可通过专用接口获取
 type ExportFile struct {
-	FileName      string `json:"file_name"`      // 文件名
-	FileSize      int64  `json:"file_size"`      // 文件大小
-	FilePath      string `json:"file_path"`      // 文件路径
-	ContentType   string `json:"content_type"`   // 内容类型
-	EncryptionKey string `json:"encryption_key"` // 加密密钥
-	ExportRows    int64  `json:"export_rows"`    // 导出行数
-	DownloadUrl   string `json:"download_url"`   // 下载地址
+	FileName    string `json:"file_name"`    // 文件名
+	FileSize    int64  `json:"file_size"`    // 文件大小
+	FilePath    string `json:"file_path"`    // 文件路径
+	ContentType string `json:"content_type"` // 内容类型
+	ExportRows  int64  `json:"export_rows"`  // 导出行数
+	DownloadUrl string `json:"download_url"` // 下载地址
+	ExpireTime  string `json:"expire_time"`  // 下载过期时间
+
+	// 升级前导出的文件使用文件级别的解压密码并保存在任务结果中，仅用于读取历史结果
+	LegacyEncryptionKey string `json:"encryption_key,omitempty"`
 }
 
 // ReturnData 执行结果
@@ -34,12 +60,22 @@ type ReturnData struct {
 	AffectedRows    int64  `json:"affected_rows"`     // 影响行数
 	ExecuteCostTime string `json:"execute_cost_time"` // 执行耗时
 	BackupCostTime  string `json:"backup_cost_time"`  // 备份耗时
+	BackupStrategy  string `json:"backup_strategy"`   // 回滚SQL生成方式（binlog/pre_image/none）
 	ExecuteLog      string `json:"execute_log"`       // 执行日志
 	ExportFile             // 导出文件信息
 	Error           string `json:"error"` // 错误信息
+
+	// 回滚SQL和执行日志保存到对象存储后，任务记录只保留引用和大小
+	RollbackSQLRef      string `json:"rollback_sql_ref,omitempty"`       // 回滚SQL对象键
+	RollbackSQLSize     int64  `json:"rollback_sql_size,omitempty"`      // 回滚SQL字节数
+	RollbackSQLCount    int    `json:"rollback_sql_count,omitempty"`     // 回滚SQL语句数
+	RollbackSQLIndexRef string `json:"rollback_sql_index_ref,omitempty"` // 回滚SQL语句偏移索引对象键（分页时按偏移读取）
+	ExecuteLogRef       string `json:"execute_log_ref,omitempty"`        // 执行日志对象键
+	ExecuteLogSize      int64  `json:"execute_log_size,omitempty"`       // 执行日志字节数
 }
 
 // Executor 执行器接口
+// ctx 取消时执行器应尽快中断当前语句并返回
 type Executor interface {
-	Run() (ReturnData, error)
+	Run(ctx context.Context) (ReturnData, error)
 }
@@ -0,0 +1,29 @@
+package insight
+
+import (
+	"context"
+	"errors"
+	"go-noah/internal/model/insight"
+
+	"gorm.io/gorm"
+)
+
+// ============ 数据归档 ============
+
+// GetArchiveCheckpoint 获取归档任务的断点，尚未开始归档时返回 nil
+func (r *InsightRepository) GetArchiveCheckpoint(ctx context.Context, taskID string) (*insight.ArchiveCheckpoint, error) {
+	var checkpoint insight.ArchiveCheckpoint
+	err := r.DB(ctx).Where("task_id = ?", taskID).First(&checkpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// SaveArchiveCheckpoint 保存归档任务的断点（ID 为 0 时创建）
+func (r *InsightRepository) SaveArchiveCheckpoint(ctx context.Context, checkpoint *insight.ArchiveCheckpoint) error {
+	return r.DB(ctx).Save(checkpoint).Error
+}
@@ -0,0 +1,57 @@
+package insight
+
+import (
+	"context"
+	"go-noah/internal/model/insight"
+)
+
+// ============ 维护窗口管理 ============
+
+// GetMaintenanceWindows 获取维护窗口列表（environment 为 0 时不过滤环境）
+func (r *InsightRepository) GetMaintenanceWindows(ctx context.Context, environment int) ([]insight.MaintenanceWindow, error) {
+	var windows []insight.MaintenanceWindow
+	db := r.DB(ctx)
+	if environment > 0 {
+		db = db.Where("environment = ?", environment)
+	}
+	if err := db.Order("environment ASC, instance_id ASC, id ASC").Find(&windows).Error; err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// GetMaintenanceWindowsForInstance 获取对实例生效的维护窗口（环境级别和实例级别）
+func (r *InsightRepository) GetMaintenanceWindowsForInstance(ctx context.Context, environment int, instanceID string) ([]insight.MaintenanceWindow, error) {
+	var windows []insight.MaintenanceWindow
+	if err := r.DB(ctx).
+		Where("environment = ?", environment).
+		Where("instance_id IN ?", []string{"", instanceID}).
+		Find(&windows).Error; err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// GetMaintenanceWindow 获取维护窗口详情
+func (r *InsightRepository) GetMaintenanceWindow(ctx context.Context, id uint) (*insight.MaintenanceWindow, error) {
+	var window insight.MaintenanceWindow
+	if err := r.DB(ctx).Where("id = ?", id).First(&window).Error; err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// CreateMaintenanceWindow 创建维护窗口
+func (r *InsightRepository) CreateMaintenanceWindow(ctx context.Context, window *insight.MaintenanceWindow) error {
+	return r.DB(ctx).Create(window).Error
+}
+
+// UpdateMaintenanceWindow 更新维护窗口
+func (r *InsightRepository) UpdateMaintenanceWindow(ctx context.Context, window *insight.MaintenanceWindow) error {
+	return r.DB(ctx).Save(window).Error
+}
+
+// DeleteMaintenanceWindow 删除维护窗口
+func (r *InsightRepository) DeleteMaintenanceWindow(ctx context.Context, id uint) error {
+	return r.DB(ctx).Delete(&insight.MaintenanceWindow{}, id).Error
+}
@@ -2,6 +2,7 @@ package insight
 
 import (
 	"context"
+	"errors"
 	"go-noah/internal/model/insight"
 	"time"
 
@@ -11,6 +12,9 @@ import (
 
 // ============ 工单管理 ============
 
+// ErrStaleFence 任务已被持有更新 fencing token 的执行者接管，拒绝旧执行者写入
+var ErrStaleFence = errors.New("执行锁已被其他节点接管，拒绝写入过期的执行状态")
+
 // OrderWithInstance 工单记录（包含实例名称和环境名称）
 type OrderWithInstance struct {
 	insight.OrderRecord
@@ -127,6 +131,29 @@ func (r *InsightRepository) GetOrderByID(ctx context.Context, orderID string) (*
 	return &orderWithInstance, nil
 }
 
+// GetOrdersByHookOrderID 获取关联到指定源工单的工单（如回滚工单）
+func (r *InsightRepository) GetOrdersByHookOrderID(ctx context.Context, hookOrderID string) ([]insight.OrderRecord, error) {
+	var orders []insight.OrderRecord
+	if err := r.DB(ctx).Where("hook_order_id = ?", hookOrderID).Order("id DESC").Find(&orders).Error; err != nil {
+		return nil, err
+	}
+	return orders, nil
+}
+
+// CreateDryRun 保存试运行记录
+func (r *InsightRepository) CreateDryRun(ctx context.Context, dryRun *insight.OrderDryRun) error {
+	return r.DB(ctx).Create(dryRun).Error
+}
+
+// GetLatestDryRun 获取工单最近一次试运行记录
+func (r *InsightRepository) GetLatestDryRun(ctx context.Context, orderID string) (*insight.OrderDryRun, error) {
+	var dryRun insight.OrderDryRun
+	if err := r.DB(ctx).Where("order_id = ?", orderID).Order("id DESC").First(&dryRun).Error; err != nil {
+		return nil, err
+	}
+	return &dryRun, nil
+}
+
 // CreateOrder 创建工单
 func (r *InsightRepository) CreateOrder(ctx context.Context, order *insight.OrderRecord) error {
 	return r.DB(ctx).Create(order).Error
@@ -187,6 +214,50 @@ func (r *InsightRepository) UpdateTaskProgress(ctx context.Context, taskID strin
 		Updates(updates).Error
 }
 
+// UpdateTaskProgressIn 仅当任务处于 from 中的状态时更新任务进度，返回是否更新到了记录
+func (r *InsightRepository) UpdateTaskProgressIn(ctx context.Context, taskID string, from []insight.TaskProgress, progress insight.TaskProgress) (bool, error) {
+	res := r.DB(ctx).Model(&insight.OrderTask{}).
+		Where("task_id = ? AND progress IN ?", taskID, from).
+		Update("progress", progress)
+	if res.Error != nil {
+		return false, res.Error
+	}
+	return res.RowsAffected > 0, nil
+}
+
+// UpdateTaskProgressFenced 持有执行锁时更新任务进度，fencing token 小于任务记录中的值时返回 ErrStaleFence
+// fence 为 0 表示未使用分布式执行锁（未配置 Redis），不做校验
+func (r *InsightRepository) UpdateTaskProgressFenced(ctx context.Context, taskID string, fence int64, progress insight.TaskProgress, result []byte) error {
+	if fence <= 0 {
+		return r.UpdateTaskProgress(ctx, taskID, progress, result)
+	}
+	updates := map[string]interface{}{
+		"progress":    progress,
+		"fence_token": fence,
+	}
+	if result != nil {
+		updates["result"] = result
+	}
+	res := r.DB(ctx).Model(&insight.OrderTask{}).
+		Where("task_id = ? AND fence_token <= ?", taskID, fence).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		return nil
+	}
+	// 没有更新到记录：可能是值未变化，也可能是已被新的持有者接管
+	var task insight.OrderTask
+	if err := r.DB(ctx).Select("fence_token").Where("task_id = ?", taskID).First(&task).Error; err != nil {
+		return err
+	}
+	if task.FenceToken > fence {
+		return ErrStaleFence
+	}
+	return nil
+}
+
 // CheckTasksProgressIsDoing 检查工单是否有任务正在执行中
 func (r *InsightRepository) CheckTasksProgressIsDoing(ctx context.Context, orderID string) (bool, error) {
 	var count int64
@@ -211,6 +282,27 @@ func (r *InsightRepository) CheckTasksProgressIsPause(ctx context.Context, order
 	return count == 0, nil // 返回 true 表示没有已暂停的任务
 }
 
+// CheckTasksProgressIsUnknown 检查工单是否有执行结果待确认的任务
+func (r *InsightRepository) CheckTasksProgressIsUnknown(ctx context.Context, orderID string) (bool, error) {
+	var count int64
+	err := r.DB(ctx).Model(&insight.OrderTask{}).
+		Where("order_id = ? AND progress = ?", orderID, insight.TaskProgressUnknown).
+		Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count == 0, nil // 返回 true 表示没有待确认的任务
+}
+
+// GetTasksByProgress 获取指定进度的所有任务
+func (r *InsightRepository) GetTasksByProgress(ctx context.Context, progress insight.TaskProgress) ([]insight.OrderTask, error) {
+	var tasks []insight.OrderTask
+	if err := r.DB(ctx).Where("progress = ?", progress).Order("id ASC").Find(&tasks).Error; err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
 // UpdateOrderExecuteResult 更新工单执行结果
 func (r *InsightRepository) UpdateOrderExecuteResult(ctx context.Context, orderID string, result string) error {
 	return r.DB(ctx).Model(&insight.OrderRecord{}).
@@ -231,13 +323,22 @@ func (r *InsightRepository) CheckAllTasksCompleted(ctx context.Context, orderID
 }
 
 // UpdateTaskAndOrderProgress 使用事务同时更新任务和工单状态
-func (r *InsightRepository) UpdateTaskAndOrderProgress(ctx context.Context, taskID string, orderID string, taskProgress insight.TaskProgress, orderProgress insight.Progress) error {
+// fence 为执行锁的 fencing token（0 表示未使用分布式执行锁），写入任务记录供后续更新校验
+func (r *InsightRepository) UpdateTaskAndOrderProgress(ctx context.Context, taskID string, orderID string, fence int64, taskProgress insight.TaskProgress, orderProgress insight.Progress) error {
 	return r.DB(ctx).Transaction(func(tx *gorm.DB) error {
 		// 更新任务状态
-		if err := tx.Model(&insight.OrderTask{}).
-			Where("task_id = ?", taskID).
-			Update("progress", taskProgress).Error; err != nil {
-			return err
+		updates := map[string]interface{}{"progress": taskProgress}
+		query := tx.Model(&insight.OrderTask{}).Where("task_id = ?", taskID)
+		if fence > 0 {
+			updates["fence_token"] = fence
+			query = query.Where("fence_token <= ?", fence)
+		}
+		res := query.Updates(updates)
+		if res.Error != nil {
+			return res.Error
+		}
+		if fence > 0 && res.RowsAffected == 0 {
+			return ErrStaleFence
 		}
 		// 更新工单状态
 		if err := tx.Model(&insight.OrderRecord{}).
@@ -319,14 +420,14 @@ func (r *InsightRepository) GetOrdersPendingSchedulerRegistration(ctx context.Co
 	now := time.Now()
 	// 只查询必要的字段，减少数据传输量
 	// 限制查询范围：查询过去24小时到未来30天内的定时工单
-	// 注意：已过期的工单也会被查询到，但在处理时会跳过执行，只标记为已注册
+	// 注意：已过期的工单也会被查询到，由调度器按错过计划时间的处理策略处理
 	// 使用复合索引：progress + scheduler_registered + schedule_time
 	if err := r.DB(ctx).
-		Select("id, order_id, progress, schedule_time, applicant, executor").
+		Select("id, order_id, progress, schedule_time, applicant, executor, schedule_missed_policy").
 		Where("progress = ?", insight.ProgressApproved).
 		Where("scheduler_registered = ?", false).
 		Where("schedule_time IS NOT NULL").
-		Where("schedule_time >= ?", now.Add(-24*time.Hour)).   // 查询过去24小时内的（处理服务器重启的情况，已过期的会被标记但不执行）
+		Where("schedule_time >= ?", now.Add(-24*time.Hour)).   // 查询过去24小时内的（处理服务器重启的情况）
 		Where("schedule_time <= ?", now.Add(30*24*time.Hour)). // 最多查询未来30天内的
 		Order("schedule_time ASC").                            // 按时间排序，优先处理即将执行的
 		Limit(limit).
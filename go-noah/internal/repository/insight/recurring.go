@@ -0,0 +1,145 @@
+package insight
+
+import (
+	"context"
+	"errors"
+	"go-noah/internal/model/insight"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ============ 周期工单 ============
+
+// RecurringScheduleWithOrder 周期工单执行计划（包含工单标题、申请人和进度）
+type RecurringScheduleWithOrder struct {
+	insight.RecurringSchedule
+	Title         string           `json:"title"`
+	Applicant     string           `json:"applicant"`
+	OrderProgress insight.Progress `json:"order_progress"`
+}
+
+// GetRecurringSchedules 获取周期工单执行计划列表
+func (r *InsightRepository) GetRecurringSchedules(ctx context.Context, page, pageSize int, state string) ([]RecurringScheduleWithOrder, int64, error) {
+	var schedules []RecurringScheduleWithOrder
+	var total int64
+
+	query := r.DB(ctx).Table("recurring_schedules a").
+		Select("a.*, b.title, b.applicant, b.progress AS order_progress").
+		Joins("LEFT JOIN order_records b ON a.order_id = b.order_id").
+		Where("a.deleted_at IS NULL")
+	if state != "" {
+		query = query.Where("a.state = ?", state)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("a.id DESC").Offset(offset).Limit(pageSize).Scan(&schedules).Error; err != nil {
+		return nil, 0, err
+	}
+	return schedules, total, nil
+}
+
+// GetRecurringScheduleByOrderID 获取工单的周期执行计划，不是周期工单时返回 nil
+func (r *InsightRepository) GetRecurringScheduleByOrderID(ctx context.Context, orderID string) (*insight.RecurringSchedule, error) {
+	var schedule insight.RecurringSchedule
+	err := r.DB(ctx).Where("order_id = ?", orderID).First(&schedule).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// CreateRecurringSchedule 创建周期工单执行计划
+func (r *InsightRepository) CreateRecurringSchedule(ctx context.Context, schedule *insight.RecurringSchedule) error {
+	return r.DB(ctx).Create(schedule).Error
+}
+
+// UpdateRecurringSchedule 更新周期工单执行计划
+func (r *InsightRepository) UpdateRecurringSchedule(ctx context.Context, id uint, updates map[string]interface{}) error {
+	return r.DB(ctx).Model(&insight.RecurringSchedule{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// TransitRecurringSchedule 更新执行计划状态（仅当状态仍为 fromState 时更新，返回是否更新成功）
+func (r *InsightRepository) TransitRecurringSchedule(ctx context.Context, id uint, fromState insight.RecurringState, updates map[string]interface{}) (bool, error) {
+	result := r.DB(ctx).Model(&insight.RecurringSchedule{}).
+		Where("id = ? AND state = ?", id, fromState).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetDueRecurringSchedules 获取工单已批准、到期（或尚未计算下次执行时间）的执行计划
+func (r *InsightRepository) GetDueRecurringSchedules(ctx context.Context, now time.Time) ([]insight.RecurringSchedule, error) {
+	var schedules []insight.RecurringSchedule
+	if err := r.DB(ctx).
+		Select("recurring_schedules.*").
+		Joins("JOIN order_records ON order_records.order_id = recurring_schedules.order_id").
+		Where("order_records.progress = ?", insight.ProgressApproved).
+		Where("recurring_schedules.state = ?", insight.RecurringStateActive).
+		Where("(recurring_schedules.next_run_at IS NULL OR recurring_schedules.next_run_at <= ?)", now).
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+// AdvanceRecurringSchedule 将下次执行时间从 current 推进到 next（条件更新，多节点同时轮询时只有一个节点推进成功并执行）
+func (r *InsightRepository) AdvanceRecurringSchedule(ctx context.Context, id uint, current *time.Time, next time.Time) (bool, error) {
+	query := r.DB(ctx).Model(&insight.RecurringSchedule{}).
+		Where("id = ?", id).
+		Where("state = ?", insight.RecurringStateActive)
+	if current == nil {
+		query = query.Where("next_run_at IS NULL")
+	} else {
+		query = query.Where("next_run_at = ?", *current)
+	}
+	result := query.Update("next_run_at", next)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetRecurringRuns 获取周期工单的执行记录
+func (r *InsightRepository) GetRecurringRuns(ctx context.Context, orderID string, page, pageSize int) ([]insight.RecurringRun, int64, error) {
+	var runs []insight.RecurringRun
+	var total int64
+	query := r.DB(ctx).Model(&insight.RecurringRun{}).Where("order_id = ?", orderID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&runs).Error; err != nil {
+		return nil, 0, err
+	}
+	return runs, total, nil
+}
+
+// CreateRecurringRun 创建周期工单执行记录
+func (r *InsightRepository) CreateRecurringRun(ctx context.Context, run *insight.RecurringRun) error {
+	return r.DB(ctx).Create(run).Error
+}
+
+// UpdateRecurringRun 更新周期工单执行记录
+func (r *InsightRepository) UpdateRecurringRun(ctx context.Context, id uint, updates map[string]interface{}) error {
+	return r.DB(ctx).Model(&insight.RecurringRun{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// FailInterruptedRecurringRuns 将仍为执行中的记录标记为失败（持有工单执行锁时调用，说明上一次执行已中断）
+func (r *InsightRepository) FailInterruptedRecurringRuns(ctx context.Context, scheduleID uint, reason string) (int64, error) {
+	result := r.DB(ctx).Model(&insight.RecurringRun{}).
+		Where("schedule_id = ? AND state = ?", scheduleID, insight.RecurringRunRunning).
+		Updates(map[string]interface{}{
+			"state":       insight.RecurringRunFailed,
+			"error":       reason,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
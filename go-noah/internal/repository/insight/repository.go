@@ -90,6 +90,24 @@ func (r *InsightRepository) GetDBConfigByInstanceID(ctx context.Context, instanc
 	return &config, nil
 }
 
+// GetDBConfigByID 根据ID获取数据库配置
+func (r *InsightRepository) GetDBConfigByID(ctx context.Context, id uint) (*insight.DBConfig, error) {
+	var config insight.DBConfig
+	if err := r.DB(ctx).First(&config, id).Error; err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// GetReplicaDBConfigs 获取源实例的从库和沙箱实例配置
+func (r *InsightRepository) GetReplicaDBConfigs(ctx context.Context, sourceInstanceID string) ([]insight.DBConfig, error) {
+	var configs []insight.DBConfig
+	if err := r.DB(ctx).Where("source_instance_id = ?", sourceInstanceID).Order("id").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+	return configs, nil
+}
+
 // CreateDBConfig 创建数据库配置
 func (r *InsightRepository) CreateDBConfig(ctx context.Context, config *insight.DBConfig) error {
 	return r.DB(ctx).Create(config).Error
@@ -0,0 +1,157 @@
+package insight
+
+import (
+	"context"
+	"errors"
+	"go-noah/internal/model/insight"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ============ 工单定时执行 ============
+
+// ScheduledExecutionWithOrder 定时执行记录（包含工单标题和进度）
+type ScheduledExecutionWithOrder struct {
+	insight.ScheduledExecution
+	Title         string           `json:"title"`
+	OrderProgress insight.Progress `json:"order_progress"`
+	Applicant     string           `json:"applicant"`
+}
+
+// ScheduledExecutionQueryParams 定时执行记录查询参数
+type ScheduledExecutionQueryParams struct {
+	Page     int
+	PageSize int
+	State    string
+	OrderID  string
+	Upcoming bool   // 只查询未结束的记录（等待执行、执行中、等待确认）
+	Viewer   string // 非空时只返回该用户可见的工单（不限制访问的工单，或该用户为申请人、审核人、执行人的工单）
+}
+
+// GetScheduledExecutions 获取定时执行记录列表（按计划时间升序）
+func (r *InsightRepository) GetScheduledExecutions(ctx context.Context, params *ScheduledExecutionQueryParams) ([]ScheduledExecutionWithOrder, int64, error) {
+	var executions []ScheduledExecutionWithOrder
+	var total int64
+
+	query := r.DB(ctx).Table("scheduled_executions a").
+		Select("a.*, b.title, b.progress AS order_progress, b.applicant").
+		Joins("LEFT JOIN order_records b ON a.order_id = b.order_id").
+		Where("a.deleted_at IS NULL")
+	if params.State != "" {
+		query = query.Where("a.state = ?", params.State)
+	} else if params.Upcoming {
+		query = query.Where("a.state IN ?", []insight.ScheduleState{
+			insight.ScheduleStatePending, insight.ScheduleStateRunning, insight.ScheduleStateAsking,
+		})
+	}
+	if params.OrderID != "" {
+		query = query.Where("a.order_id = ?", params.OrderID)
+	}
+	if params.Viewer != "" {
+		// 审核人、执行人为 JSON 数组（字符串或 {"user": ...} 对象）
+		query = query.Where("b.is_restrict_access = 0 OR b.applicant = ? OR JSON_SEARCH(b.approver, 'one', ?) IS NOT NULL OR JSON_SEARCH(b.executor, 'one', ?) IS NOT NULL",
+			params.Viewer, params.Viewer, params.Viewer)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	offset := (params.Page - 1) * params.PageSize
+	if err := query.Order("a.next_attempt_at ASC, a.id ASC").Offset(offset).Limit(params.PageSize).Scan(&executions).Error; err != nil {
+		return nil, 0, err
+	}
+	return executions, total, nil
+}
+
+// GetScheduledExecution 根据ID获取定时执行记录
+func (r *InsightRepository) GetScheduledExecution(ctx context.Context, id uint) (*insight.ScheduledExecution, error) {
+	var execution insight.ScheduledExecution
+	if err := r.DB(ctx).First(&execution, id).Error; err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// GetOpenScheduledExecution 获取工单尚未触发的定时执行记录（等待执行或等待确认），不存在时返回 nil
+func (r *InsightRepository) GetOpenScheduledExecution(ctx context.Context, orderID string) (*insight.ScheduledExecution, error) {
+	var execution insight.ScheduledExecution
+	err := r.DB(ctx).
+		Where("order_id = ?", orderID).
+		Where("state IN ?", []insight.ScheduleState{insight.ScheduleStatePending, insight.ScheduleStateAsking}).
+		Order("id DESC").
+		First(&execution).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &execution, nil
+}
+
+// CreateScheduledExecution 创建定时执行记录
+func (r *InsightRepository) CreateScheduledExecution(ctx context.Context, execution *insight.ScheduledExecution) error {
+	return r.DB(ctx).Create(execution).Error
+}
+
+// UpdateScheduledExecution 更新定时执行记录（仅当状态仍为 fromState 时更新，返回是否更新成功）
+func (r *InsightRepository) UpdateScheduledExecution(ctx context.Context, id uint, fromState insight.ScheduleState, updates map[string]interface{}) (bool, error) {
+	result := r.DB(ctx).Model(&insight.ScheduledExecution{}).
+		Where("id = ? AND state = ?", id, fromState).
+		Updates(updates)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetDueScheduledExecutions 获取已到期的待执行记录
+func (r *InsightRepository) GetDueScheduledExecutions(ctx context.Context, now time.Time, limit int) ([]insight.ScheduledExecution, error) {
+	var executions []insight.ScheduledExecution
+	if err := r.DB(ctx).
+		Where("state = ?", insight.ScheduleStatePending).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&executions).Error; err != nil {
+		return nil, err
+	}
+	return executions, nil
+}
+
+// ClaimScheduledExecution 认领待执行记录（条件更新，只有一个节点能认领成功），同时累加尝试次数
+func (r *InsightRepository) ClaimScheduledExecution(ctx context.Context, id uint, owner string, claimedUntil time.Time) (bool, error) {
+	return r.UpdateScheduledExecution(ctx, id, insight.ScheduleStatePending, map[string]interface{}{
+		"state":         insight.ScheduleStateRunning,
+		"owner":         owner,
+		"claimed_until": claimedUntil,
+		"attempts":      gorm.Expr("attempts + 1"),
+	})
+}
+
+// ReleaseExpiredScheduledExecutions 认领已过期的记录（认领节点在触发执行过程中宕机）重新置为待执行
+func (r *InsightRepository) ReleaseExpiredScheduledExecutions(ctx context.Context, now time.Time) (int64, error) {
+	result := r.DB(ctx).Model(&insight.ScheduledExecution{}).
+		Where("state = ?", insight.ScheduleStateRunning).
+		Where("claimed_until < ?", now).
+		Updates(map[string]interface{}{
+			"state":           insight.ScheduleStatePending,
+			"owner":           "",
+			"claimed_until":   nil,
+			"next_attempt_at": now,
+		})
+	return result.RowsAffected, result.Error
+}
+
+// CancelScheduledExecutions 取消工单尚未触发的定时执行记录（工单已手动执行或被驳回、关闭）
+func (r *InsightRepository) CancelScheduledExecutions(ctx context.Context, orderID string, reason string) error {
+	return r.DB(ctx).Model(&insight.ScheduledExecution{}).
+		Where("order_id = ?", orderID).
+		Where("state IN ?", []insight.ScheduleState{insight.ScheduleStatePending, insight.ScheduleStateAsking}).
+		Updates(map[string]interface{}{
+			"state":       insight.ScheduleStateCancelled,
+			"last_error":  reason,
+			"finished_at": time.Now(),
+		}).Error
+}
@@ -23,6 +23,13 @@ func InitInsightRouter(r gin.IRouter, jwt *jwt.JWT, e *casbin.SyncedEnforcer, lo
 			authRouter.PUT("/environments/:id", insight.EnvironmentHandlerApp.UpdateEnvironment)
 			authRouter.DELETE("/environments/:id", insight.EnvironmentHandlerApp.DeleteEnvironment)
 
+			// ============ 维护窗口 ============
+			authRouter.GET("/maintenance-windows", insight.MaintenanceHandlerApp.GetMaintenanceWindows)
+			authRouter.POST("/maintenance-windows", insight.MaintenanceHandlerApp.CreateMaintenanceWindow)
+			authRouter.PUT("/maintenance-windows/:id", insight.MaintenanceHandlerApp.UpdateMaintenanceWindow)
+			authRouter.DELETE("/maintenance-windows/:id", insight.MaintenanceHandlerApp.DeleteMaintenanceWindow)
+			authRouter.GET("/maintenance-windows/status", insight.MaintenanceHandlerApp.GetMaintenanceStatus) // 查询实例当前是否允许执行
+
 			// ============ 数据库配置管理 ============
 			authRouter.GET("/dbconfigs", insight.DBConfigHandlerApp.GetDBConfigs)
 			authRouter.GET("/dbconfigs/:instance_id", insight.DBConfigHandlerApp.GetDBConfig)
@@ -88,17 +95,40 @@ func InitInsightRouter(r gin.IRouter, jwt *jwt.JWT, e *casbin.SyncedEnforcer, lo
 			authRouter.GET("/orders/my", insight.OrderHandlerApp.GetMyOrders)
 			authRouter.GET("/orders/:order_id", insight.OrderHandlerApp.GetOrder)
 			authRouter.POST("/orders", insight.OrderHandlerApp.CreateOrder)
+			authRouter.POST("/orders/rollback", insight.OrderHandlerApp.CreateRollbackOrder)              // 基于任务回滚SQL创建回滚工单
 			authRouter.GET("/orders/tables/:instance_id/:schema", insight.OrderHandlerApp.GetOrderTables) // 工单场景获取表列表（不检查DAS权限）
 			authRouter.PUT("/orders/progress", insight.OrderHandlerApp.UpdateOrderProgress)
-			authRouter.POST("/orders/approve", insight.OrderHandlerApp.ApproveOrder) // 审批工单
+			authRouter.POST("/orders/approve", insight.OrderHandlerApp.ApproveOrder)  // 审批工单
+			authRouter.POST("/orders/dry-run", insight.OrderHandlerApp.DryRunOrder)  // DML工单试运行（执行后回滚）
 			authRouter.GET("/orders/:order_id/tasks", insight.OrderHandlerApp.GetOrderTasks)
 			authRouter.GET("/orders/:order_id/tasks/:task_id/rollback-sql", insight.OrderHandlerApp.GetTaskRollbackSQL)
+			authRouter.GET("/orders/:order_id/tasks/:task_id/rollback-sql/page", insight.OrderHandlerApp.GetTaskRollbackSQLPage)
+			authRouter.GET("/orders/:order_id/tasks/:task_id/rollback-sql/download", insight.OrderHandlerApp.DownloadTaskRollbackSQL)
+			authRouter.GET("/orders/:order_id/tasks/:task_id/execute-log", insight.OrderHandlerApp.DownloadTaskExecuteLog)
+			authRouter.GET("/orders/:order_id/tasks/:task_id/export-file", insight.OrderHandlerApp.DownloadExportFile) // 下载导出文件（仅申请人）
+			authRouter.GET("/orders/:order_id/tasks/:task_id/export-key", insight.OrderHandlerApp.GetExportFileKey)    // 获取导出文件解压密码（仅申请人）
 			authRouter.PUT("/orders/tasks/progress", insight.OrderHandlerApp.UpdateTaskProgress)
 			authRouter.POST("/orders/tasks/execute", insight.OrderHandlerApp.ExecuteTask)
+			authRouter.POST("/orders/tasks/cancel", insight.OrderHandlerApp.CancelTask) // 取消正在执行的任务
 			authRouter.POST("/orders/ghost/control", insight.OrderHandlerApp.ControlGhost) // gh-ost 控制（暂停/取消/速度调节）
 			authRouter.GET("/orders/:order_id/logs", insight.OrderHandlerApp.GetOrderLogs)
 			authRouter.GET("/orders/:order_id/ghost-progress", insight.OrderHandlerApp.GetGhostProgress) // 获取 gh-ost 最新进度（从 Redis）
 
+			// ============ 工单定时执行 ============
+			authRouter.GET("/scheduled-executions", insight.ScheduleHandlerApp.GetScheduledExecutions)
+			authRouter.POST("/scheduled-executions/:id/confirm", insight.ScheduleHandlerApp.ConfirmScheduledExecution) // 确认错过计划时间的定时执行
+
+			// ============ 周期工单 ============
+			authRouter.GET("/recurring-orders", insight.RecurringHandlerApp.GetRecurringOrders)
+			authRouter.GET("/recurring-orders/:order_id/runs", insight.RecurringHandlerApp.GetRecurringRuns)
+			authRouter.POST("/recurring-orders/:order_id/suspend", insight.RecurringHandlerApp.SuspendRecurringOrder)
+			authRouter.POST("/recurring-orders/:order_id/resume", insight.RecurringHandlerApp.ResumeRecurringOrder)
+
+			// ============ binlog闪回 ============
+			authRouter.POST("/flashback", insight.FlashbackHandlerApp.CreateFlashback)
+			authRouter.GET("/flashback/:job_id", insight.FlashbackHandlerApp.GetFlashback)
+			authRouter.GET("/flashback/:job_id/download", insight.FlashbackHandlerApp.DownloadFlashback)
+
 			// ============ SQL审核 ============
 			authRouter.POST("/inspect/sql", insight.InspectHandlerApp.InspectSQL)
 			authRouter.GET("/inspect/params", insight.InspectHandlerApp.GetInspectParams)
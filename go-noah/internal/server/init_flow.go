@@ -24,6 +24,16 @@ func InitDefaultFlowDefinitions() error {
 		return err
 	}
 
+	// ARCHIVE工单流程
+	if err := initFlowDefinition("order_archive", "order_archive_default", "ARCHIVE工单审批流程", "ARCHIVE工单默认审批流程"); err != nil {
+		return err
+	}
+
+	// MIGRATE工单流程
+	if err := initFlowDefinition("order_migrate", "order_migrate_default", "MIGRATE工单审批流程", "MIGRATE工单默认审批流程"); err != nil {
+		return err
+	}
+
 	return nil
 }
 
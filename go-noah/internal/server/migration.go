@@ -84,7 +84,13 @@ func (m *MigrateServer) Start(ctx context.Context) error {
 		&insight.OrderTask{},
 		&insight.OrderOpLog{},
 		&insight.OrderMessage{},
+		&insight.OrderDryRun{},
 		&insight.InspectParams{},
+		&insight.MaintenanceWindow{},
+		&insight.ScheduledExecution{},
+		&insight.RecurringSchedule{},
+		&insight.RecurringRun{},
+		&insight.ArchiveCheckpoint{},
 	); err != nil {
 		m.log.Error("user migrate error", zap.Error(err))
 		return err
@@ -172,7 +178,13 @@ func AutoMigrateTables(db *gorm.DB, logger *log.Logger) error {
 		&insight.OrderTask{},
 		&insight.OrderOpLog{},
 		&insight.OrderMessage{},
+		&insight.OrderDryRun{},
 		&insight.InspectParams{},
+		&insight.MaintenanceWindow{},
+		&insight.ScheduledExecution{},
+		&insight.RecurringSchedule{},
+		&insight.RecurringRun{},
+		&insight.ArchiveCheckpoint{},
 	); err != nil {
 		logger.Error("AutoMigrate tables error", zap.Error(err))
 		return err
@@ -1169,7 +1181,27 @@ func (m *MigrateServer) initialFlowDefinitions(ctx context.Context) error {
 		Status:      1,
 	}
 
-	flows := []model.FlowDefinition{ddlFlow, dmlFlow, exportFlow}
+	// 数据归档审批流程
+	archiveFlow := model.FlowDefinition{
+		Code:        "order_archive",
+		Name:        "数据归档审批流程",
+		Type:        "order_archive",
+		Description: "用于数据归档的审批流程",
+		Version:     1,
+		Status:      1,
+	}
+
+	// 数据迁移审批流程
+	migrateFlow := model.FlowDefinition{
+		Code:        "order_migrate",
+		Name:        "数据迁移审批流程",
+		Type:        "order_migrate",
+		Description: "用于跨实例数据迁移的审批流程",
+		Version:     1,
+		Status:      1,
+	}
+
+	flows := []model.FlowDefinition{ddlFlow, dmlFlow, exportFlow, archiveFlow, migrateFlow}
 
 	for _, flow := range flows {
 		var existing model.FlowDefinition
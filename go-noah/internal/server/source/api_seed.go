@@ -38,11 +38,18 @@ var ApiSeedData = []ApiSeedItem{
 	{Group: "审批流程", Name: "审批通过", Path: "/v1/flow/task/approve", Method: "POST"},
 	{Group: "审批流程", Name: "审批通过", Path: "/v1/flow/task/reject", Method: "POST"},
 	{Group: "审批流程", Name: "获取我的待办任务", Path: "/v1/flow/tasks/pending", Method: "GET"},
+	{Group: "数据库工单管理", Name: "创建binlog闪回任务", Path: "/v1/insight/flashback", Method: "POST"},
+	{Group: "数据库工单管理", Name: "获取binlog闪回任务", Path: "/v1/insight/flashback/:job_id", Method: "GET"},
+	{Group: "数据库工单管理", Name: "下载binlog闪回文件", Path: "/v1/insight/flashback/:job_id/download", Method: "GET"},
 	{Group: "数据库工单管理", Name: "审批工单", Path: "/v1/insight/orders/approve", Method: "POST"},
 	{Group: "数据库工单管理", Name: "控制 gh-ost 执行", Path: "/v1/insight/orders/ghost/control", Method: "POST"},
 	{Group: "数据库工单管理", Name: "更新工单进度", Path: "/v1/insight/orders/progress", Method: "PUT"},
 	{Group: "数据库工单管理", Name: "执行工单任务", Path: "/v1/insight/orders/tasks/execute", Method: "POST"},
+	{Group: "数据库工单管理", Name: "取消工单任务", Path: "/v1/insight/orders/tasks/cancel", Method: "POST"},
 	{Group: "数据库工单管理", Name: "更新任务进度", Path: "/v1/insight/orders/tasks/progress", Method: "PUT"},
+	{Group: "数据库工单管理", Name: "确认定时执行", Path: "/v1/insight/scheduled-executions/:id/confirm", Method: "POST"},
+	{Group: "数据库工单管理", Name: "暂停周期工单", Path: "/v1/insight/recurring-orders/:order_id/suspend", Method: "POST"},
+	{Group: "数据库工单管理", Name: "恢复周期工单", Path: "/v1/insight/recurring-orders/:order_id/resume", Method: "POST"},
 	{Group: "数据库服务", Name: "获取数据列信息", Path: "/v1/insight/das/columns/:instance_id/:schema/:table", Method: "GET"},
 	{Group: "数据库服务", Name: "获取收藏", Path: "/v1/insight/das/favorites", Method: "GET"},
 	{Group: "数据库服务", Name: "创建收藏", Path: "/v1/insight/das/favorites", Method: "POST"},
@@ -55,14 +62,25 @@ var ApiSeedData = []ApiSeedItem{
 	{Group: "数据库服务", Name: "获取实例下的数据库列表", Path: "/v1/insight/das/schemas/:instance_id", Method: "GET"},
 	{Group: "数据库服务", Name: "查询实例数据库", Path: "/v1/insight/das/tables/:instance_id/:schema", Method: "GET"},
 	{Group: "数据库服务", Name: "获取数据库环境", Path: "/v1/insight/environments", Method: "GET"},
+	{Group: "数据库服务", Name: "查询维护窗口状态", Path: "/v1/insight/maintenance-windows/status", Method: "GET"},
 	{Group: "数据库服务", Name: "审核SQL", Path: "/v1/insight/inspect/sql", Method: "POST"},
 	{Group: "数据库服务", Name: "获取工单列表", Path: "/v1/insight/orders", Method: "GET"},
 	{Group: "数据库服务", Name: "创建工单", Path: "/v1/insight/orders", Method: "POST"},
+	{Group: "数据库服务", Name: "创建回滚工单", Path: "/v1/insight/orders/rollback", Method: "POST"},
+	{Group: "数据库服务", Name: "试运行工单", Path: "/v1/insight/orders/dry-run", Method: "POST"},
 	{Group: "数据库服务", Name: "获取工单详情", Path: "/v1/insight/orders/:order_id", Method: "GET"},
 	{Group: "数据库服务", Name: "获取ghost进程信息", Path: "/v1/insight/orders/:order_id/ghost-progress", Method: "GET"},
 	{Group: "数据库服务", Name: "获取工单执行日志", Path: "/v1/insight/orders/:order_id/logs", Method: "GET"},
+	{Group: "数据库服务", Name: "获取定时执行列表", Path: "/v1/insight/scheduled-executions", Method: "GET"},
+	{Group: "数据库服务", Name: "获取周期工单列表", Path: "/v1/insight/recurring-orders", Method: "GET"},
+	{Group: "数据库服务", Name: "获取周期工单执行记录", Path: "/v1/insight/recurring-orders/:order_id/runs", Method: "GET"},
 	{Group: "数据库服务", Name: "获取任务信息", Path: "/v1/insight/orders/:order_id/tasks", Method: "GET"},
 	{Group: "数据库服务", Name: "获取回滚语句", Path: "/v1/insight/orders/:order_id/tasks/:task_id/rollback-sql", Method: "GET"},
+	{Group: "数据库服务", Name: "分页获取回滚语句", Path: "/v1/insight/orders/:order_id/tasks/:task_id/rollback-sql/page", Method: "GET"},
+	{Group: "数据库服务", Name: "下载回滚语句", Path: "/v1/insight/orders/:order_id/tasks/:task_id/rollback-sql/download", Method: "GET"},
+	{Group: "数据库服务", Name: "下载执行日志", Path: "/v1/insight/orders/:order_id/tasks/:task_id/execute-log", Method: "GET"},
+	{Group: "数据库服务", Name: "下载导出文件", Path: "/v1/insight/orders/:order_id/tasks/:task_id/export-file", Method: "GET"},
+	{Group: "数据库服务", Name: "获取导出文件解压密码", Path: "/v1/insight/orders/:order_id/tasks/:task_id/export-key", Method: "GET"},
 	{Group: "数据库服务", Name: "获取我的工单", Path: "/v1/insight/orders/my", Method: "GET"},
 	{Group: "数据库服务", Name: "获取工单场景的表列表", Path: "/v1/insight/orders/tables/:instance_id/:schema", Method: "GET"},
 	{Group: "数据库管理", Name: "创建角色权限", Path: "/v1/insight/das/permissions/roles", Method: "POST"},
@@ -86,6 +104,10 @@ var ApiSeedData = []ApiSeedItem{
 	{Group: "数据库管理", Name: "删除环境", Path: "/v1/insight/environments/:id", Method: "DELETE"},
 	{Group: "数据库管理", Name: "修改数据库环境", Path: "/v1/insight/environments/:id", Method: "PUT"},
 	{Group: "数据库管理", Name: "获取审核参数", Path: "/v1/insight/inspect/params", Method: "GET"},
+	{Group: "数据库管理", Name: "获取维护窗口", Path: "/v1/insight/maintenance-windows", Method: "GET"},
+	{Group: "数据库管理", Name: "创建维护窗口", Path: "/v1/insight/maintenance-windows", Method: "POST"},
+	{Group: "数据库管理", Name: "删除维护窗口", Path: "/v1/insight/maintenance-windows/:id", Method: "DELETE"},
+	{Group: "数据库管理", Name: "修改维护窗口", Path: "/v1/insight/maintenance-windows/:id", Method: "PUT"},
 	{Group: "数据库管理", Name: "创建审查参数", Path: "/v1/insight/inspect/params", Method: "POST"},
 	{Group: "数据库管理", Name: "删除审核参数", Path: "/v1/insight/inspect/params/:id", Method: "DELETE"},
 	{Group: "数据库管理", Name: "获取审核参数详情", Path: "/v1/insight/inspect/params/:id", Method: "GET"},
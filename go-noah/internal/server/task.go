@@ -75,6 +75,95 @@ func (t *TaskServer) Start(ctx context.Context) error {
 		}
 	}
 
+	// 清理过期导出文件任务
+	if t.insightTask != nil {
+		purgeCron := t.conf.GetString("crontab.purge_export_files")
+		if purgeCron == "" {
+			purgeCron = "0 * * * *" // 默认每小时
+		}
+		_, err = t.scheduler.Cron(purgeCron).Do(func() {
+			if err := t.insightTask.PurgeExpiredExportFiles(ctx); err != nil {
+				t.log.Error("清理过期导出文件失败", zap.Error(err))
+			}
+		})
+		if err != nil {
+			t.log.Error("注册清理过期导出文件任务失败", zap.Error(err))
+		} else {
+			t.log.Info("已注册清理过期导出文件任务", zap.String("cron", purgeCron))
+		}
+	}
+
+	// 清理过期归档文件任务
+	if t.insightTask != nil {
+		purgeArchiveCron := t.conf.GetString("crontab.purge_archive_files")
+		if purgeArchiveCron == "" {
+			purgeArchiveCron = "15 3 * * *" // 默认每天凌晨3点15分
+		}
+		_, err = t.scheduler.Cron(purgeArchiveCron).Do(func() {
+			if err := t.insightTask.PurgeExpiredArchiveFiles(ctx); err != nil {
+				t.log.Error("清理过期归档文件失败", zap.Error(err))
+			}
+		})
+		if err != nil {
+			t.log.Error("注册清理过期归档文件任务失败", zap.Error(err))
+		} else {
+			t.log.Info("已注册清理过期归档文件任务", zap.String("cron", purgeArchiveCron))
+		}
+	}
+
+	// 清理过期闪回文件任务
+	purgeFlashbackCron := t.conf.GetString("crontab.purge_flashback_files")
+	if purgeFlashbackCron == "" {
+		purgeFlashbackCron = "30 * * * *" // 默认每小时
+	}
+	_, err = t.scheduler.Cron(purgeFlashbackCron).Do(func() {
+		purged, err := service.FlashbackServiceApp.PurgeExpiredFiles(ctx)
+		if err != nil {
+			t.log.Error("清理过期闪回文件失败", zap.Error(err))
+		} else if purged > 0 {
+			t.log.Info("已清理过期闪回文件", zap.Int("count", purged))
+		}
+	})
+	if err != nil {
+		t.log.Error("注册清理过期闪回文件任务失败", zap.Error(err))
+	} else {
+		t.log.Info("已注册清理过期闪回文件任务", zap.String("cron", purgeFlashbackCron))
+	}
+
+	// 核对中断任务（服务在执行过程中重启时任务会停留在“执行中”），启动时核对一次并定期核对
+	reconcileCron := t.conf.GetString("crontab.reconcile_stuck_tasks")
+	if reconcileCron == "" {
+		reconcileCron = "*/2 * * * *" // 默认每2分钟
+	}
+	reconcile := func() {
+		if err := service.InsightServiceApp.ReconcileStuckTasks(ctx); err != nil {
+			t.log.Error("核对中断任务失败", zap.Error(err))
+		}
+	}
+	go reconcile()
+	_, err = t.scheduler.Cron(reconcileCron).Do(reconcile)
+	if err != nil {
+		t.log.Error("注册核对中断任务失败", zap.Error(err))
+	} else {
+		t.log.Info("已注册核对中断任务", zap.String("cron", reconcileCron))
+	}
+
+	// 周期工单（按 cron 表达式重复执行的 DML 工单）
+	recurringCron := t.conf.GetString("crontab.run_recurring_orders")
+	if recurringCron == "" {
+		recurringCron = "* * * * *" // 默认每分钟
+	}
+	_, err = t.scheduler.Cron(recurringCron).Do(func() {
+		if err := service.InsightServiceApp.RunDueRecurringOrders(ctx); err != nil {
+			t.log.Error("检查周期工单失败", zap.Error(err))
+		}
+	})
+	if err != nil {
+		t.log.Error("注册周期工单任务失败", zap.Error(err))
+	} else {
+		t.log.Info("已注册周期工单任务", zap.String("cron", recurringCron))
+	}
+
 	// 初始化工单定时任务调度器
 	orderScheduler := task.GetOrderScheduler()
 	if orderScheduler != nil {
@@ -86,24 +175,9 @@ func (t *TaskServer) Start(ctx context.Context) error {
 			)
 			return service.InsightServiceApp.ExecuteOrder(ctx, orderID, username)
 		})
-		// 启动调度器（启动时扫描一次）
+		// 启动调度器（竞选调度节点，当选后轮询到期的定时执行记录）
 		orderScheduler.Start(ctx)
 		t.log.Info("工单定时任务调度器已启动并设置执行器")
-
-		// 注册定期扫描任务（每30秒扫描一次，发现需要注册的定时工单）
-		scanCron := t.conf.GetString("crontab.scan_scheduled_orders")
-		if scanCron == "" {
-			scanCron = "*/30 * * * * *" // 默认每30秒
-		}
-		_, err = t.scheduler.CronWithSeconds(scanCron).Do(func() {
-			t.log.Debug("开始扫描需要注册的定时工单", zap.Time("time", time.Now()))
-			orderScheduler.ScanAndRegister(ctx)
-		})
-		if err != nil {
-			t.log.Error("注册定时工单扫描任务失败", zap.Error(err))
-		} else {
-			t.log.Info("已注册定时工单扫描任务", zap.String("cron", scanCron))
-		}
 	} else {
 		t.log.Warn("工单定时任务调度器获取失败")
 	}
@@ -113,6 +187,8 @@ func (t *TaskServer) Start(ctx context.Context) error {
 }
 func (t *TaskServer) Stop(ctx context.Context) error {
 	t.scheduler.Stop()
+	// 释放调度节点租约，其他节点随后接管定时工单
+	task.GetOrderScheduler().Stop()
 	t.log.Info("TaskServer stop...")
 	return nil
 }
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/internal/model/insight"
+	"go-noah/internal/orders/executor"
+
+	"github.com/google/uuid"
+)
+
+// ValidateArchiveOrder 校验归档工单：仅支持 MySQL/TiDB，每条语句为 SELECT * FROM 源表 WHERE 归档条件
+// 校验通过后将归档选项写入工单
+func (s *InsightService) ValidateArchiveOrder(ctx context.Context, order *insight.OrderRecord, opts *insight.ArchiveOptions) error {
+	if order.DBType != insight.DbTypeMySQL && order.DBType != insight.DbTypeTiDB {
+		return errors.New("归档工单仅支持 MySQL/TiDB")
+	}
+	if opts == nil {
+		return errors.New("归档工单需要设置归档选项")
+	}
+	if !insight.IsValidArchiveMode(string(opts.Mode)) {
+		return fmt.Errorf("不支持的归档目标类型: %s", opts.Mode)
+	}
+	if opts.Mode == insight.ArchiveModeTable && opts.TargetInstanceID != "" && opts.TargetInstanceID != order.InstanceID.String() {
+		if _, err := uuid.Parse(opts.TargetInstanceID); err != nil {
+			return fmt.Errorf("目标实例ID格式错误: %s", opts.TargetInstanceID)
+		}
+		if _, err := s.targetInstanceConfig(ctx, opts.TargetInstanceID); err != nil {
+			return err
+		}
+	}
+
+	sqls, err := s.splitSQLText(order.DBType, order.Content)
+	if err != nil {
+		return err
+	}
+	if len(sqls) == 0 {
+		return errors.New("归档工单内容不能为空")
+	}
+	for _, sql := range sqls {
+		if err := executor.ValidateArchiveSQL(sql, order.Schema); err != nil {
+			return err
+		}
+	}
+
+	order.ArchiveOptions, err = json.Marshal(opts)
+	return err
+}
+
+// newArchiveConfig 根据工单的归档选项构造执行器的归档配置
+func newArchiveConfig(order *insight.OrderRecord) *executor.ArchiveConfig {
+	var opts insight.ArchiveOptions
+	if len(order.ArchiveOptions) > 0 {
+		_ = json.Unmarshal(order.ArchiveOptions, &opts)
+	}
+	return &executor.ArchiveConfig{
+		Mode:             string(opts.Mode),
+		TargetInstanceID: opts.TargetInstanceID,
+		TargetSchema:     opts.TargetSchema,
+		TargetTable:      opts.TargetTable,
+		KeepSource:       opts.KeepSource,
+		Store:            archiveStore{instanceResolver{s: InsightServiceApp}},
+	}
+}
+
+// targetInstanceConfig 获取归档、数据迁移目标实例的连接配置（仅支持 MySQL/TiDB）
+func (s *InsightService) targetInstanceConfig(ctx context.Context, instanceID string) (*executor.DBConfig, error) {
+	dbConfig, err := s.getRepo().GetDBConfigByInstanceID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("目标实例不存在: %w", err)
+	}
+	if dbConfig.DbType != insight.DbTypeMySQL && dbConfig.DbType != insight.DbTypeTiDB {
+		return nil, errors.New("目标实例仅支持 MySQL/TiDB")
+	}
+	return &executor.DBConfig{
+		InstanceID: dbConfig.InstanceID.String(),
+		Hostname:   dbConfig.Hostname,
+		Port:       dbConfig.Port,
+		UserName:   dbConfig.UserName,
+		Password:   dbConfig.Password,
+		DBType:     string(dbConfig.DbType),
+
+		ConnOptions: dbConfig.ConnOptions(),
+	}, nil
+}
+
+// instanceResolver 目标实例配置
+type instanceResolver struct {
+	s *InsightService
+}
+
+func (r instanceResolver) TargetConfig(ctx context.Context, instanceID string) (*executor.DBConfig, error) {
+	return r.s.targetInstanceConfig(ctx, instanceID)
+}
+
+// archiveStore 归档任务的断点和目标实例配置存储
+type archiveStore struct {
+	instanceResolver
+}
+
+func (st archiveStore) LoadCheckpoint(ctx context.Context, taskID string) (*executor.ArchiveCheckpoint, error) {
+	checkpoint, err := st.s.getRepo().GetArchiveCheckpoint(ctx, taskID)
+	if err != nil || checkpoint == nil {
+		return nil, err
+	}
+	return &executor.ArchiveCheckpoint{
+		LastPK:        checkpoint.LastPK,
+		ArchivedRows:  checkpoint.ArchivedRows,
+		DeletedRows:   checkpoint.DeletedRows,
+		FilePath:      checkpoint.FilePath,
+		FileOffset:    checkpoint.FileOffset,
+		PendingPK:     checkpoint.PendingPK,
+		PendingOffset: checkpoint.PendingOffset,
+		PendingRows:   checkpoint.PendingRows,
+	}, nil
+}
+
+func (st archiveStore) SaveCheckpoint(ctx context.Context, taskID string, checkpoint *executor.ArchiveCheckpoint) error {
+	repo := st.s.getRepo()
+	record, err := repo.GetArchiveCheckpoint(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if record == nil {
+		task, err := repo.GetTaskByID(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		record = &insight.ArchiveCheckpoint{TaskID: task.TaskID, OrderID: task.OrderID}
+	}
+	record.LastPK = checkpoint.LastPK
+	record.ArchivedRows = checkpoint.ArchivedRows
+	record.DeletedRows = checkpoint.DeletedRows
+	record.FilePath = checkpoint.FilePath
+	record.FileOffset = checkpoint.FileOffset
+	record.PendingPK = checkpoint.PendingPK
+	record.PendingOffset = checkpoint.PendingOffset
+	record.PendingRows = checkpoint.PendingRows
+	return repo.SaveArchiveCheckpoint(ctx, record)
+}
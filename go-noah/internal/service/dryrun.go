@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go-noah/internal/model/insight"
+	"go-noah/internal/orders/executor"
+	"go-noah/pkg/global"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const defaultDryRunTimeoutSeconds = 60
+
+// getDryRunTimeout 获取单个任务试运行的最长执行时间
+func getDryRunTimeout() time.Duration {
+	seconds := 0
+	if global.Conf != nil {
+		seconds = global.Conf.GetInt("dry_run.timeout_seconds")
+	}
+	if seconds <= 0 {
+		seconds = defaultDryRunTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// DryRunOrder 试运行DML工单：每个任务在独立事务中执行后回滚，结果保存到工单
+// 仅在工单实例的从库或沙箱实例上执行（库名与工单相同），不在工单实例上执行；
+// targetInstanceID 为空时使用第一个配置的从库或沙箱实例，实例开启只读（read_only/super_read_only）时返回错误
+func (s *InsightService) DryRunOrder(ctx context.Context, orderID, username, targetInstanceID string) (*insight.OrderDryRun, error) {
+	order, err := s.GetOrderByID(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("工单不存在: %s", err.Error())
+	}
+	if order.SQLType != insight.SQLTypeDML {
+		return nil, fmt.Errorf("仅DML工单支持试运行")
+	}
+	if order.Progress != insight.ProgressPending && order.Progress != insight.ProgressApproved {
+		return nil, fmt.Errorf("当前工单状态不允许试运行: %s", order.Progress)
+	}
+
+	dbConfig, err := s.getDryRunInstance(ctx, order.InstanceID.String(), targetInstanceID)
+	if err != nil {
+		return nil, err
+	}
+	if dbConfig.DbType != insight.DbTypeMySQL && dbConfig.DbType != insight.DbTypeTiDB {
+		return nil, fmt.Errorf("试运行仅支持MySQL/TiDB实例，当前实例类型: %s", dbConfig.DbType)
+	}
+	// 试运行在事务中执行后回滚，只读的从库会拒绝写入
+	if err := executor.NewMySQLExecutor(NewExecutorConfig(&order.OrderRecord, &insight.OrderTask{}, dbConfig)).CheckDryRunWritable(ctx); err != nil {
+		return nil, err
+	}
+
+	tasks, err := s.getRepo().GetOrderTasks(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, fmt.Errorf("工单没有可试运行的任务")
+	}
+
+	dryRun := &insight.OrderDryRun{
+		OrderID:          order.OrderID,
+		Username:         username,
+		TargetInstanceID: dbConfig.InstanceID,
+		Success:          true,
+	}
+	results := make([]*executor.DryRunResult, 0, len(tasks))
+	for i := range tasks {
+		config := NewExecutorConfig(&order.OrderRecord, &tasks[i], dbConfig)
+		taskCtx, cancel := context.WithTimeout(ctx, getDryRunTimeout())
+		result := executor.NewMySQLExecutor(config).DryRunDML(taskCtx)
+		cancel()
+
+		if result.Error != "" {
+			dryRun.Success = false
+			global.Logger.Warn("任务试运行失败",
+				zap.String("order_id", orderID),
+				zap.String("task_id", result.TaskID),
+				zap.String("error", result.Error),
+			)
+		}
+		dryRun.AffectedRows += result.AffectedRows
+		results = append(results, result)
+	}
+	if dryRun.Result, err = json.Marshal(results); err != nil {
+		return nil, err
+	}
+	if err := s.getRepo().CreateDryRun(ctx, dryRun); err != nil {
+		return nil, fmt.Errorf("保存试运行结果失败: %s", err.Error())
+	}
+
+	msg := fmt.Sprintf("在实例 %s:%d 上试运行工单，实际影响行数: %d", dbConfig.Hostname, dbConfig.Port, dryRun.AffectedRows)
+	if !dryRun.Success {
+		msg += "（部分任务失败）"
+	}
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: username,
+		OrderID:  order.OrderID,
+		Msg:      msg,
+	})
+	return dryRun, nil
+}
+
+// getDryRunInstance 获取试运行实例，只能是工单实例的从库或沙箱实例（实例配置中的源实例为工单实例）
+func (s *InsightService) getDryRunInstance(ctx context.Context, sourceInstanceID, targetInstanceID string) (*insight.DBConfig, error) {
+	if targetInstanceID == "" {
+		replicas, err := s.getRepo().GetReplicaDBConfigs(ctx, sourceInstanceID)
+		if err != nil {
+			return nil, err
+		}
+		if len(replicas) == 0 {
+			return nil, fmt.Errorf("工单实例未配置从库或沙箱实例，无法试运行")
+		}
+		return &replicas[0], nil
+	}
+	dbConfig, err := s.GetDBConfigByInstanceID(ctx, targetInstanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库配置失败: %s", err.Error())
+	}
+	if dbConfig.SourceInstanceID != sourceInstanceID {
+		return nil, fmt.Errorf("实例 %s:%d 不是工单实例的从库或沙箱实例，不能用于试运行", dbConfig.Hostname, dbConfig.Port)
+	}
+	return dbConfig, nil
+}
+
+// GetLatestDryRun 获取工单最近一次试运行结果，没有时返回 nil
+func (s *InsightService) GetLatestDryRun(ctx context.Context, orderID string) *insight.OrderDryRun {
+	dryRun, err := s.getRepo().GetLatestDryRun(ctx, orderID)
+	if err != nil {
+		return nil
+	}
+	return dryRun
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/internal/model/insight"
+	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/pkg/global"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+const (
+	defaultFlashbackPath           = "./storage/flashback"
+	defaultFlashbackTimeoutMinutes = 120
+	// flashbackJobTTL 任务信息在 Redis 中的保留时间，闪回文件超过该时间后一并清理
+	flashbackJobTTL = 7 * 24 * time.Hour
+)
+
+// 闪回任务状态
+const (
+	FlashbackStatusRunning = "running"
+	FlashbackStatusSuccess = "success"
+	FlashbackStatusFailed  = "failed"
+)
+
+// FlashbackServiceApp 全局 Service 实例
+var FlashbackServiceApp = new(FlashbackService)
+
+// FlashbackService binlog闪回任务（异步解析，结果写入文件供下载）
+type FlashbackService struct{}
+
+// FlashbackJob 闪回任务
+type FlashbackJob struct {
+	JobID      string                    `json:"job_id"`
+	InstanceID string                    `json:"instance_id"`
+	Username   string                    `json:"username"`
+	Status     string                    `json:"status"`
+	FileName   string                    `json:"file_name"`
+	FilePath   string                    `json:"-"`
+	Result     *mysqlpkg.FlashbackResult `json:"result"`
+	Error      string                    `json:"error"`
+	CreatedAt  string                    `json:"created_at"`
+	FinishedAt string                    `json:"finished_at"`
+}
+
+// getFlashbackPath 获取闪回文件存放目录
+func getFlashbackPath() string {
+	if global.Conf != nil {
+		if path := global.Conf.GetString("flashback.path"); path != "" {
+			return path
+		}
+	}
+	return defaultFlashbackPath
+}
+
+// getFlashbackTimeout 获取单个闪回任务的最长执行时间
+func getFlashbackTimeout() time.Duration {
+	minutes := 0
+	if global.Conf != nil {
+		minutes = global.Conf.GetInt("flashback.timeout_minutes")
+	}
+	if minutes <= 0 {
+		minutes = defaultFlashbackTimeoutMinutes
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// StartFlashback 创建闪回任务并在后台解析binlog
+func (s *FlashbackService) StartFlashback(ctx context.Context, username, instanceID string, options *mysqlpkg.FlashbackOptions) (*FlashbackJob, error) {
+	if global.Redis == nil {
+		return nil, fmt.Errorf("Redis 未配置")
+	}
+	dbConfig, err := InsightServiceApp.GetDBConfigByInstanceID(ctx, instanceID)
+	if err != nil {
+		return nil, fmt.Errorf("获取数据库配置失败: %s", err.Error())
+	}
+	if dbConfig.DbType != insight.DbTypeMySQL {
+		return nil, fmt.Errorf("闪回仅支持MySQL实例，当前实例类型: %s", dbConfig.DbType)
+	}
+
+	dir := getFlashbackPath()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建闪回目录失败: %s", err.Error())
+	}
+	jobID := uuid.New().String()
+	job := &FlashbackJob{
+		JobID:      jobID,
+		InstanceID: instanceID,
+		Username:   username,
+		Status:     FlashbackStatusRunning,
+		FileName:   fmt.Sprintf("flashback_%s_%s.sql", dbConfig.Hostname, time.Now().Format("20060102150405")),
+		FilePath:   filepath.Join(dir, jobID+".sql"),
+		CreatedAt:  time.Now().Format("2006-01-02 15:04:05"),
+	}
+	if err := s.saveJob(ctx, job); err != nil {
+		return nil, err
+	}
+
+	binlogConfig := &mysqlpkg.BinlogConfig{
+		Hostname: dbConfig.Hostname,
+		Port:     dbConfig.Port,
+		UserName: dbConfig.UserName,
+		Password: dbConfig.Password,
+
+		ConnOptions: dbConfig.ConnOptions(),
+	}
+	go s.run(job, binlogConfig, options)
+	return job, nil
+}
+
+// run 执行闪回并更新任务状态
+func (s *FlashbackService) run(job *FlashbackJob, config *mysqlpkg.BinlogConfig, options *mysqlpkg.FlashbackOptions) {
+	ctx, cancel := context.WithTimeout(context.Background(), getFlashbackTimeout())
+	defer cancel()
+
+	result, err := s.writeFile(ctx, job.FilePath, config, options)
+	job.FinishedAt = time.Now().Format("2006-01-02 15:04:05")
+	if err != nil {
+		job.Status = FlashbackStatusFailed
+		job.Error = err.Error()
+		os.Remove(job.FilePath)
+		global.Logger.Error("binlog闪回失败", zap.String("job_id", job.JobID), zap.Error(err))
+	} else {
+		job.Status = FlashbackStatusSuccess
+		job.Result = result
+	}
+	if err := s.saveJob(context.Background(), job); err != nil {
+		global.Logger.Error("保存闪回任务状态失败", zap.String("job_id", job.JobID), zap.Error(err))
+	}
+}
+
+// writeFile 将闪回SQL流式写入文件
+func (s *FlashbackService) writeFile(ctx context.Context, path string, config *mysqlpkg.BinlogConfig, options *mysqlpkg.FlashbackOptions) (*mysqlpkg.FlashbackResult, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("创建闪回文件失败: %w", err)
+	}
+	defer file.Close()
+
+	w := bufio.NewWriter(file)
+	result, err := mysqlpkg.NewFlashback(config, options).Run(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("写入闪回文件失败: %w", err)
+	}
+	return result, nil
+}
+
+// GetFlashbackJob 获取闪回任务
+func (s *FlashbackService) GetFlashbackJob(ctx context.Context, jobID string) (*FlashbackJob, error) {
+	if global.Redis == nil {
+		return nil, fmt.Errorf("Redis 未配置")
+	}
+	data, err := global.Redis.Get(ctx, flashbackJobKey(jobID)).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			return nil, fmt.Errorf("闪回任务不存在或已过期")
+		}
+		return nil, err
+	}
+	var job FlashbackJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, err
+	}
+	job.FilePath = filepath.Join(getFlashbackPath(), job.JobID+".sql")
+	return &job, nil
+}
+
+func (s *FlashbackService) saveJob(ctx context.Context, job *FlashbackJob) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	if err := global.Redis.Set(ctx, flashbackJobKey(job.JobID), data, flashbackJobTTL).Err(); err != nil {
+		return fmt.Errorf("保存闪回任务失败: %w", err)
+	}
+	return nil
+}
+
+func flashbackJobKey(jobID string) string {
+	return fmt.Sprintf("flashback:job:%s", jobID)
+}
+
+// PurgeExpiredFiles 清理过期的闪回文件
+// 任务信息在 Redis 中保留 flashbackJobTTL（任务结束时重新计时），过期后文件已无法下载，按文件修改时间同样保留 flashbackJobTTL
+func (s *FlashbackService) PurgeExpiredFiles(ctx context.Context) (int, error) {
+	return purgeFlashbackFiles(ctx, getFlashbackPath(), time.Now().Add(-flashbackJobTTL))
+}
+
+// purgeFlashbackFiles 删除 dir 下修改时间早于 expireBefore 的闪回文件（<job_id>.sql）
+func purgeFlashbackFiles(ctx context.Context, dir string, expireBefore time.Time) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	var purged int
+	for _, entry := range entries {
+		if ctx.Err() != nil {
+			return purged, ctx.Err()
+		}
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(expireBefore) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if err := os.Remove(path); err != nil {
+			global.Logger.Warn("删除过期闪回文件失败", zap.String("file", path), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
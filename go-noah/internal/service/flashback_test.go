@@ -0,0 +1,55 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPurgeFlashbackFiles(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Now()
+	files := []struct {
+		Name        string
+		Age         time.Duration
+		ExpectExist bool
+	}{
+		{Name: "expired.sql", Age: flashbackJobTTL + time.Hour},
+		{Name: "recent.sql", Age: time.Hour, ExpectExist: true},
+		{Name: "just-expired.sql", Age: flashbackJobTTL + time.Minute},
+		{Name: "other.txt", Age: flashbackJobTTL + time.Hour, ExpectExist: true},
+	}
+	for _, f := range files {
+		path := filepath.Join(dir, f.Name)
+		if err := os.WriteFile(path, []byte("SELECT 1;"), 0o644); err != nil {
+			t.Fatalf("写入文件失败: %v", err)
+		}
+		modTime := now.Add(-f.Age)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("修改文件时间失败: %v", err)
+		}
+	}
+
+	purged, err := purgeFlashbackFiles(context.Background(), dir, now.Add(-flashbackJobTTL))
+	if err != nil {
+		t.Fatalf("清理闪回文件失败: %v", err)
+	}
+	if purged != 2 {
+		t.Errorf("期望清理 2 个文件，实际 %d", purged)
+	}
+	for _, f := range files {
+		_, err := os.Stat(filepath.Join(dir, f.Name))
+		if exist := err == nil; exist != f.ExpectExist {
+			t.Errorf("%s 期望存在=%v，实际 %v", f.Name, f.ExpectExist, exist)
+		}
+	}
+
+	t.Run("目录不存在", func(t *testing.T) {
+		purged, err := purgeFlashbackFiles(context.Background(), filepath.Join(dir, "missing"), now)
+		if err != nil || purged != 0 {
+			t.Errorf("期望不清理且无错误，实际 %d, %v", purged, err)
+		}
+	})
+}
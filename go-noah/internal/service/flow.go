@@ -28,7 +28,7 @@ func (s *FlowService) getFlowRepo() *repository.FlowRepository {
 }
 
 func (s *FlowService) ensureOrderExecuteNode(ctx context.Context, flowDefID uint, businessType string) {
-	if businessType != "order_ddl" && businessType != "order_dml" && businessType != "order_export" {
+	if businessType != "order_ddl" && businessType != "order_dml" && businessType != "order_export" && businessType != "order_archive" && businessType != "order_migrate" {
 		return
 	}
 
@@ -473,7 +473,7 @@ func (s *FlowService) ApproveTask(ctx context.Context, req *api.ApproveTaskReque
 	}
 
 	// 发送通知：审批通过，通知申请人（非结束节点的情况）
-	if instance.BusinessType == "order_ddl" || instance.BusinessType == "order_dml" || instance.BusinessType == "order_export" {
+	if instance.BusinessType == "order_ddl" || instance.BusinessType == "order_dml" || instance.BusinessType == "order_export" || instance.BusinessType == "order_archive" || instance.BusinessType == "order_migrate" {
 		go func() {
 			order, err := InsightServiceApp.GetOrderByID(context.Background(), instance.BusinessID)
 			if err == nil && order != nil {
@@ -529,7 +529,7 @@ func (s *FlowService) RejectTask(ctx context.Context, req *api.RejectTaskRequest
 
 	// 同步更新工单状态
 	instance, _ := repo.GetFlowInstance(ctx, task.FlowInstID)
-	if instance != nil && (instance.BusinessType == "order_ddl" || instance.BusinessType == "order_dml" || instance.BusinessType == "order_export") {
+	if instance != nil && (instance.BusinessType == "order_ddl" || instance.BusinessType == "order_dml" || instance.BusinessType == "order_export" || instance.BusinessType == "order_archive" || instance.BusinessType == "order_migrate") {
 		_ = InsightServiceApp.UpdateOrderProgress(ctx, instance.BusinessID, insight.ProgressRejected)
 
 		// 发送通知：审批驳回，通知申请人
@@ -602,7 +602,7 @@ func (s *FlowService) moveToNextNode(ctx context.Context, instance *model.FlowIn
 
 // syncOrderStatusOnFlowApproved 流程审批通过后同步工单状态
 func (s *FlowService) syncOrderStatusOnFlowApproved(ctx context.Context, instance *model.FlowInstance) {
-	if instance.BusinessType != "order_ddl" && instance.BusinessType != "order_dml" && instance.BusinessType != "order_export" {
+	if instance.BusinessType != "order_ddl" && instance.BusinessType != "order_dml" && instance.BusinessType != "order_export" && instance.BusinessType != "order_archive" && instance.BusinessType != "order_migrate" {
 		return
 	}
 
@@ -617,7 +617,7 @@ func (s *FlowService) syncOrderStatusOnFlowApproved(ctx context.Context, instanc
 
 // syncOrderStatusOnFlowExecute 流程执行节点通过后同步工单状态（进入执行阶段）
 func (s *FlowService) syncOrderStatusOnFlowExecute(ctx context.Context, instance *model.FlowInstance) {
-	if instance.BusinessType != "order_ddl" && instance.BusinessType != "order_dml" && instance.BusinessType != "order_export" {
+	if instance.BusinessType != "order_ddl" && instance.BusinessType != "order_dml" && instance.BusinessType != "order_export" && instance.BusinessType != "order_archive" && instance.BusinessType != "order_migrate" {
 		return
 	}
 
@@ -635,7 +635,7 @@ func (s *FlowService) syncOrderStatusOnFlowExecute(ctx context.Context, instance
 
 // syncOrderStatusOnFlowCompleted 流程执行节点通过后同步工单状态（执行完成）
 func (s *FlowService) syncOrderStatusOnFlowCompleted(ctx context.Context, instance *model.FlowInstance) {
-	if instance.BusinessType != "order_ddl" && instance.BusinessType != "order_dml" && instance.BusinessType != "order_export" {
+	if instance.BusinessType != "order_ddl" && instance.BusinessType != "order_dml" && instance.BusinessType != "order_export" && instance.BusinessType != "order_archive" && instance.BusinessType != "order_migrate" {
 		return
 	}
 
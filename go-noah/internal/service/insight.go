@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-noah/api"
 	"go-noah/internal/inspect/parser"
@@ -10,10 +11,17 @@ import (
 	"go-noah/internal/orders/executor"
 	"go-noah/internal/repository"
 	insightRepo "go-noah/internal/repository/insight"
+	"go-noah/pkg/dbpool"
 	"go-noah/pkg/global"
+	"go-noah/pkg/lease"
 	"go-noah/pkg/notifier"
+	"go-noah/pkg/secret"
+	"go-noah/pkg/utils"
+	"io"
 	"strings"
+	"time"
 
+	"github.com/google/uuid"
 	"go.uber.org/zap"
 )
 
@@ -62,22 +70,59 @@ func (s *InsightService) GetDBConfigByInstanceID(ctx context.Context, instanceID
 	return s.getRepo().GetDBConfigByInstanceID(ctx, instanceID)
 }
 
+func (s *InsightService) GetDBConfigByID(ctx context.Context, id uint) (*insight.DBConfig, error) {
+	return s.getRepo().GetDBConfigByID(ctx, id)
+}
+
 func (s *InsightService) CreateDBConfig(ctx context.Context, config *insight.DBConfig) error {
-	// TODO: 密码加密存储
+	if err := config.EncryptSecrets(); err != nil {
+		return err
+	}
 	return s.getRepo().CreateDBConfig(ctx, config)
 }
 
 func (s *InsightService) UpdateDBConfig(ctx context.Context, config *insight.DBConfig) error {
-	// TODO: 密码加密存储
-	return s.getRepo().UpdateDBConfig(ctx, config)
+	if err := config.EncryptSecrets(); err != nil {
+		return err
+	}
+	if err := s.getRepo().UpdateDBConfig(ctx, config); err != nil {
+		return err
+	}
+	// 连接信息可能已变化，关闭实例的连接池
+	dbpool.Invalidate(config.InstanceID.String())
+	return nil
 }
 
 func (s *InsightService) UpdateDBConfigFields(ctx context.Context, id uint, updates map[string]interface{}) error {
-	return s.getRepo().UpdateDBConfigFields(ctx, id, updates)
+	for _, column := range insight.DBConfigSecretColumns {
+		value, ok := updates[column].(string)
+		if !ok || value == "" || secret.IsEncrypted(value) {
+			continue
+		}
+		encrypted, err := secret.Encrypt(value)
+		if err != nil {
+			return err
+		}
+		updates[column] = encrypted
+	}
+	if err := s.getRepo().UpdateDBConfigFields(ctx, id, updates); err != nil {
+		return err
+	}
+	if config, err := s.getRepo().GetDBConfigByID(ctx, id); err == nil {
+		dbpool.Invalidate(config.InstanceID.String())
+	}
+	return nil
 }
 
 func (s *InsightService) DeleteDBConfig(ctx context.Context, id uint) error {
-	return s.getRepo().DeleteDBConfig(ctx, id)
+	config, _ := s.getRepo().GetDBConfigByID(ctx, id)
+	if err := s.getRepo().DeleteDBConfig(ctx, id); err != nil {
+		return err
+	}
+	if config != nil {
+		dbpool.Invalidate(config.InstanceID.String())
+	}
+	return nil
 }
 
 // ============ Schema 管理 ============
@@ -253,6 +298,10 @@ func (s *InsightService) GetOrderByID(ctx context.Context, orderID string) (*ins
 	return s.getRepo().GetOrderByID(ctx, orderID)
 }
 
+func (s *InsightService) GetOrdersByHookOrderID(ctx context.Context, hookOrderID string) ([]insight.OrderRecord, error) {
+	return s.getRepo().GetOrdersByHookOrderID(ctx, hookOrderID)
+}
+
 func (s *InsightService) CreateOrder(ctx context.Context, order *insight.OrderRecord) error {
 	return s.getRepo().CreateOrder(ctx, order)
 }
@@ -296,6 +345,13 @@ func (s *InsightService) UpdateOrderProgress(ctx context.Context, orderID string
 		)
 	}
 
+	// 工单已开始执行、被驳回或关闭后，取消尚未触发的定时执行
+	if progress == insight.ProgressExecuting || progress == insight.ProgressRejected || progress == insight.ProgressClosed {
+		if err := s.getRepo().CancelScheduledExecutions(ctx, orderID, "工单"+string(progress)+"，取消定时执行"); err != nil {
+			global.Logger.Warn("取消定时执行失败", zap.String("order_id", orderID), zap.Error(err))
+		}
+	}
+
 	return nil
 }
 
@@ -315,11 +371,16 @@ func (s *InsightService) GenerateOrderTasks(ctx context.Context, orderID string)
 	}
 
 	// 拆分 SQL
-	sqls, err := s.splitSQLText(order.Content)
+	sqls, err := s.splitSQLText(order.DBType, order.Content)
 	if err != nil {
 		return err
 	}
 
+	// 导出工单生成工单级别的加密密钥（用于导出文件加密压缩）
+	if err := s.EnsureOrderExportKey(ctx, &order.OrderRecord); err != nil {
+		return err
+	}
+
 	// 创建任务
 	var tasks []insight.OrderTask
 	for _, sql := range sqls {
@@ -342,7 +403,12 @@ func (s *InsightService) GenerateOrderTasks(ctx context.Context, orderID string)
 }
 
 // splitSQLText 拆分SQL文本（内部辅助方法）
-func (s *InsightService) splitSQLText(sqltext string) ([]string, error) {
+func (s *InsightService) splitSQLText(dbType insight.DbType, sqltext string) ([]string, error) {
+	// ClickHouse 语法（ON CLUSTER、ALTER ... UPDATE/DELETE 等）无法被 TiDB parser 解析，按分号拆分
+	if dbType == insight.DbTypeClickHouse {
+		return parser.SplitSQLTextByDelimiter(sqltext), nil
+	}
+
 	// 使用 inspect parser 拆分 SQL
 	audit, warns, err := parser.ParseSQL(sqltext)
 	if err != nil {
@@ -378,9 +444,24 @@ func (s *InsightService) GetOrderTasks(ctx context.Context, orderID string) ([]i
 				if rollbackSQL, ok := resultMap["rollback_sql"].(string); ok && rollbackSQL != "" {
 					hasRollbackSQL = true
 				}
+				if ref, ok := resultMap["rollback_sql_ref"].(string); ok && ref != "" {
+					hasRollbackSQL = true
+				}
+				// 执行日志保存在对象存储时，只返回日志末尾部分
+				if ref, ok := resultMap["execute_log_ref"].(string); ok && ref != "" {
+					if result, err := parseTaskResult(tasks[i].Result); err == nil {
+						if tail, err := readExecuteLogTail(ctx, result); err == nil {
+							resultMap["execute_log"] = tail
+						} else {
+							global.Logger.Warn("读取执行日志失败", zap.String("task_id", tasks[i].TaskID.String()), zap.Error(err))
+						}
+					}
+				}
 				// 移除 rollback_sql，添加 has_rollback_sql 标志
 				delete(resultMap, "rollback_sql")
 				resultMap["has_rollback_sql"] = hasRollbackSQL
+				// 历史任务结果中可能包含导出文件解压密码，不随任务列表返回
+				delete(resultMap, "encryption_key")
 				// 重新序列化
 				if newResult, err := json.Marshal(resultMap); err == nil {
 					tasks[i].Result = newResult
@@ -407,16 +488,89 @@ func (s *InsightService) GetTaskRollbackSQL(ctx context.Context, taskID string)
 		return "", nil
 	}
 	
-	var resultMap map[string]interface{}
-	if err := json.Unmarshal(task.Result, &resultMap); err != nil {
+	result, err := parseTaskResult(task.Result)
+	if err != nil {
 		return "", err
 	}
-	
-	if rollbackSQL, ok := resultMap["rollback_sql"].(string); ok {
-		return rollbackSQL, nil
+	if result.RollbackSQLRef == "" {
+		return result.RollbackSQL, nil
 	}
-	
-	return "", nil
+
+	// 回滚SQL保存在对象存储中
+	reader, _, err := s.OpenTaskRollbackSQL(ctx, taskID)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// EnsureOrderExportKey 导出工单缺少加密密钥时生成并加密保存
+// 升级前创建的导出工单任务已生成但没有工单级别的密钥，执行前在此补齐
+func (s *InsightService) EnsureOrderExportKey(ctx context.Context, order *insight.OrderRecord) error {
+	if order.SQLType != insight.SQLTypeExport || order.ExportEncryptionKey != "" {
+		return nil
+	}
+	key, err := utils.GenerateSecureRandomString(16)
+	if err != nil {
+		return err
+	}
+	// 解压密码使用主密钥加密保存
+	if key, err = secret.Encrypt(key); err != nil {
+		return fmt.Errorf("加密导出文件密钥失败: %w", err)
+	}
+	if err := s.getRepo().UpdateOrderFields(ctx, order.OrderID.String(), map[string]interface{}{"export_encryption_key": key}); err != nil {
+		return err
+	}
+	order.ExportEncryptionKey = key
+	return nil
+}
+
+// GetOrderExportKey 获取导出文件解压密码（工单中加密保存，在此解密）
+// 升级前导出的文件使用文件级别的密钥，保存在任务结果中，工单没有密钥时使用
+func (s *InsightService) GetOrderExportKey(order *insight.OrderRecord, exportFile *executor.ExportFile) (string, error) {
+	if order.ExportEncryptionKey == "" {
+		if exportFile.LegacyEncryptionKey != "" {
+			return exportFile.LegacyEncryptionKey, nil
+		}
+		return "", fmt.Errorf("导出文件未加密")
+	}
+	key, err := secret.Decrypt(order.ExportEncryptionKey)
+	if err != nil {
+		return "", fmt.Errorf("解密导出文件密钥失败: %w", err)
+	}
+	return key, nil
+}
+
+// GetTaskExportFile 获取任务导出文件信息
+func (s *InsightService) GetTaskExportFile(ctx context.Context, orderID, taskID string) (*executor.ExportFile, error) {
+	task, err := s.getRepo().GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, err
+	}
+	if task.OrderID.String() != orderID {
+		return nil, fmt.Errorf("任务不属于当前工单")
+	}
+	// 归档到文件的任务复用导出文件下载
+	if task.SQLType != insight.SQLTypeExport && task.SQLType != insight.SQLTypeArchive {
+		return nil, fmt.Errorf("当前任务不是导出任务")
+	}
+	if len(task.Result) == 0 {
+		return nil, fmt.Errorf("导出文件不存在")
+	}
+
+	var result executor.ReturnData
+	if err := json.Unmarshal(task.Result, &result); err != nil {
+		return nil, err
+	}
+	if result.FilePath == "" {
+		return nil, fmt.Errorf("导出文件不存在")
+	}
+	return &result.ExportFile, nil
 }
 
 func (s *InsightService) CreateOrderTasks(ctx context.Context, tasks []insight.OrderTask) error {
@@ -427,6 +581,130 @@ func (s *InsightService) UpdateTaskProgress(ctx context.Context, taskID string,
 	return s.getRepo().UpdateTaskProgress(ctx, taskID, progress, result)
 }
 
+// UpdateTaskProgressFenced 持有执行锁时更新任务进度，已被其他节点接管时拒绝写入
+func (s *InsightService) UpdateTaskProgressFenced(ctx context.Context, taskID string, fence int64, progress insight.TaskProgress, result []byte) error {
+	err := s.getRepo().UpdateTaskProgressFenced(ctx, taskID, fence, progress, result)
+	if errors.Is(err, insightRepo.ErrStaleFence) {
+		global.Logger.Warn("执行锁已被其他节点接管，丢弃本节点的任务状态",
+			zap.String("task_id", taskID),
+			zap.Int64("fence", fence),
+			zap.String("progress", string(progress)),
+		)
+	}
+	return err
+}
+
+// MarkTaskLeaseLost 执行锁丢失（被其他节点接管或 Redis 长时间不可用）导致执行中断时，将任务标记为待确认
+// 中断时语句可能已部分或全部执行，不能视为用户取消或执行失败，需要人工确认实际执行结果
+func (s *InsightService) MarkTaskLeaseLost(ctx context.Context, orderID uuid.UUID, taskID string, fence int64, result []byte) {
+	global.Logger.Error("执行锁丢失，任务执行被中断",
+		zap.String("task_id", taskID),
+		zap.String("order_id", orderID.String()),
+		zap.Int64("fence", fence),
+	)
+	if err := s.UpdateTaskProgressFenced(ctx, taskID, fence, insight.TaskProgressUnknown, result); err != nil {
+		return
+	}
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: recoveryUsername,
+		OrderID:  orderID,
+		Msg:      fmt.Sprintf("任务 %s 执行期间执行锁丢失（Redis 长时间不可用或被其他节点接管），执行被中断，请人工确认实际执行结果后更新任务状态", taskID),
+	})
+}
+
+// CancelTask 取消正在执行的任务
+// gh-ost 执行的 DDL 通过 socket 发送 panic；其他语句对执行连接发送 KILL QUERY；同时取消本进程内的执行上下文
+// 至少一项取消操作成功后才将任务标记为已取消，否则返回错误且不修改任务状态
+func (s *InsightService) CancelTask(ctx context.Context, taskID string, username string) error {
+	task, err := s.getRepo().GetTaskByID(ctx, taskID)
+	if err != nil {
+		return err
+	}
+	if task.Progress != insight.TaskProgressExecuting {
+		return fmt.Errorf("当前任务未在执行中，无法取消")
+	}
+
+	order, err := s.getRepo().GetOrderByID(ctx, task.OrderID.String())
+	if err != nil {
+		return err
+	}
+
+	// 标记取消，执行器据此将中断识别为取消而不是失败
+	if err := utils.SetTaskCancelled(taskID); err != nil {
+		global.Logger.Warn("标记任务取消失败", zap.String("task_id", taskID), zap.Error(err))
+	}
+
+	var actions []string
+	if task.SQLType == insight.SQLTypeDDL && order.DBType != insight.DbTypeClickHouse {
+		if socketPath, err := utils.GetGhostSocketPathFromOrderID(order.OrderID.String(), "", ""); err == nil {
+			if err := utils.GhostControl(socketPath, "panic"); err != nil {
+				global.Logger.Warn("发送 gh-ost panic 命令失败", zap.String("task_id", taskID), zap.Error(err))
+			} else {
+				actions = append(actions, "gh-ost panic")
+			}
+		}
+	}
+	if order.DBType != insight.DbTypeClickHouse {
+		if connectionID, err := utils.GetTaskConnectionID(taskID); err == nil {
+			dbConfig, err := s.GetDBConfigByInstanceID(ctx, order.InstanceID.String())
+			if err != nil {
+				return err
+			}
+			killErr := executor.KillQuery(&executor.DBConfig{
+				InstanceID: dbConfig.InstanceID.String(),
+				Hostname:   dbConfig.Hostname,
+				Port:       dbConfig.Port,
+				UserName:   dbConfig.UserName,
+				Password:   dbConfig.Password,
+
+				ConnOptions: dbConfig.ConnOptions(),
+			}, connectionID)
+			if killErr != nil {
+				global.Logger.Warn("KILL QUERY 失败", zap.String("task_id", taskID), zap.Int64("connection_id", connectionID), zap.Error(killErr))
+			} else {
+				actions = append(actions, fmt.Sprintf("KILL QUERY %d", connectionID))
+			}
+		}
+	}
+	if executor.CancelRunningTask(taskID) {
+		actions = append(actions, "中断执行上下文")
+	}
+	if len(actions) == 0 {
+		// 语句仍在执行，清除取消标记，避免之后的执行失败被误判为取消
+		utils.ClearTaskCancelled(taskID)
+		return fmt.Errorf("取消任务失败：未找到正在执行的语句或取消操作均失败，任务仍在执行中")
+	}
+
+	// 仅更新仍在执行中或未执行的任务，取消期间任务已结束时不覆盖执行器写入的最终状态
+	updated, err := s.getRepo().UpdateTaskProgressIn(ctx, taskID,
+		[]insight.TaskProgress{insight.TaskProgressExecuting, insight.TaskProgressPending}, insight.TaskProgressCancelled)
+	if err != nil {
+		return err
+	}
+	if !updated {
+		current, err := s.getRepo().GetTaskByID(ctx, taskID)
+		if err != nil {
+			return err
+		}
+		// 执行器识别到取消后已写入“已取消”
+		if current.Progress != insight.TaskProgressCancelled {
+			utils.ClearTaskCancelled(taskID)
+			return fmt.Errorf("任务已结束（当前状态: %s），未更新为已取消", current.Progress)
+		}
+	}
+
+	msg := "取消任务: " + taskID
+	if len(actions) > 0 {
+		msg += "（" + strings.Join(actions, "，") + "）"
+	}
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: username,
+		OrderID:  order.OrderID,
+		Msg:      msg,
+	})
+	return nil
+}
+
 // CheckTasksProgressIsDoing 检查工单是否有任务正在执行中
 func (s *InsightService) CheckTasksProgressIsDoing(ctx context.Context, orderID string) (bool, error) {
 	return s.getRepo().CheckTasksProgressIsDoing(ctx, orderID)
@@ -437,6 +715,11 @@ func (s *InsightService) CheckTasksProgressIsPause(ctx context.Context, orderID
 	return s.getRepo().CheckTasksProgressIsPause(ctx, orderID)
 }
 
+// CheckTasksProgressIsUnknown 检查工单是否有执行结果待确认的任务
+func (s *InsightService) CheckTasksProgressIsUnknown(ctx context.Context, orderID string) (bool, error) {
+	return s.getRepo().CheckTasksProgressIsUnknown(ctx, orderID)
+}
+
 // UpdateOrderExecuteResult 更新工单执行结果
 func (s *InsightService) UpdateOrderExecuteResult(ctx context.Context, orderID string, result string) error {
 	return s.getRepo().UpdateOrderExecuteResult(ctx, orderID, result)
@@ -447,9 +730,64 @@ func (s *InsightService) CheckAllTasksCompleted(ctx context.Context, orderID str
 	return s.getRepo().CheckAllTasksCompleted(ctx, orderID)
 }
 
-// UpdateTaskAndOrderProgress 使用事务同时更新任务和工单状态
-func (s *InsightService) UpdateTaskAndOrderProgress(ctx context.Context, taskID string, orderID string, taskProgress insight.TaskProgress, orderProgress insight.Progress) error {
-	return s.getRepo().UpdateTaskAndOrderProgress(ctx, taskID, orderID, taskProgress, orderProgress)
+// UpdateTaskAndOrderProgress 使用事务同时更新任务和工单状态，fence 为执行锁的 fencing token
+func (s *InsightService) UpdateTaskAndOrderProgress(ctx context.Context, taskID string, orderID string, fence int64, taskProgress insight.TaskProgress, orderProgress insight.Progress) error {
+	return s.getRepo().UpdateTaskAndOrderProgress(ctx, taskID, orderID, fence, taskProgress, orderProgress)
+}
+
+// NewExecutorConfig 根据工单、任务和实例配置构造执行器配置（手动执行、批量执行和定时执行共用）
+func NewExecutorConfig(order *insight.OrderRecord, task *insight.OrderTask, dbConfig *insight.DBConfig) *executor.DBConfig {
+	config := &executor.DBConfig{
+		InstanceID:         dbConfig.InstanceID.String(),
+		Hostname:           dbConfig.Hostname,
+		Port:               dbConfig.Port,
+		UserName:           dbConfig.UserName,
+		Password:           dbConfig.Password,
+		Schema:             order.Schema,
+		DBType:             string(dbConfig.DbType),
+		SQLType:            string(task.SQLType),
+		SQL:                task.SQL,
+		OrderID:            order.OrderID.String(),
+		TaskID:             task.TaskID.String(),
+		ExportFileFormat:   string(order.ExportFileFormat),
+		GhostOkToDropTable: order.GhostOkToDropTable,
+		EncryptionKey:      order.ExportEncryptionKey,
+		DDLEngine:          dbConfig.DDLEngine,
+		ChunkedDML:         order.ChunkedDML,
+		ChunkSize:          order.ChunkSize,
+		ChunkSleepMs:       order.ChunkSleepMs,
+
+		ThrottleMaxReplicaLag:     dbConfig.ThrottleMaxReplicaLag,
+		ThrottleMaxThreadsRunning: dbConfig.ThrottleMaxThreadsRunning,
+		ThrottleQuery:             dbConfig.ThrottleQuery,
+
+		GhostPostponeCutOver: order.GhostPostponeCutOver,
+
+		ConnOptions: dbConfig.ConnOptions(),
+	}
+	// 工单指定的在线DDL引擎优先于实例配置
+	if order.DDLEngine != "" {
+		config.DDLEngine = order.DDLEngine
+	}
+	switch task.SQLType {
+	case insight.SQLTypeArchive:
+		config.Archive = newArchiveConfig(order)
+	case insight.SQLTypeMigrate:
+		config.Migrate = newMigrateConfig(order)
+	}
+	return config
+}
+
+// AcquireOrderLock 获取工单执行锁（手动执行、批量执行和定时执行共用，防止多个节点同时执行同一工单）
+// 执行结束后必须调用 Release；执行时使用锁的 Context，写入任务状态时使用锁的 Fence
+func (s *InsightService) AcquireOrderLock(orderID string) (*lease.Lease, error) {
+	l, err := lease.Acquire(context.Background(), lease.OrderLockName(orderID), 0)
+	var held *lease.HeldError
+	if errors.As(err, &held) {
+		return nil, fmt.Errorf("当前工单正在节点 %s 上执行（fencing token %d，开始于 %s），请勿重复执行",
+			held.Node, held.Fence, held.Acquired.Format(time.DateTime))
+	}
+	return l, err
 }
 
 // ExecuteOrder 执行工单的所有任务（用于定时任务调度器）
@@ -459,6 +797,22 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 		zap.String("username", username),
 	)
 
+	// 获取工单执行锁，持有锁之后再检查工单和任务状态
+	lock, err := s.AcquireOrderLock(orderID)
+	if err != nil {
+		global.Logger.Warn("获取工单执行锁失败",
+			zap.String("order_id", orderID),
+			zap.Error(err),
+		)
+		return err
+	}
+	started := false
+	defer func() {
+		if !started {
+			lock.Release()
+		}
+	}()
+
 	// 获取工单信息
 	order, err := s.getRepo().GetOrderByID(ctx, orderID)
 	if err != nil {
@@ -468,6 +822,9 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 		)
 		return fmt.Errorf("获取工单信息失败: %w", err)
 	}
+	if err := s.EnsureOrderExportKey(ctx, &order.OrderRecord); err != nil {
+		return fmt.Errorf("生成导出文件密钥失败: %w", err)
+	}
 
 	global.Logger.Info("获取工单信息成功",
 		zap.String("order_id", orderID),
@@ -503,6 +860,30 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 		}
 	}
 
+	// 周期工单由 RunDueRecurringOrders 按计划执行
+	if recurring, err := s.GetRecurringSchedule(ctx, orderID); err != nil {
+		return fmt.Errorf("获取周期执行计划失败: %w", err)
+	} else if recurring != nil {
+		return errors.New("周期工单由系统按计划执行，不能手动执行")
+	}
+
+	// 检查维护窗口，不在窗口内时顺延到下一个维护窗口
+	status, err := s.CheckOrderMaintenanceWindow(ctx, &order.OrderRecord)
+	if err != nil {
+		return fmt.Errorf("检查维护窗口失败: %w", err)
+	}
+	if !status.Allowed {
+		if status.NextWindow == nil || order.Progress != insight.ProgressApproved {
+			_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+				Username: username,
+				OrderID:  order.OrderID,
+				Msg:      "定时执行已取消: " + status.Message(),
+			})
+			return errors.New(status.Message())
+		}
+		return s.QueueOrderToMaintenanceWindow(ctx, orderID, *status.NextWindow, username)
+	}
+
 	// 检查是否有任务正在执行中
 	noExecutingTasks, err := s.CheckTasksProgressIsDoing(ctx, orderID)
 	if err != nil {
@@ -512,6 +893,15 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 		return fmt.Errorf("当前有任务正在执行中，请先等待执行完成")
 	}
 
+	// 检查是否有执行结果待确认的任务（执行中断后无法核对结果，重新执行可能重复变更）
+	noUnknownTasks, err := s.CheckTasksProgressIsUnknown(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("检查任务状态失败: %w", err)
+	}
+	if !noUnknownTasks {
+		return fmt.Errorf("当前有任务执行结果待确认，请先人工确认并更新任务状态")
+	}
+
 	// 获取工单的所有任务
 	tasks, err := s.getRepo().GetOrderTasks(ctx, orderID)
 	if err != nil {
@@ -540,9 +930,12 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 		return fmt.Errorf("获取数据库配置失败: %w", err)
 	}
 
-	// 在后台 goroutine 中异步执行所有任务
+	// 在后台 goroutine 中异步执行所有任务，执行结束后释放执行锁
+	started = true
 	go func() {
+		defer lock.Release()
 		ctx := context.Background()
+		fence := lock.Fence()
 		var executedCount, successCount, failCount int
 
 		// 逐个执行任务
@@ -552,26 +945,29 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 				continue
 			}
 
+			// 执行锁丢失（续期失败或被其他节点接管）后不再执行后续任务
+			if err := lock.Err(); err != nil {
+				global.Logger.Error("执行锁已丢失，停止执行后续任务",
+					zap.String("order_id", orderID),
+					zap.Error(err),
+				)
+				break
+			}
+
 			executedCount++
 
 			// 更新任务状态为执行中
-			_ = s.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressExecuting, nil)
+			if err := s.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressExecuting, nil); err != nil {
+				global.Logger.Error("更新任务状态失败，停止执行",
+					zap.String("task_id", task.TaskID.String()),
+					zap.String("order_id", orderID),
+					zap.Error(err),
+				)
+				return
+			}
 
 			// 创建执行器配置
-			execConfig := &executor.DBConfig{
-				Hostname:           dbConfig.Hostname,
-				Port:               dbConfig.Port,
-				UserName:           dbConfig.UserName,
-				Password:           dbConfig.Password,
-				Schema:             order.Schema,
-				DBType:             string(dbConfig.DbType),
-				SQLType:            string(task.SQLType),
-				SQL:                task.SQL,
-				OrderID:            order.OrderID.String(),
-				TaskID:             task.TaskID.String(),
-				ExportFileFormat:   string(order.ExportFileFormat),
-				GhostOkToDropTable: order.GhostOkToDropTable,
-			}
+			execConfig := NewExecutorConfig(&order.OrderRecord, &task, dbConfig)
 
 			// 创建执行器
 			exec, err := executor.NewExecuteSQL(execConfig)
@@ -582,14 +978,24 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 					zap.String("order_id", orderID),
 					zap.Error(err),
 				)
-				_ = s.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressFailed, nil)
+				_ = s.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressFailed, nil)
 				continue
 			}
 
 			// 执行SQL
-			result, err := exec.Run()
-			resultJSON, _ := json.Marshal(result)
+			result, err := exec.Run(lock.Context())
+			resultJSON := s.MarshalTaskResult(ctx, task.TaskID.String(), result)
 
+			if errors.Is(err, executor.ErrTaskCancelled) {
+				// 任务被取消，停止执行后续任务
+				_ = s.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressCancelled, resultJSON)
+				break
+			}
+			if errors.Is(err, lease.ErrLost) {
+				// 执行锁丢失，执行结果待确认，停止执行后续任务
+				s.MarkTaskLeaseLost(ctx, order.OrderID, task.TaskID.String(), fence, resultJSON)
+				break
+			}
 			if err != nil {
 				failCount++
 				global.Logger.Error("任务执行失败",
@@ -597,7 +1003,7 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 					zap.String("order_id", orderID),
 					zap.Error(err),
 				)
-				_ = s.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressFailed, resultJSON)
+				_ = s.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressFailed, resultJSON)
 			} else {
 				successCount++
 				global.Logger.Info("任务执行成功",
@@ -605,7 +1011,7 @@ func (s *InsightService) ExecuteOrder(ctx context.Context, orderID string, usern
 					zap.String("order_id", orderID),
 					zap.Int64("affected_rows", result.AffectedRows),
 				)
-				_ = s.UpdateTaskProgress(ctx, task.TaskID.String(), insight.TaskProgressCompleted, resultJSON)
+				_ = s.UpdateTaskProgressFenced(ctx, task.TaskID.String(), fence, insight.TaskProgressCompleted, resultJSON)
 			}
 		}
 
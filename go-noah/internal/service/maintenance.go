@@ -0,0 +1,293 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-noah/internal/model"
+	"go-noah/internal/model/insight"
+	"go-noah/pkg/global"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maintenanceLookaheadDays 计算下一个维护窗口时最多向后查找的天数
+const maintenanceLookaheadDays = 366
+
+// MaintenanceStatus 维护窗口检查结果
+type MaintenanceStatus struct {
+	Allowed    bool       `json:"allowed"`
+	Reason     string     `json:"reason,omitempty"`      // 不允许执行的原因
+	NextWindow *time.Time `json:"next_window,omitempty"` // 下一次允许执行的时间（不允许执行时返回，为空表示一年内没有可用窗口）
+}
+
+// Message 不允许执行时的提示信息
+func (s *MaintenanceStatus) Message() string {
+	if s.NextWindow != nil {
+		return fmt.Sprintf("%s，下一个维护窗口: %s", s.Reason, s.NextWindow.Format(time.DateTime))
+	}
+	return s.Reason + "，一年内没有可用的维护窗口"
+}
+
+// weeklyWindow 解析后的每周窗口
+type weeklyWindow struct {
+	days       [7]bool
+	start, end int // 距当天零点的分钟数
+}
+
+// at 窗口在指定日期的开始和结束时间
+func (w weeklyWindow) at(day time.Time) (time.Time, time.Time) {
+	start := time.Date(day.Year(), day.Month(), day.Day(), w.start/60, w.start%60, 0, 0, day.Location())
+	end := time.Date(day.Year(), day.Month(), day.Day(), w.end/60, w.end%60, 0, 0, day.Location())
+	if w.end <= w.start {
+		end = end.AddDate(0, 0, 1) // 跨天
+	}
+	return start, end
+}
+
+// contains 时间是否在窗口内（包括前一天开始的跨天窗口）
+func (w weeklyWindow) contains(t time.Time) bool {
+	for offset := 0; offset >= -1; offset-- {
+		day := t.AddDate(0, 0, offset)
+		if !w.days[day.Weekday()] {
+			continue
+		}
+		start, end := w.at(day)
+		if !t.Before(start) && t.Before(end) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock 解析 HH:MM，返回距零点的分钟数
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("时间格式错误: %s，应为 HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func parseWeeklyWindow(w *insight.MaintenanceWindow) (weeklyWindow, error) {
+	var parsed weeklyWindow
+	for _, day := range strings.Split(w.Weekdays, ",") {
+		day = strings.TrimSpace(day)
+		if day == "" {
+			continue
+		}
+		n, err := strconv.Atoi(day)
+		if err != nil || n < 0 || n > 6 {
+			return parsed, fmt.Errorf("星期格式错误: %s，应为 0-6（0为周日）", day)
+		}
+		parsed.days[n] = true
+	}
+	if parsed.days == [7]bool{} {
+		return parsed, errors.New("每周窗口至少需要指定一天")
+	}
+	var err error
+	if parsed.start, err = parseClock(w.StartTime); err != nil {
+		return parsed, err
+	}
+	if parsed.end, err = parseClock(w.EndTime); err != nil {
+		return parsed, err
+	}
+	return parsed, nil
+}
+
+// ValidateMaintenanceWindow 校验维护窗口配置
+func ValidateMaintenanceWindow(w *insight.MaintenanceWindow) error {
+	if w.Environment <= 0 {
+		return errors.New("请指定环境")
+	}
+	if w.InstanceID != "" {
+		if _, err := uuid.Parse(w.InstanceID); err != nil {
+			return fmt.Errorf("实例ID格式错误: %s", w.InstanceID)
+		}
+	}
+	switch w.Type {
+	case insight.MaintenanceTypeWeekly:
+		_, err := parseWeeklyWindow(w)
+		return err
+	case insight.MaintenanceTypeFreeze:
+		if w.FreezeStart == nil || w.FreezeEnd == nil || !w.FreezeEnd.After(*w.FreezeStart) {
+			return errors.New("冻结期需要指定开始和结束时间，且结束时间晚于开始时间")
+		}
+		return nil
+	default:
+		return fmt.Errorf("不支持的维护窗口类型: %s", w.Type)
+	}
+}
+
+// ============ 维护窗口管理 ============
+
+func (s *InsightService) GetMaintenanceWindows(ctx context.Context, environment int) ([]insight.MaintenanceWindow, error) {
+	return s.getRepo().GetMaintenanceWindows(ctx, environment)
+}
+
+func (s *InsightService) GetMaintenanceWindow(ctx context.Context, id uint) (*insight.MaintenanceWindow, error) {
+	return s.getRepo().GetMaintenanceWindow(ctx, id)
+}
+
+func (s *InsightService) CreateMaintenanceWindow(ctx context.Context, window *insight.MaintenanceWindow) error {
+	if err := ValidateMaintenanceWindow(window); err != nil {
+		return err
+	}
+	return s.getRepo().CreateMaintenanceWindow(ctx, window)
+}
+
+func (s *InsightService) UpdateMaintenanceWindow(ctx context.Context, window *insight.MaintenanceWindow) error {
+	if err := ValidateMaintenanceWindow(window); err != nil {
+		return err
+	}
+	return s.getRepo().UpdateMaintenanceWindow(ctx, window)
+}
+
+func (s *InsightService) DeleteMaintenanceWindow(ctx context.Context, id uint) error {
+	return s.getRepo().DeleteMaintenanceWindow(ctx, id)
+}
+
+// CheckMaintenanceWindow 检查实例在指定时间是否允许执行工单
+// 环境和实例都没有配置每周窗口时不限制执行时间（冻结期仍然生效）
+func (s *InsightService) CheckMaintenanceWindow(ctx context.Context, environment int, instanceID string, now time.Time) (*MaintenanceStatus, error) {
+	windows, err := s.getRepo().GetMaintenanceWindowsForInstance(ctx, environment, instanceID)
+	if err != nil {
+		return nil, err
+	}
+	return evaluateMaintenanceWindows(windows, now)
+}
+
+// evaluateMaintenanceWindows 根据维护窗口配置判断指定时间是否允许执行，不允许时计算下一次允许执行的时间
+// 每周窗口按 now 所在时区计算，实例级每周窗口优先于环境级每周窗口
+func evaluateMaintenanceWindows(windows []insight.MaintenanceWindow, now time.Time) (*MaintenanceStatus, error) {
+	var (
+		envWeekly, instanceWeekly []weeklyWindow
+		freezes                   []insight.MaintenanceWindow
+	)
+	for i := range windows {
+		w := &windows[i]
+		switch w.Type {
+		case insight.MaintenanceTypeWeekly:
+			parsed, err := parseWeeklyWindow(w)
+			if err != nil {
+				return nil, fmt.Errorf("维护窗口 %d 配置错误: %w", w.ID, err)
+			}
+			if w.InstanceID != "" {
+				instanceWeekly = append(instanceWeekly, parsed)
+			} else {
+				envWeekly = append(envWeekly, parsed)
+			}
+		case insight.MaintenanceTypeFreeze:
+			if w.FreezeStart != nil && w.FreezeEnd != nil {
+				freezes = append(freezes, *w)
+			}
+		}
+	}
+	weekly := envWeekly
+	if len(instanceWeekly) > 0 {
+		weekly = instanceWeekly
+	}
+
+	check := func(t time.Time) (bool, string) {
+		for _, f := range freezes {
+			if !t.Before(*f.FreezeStart) && t.Before(*f.FreezeEnd) {
+				reason := fmt.Sprintf("处于冻结期（%s ~ %s）", f.FreezeStart.Format(time.DateTime), f.FreezeEnd.Format(time.DateTime))
+				if f.Remark != "" {
+					reason += "：" + f.Remark
+				}
+				return false, reason
+			}
+		}
+		if len(weekly) == 0 {
+			return true, ""
+		}
+		for _, w := range weekly {
+			if w.contains(t) {
+				return true, ""
+			}
+		}
+		return false, "不在维护窗口内"
+	}
+
+	allowed, reason := check(now)
+	status := &MaintenanceStatus{Allowed: allowed, Reason: reason}
+	if allowed {
+		return status, nil
+	}
+
+	// 下一次允许执行的时间只可能是某个每周窗口的开始时间或某个冻结期的结束时间
+	var candidates []time.Time
+	for _, f := range freezes {
+		if f.FreezeEnd.After(now) {
+			candidates = append(candidates, *f.FreezeEnd)
+		}
+	}
+	if len(weekly) > 0 {
+		for d := 0; d <= maintenanceLookaheadDays; d++ {
+			day := now.AddDate(0, 0, d)
+			for _, w := range weekly {
+				if !w.days[day.Weekday()] {
+					continue
+				}
+				if start, _ := w.at(day); start.After(now) {
+					candidates = append(candidates, start)
+				}
+			}
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].Before(candidates[j]) })
+	for _, t := range candidates {
+		if ok, _ := check(t); ok {
+			next := t
+			status.NextWindow = &next
+			break
+		}
+	}
+	return status, nil
+}
+
+// CheckOrderMaintenanceWindow 检查工单当前是否允许执行
+func (s *InsightService) CheckOrderMaintenanceWindow(ctx context.Context, order *insight.OrderRecord) (*MaintenanceStatus, error) {
+	return s.CheckMaintenanceWindow(ctx, order.Environment, order.InstanceID.String(), time.Now())
+}
+
+// getMaintenanceOverrideRole 获取允许在维护窗口外紧急执行的角色
+func getMaintenanceOverrideRole() string {
+	if global.Conf != nil {
+		if role := global.Conf.GetString("maintenance_window.override_role"); role != "" {
+			return role
+		}
+	}
+	return model.RoleDBA
+}
+
+// CanOverrideMaintenanceWindow 用户是否可以在维护窗口外紧急执行（超级管理员或拥有指定角色）
+func (s *InsightService) CanOverrideMaintenanceWindow(userID uint) bool {
+	return userHasRole(userID, getMaintenanceOverrideRole())
+}
+
+// QueueOrderToMaintenanceWindow 将工单改为在下一个维护窗口定时执行（由定时工单调度器注册执行）
+func (s *InsightService) QueueOrderToMaintenanceWindow(ctx context.Context, orderID string, next time.Time, username string) error {
+	order, err := s.getRepo().GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if order.Progress != insight.ProgressApproved {
+		return fmt.Errorf("仅已批准的工单可以排队到维护窗口执行，当前状态: %s", order.Progress)
+	}
+	if err := s.getRepo().UpdateOrderFields(ctx, orderID, map[string]interface{}{
+		"schedule_time":        next,
+		"scheduler_registered": false,
+	}); err != nil {
+		return err
+	}
+	return s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: username,
+		OrderID:  order.OrderID,
+		Msg:      "不在维护窗口内，已排队到下一个维护窗口执行: " + next.Format(time.DateTime),
+	})
+}
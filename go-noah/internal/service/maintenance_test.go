@@ -0,0 +1,200 @@
+package service
+
+import (
+	"go-noah/internal/model/insight"
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func weekly(weekdays, start, end string) insight.MaintenanceWindow {
+	return insight.MaintenanceWindow{Environment: 1, Type: insight.MaintenanceTypeWeekly, Weekdays: weekdays, StartTime: start, EndTime: end}
+}
+
+func freeze(start, end time.Time) insight.MaintenanceWindow {
+	return insight.MaintenanceWindow{Environment: 1, Type: insight.MaintenanceTypeFreeze, FreezeStart: &start, FreezeEnd: &end}
+}
+
+func mustLoadLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatalf("加载时区失败: %v", err)
+	}
+	return loc
+}
+
+func TestEvaluateMaintenanceWindows(t *testing.T) {
+	shanghai := mustLoadLocation(t, "Asia/Shanghai")
+	newYork := mustLoadLocation(t, "America/New_York")
+	at := func(loc *time.Location, year int, month time.Month, day, hour, min int) time.Time {
+		return time.Date(year, month, day, hour, min, 0, 0, loc)
+	}
+	// 2024-06-03 为周一
+	nightly := weekly("1", "22:00", "02:00")
+	instanceWindow := weekly("3", "10:00", "12:00")
+	instanceWindow.InstanceID = "5f0c1a52-4a6c-4b8e-9b1e-3f0e2a1d2c3b"
+
+	testCases := []struct {
+		Name       string
+		Windows    []insight.MaintenanceWindow
+		Now        time.Time
+		Allowed    bool
+		NextWindow *time.Time
+	}{
+		{
+			Name:    "未配置窗口",
+			Now:     at(shanghai, 2024, 6, 3, 12, 0),
+			Allowed: true,
+		},
+		{
+			Name:    "跨天窗口当天部分",
+			Windows: []insight.MaintenanceWindow{nightly},
+			Now:     at(shanghai, 2024, 6, 3, 23, 0),
+			Allowed: true,
+		},
+		{
+			Name:    "跨天窗口次日凌晨部分",
+			Windows: []insight.MaintenanceWindow{nightly},
+			Now:     at(shanghai, 2024, 6, 4, 1, 59),
+			Allowed: true,
+		},
+		{
+			Name:       "跨天窗口结束时间不包含在内",
+			Windows:    []insight.MaintenanceWindow{nightly},
+			Now:        at(shanghai, 2024, 6, 4, 2, 0),
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 10, 22, 0)),
+		},
+		{
+			Name:       "窗口开始前",
+			Windows:    []insight.MaintenanceWindow{nightly},
+			Now:        at(shanghai, 2024, 6, 3, 21, 59),
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 3, 22, 0)),
+		},
+		{
+			Name:       "前一天不在窗口星期内",
+			Windows:    []insight.MaintenanceWindow{nightly},
+			Now:        at(shanghai, 2024, 6, 3, 1, 0),
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 3, 22, 0)),
+		},
+		{
+			Name:    "开始和结束时间相同为全天",
+			Windows: []insight.MaintenanceWindow{weekly("0,6", "00:00", "00:00")},
+			Now:     at(shanghai, 2024, 6, 8, 12, 0),
+			Allowed: true,
+		},
+		{
+			Name:       "实例窗口优先于环境窗口",
+			Windows:    []insight.MaintenanceWindow{nightly, instanceWindow},
+			Now:        at(shanghai, 2024, 6, 3, 23, 0),
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 5, 10, 0)),
+		},
+		{
+			Name:       "冻结期内无每周窗口",
+			Windows:    []insight.MaintenanceWindow{freeze(at(shanghai, 2024, 6, 1, 0, 0), at(shanghai, 2024, 6, 5, 0, 0))},
+			Now:        at(shanghai, 2024, 6, 3, 12, 0),
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 5, 0, 0)),
+		},
+		{
+			Name: "冻结期覆盖下一个窗口",
+			Windows: []insight.MaintenanceWindow{
+				nightly,
+				freeze(at(shanghai, 2024, 6, 3, 0, 0), at(shanghai, 2024, 6, 4, 0, 0)),
+			},
+			Now: at(shanghai, 2024, 6, 3, 23, 0),
+			// 冻结期在窗口中间结束，结束后仍在窗口内即可执行
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 4, 0, 0)),
+		},
+		{
+			Name: "冻结期结束时已不在窗口内",
+			Windows: []insight.MaintenanceWindow{
+				nightly,
+				freeze(at(shanghai, 2024, 6, 3, 0, 0), at(shanghai, 2024, 6, 4, 3, 0)),
+			},
+			Now:        at(shanghai, 2024, 6, 3, 23, 0),
+			NextWindow: ptrTime(at(shanghai, 2024, 6, 10, 22, 0)),
+		},
+		{
+			// 同一时刻 UTC 周一 23:00 在窗口内，纽约为周一 19:00 不在窗口内
+			Name:    "按当前时间的时区计算（UTC）",
+			Windows: []insight.MaintenanceWindow{nightly},
+			Now:     at(time.UTC, 2024, 6, 3, 23, 0),
+			Allowed: true,
+		},
+		{
+			Name:       "按当前时间的时区计算（纽约）",
+			Windows:    []insight.MaintenanceWindow{nightly},
+			Now:        at(time.UTC, 2024, 6, 3, 23, 0).In(newYork),
+			NextWindow: ptrTime(at(newYork, 2024, 6, 3, 22, 0)),
+		},
+		{
+			// 2024-03-10 02:00 纽约进入夏令时，跨天窗口按当地时间 03:00 结束
+			Name:    "夏令时切换当天窗口内",
+			Windows: []insight.MaintenanceWindow{weekly("6", "23:00", "03:00")},
+			Now:     at(newYork, 2024, 3, 10, 1, 59),
+			Allowed: true,
+		},
+		{
+			Name:       "夏令时切换当天窗口结束后",
+			Windows:    []insight.MaintenanceWindow{weekly("6", "23:00", "03:00")},
+			Now:        at(newYork, 2024, 3, 10, 3, 0),
+			NextWindow: ptrTime(at(newYork, 2024, 3, 16, 23, 0)),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			status, err := evaluateMaintenanceWindows(tc.Windows, tc.Now)
+			if err != nil {
+				t.Fatalf("检查维护窗口失败: %v", err)
+			}
+			if status.Allowed != tc.Allowed {
+				t.Fatalf("期望 allowed=%v，实际 %v（%s）", tc.Allowed, status.Allowed, status.Reason)
+			}
+			if tc.Allowed {
+				return
+			}
+			if tc.NextWindow == nil || status.NextWindow == nil {
+				if tc.NextWindow != status.NextWindow {
+					t.Fatalf("期望下一个窗口 %v，实际 %v", tc.NextWindow, status.NextWindow)
+				}
+				return
+			}
+			if !status.NextWindow.Equal(*tc.NextWindow) {
+				t.Errorf("期望下一个窗口 %s，实际 %s", tc.NextWindow, status.NextWindow)
+			}
+		})
+	}
+}
+
+func TestValidateMaintenanceWindow(t *testing.T) {
+	start := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	testCases := []struct {
+		Name    string
+		Window  insight.MaintenanceWindow
+		WantErr bool
+	}{
+		{Name: "每周窗口", Window: weekly("1,2,3", "22:00", "06:00")},
+		{Name: "冻结期", Window: freeze(start, end)},
+		{Name: "未指定环境", Window: insight.MaintenanceWindow{Type: insight.MaintenanceTypeWeekly, Weekdays: "1", StartTime: "00:00", EndTime: "01:00"}, WantErr: true},
+		{Name: "星期超出范围", Window: weekly("7", "22:00", "06:00"), WantErr: true},
+		{Name: "未指定星期", Window: weekly(" , ", "22:00", "06:00"), WantErr: true},
+		{Name: "时间格式错误", Window: weekly("1", "24:00", "06:00"), WantErr: true},
+		{Name: "冻结期结束早于开始", Window: freeze(end, start), WantErr: true},
+		{Name: "实例ID格式错误", Window: insight.MaintenanceWindow{Environment: 1, InstanceID: "abc", Type: insight.MaintenanceTypeFreeze, FreezeStart: &start, FreezeEnd: &end}, WantErr: true},
+		{Name: "未知类型", Window: insight.MaintenanceWindow{Environment: 1, Type: "daily"}, WantErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := ValidateMaintenanceWindow(&tc.Window)
+			if (err != nil) != tc.WantErr {
+				t.Errorf("期望返回错误=%v，实际 %v", tc.WantErr, err)
+			}
+		})
+	}
+}
+
+func ptrTime(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,135 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/internal/model/insight"
+	"go-noah/internal/orders/executor"
+	"go-noah/pkg/secret"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ValidateMigrateOrder 校验数据迁移工单：源实例和目标实例仅支持 MySQL/TiDB，每条语句为 SELECT * FROM 源表 [WHERE 过滤条件]
+// 目标实例须与源实例属于不同环境，申请人（DBA除外）须有目标库的权限
+// 校验通过后将迁移选项写入工单
+func (s *InsightService) ValidateMigrateOrder(ctx context.Context, order *insight.OrderRecord, opts *insight.MigrateOptions, userID uint) error {
+	if order.DBType != insight.DbTypeMySQL && order.DBType != insight.DbTypeTiDB {
+		return errors.New("数据迁移工单仅支持 MySQL/TiDB")
+	}
+	if opts == nil || opts.TargetInstanceID == "" {
+		return errors.New("数据迁移工单需要设置目标实例")
+	}
+	if _, err := uuid.Parse(opts.TargetInstanceID); err != nil {
+		return fmt.Errorf("目标实例ID格式错误: %s", opts.TargetInstanceID)
+	}
+	if _, err := s.targetInstanceConfig(ctx, opts.TargetInstanceID); err != nil {
+		return err
+	}
+	if err := s.checkMigrateTarget(ctx, order, opts, userID); err != nil {
+		return err
+	}
+	if !insight.IsValidConflictStrategy(string(opts.ConflictStrategy)) {
+		return fmt.Errorf("不支持的冲突处理策略: %s", opts.ConflictStrategy)
+	}
+	// 哈希脱敏使用工单级随机密钥（加密保存），忽略请求中传入的密钥
+	opts.MaskSecret = ""
+	for column, rule := range opts.Masking {
+		if !executor.IsValidMaskRule(rule) {
+			return fmt.Errorf("列 %s 的脱敏规则 %s 不支持", column, rule)
+		}
+		if rule == executor.MaskRuleHash && opts.MaskSecret == "" {
+			key, err := secret.GenerateKey()
+			if err != nil {
+				return fmt.Errorf("生成脱敏密钥失败: %w", err)
+			}
+			if opts.MaskSecret, err = secret.Encrypt(key); err != nil {
+				return fmt.Errorf("加密脱敏密钥失败: %w", err)
+			}
+		}
+	}
+	if opts.BatchSize < 0 {
+		return errors.New("每批写入行数不能小于0")
+	}
+
+	sqls, err := s.splitSQLText(order.DBType, order.Content)
+	if err != nil {
+		return err
+	}
+	if len(sqls) == 0 {
+		return errors.New("数据迁移工单内容不能为空")
+	}
+	if len(sqls) > 1 && (opts.TargetTable != "" || len(opts.ColumnMapping) > 0) {
+		return errors.New("指定目标表或列映射时，工单只能包含一条语句")
+	}
+	for _, sql := range sqls {
+		if err := executor.ValidateMigrateSQL(sql, order.Schema); err != nil {
+			return err
+		}
+	}
+
+	order.MigrateOptions, err = json.Marshal(opts)
+	return err
+}
+
+// checkMigrateTarget 检查迁移目标：目标实例与源实例须属于不同环境（避免绕过目标环境的审批向其写入数据），
+// 申请人须有目标库（或指定目标表）的权限，DBA 不受限制
+func (s *InsightService) checkMigrateTarget(ctx context.Context, order *insight.OrderRecord, opts *insight.MigrateOptions, userID uint) error {
+	source, err := s.getRepo().GetDBConfigByInstanceID(ctx, order.InstanceID.String())
+	if err != nil {
+		return fmt.Errorf("源实例不存在: %w", err)
+	}
+	target, err := s.getRepo().GetDBConfigByInstanceID(ctx, opts.TargetInstanceID)
+	if err != nil {
+		return fmt.Errorf("目标实例不存在: %w", err)
+	}
+	if source.Environment == 0 || target.Environment == 0 {
+		return errors.New("源实例或目标实例未设置环境，无法创建数据迁移工单")
+	}
+	if source.Environment == target.Environment {
+		return errors.New("目标实例须与源实例属于不同环境（同一环境内的数据变更请提交DML工单）")
+	}
+
+	if s.IsDBA(userID) {
+		return nil
+	}
+	schema := opts.TargetSchema
+	if schema == "" {
+		schema = order.Schema
+	}
+	perms, err := s.GetUserEffectivePermissions(ctx, order.Applicant)
+	if err != nil {
+		return err
+	}
+	for _, perm := range perms {
+		if perm.InstanceID != opts.TargetInstanceID || perm.Schema != schema {
+			continue
+		}
+		if perm.Table == "" || (opts.TargetTable != "" && strings.EqualFold(perm.Table, opts.TargetTable)) {
+			return nil
+		}
+	}
+	return fmt.Errorf("没有目标实例库 %s 的权限，请先申请权限", schema)
+}
+
+// newMigrateConfig 根据工单的迁移选项构造执行器的迁移配置
+func newMigrateConfig(order *insight.OrderRecord) *executor.MigrateConfig {
+	var opts insight.MigrateOptions
+	if len(order.MigrateOptions) > 0 {
+		_ = json.Unmarshal(order.MigrateOptions, &opts)
+	}
+	return &executor.MigrateConfig{
+		TargetInstanceID: opts.TargetInstanceID,
+		TargetSchema:     opts.TargetSchema,
+		TargetTable:      opts.TargetTable,
+		ColumnMapping:    opts.ColumnMapping,
+		Masking:          opts.Masking,
+		MaskSecret:       opts.MaskSecret,
+		ConflictStrategy: string(opts.ConflictStrategy),
+		BatchSize:        opts.BatchSize,
+		Resolver:         instanceResolver{s: InsightServiceApp},
+	}
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"go-noah/internal/model"
+	"go-noah/pkg/global"
+	"strconv"
+)
+
+// userHasRole 用户是否为超级管理员或拥有任一指定角色
+func userHasRole(userID uint, roles ...string) bool {
+	if userID == 0 {
+		return false
+	}
+	uid := strconv.FormatUint(uint64(userID), 10)
+	if uid == model.AdminUserID {
+		return true
+	}
+	userRoles, err := global.Enforcer.GetRolesForUser(uid)
+	if err != nil {
+		return false
+	}
+	for _, role := range userRoles {
+		if role == model.AdminRole {
+			return true
+		}
+		for _, r := range roles {
+			if role == r {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsDBA 用户是否为 DBA（超级管理员、admin 或 dba 角色）
+func (s *InsightService) IsDBA(userID uint) bool {
+	return userHasRole(userID, model.RoleDBA)
+}
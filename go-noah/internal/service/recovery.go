@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/internal/model/insight"
+	"go-noah/internal/orders/executor"
+	mysqlpkg "go-noah/internal/orders/executor/mysql"
+	"go-noah/pkg/global"
+	"go-noah/pkg/notifier"
+	"go-noah/pkg/utils"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// recoveryUsername 核对中断任务时记录操作日志使用的用户名
+const recoveryUsername = "system"
+
+// binlogScanTimeout 核对执行结果时扫描 binlog 的最长时间
+const binlogScanTimeout = 5 * time.Minute
+
+// taskRecovery 中断任务的核对结果
+type taskRecovery struct {
+	progress insight.TaskProgress
+	reason   string
+	data     executor.ReturnData
+}
+
+// ReconcileStuckTasks 核对停留在“执行中”的任务（服务在执行过程中重启，执行 goroutine 已不存在）
+// 工单执行锁仍被持有说明任务正在其他节点执行，跳过；否则根据执行检查点、目标实例的 processlist 和 binlog
+// 将任务标记为已完成、已失败或待确认，通知执行人并记录操作日志
+func (s *InsightService) ReconcileStuckTasks(ctx context.Context) error {
+	tasks, err := s.getRepo().GetTasksByProgress(ctx, insight.TaskProgressExecuting)
+	if err != nil {
+		return err
+	}
+	var orderIDs []string
+	seen := make(map[string]bool)
+	for _, task := range tasks {
+		orderID := task.OrderID.String()
+		if !seen[orderID] {
+			seen[orderID] = true
+			orderIDs = append(orderIDs, orderID)
+		}
+	}
+	for _, orderID := range orderIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := s.reconcileOrder(ctx, orderID); err != nil {
+			global.Logger.Warn("核对中断任务失败", zap.String("order_id", orderID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// reconcileOrder 持有工单执行锁核对工单中停留在“执行中”的任务
+func (s *InsightService) reconcileOrder(ctx context.Context, orderID string) error {
+	lock, err := s.AcquireOrderLock(orderID)
+	if err != nil {
+		// 正在其他节点（或本节点）执行，不是中断的任务
+		global.Logger.Debug("工单正在执行，跳过核对", zap.String("order_id", orderID), zap.Error(err))
+		return nil
+	}
+	defer lock.Release()
+
+	order, err := s.getRepo().GetOrderByID(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	tasks, err := s.getRepo().GetOrderTasks(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	dbConfig, err := s.GetDBConfigByInstanceID(ctx, order.InstanceID.String())
+	if err != nil {
+		return err
+	}
+
+	var lines []string
+	for i := range tasks {
+		task := &tasks[i]
+		if task.Progress != insight.TaskProgressExecuting {
+			continue
+		}
+		taskID := task.TaskID.String()
+		recovery, err := s.inspectInterruptedTask(ctx, &order.OrderRecord, task, dbConfig)
+		if err != nil {
+			global.Logger.Warn("暂时无法核对中断任务，稍后重试",
+				zap.String("order_id", orderID),
+				zap.String("task_id", taskID),
+				zap.Error(err),
+			)
+			continue
+		}
+
+		if recovery.progress != insight.TaskProgressCompleted {
+			recovery.data.Error = recovery.reason
+		}
+		recovery.data.ExecuteLog = fmt.Sprintf("[%s] 服务重启后核对执行结果：%s，%s",
+			time.Now().Format("2006-01-02 15:04:05"), recovery.progress, recovery.reason)
+		resultJSON := s.MarshalTaskResult(ctx, taskID, recovery.data)
+		if err := s.UpdateTaskProgressFenced(ctx, taskID, lock.Fence(), recovery.progress, resultJSON); err != nil {
+			return err
+		}
+		utils.DeleteTaskCheckpoint(taskID)
+		utils.DeleteTaskConnectionID(taskID)
+
+		msg := fmt.Sprintf("服务重启后核对中断的任务 %s：%s，%s", taskID, recovery.progress, recovery.reason)
+		_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+			Username: recoveryUsername,
+			OrderID:  order.OrderID,
+			Msg:      msg,
+		})
+		global.Logger.Info("已核对中断任务",
+			zap.String("order_id", orderID),
+			zap.String("task_id", taskID),
+			zap.String("progress", string(recovery.progress)),
+			zap.String("reason", recovery.reason),
+		)
+		lines = append(lines, fmt.Sprintf(">任务ID：%s\n>核对结果：%s\n>说明：%s", taskID, recovery.progress, recovery.reason))
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+
+	if allCompleted, err := s.CheckAllTasksCompleted(ctx, orderID); err == nil && allCompleted {
+		_ = s.UpdateOrderProgress(ctx, orderID, insight.ProgressCompleted)
+	}
+
+	// 通知执行人（同时通知申请人）
+	var executors []string
+	if len(order.Executor) > 0 {
+		_ = json.Unmarshal(order.Executor, &executors)
+	}
+	msg := fmt.Sprintf("您好，服务重启导致工单执行中断，已核对中断任务的执行结果，请悉知\n>工单标题：%s\n%s",
+		order.Title, strings.Join(lines, "\n"))
+	notifier.SendOrderNotification(orderID, order.Title, order.Applicant, executors, msg)
+	return nil
+}
+
+// inspectInterruptedTask 根据执行检查点核对中断任务的执行结果，返回 error 表示暂时无法判断（下次重试）
+func (s *InsightService) inspectInterruptedTask(ctx context.Context, order *insight.OrderRecord, task *insight.OrderTask, dbConfig *insight.DBConfig) (*taskRecovery, error) {
+	if task.SQLType == insight.SQLTypeExport {
+		return &taskRecovery{progress: insight.TaskProgressFailed, reason: "导出任务不修改数据，可直接重新执行"}, nil
+	}
+	if task.SQLType == insight.SQLTypeArchive {
+		return &taskRecovery{progress: insight.TaskProgressFailed, reason: "归档任务每批完成后保存断点，重新执行将从断点继续"}, nil
+	}
+	if task.SQLType == insight.SQLTypeMigrate {
+		return &taskRecovery{progress: insight.TaskProgressFailed, reason: "数据迁移任务重新执行将从头复制（冲突策略为 fail 时需先清理目标表中已复制的数据）"}, nil
+	}
+	unknown := func(reason string) (*taskRecovery, error) {
+		return &taskRecovery{progress: insight.TaskProgressUnknown, reason: reason + "，请人工确认实际执行结果后更新任务状态"}, nil
+	}
+	if order.DBType == insight.DbTypeClickHouse {
+		return unknown("ClickHouse 任务无法自动核对执行结果")
+	}
+	checkpoint, err := utils.GetTaskCheckpoint(task.TaskID.String())
+	if err != nil {
+		return unknown("未找到执行检查点（执行连接尚未建立，或由 gh-ost/pt-osc 等外部工具执行）")
+	}
+
+	execConfig := NewExecutorConfig(order, task, dbConfig)
+	db, err := executor.NewMySQLExecutor(execConfig).Connect()
+	if err != nil {
+		return nil, fmt.Errorf("连接实例失败: %w", err)
+	}
+	defer db.Close()
+
+	// 执行连接仍然存在：DML 的事务只能由已退出的执行进程提交，终止连接使事务回滚；DDL 等待语句执行结束
+	var command string
+	err = db.QueryRowContext(ctx,
+		"SELECT COMMAND FROM INFORMATION_SCHEMA.PROCESSLIST WHERE ID = ? AND USER = ?",
+		checkpoint.ConnectionID, dbConfig.UserName).Scan(&command)
+	if err == nil {
+		if task.SQLType != insight.SQLTypeDML {
+			return nil, fmt.Errorf("执行连接 %d 仍在执行（%s），等待执行结束", checkpoint.ConnectionID, command)
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("KILL %d", checkpoint.ConnectionID)); err != nil {
+			return nil, fmt.Errorf("终止执行连接 %d 失败: %w", checkpoint.ConnectionID, err)
+		}
+		global.Logger.Info("已终止中断任务残留的执行连接",
+			zap.String("task_id", task.TaskID.String()),
+			zap.Int64("connection_id", checkpoint.ConnectionID),
+		)
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("查询 processlist 失败: %w", err)
+	}
+
+	if checkpoint.BinlogFile == "" {
+		return unknown("执行开始时未获取到 binlog 位置，无法核对执行结果")
+	}
+	endFile, endPosition, err := mysqlpkg.GetBinlogPos(db)
+	if err != nil {
+		return unknown("获取当前 binlog 位置失败（" + err.Error() + "）")
+	}
+	binlog := mysqlpkg.Binlog{
+		Config: &mysqlpkg.BinlogConfig{
+			Hostname: execConfig.Hostname,
+			Port:     execConfig.Port,
+			UserName: execConfig.UserName,
+			Password: execConfig.Password,
+			Schema:   execConfig.Schema,
+
+			ConnOptions: execConfig.ConnOptions,
+		},
+		ConnectionID:  checkpoint.ConnectionID,
+		StartFile:     checkpoint.BinlogFile,
+		StartPosition: checkpoint.BinlogPos,
+		EndFile:       endFile,
+		EndPosition:   endPosition,
+	}
+	scanCtx, cancel := context.WithTimeout(ctx, binlogScanTimeout)
+	activity, err := binlog.ScanThread(scanCtx)
+	cancel()
+	if err != nil {
+		return unknown("解析 binlog 失败（" + err.Error() + "）")
+	}
+
+	if task.SQLType != insight.SQLTypeDML {
+		if len(activity.Statements) > 0 {
+			return &taskRecovery{progress: insight.TaskProgressCompleted, reason: "binlog 中已有该连接执行的DDL，执行已生效"}, nil
+		}
+		return &taskRecovery{progress: insight.TaskProgressFailed, reason: "执行连接已断开且 binlog 中没有该连接执行的DDL，执行未生效，可重新执行"}, nil
+	}
+	if activity.Transactions == 0 {
+		return &taskRecovery{progress: insight.TaskProgressFailed, reason: "执行连接已断开且 binlog 中没有该连接提交的事务，变更已回滚，可重新执行"}, nil
+	}
+
+	// 已提交的变更尽量生成回滚SQL
+	recovery := &taskRecovery{progress: insight.TaskProgressCompleted, reason: "binlog 中已有该连接提交的事务，执行已生效"}
+	if checkpoint.Chunked {
+		recovery.progress = insight.TaskProgressUnknown
+		recovery.reason = fmt.Sprintf("分批执行已提交 %d 个批次，无法确认是否全部完成，请人工确认实际执行结果后更新任务状态", activity.Transactions)
+	}
+	if rollbackSQL, err := binlog.Run(); err != nil {
+		recovery.reason += "（生成回滚SQL失败: " + err.Error() + "）"
+	} else if rollbackSQL != "" {
+		recovery.data.RollbackSQL = rollbackSQL
+		recovery.data.BackupStrategy = executor.BackupStrategyBinlog
+	}
+	return recovery, nil
+}
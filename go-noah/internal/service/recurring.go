@@ -0,0 +1,403 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/internal/model/insight"
+	"go-noah/internal/orders/executor"
+	insightRepo "go-noah/internal/repository/insight"
+	"go-noah/pkg/global"
+	"go-noah/pkg/notifier"
+	"go-noah/pkg/utils"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	// defaultRecurringMaxFailures 周期工单连续失败多少次后自动暂停（recurring_order.max_consecutive_failures）
+	defaultRecurringMaxFailures = 3
+	// recurringErrorMaxLen 执行记录中失败原因的最大长度
+	recurringErrorMaxLen = 1024
+	// recurringUsername 周期执行记录操作日志使用的用户名
+	recurringUsername = "system"
+)
+
+// ParseRecurringCron 解析周期工单的 cron 表达式（分 时 日 月 周，不支持 @every）
+func ParseRecurringCron(expr string) (cron.Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every") {
+		return nil, errors.New("周期工单不支持 @every 表达式")
+	}
+	schedule, err := cron.ParseStandard(expr)
+	if err != nil {
+		return nil, fmt.Errorf("cron 表达式格式错误: %w", err)
+	}
+	return schedule, nil
+}
+
+// ValidateRecurringOrder 校验周期工单：仅支持 MySQL/TiDB 的 DML 工单，必须设置有效期和影响行数上限
+func (s *InsightService) ValidateRecurringOrder(order *insight.OrderRecord, schedule *insight.RecurringSchedule) error {
+	if order.SQLType != insight.SQLTypeDML {
+		return errors.New("周期工单仅支持 DML 工单")
+	}
+	if order.DBType != insight.DbTypeMySQL && order.DBType != insight.DbTypeTiDB {
+		return errors.New("周期工单仅支持 MySQL/TiDB")
+	}
+	if order.ScheduleTime != nil {
+		return errors.New("周期工单不能同时设置定时执行")
+	}
+	cronSchedule, err := ParseRecurringCron(schedule.CronExpr)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	if !schedule.ExpireAt.After(now) {
+		return errors.New("有效期截止时间必须晚于当前时间")
+	}
+	if cronSchedule.Next(now).After(schedule.ExpireAt) {
+		return errors.New("有效期内没有需要执行的时间点")
+	}
+	if schedule.MaxAffectedRows <= 0 {
+		return errors.New("周期工单必须设置单次执行影响行数上限")
+	}
+	if schedule.MaxConsecutiveFailures < 0 {
+		return errors.New("连续失败次数上限不能小于0")
+	}
+	return nil
+}
+
+// CreateRecurringSchedule 创建周期工单执行计划（工单审批通过后开始按计划执行）
+func (s *InsightService) CreateRecurringSchedule(ctx context.Context, schedule *insight.RecurringSchedule) error {
+	if schedule.MaxConsecutiveFailures == 0 {
+		schedule.MaxConsecutiveFailures = defaultRecurringMaxFailures
+		if global.Conf != nil {
+			if v := global.Conf.GetInt("recurring_order.max_consecutive_failures"); v > 0 {
+				schedule.MaxConsecutiveFailures = v
+			}
+		}
+	}
+	schedule.State = insight.RecurringStateActive
+	return s.getRepo().CreateRecurringSchedule(ctx, schedule)
+}
+
+// GetRecurringSchedule 获取工单的周期执行计划，不是周期工单时返回 nil
+func (s *InsightService) GetRecurringSchedule(ctx context.Context, orderID string) (*insight.RecurringSchedule, error) {
+	return s.getRepo().GetRecurringScheduleByOrderID(ctx, orderID)
+}
+
+// GetRecurringSchedules 获取周期工单执行计划列表
+func (s *InsightService) GetRecurringSchedules(ctx context.Context, page, pageSize int, state string) ([]insightRepo.RecurringScheduleWithOrder, int64, error) {
+	return s.getRepo().GetRecurringSchedules(ctx, page, pageSize, state)
+}
+
+// GetRecurringRuns 获取周期工单的执行记录
+func (s *InsightService) GetRecurringRuns(ctx context.Context, orderID string, page, pageSize int) ([]insight.RecurringRun, int64, error) {
+	return s.getRepo().GetRecurringRuns(ctx, orderID, page, pageSize)
+}
+
+// SuspendRecurringOrder 手动暂停周期工单
+func (s *InsightService) SuspendRecurringOrder(ctx context.Context, orderID string, username string) error {
+	schedule, err := s.getRecurringScheduleOrError(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	updated, err := s.getRepo().TransitRecurringSchedule(ctx, schedule.ID, insight.RecurringStateActive, map[string]interface{}{
+		"state":          insight.RecurringStateSuspended,
+		"suspend_reason": "由 " + username + " 手动暂停",
+	})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("只有执行中的周期工单可以暂停，当前状态: %s", schedule.State)
+	}
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: username,
+		OrderID:  schedule.OrderID,
+		Msg:      "暂停周期工单",
+	})
+	return nil
+}
+
+// ResumeRecurringOrder 恢复已暂停的周期工单（清零连续失败次数，从恢复时间起重新计算下次执行时间）
+func (s *InsightService) ResumeRecurringOrder(ctx context.Context, orderID string, username string) error {
+	schedule, err := s.getRecurringScheduleOrError(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	if !schedule.ExpireAt.After(time.Now()) {
+		return errors.New("周期工单已过有效期，无法恢复")
+	}
+	updated, err := s.getRepo().TransitRecurringSchedule(ctx, schedule.ID, insight.RecurringStateSuspended, map[string]interface{}{
+		"state":                insight.RecurringStateActive,
+		"suspend_reason":       "",
+		"consecutive_failures": 0,
+		"next_run_at":          nil,
+	})
+	if err != nil {
+		return err
+	}
+	if !updated {
+		return fmt.Errorf("只有已暂停的周期工单可以恢复，当前状态: %s", schedule.State)
+	}
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: username,
+		OrderID:  schedule.OrderID,
+		Msg:      "恢复周期工单",
+	})
+	return nil
+}
+
+func (s *InsightService) getRecurringScheduleOrError(ctx context.Context, orderID string) (*insight.RecurringSchedule, error) {
+	schedule, err := s.getRepo().GetRecurringScheduleByOrderID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, errors.New("工单不是周期工单")
+	}
+	return schedule, nil
+}
+
+// RunDueRecurringOrders 触发到期的周期工单（TaskServer 定期调用）
+// 多节点同时轮询时通过条件更新下次执行时间保证只有一个节点触发；错过的执行时间只补执行一次
+func (s *InsightService) RunDueRecurringOrders(ctx context.Context) error {
+	now := time.Now()
+	schedules, err := s.getRepo().GetDueRecurringSchedules(ctx, now)
+	if err != nil {
+		return err
+	}
+	for i := range schedules {
+		schedule := &schedules[i]
+		if !now.Before(schedule.ExpireAt) {
+			s.expireRecurringSchedule(ctx, schedule)
+			continue
+		}
+		cronSchedule, err := ParseRecurringCron(schedule.CronExpr)
+		if err != nil {
+			global.Logger.Warn("周期工单 cron 表达式无效", zap.String("order_id", schedule.OrderID.String()), zap.Error(err))
+			continue
+		}
+		advanced, err := s.getRepo().AdvanceRecurringSchedule(ctx, schedule.ID, schedule.NextRunAt, cronSchedule.Next(now))
+		if err != nil || !advanced {
+			continue
+		}
+		// 审批通过（或恢复）后首次轮询只计算下次执行时间
+		if schedule.NextRunAt == nil {
+			continue
+		}
+		go s.runRecurringOrder(context.Background(), schedule)
+	}
+	return nil
+}
+
+// expireRecurringSchedule 周期工单过有效期后结束工单并通知
+func (s *InsightService) expireRecurringSchedule(ctx context.Context, schedule *insight.RecurringSchedule) {
+	orderID := schedule.OrderID.String()
+	updated, err := s.getRepo().TransitRecurringSchedule(ctx, schedule.ID, insight.RecurringStateActive, map[string]interface{}{
+		"state":       insight.RecurringStateExpired,
+		"next_run_at": nil,
+	})
+	if err != nil || !updated {
+		return
+	}
+	_ = s.UpdateOrderProgress(ctx, orderID, insight.ProgressCompleted)
+	msg := fmt.Sprintf("周期工单已过有效期（%s），共执行 %d 次，不再执行", schedule.ExpireAt.Format(time.DateTime), schedule.RunCount)
+	s.reportRecurringRun(ctx, schedule, msg)
+}
+
+// runRecurringOrder 持有工单执行锁执行一次周期工单，记录执行结果，连续失败次数达到上限时自动暂停
+func (s *InsightService) runRecurringOrder(ctx context.Context, schedule *insight.RecurringSchedule) {
+	orderID := schedule.OrderID.String()
+	startedAt := time.Now()
+
+	// 本次跳过，不计入失败次数
+	skip := func(reason string) {
+		_ = s.getRepo().CreateRecurringRun(ctx, &insight.RecurringRun{
+			ScheduleID: schedule.ID,
+			OrderID:    schedule.OrderID,
+			State:      insight.RecurringRunSkipped,
+			Error:      utils.TruncateString(reason, recurringErrorMaxLen),
+			StartedAt:  startedAt,
+			FinishedAt: &startedAt,
+		})
+		s.reportRecurringRun(ctx, schedule, "周期工单本次执行已跳过："+reason)
+	}
+
+	// 上一次执行尚未结束
+	lock, err := s.AcquireOrderLock(orderID)
+	if err != nil {
+		skip(err.Error())
+		return
+	}
+	defer lock.Release()
+
+	// 维护窗口外或冻结期内
+	if order, err := s.getRepo().GetOrderByID(ctx, orderID); err == nil {
+		if status, err := s.CheckOrderMaintenanceWindow(ctx, &order.OrderRecord); err == nil && !status.Allowed {
+			skip(status.Message())
+			return
+		}
+	}
+
+	// 持有执行锁时仍为执行中的记录说明上一次执行被中断（服务重启）
+	if n, err := s.getRepo().FailInterruptedRecurringRuns(ctx, schedule.ID, "执行中断（服务重启），执行结果未知，请检查目标表数据"); err == nil && n > 0 {
+		global.Logger.Warn("周期工单存在中断的执行记录", zap.String("order_id", orderID), zap.Int64("count", n))
+	}
+
+	run := &insight.RecurringRun{
+		ScheduleID: schedule.ID,
+		OrderID:    schedule.OrderID,
+		State:      insight.RecurringRunRunning,
+		StartedAt:  startedAt,
+	}
+	if err := s.getRepo().CreateRecurringRun(ctx, run); err != nil {
+		global.Logger.Error("创建周期工单执行记录失败", zap.String("order_id", orderID), zap.Error(err))
+		return
+	}
+
+	data, execErr := s.executeRecurringRun(lock.Context(), schedule)
+
+	finishedAt := time.Now()
+	runUpdates := map[string]interface{}{
+		"state":         insight.RecurringRunSucceeded,
+		"affected_rows": data.AffectedRows,
+		"result":        s.MarshalTaskResult(ctx, fmt.Sprintf("recurring-%d", run.ID), data),
+		"finished_at":   finishedAt,
+	}
+	scheduleUpdates := map[string]interface{}{
+		"last_run_at":          startedAt,
+		"run_count":            gorm.Expr("run_count + 1"),
+		"consecutive_failures": 0,
+	}
+	if execErr != nil {
+		runUpdates["state"] = insight.RecurringRunFailed
+		runUpdates["error"] = utils.TruncateString(execErr.Error(), recurringErrorMaxLen)
+		scheduleUpdates["consecutive_failures"] = gorm.Expr("consecutive_failures + 1")
+	}
+	if err := s.getRepo().UpdateRecurringRun(ctx, run.ID, runUpdates); err != nil {
+		global.Logger.Error("更新周期工单执行记录失败", zap.String("order_id", orderID), zap.Error(err))
+	}
+	if err := s.getRepo().UpdateRecurringSchedule(ctx, schedule.ID, scheduleUpdates); err != nil {
+		global.Logger.Error("更新周期工单执行计划失败", zap.String("order_id", orderID), zap.Error(err))
+	}
+
+	current, err := s.getRepo().GetRecurringScheduleByOrderID(ctx, orderID)
+	if err != nil || current == nil {
+		current = schedule
+	}
+	costTime := finishedAt.Sub(startedAt).Round(time.Millisecond)
+	if execErr == nil {
+		global.Logger.Info("周期工单执行成功", zap.String("order_id", orderID), zap.Int64("affected_rows", data.AffectedRows))
+		s.reportRecurringRun(ctx, current, fmt.Sprintf("周期工单第 %d 次执行成功，影响行数 %d，耗时 %s",
+			current.RunCount, data.AffectedRows, costTime))
+		return
+	}
+
+	global.Logger.Warn("周期工单执行失败", zap.String("order_id", orderID), zap.Error(execErr))
+	msg := fmt.Sprintf("周期工单第 %d 次执行失败（连续失败 %d 次），影响行数 %d，耗时 %s，原因：%s",
+		current.RunCount, current.ConsecutiveFailures, data.AffectedRows, costTime, execErr.Error())
+	if reason, ok := recurringSuspendReason(current); ok {
+		suspended, err := s.getRepo().TransitRecurringSchedule(ctx, schedule.ID, insight.RecurringStateActive, map[string]interface{}{
+			"state":          insight.RecurringStateSuspended,
+			"suspend_reason": reason,
+		})
+		if err == nil && suspended {
+			msg += "；" + reason + "，排查原因后可手动恢复"
+		}
+	}
+	s.reportRecurringRun(ctx, current, msg)
+}
+
+// recurringSuspendReason 连续失败次数达到上限时返回自动暂停的原因（上限为 0 时不自动暂停）
+func recurringSuspendReason(schedule *insight.RecurringSchedule) (string, bool) {
+	if schedule.MaxConsecutiveFailures <= 0 || schedule.ConsecutiveFailures < schedule.MaxConsecutiveFailures {
+		return "", false
+	}
+	return fmt.Sprintf("连续失败 %d 次，已自动暂停", schedule.ConsecutiveFailures), true
+}
+
+// executeRecurringRun 依次执行工单的DML任务，所有任务累计的影响行数不能超过上限
+func (s *InsightService) executeRecurringRun(ctx context.Context, schedule *insight.RecurringSchedule) (result executor.ReturnData, err error) {
+	orderID := schedule.OrderID.String()
+
+	order, err := s.getRepo().GetOrderByID(ctx, orderID)
+	if err != nil {
+		return result, fmt.Errorf("获取工单信息失败: %w", err)
+	}
+	if order.Progress != insight.ProgressApproved {
+		return result, fmt.Errorf("工单状态不允许执行，当前状态: %s", order.Progress)
+	}
+	tasks, err := s.getRepo().GetOrderTasks(ctx, orderID)
+	if err != nil {
+		return result, fmt.Errorf("获取任务列表失败: %w", err)
+	}
+	if len(tasks) == 0 {
+		return result, errors.New("没有需要执行的任务")
+	}
+	dbConfig, err := s.GetDBConfigByInstanceID(ctx, order.InstanceID.String())
+	if err != nil {
+		return result, fmt.Errorf("获取数据库配置失败: %w", err)
+	}
+
+	var logs, rollbackSQLs []string
+	defer func() {
+		result.ExecuteLog = strings.Join(logs, "\n")
+		result.RollbackSQL = strings.Join(rollbackSQLs, "\n")
+	}()
+	for i := range tasks {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		remaining := schedule.MaxAffectedRows - result.AffectedRows
+		if remaining <= 0 {
+			return result, fmt.Errorf("%w（已达到单次执行上限 %d）", executor.ErrAffectedRowsExceeded, schedule.MaxAffectedRows)
+		}
+		config := NewExecutorConfig(&order.OrderRecord, &tasks[i], dbConfig)
+		// 周期执行不占用任务的执行检查点和取消标记，任务本身的状态保持不变
+		config.TaskID = ""
+		config.MaxAffectedRows = remaining
+
+		data, err := executor.NewMySQLExecutor(config).Run(ctx)
+		result.AffectedRows += data.AffectedRows
+		if data.ExecuteLog != "" {
+			logs = append(logs, data.ExecuteLog)
+		}
+		if data.RollbackSQL != "" {
+			rollbackSQLs = append(rollbackSQLs, data.RollbackSQL)
+			result.BackupStrategy = data.BackupStrategy
+		}
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// reportRecurringRun 记录操作日志并通知执行人（同时通知申请人）
+func (s *InsightService) reportRecurringRun(ctx context.Context, schedule *insight.RecurringSchedule, msg string) {
+	orderID := schedule.OrderID.String()
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: recurringUsername,
+		OrderID:  schedule.OrderID,
+		Msg:      utils.TruncateString(msg, recurringErrorMaxLen),
+	})
+
+	order, err := s.getRepo().GetOrderByID(ctx, orderID)
+	if err != nil {
+		global.Logger.Warn("获取工单信息失败，无法发送通知", zap.String("order_id", orderID), zap.Error(err))
+		return
+	}
+	var executors []string
+	if len(order.Executor) > 0 {
+		_ = json.Unmarshal(order.Executor, &executors)
+	}
+	notifier.SendOrderNotification(orderID, order.Title, order.Applicant, executors,
+		fmt.Sprintf("您好，%s，请悉知\n>工单标题：%s", msg, order.Title))
+}
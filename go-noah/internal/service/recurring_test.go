@@ -0,0 +1,128 @@
+package service
+
+import (
+	"go-noah/internal/model/insight"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRecurringCron(t *testing.T) {
+	from := time.Date(2024, 6, 3, 10, 30, 0, 0, time.UTC) // 周一
+	testCases := []struct {
+		Name       string
+		Expr       string
+		ExpectNext time.Time
+		ExpectErr  string // 期望错误包含的消息
+	}{
+		{Name: "每天凌晨两点", Expr: "0 2 * * *", ExpectNext: time.Date(2024, 6, 4, 2, 0, 0, 0, time.UTC)},
+		{Name: "首尾空白", Expr: "  */15 * * * *  ", ExpectNext: time.Date(2024, 6, 3, 10, 45, 0, 0, time.UTC)},
+		{Name: "工作日", Expr: "0 9 * * 1-5", ExpectNext: time.Date(2024, 6, 4, 9, 0, 0, 0, time.UTC)},
+		{Name: "每周日", Expr: "30 3 * * 0", ExpectNext: time.Date(2024, 6, 9, 3, 30, 0, 0, time.UTC)},
+		{Name: "每月1日", Expr: "0 0 1 * *", ExpectNext: time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{Name: "预定义表达式", Expr: "@daily", ExpectNext: time.Date(2024, 6, 4, 0, 0, 0, 0, time.UTC)},
+		{Name: "不支持@every", Expr: "@every 1h", ExpectErr: "@every"},
+		{Name: "不支持秒字段", Expr: "0 0 2 * * *", ExpectErr: "格式错误"},
+		{Name: "字段超出范围", Expr: "0 25 * * *", ExpectErr: "格式错误"},
+		{Name: "空表达式", Expr: "", ExpectErr: "格式错误"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			schedule, err := ParseRecurringCron(tc.Expr)
+			if tc.ExpectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tc.ExpectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if next := schedule.Next(from); !next.Equal(tc.ExpectNext) {
+				t.Errorf("期望下次执行时间 %s，实际 %s", tc.ExpectNext, next)
+			}
+		})
+	}
+}
+
+func TestValidateRecurringOrder(t *testing.T) {
+	s := &InsightService{}
+	now := time.Now()
+	scheduleTime := now.Add(time.Hour)
+	order := func(modify func(o *insight.OrderRecord)) *insight.OrderRecord {
+		o := &insight.OrderRecord{SQLType: insight.SQLTypeDML, DBType: insight.DbTypeMySQL}
+		if modify != nil {
+			modify(o)
+		}
+		return o
+	}
+	schedule := func(modify func(s *insight.RecurringSchedule)) *insight.RecurringSchedule {
+		sc := &insight.RecurringSchedule{CronExpr: "0 2 * * *", ExpireAt: now.AddDate(0, 1, 0), MaxAffectedRows: 1000}
+		if modify != nil {
+			modify(sc)
+		}
+		return sc
+	}
+	testCases := []struct {
+		Name      string
+		Order     *insight.OrderRecord
+		Schedule  *insight.RecurringSchedule
+		ExpectErr string
+	}{
+		{Name: "有效的周期工单", Order: order(nil), Schedule: schedule(nil)},
+		{Name: "TiDB", Order: order(func(o *insight.OrderRecord) { o.DBType = insight.DbTypeTiDB }), Schedule: schedule(nil)},
+		{Name: "DDL工单", Order: order(func(o *insight.OrderRecord) { o.SQLType = insight.SQLTypeDDL }), Schedule: schedule(nil), ExpectErr: "仅支持 DML"},
+		{Name: "ClickHouse", Order: order(func(o *insight.OrderRecord) { o.DBType = insight.DbTypeClickHouse }), Schedule: schedule(nil), ExpectErr: "仅支持 MySQL/TiDB"},
+		{Name: "同时设置定时执行", Order: order(func(o *insight.OrderRecord) { o.ScheduleTime = &scheduleTime }), Schedule: schedule(nil), ExpectErr: "定时执行"},
+		{Name: "cron错误", Order: order(nil), Schedule: schedule(func(s *insight.RecurringSchedule) { s.CronExpr = "bad" }), ExpectErr: "cron"},
+		{Name: "有效期已过", Order: order(nil), Schedule: schedule(func(s *insight.RecurringSchedule) { s.ExpireAt = now.Add(-time.Minute) }), ExpectErr: "有效期截止时间"},
+		{
+			Name:      "有效期内没有执行时间点",
+			Order:     order(nil),
+			Schedule:  schedule(func(s *insight.RecurringSchedule) { s.CronExpr = "0 0 1 1 *"; s.ExpireAt = now.Add(time.Minute) }),
+			ExpectErr: "有效期内没有",
+		},
+		{Name: "未设置影响行数上限", Order: order(nil), Schedule: schedule(func(s *insight.RecurringSchedule) { s.MaxAffectedRows = 0 }), ExpectErr: "影响行数上限"},
+		{Name: "失败次数上限为负数", Order: order(nil), Schedule: schedule(func(s *insight.RecurringSchedule) { s.MaxConsecutiveFailures = -1 }), ExpectErr: "连续失败次数"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			err := s.ValidateRecurringOrder(tc.Order, tc.Schedule)
+			if tc.ExpectErr == "" {
+				if err != nil {
+					t.Errorf("期望校验通过，实际 %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+				t.Errorf("期望错误包含 %q，实际 %v", tc.ExpectErr, err)
+			}
+		})
+	}
+}
+
+func TestRecurringSuspendReason(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Max, Failures int
+		ExpectSuspend bool
+	}{
+		{Name: "未失败", Max: 3, Failures: 0},
+		{Name: "未达到上限", Max: 3, Failures: 2},
+		{Name: "达到上限", Max: 3, Failures: 3, ExpectSuspend: true},
+		{Name: "超过上限", Max: 3, Failures: 5, ExpectSuspend: true},
+		{Name: "上限为0不自动暂停", Max: 0, Failures: 10},
+		{Name: "上限为1", Max: 1, Failures: 1, ExpectSuspend: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			reason, suspend := recurringSuspendReason(&insight.RecurringSchedule{MaxConsecutiveFailures: tc.Max, ConsecutiveFailures: tc.Failures})
+			if suspend != tc.ExpectSuspend {
+				t.Fatalf("期望自动暂停=%v，实际 %v", tc.ExpectSuspend, suspend)
+			}
+			if suspend && !strings.Contains(reason, "自动暂停") {
+				t.Errorf("暂停原因错误: %s", reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"go-noah/internal/model/insight"
+	insightRepo "go-noah/internal/repository/insight"
+	"time"
+)
+
+// GetScheduledExecutions 获取定时执行记录列表
+func (s *InsightService) GetScheduledExecutions(ctx context.Context, params *insightRepo.ScheduledExecutionQueryParams) ([]insightRepo.ScheduledExecutionWithOrder, int64, error) {
+	return s.getRepo().GetScheduledExecutions(ctx, params)
+}
+
+// GetScheduledExecution 根据ID获取定时执行记录
+func (s *InsightService) GetScheduledExecution(ctx context.Context, id uint) (*insight.ScheduledExecution, error) {
+	return s.getRepo().GetScheduledExecution(ctx, id)
+}
+
+// ConfirmScheduledExecution 确认错过计划时间的定时执行：run 为 true 时由调度节点尽快执行，否则跳过
+func (s *InsightService) ConfirmScheduledExecution(ctx context.Context, id uint, run bool, username string) (*insight.ScheduledExecution, error) {
+	execution, err := s.getRepo().GetScheduledExecution(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if execution.State != insight.ScheduleStateAsking {
+		return nil, errors.New("只有等待确认的定时执行可以确认，当前状态: " + string(execution.State))
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{"last_error": ""}
+	msg := "确认错过计划时间的定时执行：跳过执行"
+	if run {
+		// 改为补执行策略，避免再次因错过计划时间等待确认
+		updates["state"] = insight.ScheduleStatePending
+		updates["missed_policy"] = insight.MissedRunLate
+		updates["next_attempt_at"] = now
+		msg = "确认错过计划时间的定时执行：继续执行"
+	} else {
+		updates["state"] = insight.ScheduleStateSkipped
+		updates["finished_at"] = now
+	}
+	updated, err := s.getRepo().UpdateScheduledExecution(ctx, id, insight.ScheduleStateAsking, updates)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errors.New("定时执行状态已变化，请刷新后重试")
+	}
+
+	_ = s.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: username,
+		OrderID:  execution.OrderID,
+		Msg:      msg,
+	})
+	return s.getRepo().GetScheduledExecution(ctx, id)
+}
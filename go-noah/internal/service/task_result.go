@@ -0,0 +1,416 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/internal/orders/executor"
+	"go-noah/pkg/blobstore"
+	"go-noah/pkg/global"
+	"io"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// 执行日志超过该大小时才写入对象存储，较小的日志仍保存在任务记录中便于列表直接展示
+	defaultExecuteLogInlineSize = 64 * 1024
+	// 任务列表中展示的执行日志尾部大小
+	executeLogTailSize = 64 * 1024
+
+	defaultRollbackSQLPageSize = 100
+	maxRollbackSQLPageSize     = 1000
+	// 回滚SQL偏移索引每隔多少条语句记录一次起始字节偏移
+	rollbackSQLIndexInterval = 100
+)
+
+// 对象键
+func rollbackSQLKey(taskID string) string {
+	return fmt.Sprintf("tasks/%s/rollback.sql", taskID)
+}
+
+func rollbackSQLIndexKey(taskID string) string {
+	return fmt.Sprintf("tasks/%s/rollback.idx", taskID)
+}
+
+func executeLogKey(taskID string) string {
+	return fmt.Sprintf("tasks/%s/execute.log", taskID)
+}
+
+// getExecuteLogInlineSize 获取执行日志保存在任务记录中的最大字节数
+func getExecuteLogInlineSize() int {
+	if global.Conf != nil {
+		if size := global.Conf.GetInt("blob_store.log_inline_size"); size > 0 {
+			return size
+		}
+	}
+	return defaultExecuteLogInlineSize
+}
+
+// MarshalTaskResult 序列化任务执行结果，回滚SQL和较大的执行日志写入对象存储，任务记录中只保留引用和大小
+// 对象存储不可用时退回到保存在任务记录中，保证执行结果不丢失
+func (s *InsightService) MarshalTaskResult(ctx context.Context, taskID string, result executor.ReturnData) []byte {
+	store, err := blobstore.Default()
+	if err != nil {
+		global.Logger.Warn("对象存储不可用，执行结果保存在任务记录中", zap.String("task_id", taskID), zap.Error(err))
+		store = nil
+	}
+	// 执行可能因取消而结束，写入对象存储不能使用已取消的上下文
+	ctx = context.WithoutCancel(ctx)
+
+	if store != nil && result.RollbackSQL != "" {
+		key := rollbackSQLKey(taskID)
+		if err := store.Put(ctx, key, strings.NewReader(result.RollbackSQL), int64(len(result.RollbackSQL))); err != nil {
+			global.Logger.Warn("回滚SQL写入对象存储失败，保存在任务记录中", zap.String("task_id", taskID), zap.Error(err))
+		} else {
+			count, offsets := buildStatementIndex(result.RollbackSQL)
+			result.RollbackSQLRef = key
+			result.RollbackSQLSize = int64(len(result.RollbackSQL))
+			result.RollbackSQLCount = count
+			result.RollbackSQL = ""
+
+			// 语句较多时保存偏移索引，分页读取时从对应偏移开始读取，不需要扫描整个文件
+			if count > rollbackSQLIndexInterval {
+				indexKey := rollbackSQLIndexKey(taskID)
+				index := encodeStatementIndex(offsets)
+				if err := store.Put(ctx, indexKey, strings.NewReader(index), int64(len(index))); err != nil {
+					global.Logger.Warn("回滚SQL偏移索引写入对象存储失败，分页时扫描整个文件", zap.String("task_id", taskID), zap.Error(err))
+				} else {
+					result.RollbackSQLIndexRef = indexKey
+				}
+			}
+		}
+	}
+	if store != nil && len(result.ExecuteLog) > getExecuteLogInlineSize() {
+		key := executeLogKey(taskID)
+		if err := store.Put(ctx, key, strings.NewReader(result.ExecuteLog), int64(len(result.ExecuteLog))); err != nil {
+			global.Logger.Warn("执行日志写入对象存储失败，保存在任务记录中", zap.String("task_id", taskID), zap.Error(err))
+		} else {
+			result.ExecuteLogRef = key
+			result.ExecuteLogSize = int64(len(result.ExecuteLog))
+			result.ExecuteLog = ""
+		}
+	}
+
+	data, _ := json.Marshal(result)
+	return data
+}
+
+// parseTaskResult 解析任务执行结果
+func parseTaskResult(data []byte) (*executor.ReturnData, error) {
+	var result executor.ReturnData
+	if len(data) == 0 {
+		return &result, nil
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// readExecuteLogTail 读取对象存储中执行日志的尾部
+func readExecuteLogTail(ctx context.Context, result *executor.ReturnData) (string, error) {
+	store, err := blobstore.Default()
+	if err != nil {
+		return "", err
+	}
+	offset := int64(0)
+	if result.ExecuteLogSize > executeLogTailSize {
+		offset = result.ExecuteLogSize - executeLogTailSize
+	}
+	reader, err := store.Open(ctx, result.ExecuteLogRef, offset, -1)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+	if offset == 0 {
+		return string(data), nil
+	}
+	// 跳过被截断的第一行
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return fmt.Sprintf("... 日志共 %d 字节，仅展示末尾部分，完整日志请下载查看 ...\n%s", result.ExecuteLogSize, data), nil
+}
+
+// OpenTaskRollbackSQL 打开任务回滚SQL（对象存储或任务记录中的内容），返回内容和字节数
+func (s *InsightService) OpenTaskRollbackSQL(ctx context.Context, taskID string) (io.ReadCloser, int64, error) {
+	task, err := s.getRepo().GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, 0, err
+	}
+	result, err := parseTaskResult(task.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+	return openRollbackSQL(ctx, result, 0)
+}
+
+// openRollbackSQL 从 offset 开始读取回滚SQL，返回内容和回滚SQL总字节数
+func openRollbackSQL(ctx context.Context, result *executor.ReturnData, offset int64) (io.ReadCloser, int64, error) {
+	if result.RollbackSQLRef == "" {
+		return io.NopCloser(strings.NewReader(result.RollbackSQL)), int64(len(result.RollbackSQL)), nil
+	}
+	store, err := blobstore.Default()
+	if err != nil {
+		return nil, 0, err
+	}
+	reader, err := store.Open(ctx, result.RollbackSQLRef, offset, -1)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, 0, fmt.Errorf("回滚SQL文件不存在或已被清理")
+		}
+		return nil, 0, err
+	}
+	return reader, result.RollbackSQLSize, nil
+}
+
+// OpenTaskExecuteLog 打开任务完整执行日志，返回内容和字节数
+func (s *InsightService) OpenTaskExecuteLog(ctx context.Context, taskID string) (io.ReadCloser, int64, error) {
+	task, err := s.getRepo().GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, 0, err
+	}
+	result, err := parseTaskResult(task.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+	if result.ExecuteLogRef == "" {
+		return io.NopCloser(strings.NewReader(result.ExecuteLog)), int64(len(result.ExecuteLog)), nil
+	}
+	store, err := blobstore.Default()
+	if err != nil {
+		return nil, 0, err
+	}
+	reader, err := store.Open(ctx, result.ExecuteLogRef, 0, -1)
+	if err != nil {
+		if errors.Is(err, blobstore.ErrNotFound) {
+			return nil, 0, fmt.Errorf("执行日志文件不存在或已被清理")
+		}
+		return nil, 0, err
+	}
+	return reader, result.ExecuteLogSize, nil
+}
+
+// GetTaskRollbackSQLPage 分页获取任务回滚SQL（按语句分页，流式读取，不加载整个文件）
+// 有偏移索引时从本页所在的索引位置开始读取，读满一页即停止；没有索引时（旧任务、语句较少）扫描整个文件
+func (s *InsightService) GetTaskRollbackSQLPage(ctx context.Context, taskID string, page, pageSize int) ([]string, int, error) {
+	if page <= 0 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = defaultRollbackSQLPageSize
+	}
+	if pageSize > maxRollbackSQLPageSize {
+		pageSize = maxRollbackSQLPageSize
+	}
+
+	task, err := s.getRepo().GetTaskByID(ctx, taskID)
+	if err != nil {
+		return nil, 0, err
+	}
+	result, err := parseTaskResult(task.Result)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	start := (page - 1) * pageSize
+	statements := make([]string, 0, pageSize)
+	if result.RollbackSQLRef != "" && result.RollbackSQLIndexRef != "" {
+		offsets, err := readStatementIndex(ctx, result.RollbackSQLIndexRef)
+		if err == nil {
+			block := start / rollbackSQLIndexInterval
+			if block >= len(offsets) || start >= result.RollbackSQLCount {
+				return statements, result.RollbackSQLCount, nil
+			}
+			reader, _, err := openRollbackSQL(ctx, result, offsets[block])
+			if err != nil {
+				return nil, 0, err
+			}
+			defer reader.Close()
+			statements, err = readStatementPage(reader, start-block*rollbackSQLIndexInterval, pageSize)
+			if err != nil {
+				return nil, 0, err
+			}
+			return statements, result.RollbackSQLCount, nil
+		}
+		global.Logger.Warn("读取回滚SQL偏移索引失败，扫描整个文件", zap.String("task_id", taskID), zap.Error(err))
+	}
+
+	reader, _, err := openRollbackSQL(ctx, result, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer reader.Close()
+
+	total := 0
+	err = scanStatements(reader, func(stmt string, _ int64) bool {
+		if total >= start && total < start+pageSize {
+			statements = append(statements, stmt)
+		}
+		total++
+		return true
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return statements, total, nil
+}
+
+// readStatementPage 跳过 skip 条语句后读取 pageSize 条语句，读满即停止
+func readStatementPage(r io.Reader, skip, pageSize int) ([]string, error) {
+	statements := make([]string, 0, pageSize)
+	err := scanStatements(r, func(stmt string, _ int64) bool {
+		if skip > 0 {
+			skip--
+			return true
+		}
+		statements = append(statements, stmt)
+		return len(statements) < pageSize
+	})
+	return statements, err
+}
+
+// buildStatementIndex 统计回滚SQL语句数，并记录每 rollbackSQLIndexInterval 条语句中第一条的起始字节偏移
+func buildStatementIndex(sql string) (int, []int64) {
+	count := 0
+	var offsets []int64
+	_ = scanStatements(strings.NewReader(sql), func(_ string, offset int64) bool {
+		if count%rollbackSQLIndexInterval == 0 {
+			offsets = append(offsets, offset)
+		}
+		count++
+		return true
+	})
+	return count, offsets
+}
+
+// encodeStatementIndex 偏移索引按行保存十进制偏移
+func encodeStatementIndex(offsets []int64) string {
+	var b strings.Builder
+	for _, offset := range offsets {
+		b.WriteString(strconv.FormatInt(offset, 10))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// readStatementIndex 读取对象存储中的回滚SQL偏移索引
+func readStatementIndex(ctx context.Context, ref string) ([]int64, error) {
+	store, err := blobstore.Default()
+	if err != nil {
+		return nil, err
+	}
+	reader, err := store.Open(ctx, ref, 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	var offsets []int64
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		offset, err := strconv.ParseInt(strings.TrimSpace(scanner.Text()), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("偏移索引格式错误: %w", err)
+		}
+		offsets = append(offsets, offset)
+	}
+	return offsets, scanner.Err()
+}
+
+// scanStatements 按语句拆分回滚SQL：以分号结尾的行为一条语句的结束，之前的注释行归属于该语句
+// 字符串、带引号的标识符和注释中的分号不作为语句结束（字符串中可能包含以分号结尾的行）
+// fn 的 offset 为语句（含之前的注释行）在 r 中的起始字节偏移，返回 false 时停止扫描
+func scanStatements(r io.Reader, fn func(stmt string, offset int64) bool) error {
+	reader := bufio.NewReader(r)
+	var current strings.Builder
+	var pos, start int64
+	var lexer statementLexer
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if current.Len() == 0 {
+				start = pos
+			}
+			pos += int64(len(line))
+			current.WriteString(line)
+			if lexer.endsStatement(line) {
+				if !fn(strings.TrimSpace(current.String()), start) {
+					return nil
+				}
+				current.Reset()
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		fn(rest, start)
+	}
+	return nil
+}
+
+// statementLexer 逐行识别语句结束的分号，跨行记录所在的字符串和注释
+type statementLexer struct {
+	quote        byte // 所在字符串或标识符的引号（' " `），0 表示不在引号中
+	escaped      bool // 上一个字符是字符串中的反斜杠
+	blockComment bool // 在 /* */ 注释中
+	end          bool // 最后一个有效字符（字符串和注释之外）是分号
+}
+
+// endsStatement 处理一行，返回语句是否在该行结束（最后一个有效字符为分号，且不在字符串或注释中）
+func (l *statementLexer) endsStatement(line string) bool {
+scan:
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case l.blockComment:
+			if c == '*' && i+1 < len(line) && line[i+1] == '/' {
+				l.blockComment = false
+				i++
+			}
+		case l.quote != 0:
+			switch {
+			case l.escaped:
+				l.escaped = false
+			case c == '\\' && l.quote != '`':
+				l.escaped = true
+			case c == l.quote:
+				// 连续两个引号为转义，按先结束再开始处理
+				l.quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			l.quote = c
+			l.end = false
+		case c == '#' || c == '-' && strings.HasPrefix(line[i:], "--") && (i+2 == len(line) || strings.IndexByte(" \t\r\n", line[i+2]) >= 0):
+			// 行注释，忽略到行尾
+			break scan
+		case c == '/' && i+1 < len(line) && line[i+1] == '*':
+			l.blockComment = true
+			i++
+		case c == ';':
+			l.end = true
+		case c != ' ' && c != '\t' && c != '\r' && c != '\n':
+			l.end = false
+		}
+	}
+	if !l.end || l.quote != 0 || l.blockComment {
+		return false
+	}
+	l.end = false
+	return true
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestScanStatements(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		SQL    string
+		Expect []string
+	}{
+		{Name: "空内容", SQL: "", Expect: nil},
+		{Name: "单条语句", SQL: "DELETE FROM t WHERE id=1;\n", Expect: []string{"DELETE FROM t WHERE id=1;"}},
+		{
+			Name:   "注释归属于下一条语句",
+			SQL:    "-- 第1批\nINSERT INTO t VALUES (1);\n-- 第2批\nINSERT INTO t VALUES (2);\n",
+			Expect: []string{"-- 第1批\nINSERT INTO t VALUES (1);", "-- 第2批\nINSERT INTO t VALUES (2);"},
+		},
+		{
+			Name:   "多行语句和末尾无分号",
+			SQL:    "UPDATE t\nSET a=1\nWHERE id=1;\nUPDATE t SET a=2",
+			Expect: []string{"UPDATE t\nSET a=1\nWHERE id=1;", "UPDATE t SET a=2"},
+		},
+		{
+			Name:   "字符串中以分号结尾的行",
+			SQL:    "INSERT INTO t VALUES (1, 'a;\nb;\n');\nUPDATE t SET c = \"x;\ny\" WHERE id=2;\n",
+			Expect: []string{"INSERT INTO t VALUES (1, 'a;\nb;\n');", "UPDATE t SET c = \"x;\ny\" WHERE id=2;"},
+		},
+		{
+			Name:   "字符串中的转义引号",
+			SQL:    "INSERT INTO t VALUES ('it\\'s;\n', 'a''b;\nc');\nDELETE FROM t WHERE id=3;\n",
+			Expect: []string{"INSERT INTO t VALUES ('it\\'s;\n', 'a''b;\nc');", "DELETE FROM t WHERE id=3;"},
+		},
+		{
+			Name:   "反斜杠结尾的字符串",
+			SQL:    "INSERT INTO t VALUES ('C:\\\\');\nDELETE FROM t WHERE id=4;\n",
+			Expect: []string{"INSERT INTO t VALUES ('C:\\\\');", "DELETE FROM t WHERE id=4;"},
+		},
+		{
+			Name:   "注释中的分号和行尾注释",
+			SQL:    "-- 第1批;\nDELETE FROM t WHERE id=5; -- 已确认\n/* 跨行;\n注释; */\nDELETE FROM t WHERE id=6;\n",
+			Expect: []string{"-- 第1批;\nDELETE FROM t WHERE id=5; -- 已确认", "/* 跨行;\n注释; */\nDELETE FROM t WHERE id=6;"},
+		},
+		{
+			Name:   "反引号标识符中的分号",
+			SQL:    "UPDATE `a;\nb` SET c=1;\n",
+			Expect: []string{"UPDATE `a;\nb` SET c=1;"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			var got []string
+			var offsets []int64
+			if err := scanStatements(strings.NewReader(tc.SQL), func(stmt string, offset int64) bool {
+				got = append(got, stmt)
+				offsets = append(offsets, offset)
+				return true
+			}); err != nil {
+				t.Fatalf("扫描失败: %v", err)
+			}
+			if fmt.Sprint(got) != fmt.Sprint(tc.Expect) {
+				t.Errorf("期望 %q，实际 %q", tc.Expect, got)
+			}
+			// 偏移指向语句（含注释）的起始位置
+			for i, offset := range offsets {
+				if !strings.HasPrefix(tc.SQL[offset:], got[i]) {
+					t.Errorf("第 %d 条语句偏移 %d 不正确", i, offset)
+				}
+			}
+		})
+	}
+}
+
+// TestStatementIndexPaging 从偏移索引位置开始读取的分页结果与扫描整个文件一致
+func TestStatementIndexPaging(t *testing.T) {
+	var b strings.Builder
+	var all []string
+	for i := 0; i < rollbackSQLIndexInterval*3+17; i++ {
+		stmt := fmt.Sprintf("-- 第%d行\nINSERT INTO t VALUES (%d);", i, i)
+		all = append(all, stmt)
+		b.WriteString(stmt + "\n")
+	}
+	sql := b.String()
+
+	count, offsets := buildStatementIndex(sql)
+	if count != len(all) {
+		t.Fatalf("语句数期望 %d，实际 %d", len(all), count)
+	}
+	if len(offsets) != 4 {
+		t.Fatalf("索引条数期望 4，实际 %d", len(offsets))
+	}
+	parsed := strings.Fields(encodeStatementIndex(offsets))
+	if len(parsed) != len(offsets) {
+		t.Fatalf("索引编码后条数不一致")
+	}
+
+	for _, pageSize := range []int{1, 7, 100, 250} {
+		for page := 1; (page-1)*pageSize < count; page++ {
+			start := (page - 1) * pageSize
+			block := start / rollbackSQLIndexInterval
+			got, err := readStatementPage(strings.NewReader(sql[offsets[block]:]), start-block*rollbackSQLIndexInterval, pageSize)
+			if err != nil {
+				t.Fatalf("读取分页失败: %v", err)
+			}
+			end := start + pageSize
+			if end > count {
+				end = count
+			}
+			if fmt.Sprint(got) != fmt.Sprint(all[start:end]) {
+				t.Fatalf("pageSize=%d page=%d 结果与全量扫描不一致", pageSize, page)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package task
+
+import (
+	"context"
+	"encoding/json"
+	"go-noah/internal/orders/executor"
+	"os"
+	"path/filepath"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PurgeExpiredExportFiles 清理过期的导出文件
+// 导出文件按 <export.path>/<order_id>/<file> 存放，按任务结果中记录的下载过期时间删除，空目录一并清理
+// 任务结果中没有记录过期时间的文件（升级前导出的文件、导出失败残留的明文文件等）按修改时间加下载有效期判断
+func (t *InsightTask) PurgeExpiredExportFiles(ctx context.Context) error {
+	return t.purgeExpiredFiles(ctx, executor.GetExportPath(), executor.GetExportExpireDuration(), "导出")
+}
+
+// PurgeExpiredArchiveFiles 清理超过保留时长的归档文件（archive.retention_days 为 0 时不清理）
+// 归档文件按 <archive.path>/<order_id>/<file> 存放，清理规则与导出文件相同；
+// 未完成的归档任务每批写入都会更新文件修改时间，中断超过保留时长的归档文件同样会被清理
+func (t *InsightTask) PurgeExpiredArchiveFiles(ctx context.Context) error {
+	retention := executor.GetArchiveRetention()
+	if retention <= 0 {
+		return nil
+	}
+	return t.purgeExpiredFiles(ctx, executor.GetArchivePath(), retention, "归档")
+}
+
+// purgeExpiredFiles 清理 <root>/<order_id>/<file> 下的过期文件，没有记录过期时间的文件按修改时间加 retention 判断
+func (t *InsightTask) purgeExpiredFiles(ctx context.Context, root string, retention time.Duration, kind string) error {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil
+	}
+
+	now := time.Now()
+	var purged int
+
+	orderDirs, err := os.ReadDir(root)
+	if err != nil {
+		return err
+	}
+	for _, orderDir := range orderDirs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if !orderDir.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, orderDir.Name())
+		expireTimes, err := t.exportFileExpireTimes(ctx, orderDir.Name())
+		if err != nil {
+			t.logger.Warn("获取"+kind+"文件过期时间失败", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			t.logger.Warn("读取"+kind+"目录失败", zap.String("dir", dir), zap.Error(err))
+			continue
+		}
+
+		remaining := len(files)
+		for _, f := range files {
+			info, err := f.Info()
+			if err != nil || f.IsDir() {
+				continue
+			}
+			path := filepath.Join(dir, f.Name())
+			expireTime, ok := expireTimes[path]
+			if !ok {
+				expireTime = info.ModTime().Add(retention)
+			}
+			if now.Before(expireTime) {
+				continue
+			}
+			if err := os.Remove(path); err != nil {
+				t.logger.Warn("删除过期"+kind+"文件失败", zap.String("file", path), zap.Error(err))
+				continue
+			}
+			remaining--
+			purged++
+		}
+		if remaining == 0 {
+			_ = os.Remove(dir)
+		}
+	}
+
+	if purged > 0 {
+		t.logger.Info("已清理过期"+kind+"文件", zap.Int("count", purged))
+	}
+	return nil
+}
+
+// exportFileExpireTimes 读取工单任务结果中记录的导出文件（含归档文件）下载过期时间，按文件路径索引
+func (t *InsightTask) exportFileExpireTimes(ctx context.Context, orderID string) (map[string]time.Time, error) {
+	tasks, err := t.insightRepo.GetOrderTasks(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	expireTimes := make(map[string]time.Time)
+	for _, task := range tasks {
+		if len(task.Result) == 0 {
+			continue
+		}
+		var result executor.ReturnData
+		if err := json.Unmarshal(task.Result, &result); err != nil || result.FilePath == "" || result.ExpireTime == "" {
+			continue
+		}
+		expireTime, err := time.ParseInLocation("2006-01-02 15:04:05", result.ExpireTime, time.Local)
+		if err != nil {
+			continue
+		}
+		expireTimes[result.FilePath] = expireTime
+	}
+	return expireTimes, nil
+}
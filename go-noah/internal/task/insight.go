@@ -180,12 +180,14 @@ func (t *InsightTask) SyncDBMeta(ctx context.Context) error {
 			switch strings.ToLower(string(cfg.DbType)) {
 			case "mysql", "tidb":
 				db := dao.MySQLDB{
-					User:     cfg.UserName,
-					Password: cfg.Password,
-					Host:     cfg.Hostname,
-					Port:     cfg.Port,
-					Params:   map[string]string{"group_concat_max_len": "67108864"},
-					Ctx:      queryCtx,
+					InstanceID:  cfg.InstanceID.String(),
+					User:        cfg.UserName,
+					Password:    cfg.Password,
+					Host:        cfg.Hostname,
+					Port:        cfg.Port,
+					Params:      map[string]string{"group_concat_max_len": "67108864"},
+					ConnOptions: cfg.ConnOptions(),
+					Ctx:         queryCtx,
 				}
 				_, data, err = db.Query(mysqlQuery)
 			case "clickhouse":
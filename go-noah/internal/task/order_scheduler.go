@@ -3,10 +3,15 @@ package task
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-noah/internal/model/insight"
-	insightRepo "go-noah/internal/repository/insight"
 	"go-noah/internal/repository"
+	insightRepo "go-noah/internal/repository/insight"
 	"go-noah/pkg/global"
+	"go-noah/pkg/lease"
+	"go-noah/pkg/notifier"
+	"go-noah/pkg/utils"
 	"sync"
 	"time"
 
@@ -17,21 +22,86 @@ import (
 var globalOrderScheduler *OrderScheduler
 var schedulerOnce sync.Once
 
+const (
+	// schedulerLeaderLock 调度节点租约名称，多副本部署时只有持有租约的节点轮询并触发定时工单
+	schedulerLeaderLock = "scheduler:leader"
+	// schedulerUsername 调度器记录操作日志使用的用户名
+	schedulerUsername = "system"
+
+	defaultPollInterval = 5 * time.Second
+	defaultMissedGrace  = 5 * time.Minute
+	defaultMaxAttempts  = 3
+	defaultRetryBackoff = 30 * time.Second
+	maxRetryBackoff     = 30 * time.Minute
+
+	// claimTTL 认领有效期，触发执行的节点宕机后超过有效期的记录会被重新执行
+	claimTTL          = 2 * time.Minute
+	dispatchBatchSize = 50
+	lastErrorMaxLen   = 1024
+)
+
+// errOrderNotSchedulable 工单状态已变化（已手动执行、被驳回或关闭），不再定时执行
+var errOrderNotSchedulable = errors.New("工单状态不是已批准，取消定时执行")
+
+// schedulerSettings 调度器配置（order_scheduler），每次轮询时读取
+type schedulerSettings struct {
+	pollInterval time.Duration
+	missedGrace  time.Duration
+	missedPolicy insight.MissedRunPolicy
+	maxAttempts  int
+	retryBackoff time.Duration
+}
+
+// loadSchedulerSettings 读取调度器配置
+func loadSchedulerSettings() schedulerSettings {
+	settings := schedulerSettings{
+		pollInterval: defaultPollInterval,
+		missedGrace:  defaultMissedGrace,
+		missedPolicy: insight.MissedRunLate,
+		maxAttempts:  defaultMaxAttempts,
+		retryBackoff: defaultRetryBackoff,
+	}
+	if global.Conf == nil {
+		return settings
+	}
+	if v := global.Conf.GetInt("order_scheduler.poll_interval_seconds"); v > 0 {
+		settings.pollInterval = time.Duration(v) * time.Second
+	}
+	if v := global.Conf.GetInt("order_scheduler.missed_grace_seconds"); v > 0 {
+		settings.missedGrace = time.Duration(v) * time.Second
+	}
+	if v := global.Conf.GetString("order_scheduler.missed_policy"); v != "" && insight.IsValidMissedRunPolicy(v) {
+		settings.missedPolicy = insight.MissedRunPolicy(v)
+	}
+	if v := global.Conf.GetInt("order_scheduler.max_attempts"); v > 0 {
+		settings.maxAttempts = v
+	}
+	if v := global.Conf.GetInt("order_scheduler.retry_backoff_seconds"); v > 0 {
+		settings.retryBackoff = time.Duration(v) * time.Second
+	}
+	return settings
+}
+
 // OrderScheduler 工单定时任务调度器
+// 定时执行记录持久化在 scheduled_executions 表中，服务重启不丢失；
+// 多副本部署时通过租约选举出一个调度节点轮询到期记录并触发执行，失败后按指数退避重试
 type OrderScheduler struct {
-	jobMap   map[string]*time.Timer
-	mapMutex sync.Mutex
 	repo     *insightRepo.InsightRepository
 	logger   *zap.Logger
 	executor func(ctx context.Context, orderID string, username string) error
+	node     string
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
 }
 
 // NewOrderScheduler 创建工单调度器
 func NewOrderScheduler(repo *insightRepo.InsightRepository, logger *zap.Logger) *OrderScheduler {
 	return &OrderScheduler{
-		jobMap: make(map[string]*time.Timer),
 		repo:   repo,
 		logger: logger,
+		node:   lease.NodeName(),
 	}
 }
 
@@ -50,33 +120,94 @@ func (s *OrderScheduler) SetExecutor(exec func(ctx context.Context, orderID stri
 	s.executor = exec
 }
 
-// Start 启动调度器
+// Start 启动调度器（后台竞选调度节点，当选后轮询到期的定时执行记录）
 func (s *OrderScheduler) Start(ctx context.Context) {
-	s.logger.Info("工单定时任务调度器已启动")
-	
-	// 扫描并注册已批准的定时工单
-	s.ScanAndRegister(ctx)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cancel != nil {
+		return
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	s.cancel = cancel
+	s.done = make(chan struct{})
+	go s.run(runCtx)
+
+	s.logger.Info("工单定时任务调度器已启动", zap.String("node", s.node))
 }
 
-// Stop 停止调度器
+// Stop 停止调度器（释放调度节点租约，其他节点随后接管）
 func (s *OrderScheduler) Stop() {
-	s.mapMutex.Lock()
-	defer s.mapMutex.Unlock()
-	
-	// 停止所有定时器
-	for orderID, timer := range s.jobMap {
-		timer.Stop()
-		delete(s.jobMap, orderID)
-	}
-	
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.cancel, s.done = nil, nil
+	s.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+
 	s.logger.Info("工单定时任务调度器已停止")
 }
 
-// ScanAndRegister 扫描数据库中的已批准定时工单并注册
+// run 竞选调度节点，未当选或失去租约后每个轮询间隔重新竞选
+func (s *OrderScheduler) run(ctx context.Context) {
+	defer close(s.done)
+	for {
+		leader, err := lease.Acquire(ctx, schedulerLeaderLock, 0)
+		if err == nil {
+			s.logger.Info("当选为定时工单调度节点",
+				zap.String("node", s.node),
+				zap.Int64("fence", leader.Fence()),
+			)
+			s.lead(leader.Context())
+			leader.Release()
+			if ctx.Err() == nil {
+				s.logger.Warn("失去定时工单调度节点租约，重新竞选", zap.String("node", s.node))
+			}
+		} else {
+			var held *lease.HeldError
+			if !errors.As(err, &held) {
+				s.logger.Warn("竞选定时工单调度节点失败", zap.Error(err))
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(loadSchedulerSettings().pollInterval):
+		}
+	}
+}
+
+// lead 持有调度节点租约期间定期轮询，租约丢失时 ctx 被取消
+func (s *OrderScheduler) lead(ctx context.Context) {
+	s.poll(ctx)
+	ticker := time.NewTicker(loadSchedulerSettings().pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.poll(ctx)
+		}
+	}
+}
+
+// poll 同步新的定时工单并触发到期的执行记录
+func (s *OrderScheduler) poll(ctx context.Context) {
+	settings := loadSchedulerSettings()
+	s.ScanAndRegister(ctx)
+	s.dispatch(ctx, settings)
+}
+
+// ScanAndRegister 扫描数据库中的已批准定时工单并写入定时执行记录
 // 优化：分批处理，限制每次处理的工单数量，避免一次性加载过多数据
 func (s *OrderScheduler) ScanAndRegister(ctx context.Context) {
 	startTime := time.Now()
 	batchSize := 50 // 每次处理50个工单
+	settings := loadSchedulerSettings()
 
 	// 分批处理，直到没有更多需要注册的工单
 	totalCount := 0
@@ -95,32 +226,17 @@ func (s *OrderScheduler) ScanAndRegister(ctx context.Context) {
 
 		// 处理这批工单
 		batchCount := 0
-		now := time.Now()
 		for _, order := range orders {
 			if order.ScheduleTime == nil {
 				continue
 			}
-
-			// 检查定时时间是否已过期
-			if order.ScheduleTime.Before(now) {
-				// 定时时间已过期，不执行，只标记为已注册（跳过）
-				if err := s.repo.MarkSchedulerRegistered(ctx, order.OrderID.String()); err != nil {
-					s.logger.Warn("标记过期工单已注册失败",
-						zap.String("order_id", order.OrderID.String()),
-						zap.Error(err),
-					)
-				} else {
-					s.logger.Info("定时工单已过期，跳过执行",
-						zap.String("order_id", order.OrderID.String()),
-						zap.Time("schedule_time", *order.ScheduleTime),
-						zap.Duration("expired_by", now.Sub(*order.ScheduleTime)),
-					)
-				}
+			if err := s.registerOrder(ctx, &order, settings); err != nil {
+				s.logger.Warn("写入定时执行记录失败",
+					zap.String("order_id", order.OrderID.String()),
+					zap.Error(err),
+				)
 				continue
 			}
-
-			// 定时时间未过期，注册定时任务
-			s.AddJob(ctx, &order)
 			// 标记为已注册
 			if err := s.repo.MarkSchedulerRegistered(ctx, order.OrderID.String()); err != nil {
 				s.logger.Warn("标记工单已注册失败",
@@ -129,7 +245,7 @@ func (s *OrderScheduler) ScanAndRegister(ctx context.Context) {
 				)
 			} else {
 				batchCount++
-				s.logger.Debug("注册定时任务并标记",
+				s.logger.Debug("写入定时执行记录并标记",
 					zap.String("order_id", order.OrderID.String()),
 					zap.Time("schedule_time", *order.ScheduleTime),
 				)
@@ -161,130 +277,242 @@ func (s *OrderScheduler) ScanAndRegister(ctx context.Context) {
 	}
 }
 
-// AddJob 添加定时执行任务
-func (s *OrderScheduler) AddJob(ctx context.Context, order *insight.OrderRecord) {
-	if order.ScheduleTime == nil {
-		return
+// registerOrder 写入工单的定时执行记录；已有未触发的记录时（计划时间被修改，如顺延到维护窗口）更新该记录
+func (s *OrderScheduler) registerOrder(ctx context.Context, order *insight.OrderRecord, settings schedulerSettings) error {
+	policy := order.ScheduleMissedPolicy
+	if policy == "" {
+		policy = settings.missedPolicy
+	}
+	dueAt := *order.ScheduleTime
+
+	existing, err := s.repo.GetOpenScheduledExecution(ctx, order.OrderID.String())
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		updated, err := s.repo.UpdateScheduledExecution(ctx, existing.ID, existing.State, map[string]interface{}{
+			"state":           insight.ScheduleStatePending,
+			"due_at":          dueAt,
+			"next_attempt_at": dueAt,
+			"missed_policy":   policy,
+			"attempts":        0,
+			"max_attempts":    settings.maxAttempts,
+			"username":        order.Applicant,
+			"last_error":      "",
+		})
+		if err != nil || updated {
+			return err
+		}
 	}
 
-	orderID := order.OrderID.String()
-	targetTime := *order.ScheduleTime
+	return s.repo.CreateScheduledExecution(ctx, &insight.ScheduledExecution{
+		OrderID:       order.OrderID,
+		DueAt:         dueAt,
+		NextAttemptAt: dueAt,
+		State:         insight.ScheduleStatePending,
+		MissedPolicy:  policy,
+		MaxAttempts:   settings.maxAttempts,
+		Username:      order.Applicant,
+	})
+}
+
+// dispatch 触发到期的定时执行记录
+func (s *OrderScheduler) dispatch(ctx context.Context, settings schedulerSettings) {
 	now := time.Now()
+	if released, err := s.repo.ReleaseExpiredScheduledExecutions(ctx, now); err != nil {
+		s.logger.Warn("回收过期认领的定时执行记录失败", zap.Error(err))
+	} else if released > 0 {
+		s.logger.Warn("定时执行记录认领已过期（触发节点可能已宕机），重新执行", zap.Int64("count", released))
+	}
 
-	// 如果定时时间已过期，不执行（已在 ScanAndRegister 中处理，这里不应该到达）
-	if targetTime.Before(now) {
-		s.logger.Warn("定时工单时间已过期，不执行",
-			zap.String("order_id", orderID),
-			zap.Time("schedule_time", targetTime),
-			zap.Duration("expired_by", now.Sub(targetTime)),
-		)
+	executions, err := s.repo.GetDueScheduledExecutions(ctx, now, dispatchBatchSize)
+	if err != nil {
+		s.logger.Error("获取到期的定时执行记录失败", zap.Error(err))
 		return
 	}
+	for i := range executions {
+		// 失去调度节点租约后不再触发，由新的调度节点继续
+		if ctx.Err() != nil {
+			return
+		}
+		s.dispatchOne(ctx, &executions[i], settings)
+	}
+}
 
-	// 移除已存在的任务（如果存在）
-	s.RemoveJob(orderID)
-
-	// 计算延迟时间
-	delay := targetTime.Sub(now)
+// dispatchOne 处理错过计划时间的记录，认领后触发执行
+func (s *OrderScheduler) dispatchOne(ctx context.Context, execution *insight.ScheduledExecution, settings schedulerSettings) {
+	orderID := execution.OrderID.String()
 
-	// 使用 time.AfterFunc 在指定时间执行一次
-	timer := time.AfterFunc(delay, func() {
-		s.logger.Info("定时任务回调函数被触发",
-			zap.String("order_id", orderID),
-			zap.Time("target_time", targetTime),
-			zap.Time("current_time", time.Now()),
-		)
-		
-		s.mapMutex.Lock()
-		delete(s.jobMap, orderID)
-		s.mapMutex.Unlock()
+	// 首次触发时已超过宽限时间，说明计划时间内没有可用的调度节点，按错过计划时间的处理策略处理
+	if missedBy := time.Since(execution.DueAt); execution.Attempts == 0 && missedBy > settings.missedGrace {
+		switch execution.MissedPolicy {
+		case insight.MissedRunSkip:
+			msg := fmt.Sprintf("定时执行错过计划时间 %s，已跳过执行", execution.DueAt.Format(time.DateTime))
+			s.handleMissed(ctx, execution, insight.ScheduleStateSkipped, msg)
+			return
+		case insight.MissedRunAsk:
+			msg := fmt.Sprintf("定时执行错过计划时间 %s，请在定时执行列表中确认是否继续执行", execution.DueAt.Format(time.DateTime))
+			s.handleMissed(ctx, execution, insight.ScheduleStateAsking, msg)
+			return
+		default:
+			s.logger.Info("定时执行错过计划时间，立即补执行",
+				zap.String("order_id", orderID),
+				zap.Time("due_at", execution.DueAt),
+				zap.Duration("missed_by", missedBy),
+			)
+		}
+	}
 
-		s.executeOrder(context.Background(), orderID, order.Applicant)
-	})
+	claimed, err := s.repo.ClaimScheduledExecution(ctx, execution.ID, s.node, time.Now().Add(claimTTL))
+	if err != nil {
+		s.logger.Warn("认领定时执行记录失败", zap.Uint("id", execution.ID), zap.Error(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+	execution.Attempts++
 
-	s.mapMutex.Lock()
-	s.jobMap[orderID] = timer
-	s.mapMutex.Unlock()
+	// 认领之后不再受调度节点租约影响，保证执行结果能写回
+	execCtx := context.WithoutCancel(ctx)
+	s.finish(execCtx, execution, s.executeOrder(execCtx, orderID, execution.Username), settings)
+}
 
-	s.logger.Info("注册定时任务成功",
-		zap.String("order_id", orderID),
-		zap.Time("schedule_time", targetTime),
-		zap.Duration("delay", delay),
-	)
+// handleMissed 错过计划时间的记录标记为已跳过或等待确认，记录操作日志并通知执行人
+func (s *OrderScheduler) handleMissed(ctx context.Context, execution *insight.ScheduledExecution, state insight.ScheduleState, msg string) {
+	updates := map[string]interface{}{
+		"state":      state,
+		"last_error": msg,
+	}
+	if state == insight.ScheduleStateSkipped {
+		updates["finished_at"] = time.Now()
+	}
+	updated, err := s.repo.UpdateScheduledExecution(ctx, execution.ID, insight.ScheduleStatePending, updates)
+	if err != nil {
+		s.logger.Warn("更新定时执行记录失败", zap.Uint("id", execution.ID), zap.Error(err))
+		return
+	}
+	if !updated {
+		return
+	}
+	s.logger.Info(msg, zap.String("order_id", execution.OrderID.String()))
+	s.report(ctx, execution, msg)
 }
 
-// RemoveJob 移除定时任务
-func (s *OrderScheduler) RemoveJob(orderID string) {
-	s.mapMutex.Lock()
-	defer s.mapMutex.Unlock()
+// finish 根据触发结果更新执行记录：成功、工单状态已变化时取消、失败时退避重试，重试次数用尽后通知执行人
+func (s *OrderScheduler) finish(ctx context.Context, execution *insight.ScheduledExecution, execErr error, settings schedulerSettings) {
+	orderID := execution.OrderID.String()
+	now := time.Now()
+	updates := map[string]interface{}{
+		"owner":         "",
+		"claimed_until": nil,
+	}
+	var report string
+	switch {
+	case execErr == nil:
+		updates["state"] = insight.ScheduleStateSucceeded
+		updates["last_error"] = ""
+		updates["finished_at"] = now
+		s.logger.Info("定时工单已触发执行", zap.String("order_id", orderID), zap.Int("attempts", execution.Attempts))
+	case errors.Is(execErr, errOrderNotSchedulable):
+		updates["state"] = insight.ScheduleStateCancelled
+		updates["last_error"] = utils.TruncateString(execErr.Error(), lastErrorMaxLen)
+		updates["finished_at"] = now
+		s.logger.Warn("工单状态已变化，取消定时执行", zap.String("order_id", orderID), zap.Error(execErr))
+	case execution.Attempts >= execution.MaxAttempts:
+		updates["state"] = insight.ScheduleStateFailed
+		updates["last_error"] = utils.TruncateString(execErr.Error(), lastErrorMaxLen)
+		updates["finished_at"] = now
+		report = fmt.Sprintf("定时执行失败，已重试 %d 次仍未成功：%s", execution.Attempts, execErr.Error())
+		s.logger.Error("定时工单执行失败，重试次数已用尽",
+			zap.String("order_id", orderID),
+			zap.Int("attempts", execution.Attempts),
+			zap.Error(execErr),
+		)
+	default:
+		backoff := retryBackoff(settings.retryBackoff, execution.Attempts)
+		updates["state"] = insight.ScheduleStatePending
+		updates["last_error"] = utils.TruncateString(execErr.Error(), lastErrorMaxLen)
+		updates["next_attempt_at"] = now.Add(backoff)
+		s.logger.Warn("定时工单执行失败，稍后重试",
+			zap.String("order_id", orderID),
+			zap.Int("attempts", execution.Attempts),
+			zap.Duration("backoff", backoff),
+			zap.Error(execErr),
+		)
+	}
 
-	if timer, ok := s.jobMap[orderID]; ok {
-		timer.Stop()
-		delete(s.jobMap, orderID)
-		s.logger.Info("移除定时任务", zap.String("order_id", orderID))
+	if _, err := s.repo.UpdateScheduledExecution(ctx, execution.ID, insight.ScheduleStateRunning, updates); err != nil {
+		s.logger.Error("更新定时执行记录失败", zap.Uint("id", execution.ID), zap.Error(err))
+		return
 	}
+	if report != "" {
+		s.report(ctx, execution, report)
+	}
+}
+
+// report 记录操作日志并通知执行人（同时通知申请人）
+func (s *OrderScheduler) report(ctx context.Context, execution *insight.ScheduledExecution, msg string) {
+	orderID := execution.OrderID.String()
+	_ = s.repo.CreateOpLog(ctx, &insight.OrderOpLog{
+		Username: schedulerUsername,
+		OrderID:  execution.OrderID,
+		Msg:      utils.TruncateString(msg, lastErrorMaxLen),
+	})
 
-	// 清理注册标记
-	ctx := context.Background()
-	_ = s.repo.MarkSchedulerUnregistered(ctx, orderID)
+	order, err := s.repo.GetOrderByID(ctx, orderID)
+	if err != nil {
+		s.logger.Warn("获取工单信息失败，无法发送通知", zap.String("order_id", orderID), zap.Error(err))
+		return
+	}
+	var executors []string
+	if len(order.Executor) > 0 {
+		_ = json.Unmarshal(order.Executor, &executors)
+	}
+	notifier.SendOrderNotification(orderID, order.Title, order.Applicant, executors,
+		fmt.Sprintf("您好，%s，请悉知\n>工单标题：%s", msg, order.Title))
 }
 
 // executeOrder 执行工单
-func (s *OrderScheduler) executeOrder(ctx context.Context, orderID string, defaultUsername string) {
+func (s *OrderScheduler) executeOrder(ctx context.Context, orderID string, defaultUsername string) error {
 	// 重新查询工单信息，确保状态是最新的
 	orderWithInstance, err := s.repo.GetOrderByID(ctx, orderID)
 	if err != nil {
-		s.logger.Error("获取工单信息失败",
-			zap.String("order_id", orderID),
-			zap.Error(err),
-		)
-		return
+		return fmt.Errorf("获取工单信息失败: %w", err)
 	}
 
-	// 清理注册标记（定时任务已执行，不再需要标记）
-	_ = s.repo.MarkSchedulerUnregistered(ctx, orderID)
-
 	// 再次检查工单状态
 	if orderWithInstance.Progress != insight.ProgressApproved {
-		s.logger.Warn("工单状态不是已批准，取消执行",
-			zap.String("order_id", orderID),
-			zap.String("progress", string(orderWithInstance.Progress)),
-		)
-		return
+		return fmt.Errorf("%w，当前状态: %s", errOrderNotSchedulable, orderWithInstance.Progress)
 	}
 
 	// 获取执行人
 	username := defaultUsername
-	if orderWithInstance.Executor != nil && len(orderWithInstance.Executor) > 0 {
+	if len(orderWithInstance.Executor) > 0 {
 		var executorList []string
 		if err := json.Unmarshal(orderWithInstance.Executor, &executorList); err == nil && len(executorList) > 0 {
 			username = executorList[0]
 		}
 	}
 
+	if s.executor == nil {
+		return errors.New("执行器未设置，无法执行定时工单")
+	}
 	s.logger.Info("开始执行定时工单",
 		zap.String("order_id", orderID),
 		zap.String("username", username),
-		zap.Bool("executor_set", s.executor != nil),
 	)
+	return s.executor(ctx, orderID, username)
+}
 
-	if s.executor != nil {
-		s.logger.Info("调用执行器函数",
-			zap.String("order_id", orderID),
-		)
-		if err := s.executor(ctx, orderID, username); err != nil {
-			s.logger.Error("定时工单执行失败",
-				zap.String("order_id", orderID),
-				zap.Error(err),
-			)
-		} else {
-			s.logger.Info("定时工单执行完成",
-				zap.String("order_id", orderID),
-			)
-		}
-	} else {
-		s.logger.Error("执行器未设置，无法执行定时工单",
-			zap.String("order_id", orderID),
-		)
+// retryBackoff 第 attempts 次失败后的重试间隔（指数退避，最长 30 分钟）
+func retryBackoff(base time.Duration, attempts int) time.Duration {
+	backoff := base
+	for i := 1; i < attempts && backoff < maxRetryBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxRetryBackoff {
+		backoff = maxRetryBackoff
 	}
+	return backoff
 }
@@ -0,0 +1,87 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-noah/pkg/global"
+	"io"
+	"sync"
+)
+
+// 存储类型
+const (
+	TypeLocal = "local"
+	TypeS3    = "s3"
+)
+
+const defaultLocalPath = "./storage/blob"
+
+// ErrNotFound 对象不存在
+var ErrNotFound = errors.New("对象不存在")
+
+// Store 对象存储接口，用于保存回滚SQL、执行日志等大文本，避免写入元数据库
+type Store interface {
+	// Put 写入对象，size 未知时传 -1
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+	// Open 读取对象，从 offset 开始读取 length 字节，length < 0 表示读取到末尾
+	Open(ctx context.Context, key string, offset, length int64) (io.ReadCloser, error)
+	// Delete 删除对象，对象不存在时不返回错误
+	Delete(ctx context.Context, key string) error
+}
+
+// Config 对象存储配置
+type Config struct {
+	Type      string // local / s3
+	LocalPath string // 本地存储目录
+
+	Endpoint  string // S3 兼容服务地址（如 MinIO），为空使用 AWS 默认地址
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	Prefix    string // 对象键前缀
+}
+
+// New 根据配置创建对象存储
+func New(cfg Config) (Store, error) {
+	switch cfg.Type {
+	case "", TypeLocal:
+		path := cfg.LocalPath
+		if path == "" {
+			path = defaultLocalPath
+		}
+		return NewLocalStore(path), nil
+	case TypeS3:
+		return NewS3Store(cfg)
+	default:
+		return nil, fmt.Errorf("不支持的对象存储类型: %s", cfg.Type)
+	}
+}
+
+var (
+	defaultStore Store
+	defaultErr   error
+	defaultOnce  sync.Once
+)
+
+// Default 返回按 blob_store 配置创建的全局对象存储（首次调用时创建）
+func Default() (Store, error) {
+	defaultOnce.Do(func() {
+		var cfg Config
+		if global.Conf != nil {
+			cfg = Config{
+				Type:      global.Conf.GetString("blob_store.type"),
+				LocalPath: global.Conf.GetString("blob_store.local.path"),
+				Endpoint:  global.Conf.GetString("blob_store.s3.endpoint"),
+				Region:    global.Conf.GetString("blob_store.s3.region"),
+				Bucket:    global.Conf.GetString("blob_store.s3.bucket"),
+				AccessKey: global.Conf.GetString("blob_store.s3.access_key"),
+				SecretKey: global.Conf.GetString("blob_store.s3.secret_key"),
+				Prefix:    global.Conf.GetString("blob_store.s3.prefix"),
+			}
+		}
+		defaultStore, defaultErr = New(cfg)
+	})
+	return defaultStore, defaultErr
+}
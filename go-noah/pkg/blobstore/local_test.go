@@ -0,0 +1,95 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// readAll 读取对象全部内容
+func readAll(t *testing.T, store Store, key string, offset, length int64) string {
+	t.Helper()
+	reader, err := store.Open(context.Background(), key, offset, length)
+	if err != nil {
+		t.Fatalf("读取对象失败: %v", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("读取对象失败: %v", err)
+	}
+	return string(data)
+}
+
+// testStore 本地存储和 S3 存储共用的读写用例
+func testStore(t *testing.T, store Store) {
+	ctx := context.Background()
+	const key = "tasks/t1/rollback.sql"
+	const content = "0123456789abcdef"
+	if err := store.Put(ctx, key, strings.NewReader(content), int64(len(content))); err != nil {
+		t.Fatalf("写入对象失败: %v", err)
+	}
+
+	testCases := []struct {
+		Name   string
+		Offset int64
+		Length int64
+		Expect string
+	}{
+		{Name: "读取全部", Offset: 0, Length: -1, Expect: content},
+		{Name: "从偏移读取到末尾", Offset: 10, Length: -1, Expect: "abcdef"},
+		{Name: "读取区间", Offset: 4, Length: 3, Expect: "456"},
+		{Name: "长度为0", Offset: 4, Length: 0, Expect: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := readAll(t, store, key, tc.Offset, tc.Length); got != tc.Expect {
+				t.Errorf("期望 %q，实际 %q", tc.Expect, got)
+			}
+		})
+	}
+
+	t.Run("覆盖写入", func(t *testing.T) {
+		if err := store.Put(ctx, key, strings.NewReader("new"), -1); err != nil {
+			t.Fatalf("写入对象失败: %v", err)
+		}
+		if got := readAll(t, store, key, 0, -1); got != "new" {
+			t.Errorf("期望 %q，实际 %q", "new", got)
+		}
+	})
+
+	t.Run("对象不存在", func(t *testing.T) {
+		_, err := store.Open(ctx, "tasks/missing/rollback.sql", 0, -1)
+		if !errors.Is(err, ErrNotFound) {
+			t.Errorf("期望 ErrNotFound，实际 %v", err)
+		}
+	})
+
+	t.Run("删除", func(t *testing.T) {
+		if err := store.Delete(ctx, key); err != nil {
+			t.Fatalf("删除对象失败: %v", err)
+		}
+		if _, err := store.Open(ctx, key, 0, -1); !errors.Is(err, ErrNotFound) {
+			t.Errorf("删除后期望 ErrNotFound，实际 %v", err)
+		}
+		// 对象不存在时不返回错误
+		if err := store.Delete(ctx, key); err != nil {
+			t.Errorf("重复删除返回错误: %v", err)
+		}
+	})
+}
+
+func TestLocalStore(t *testing.T) {
+	testStore(t, NewLocalStore(t.TempDir()))
+}
+
+func TestLocalStoreIllegalKey(t *testing.T) {
+	store := NewLocalStore(t.TempDir())
+	for _, key := range []string{"../outside", "tasks/../../outside", "", "/"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x"), 1); err == nil {
+			t.Errorf("对象键 %q 期望返回错误", key)
+		}
+	}
+}
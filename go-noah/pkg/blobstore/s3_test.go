@@ -0,0 +1,116 @@
+package blobstore
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeS3 内存中的 S3 兼容服务，只实现 PutObject/GetObject（含 Range）/DeleteObject
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	// 路径风格访问：/bucket/key
+	key := strings.TrimPrefix(r.URL.Path, "/")
+	switch r.Method {
+	case http.MethodPut:
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		f.objects[key] = data
+		w.Header().Set("ETag", `"etag"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		data, ok := f.objects[key]
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`)
+			return
+		}
+		if rng := r.Header.Get("Range"); rng != "" {
+			start, end := parseRange(rng, int64(len(data)))
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, len(data)))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(data[start : end+1])
+			return
+		}
+		w.Write(data)
+	case http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// parseRange 解析 bytes=start-end 或 bytes=start-
+func parseRange(rng string, size int64) (int64, int64) {
+	parts := strings.SplitN(strings.TrimPrefix(rng, "bytes="), "-", 2)
+	start, _ := strconv.ParseInt(parts[0], 10, 64)
+	end := size - 1
+	if len(parts) == 2 && parts[1] != "" {
+		end, _ = strconv.ParseInt(parts[1], 10, 64)
+	}
+	if end > size-1 {
+		end = size - 1
+	}
+	return start, end
+}
+
+func newFakeS3Store(t *testing.T, prefix string) (*S3Store, *fakeS3) {
+	t.Helper()
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+
+	store, err := NewS3Store(Config{
+		Type:      TypeS3,
+		Endpoint:  srv.URL,
+		Bucket:    "noah",
+		AccessKey: "test",
+		SecretKey: "test",
+		Prefix:    prefix,
+	})
+	if err != nil {
+		t.Fatalf("创建 S3 存储失败: %v", err)
+	}
+	return store, fake
+}
+
+func TestS3Store(t *testing.T) {
+	store, _ := newFakeS3Store(t, "")
+	testStore(t, store)
+}
+
+func TestS3StorePrefix(t *testing.T) {
+	store, fake := newFakeS3Store(t, "/noah-blob/")
+	if err := store.Put(t.Context(), "tasks/t1/execute.log", strings.NewReader("log"), 3); err != nil {
+		t.Fatalf("写入对象失败: %v", err)
+	}
+	if _, ok := fake.objects["noah/noah-blob/tasks/t1/execute.log"]; !ok {
+		t.Errorf("对象键未加前缀: %v", fake.objects)
+	}
+	if got := readAll(t, store, "tasks/t1/execute.log", 0, -1); got != "log" {
+		t.Errorf("期望 %q，实际 %q", "log", got)
+	}
+}
+
+func TestNewS3StoreWithoutBucket(t *testing.T) {
+	if _, err := NewS3Store(Config{Type: TypeS3}); err == nil {
+		t.Error("未配置 bucket 期望返回错误")
+	}
+}
@@ -0,0 +1,121 @@
+package dbconn
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-noah/pkg/secret"
+	"net"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Options 实例连接选项（SSH 跳板机、TLS），均未配置时直连
+type Options struct {
+	SSH *SSHOptions
+	TLS *TLSOptions
+}
+
+// TLSOptions TLS 连接选项
+type TLSOptions struct {
+	CA         string // CA 证书（PEM），为空时使用系统根证书
+	Cert       string // 客户端证书（PEM），双向认证时配置
+	Key        string // 客户端私钥（PEM，可为密文）
+	SkipVerify bool   // 跳过服务端证书校验
+}
+
+// IsDirect 是否直连（未配置 SSH 跳板机）
+func (o Options) IsDirect() bool {
+	return o.SSH == nil
+}
+
+// Validate 校验 SSH 凭据、证书和私钥格式
+func (o Options) Validate() error {
+	if o.SSH != nil {
+		if o.SSH.User == "" {
+			return errors.New("SSH跳板机未配置用户")
+		}
+		if _, err := o.SSH.clientConfig(); err != nil {
+			return err
+		}
+	}
+	_, err := o.TLSConfig("")
+	return err
+}
+
+// DialContext 建立到数据库的 TCP 连接，配置了 SSH 跳板机时通过跳板机转发
+func (o Options) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if o.SSH == nil {
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, addr)
+	}
+	return o.SSH.dial(ctx, network, addr)
+}
+
+// TLSConfig 构造 TLS 配置，未配置 TLS 时返回 nil
+func (o Options) TLSConfig(serverName string) (*tls.Config, error) {
+	if o.TLS == nil {
+		return nil, nil
+	}
+	config := &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: o.TLS.SkipVerify,
+		MinVersion:         tls.VersionTLS12,
+	}
+	if o.TLS.CA != "" {
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM([]byte(o.TLS.CA)) {
+			return nil, errors.New("CA 证书格式错误")
+		}
+		config.RootCAs = pool
+	}
+	if o.TLS.Cert != "" || o.TLS.Key != "" {
+		key, err := secret.Decrypt(o.TLS.Key)
+		if err != nil {
+			return nil, fmt.Errorf("解密客户端私钥失败: %w", err)
+		}
+		cert, err := tls.X509KeyPair([]byte(o.TLS.Cert), []byte(key))
+		if err != nil {
+			return nil, fmt.Errorf("客户端证书或私钥格式错误: %w", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// ApplyMySQL 将连接选项应用到 MySQL 驱动配置
+// 驱动通过名称引用拨号函数和 TLS 配置，名称由选项内容生成，选项不变时 DSN 不变（便于连接池复用）
+func (o Options) ApplyMySQL(config *mysql.Config) error {
+	if o.SSH != nil {
+		name := "ssh_" + fingerprint(o.SSH)
+		mysql.RegisterDialContext(name, func(ctx context.Context, addr string) (net.Conn, error) {
+			return o.SSH.dial(ctx, "tcp", addr)
+		})
+		config.Net = name
+	}
+	if o.TLS != nil {
+		// ServerName 为空时驱动使用连接地址中的主机名
+		tlsConfig, err := o.TLSConfig("")
+		if err != nil {
+			return err
+		}
+		name := "noah_" + fingerprint(o.TLS)
+		if err := mysql.RegisterTLSConfig(name, tlsConfig); err != nil {
+			return err
+		}
+		config.TLSConfig = name
+	}
+	return nil
+}
+
+// fingerprint 生成选项摘要（用于注册名称和连接缓存，不包含明文）
+func fingerprint(v interface{}) string {
+	data, _ := json.Marshal(v)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
+}
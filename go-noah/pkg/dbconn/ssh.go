@@ -0,0 +1,196 @@
+package dbconn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-noah/pkg/secret"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultSSHPort       = 22
+	sshHandshakeTimeout  = 10 * time.Second
+	sshKeepaliveInterval = 30 * time.Second
+)
+
+// SSHOptions SSH 跳板机选项，密码和私钥至少配置一种
+type SSHOptions struct {
+	Host       string
+	Port       int
+	User       string
+	Password   string
+	PrivateKey string // 私钥（PEM）
+	Passphrase string // 私钥密码
+	HostKey    string // 跳板机公钥（authorized_keys 格式）
+	// InsecureIgnoreHostKey 不校验跳板机公钥，需显式开启，仅用于测试环境
+	InsecureIgnoreHostKey bool
+}
+
+// 同一跳板机的连接复用一个 SSH 会话。sshMu 只保护缓存，
+// 建立会话时持有该跳板机的 sshDialMu，不阻塞其他跳板机的连接
+var (
+	sshMu      sync.Mutex
+	sshClients = make(map[string]*ssh.Client)
+	sshDialMu  = make(map[string]*sync.Mutex)
+)
+
+// dial 通过跳板机建立到 addr 的连接，会话已断开时重建后重试一次
+func (s *SSHOptions) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := client.DialContext(ctx, network, addr)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+	s.drop(client)
+	if client, err = s.client(); err != nil {
+		return nil, err
+	}
+	return client.DialContext(ctx, network, addr)
+}
+
+// client 获取（或建立）到跳板机的 SSH 会话
+func (s *SSHOptions) client() (*ssh.Client, error) {
+	key := fingerprint(s)
+	sshMu.Lock()
+	client, ok := sshClients[key]
+	dialMu := sshDialMu[key]
+	if dialMu == nil {
+		dialMu = &sync.Mutex{}
+		sshDialMu[key] = dialMu
+	}
+	sshMu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	// 同一跳板机只建立一个会话，等待期间其他协程已建立时直接复用
+	dialMu.Lock()
+	defer dialMu.Unlock()
+	sshMu.Lock()
+	client, ok = sshClients[key]
+	sshMu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	config, err := s.clientConfig()
+	if err != nil {
+		return nil, err
+	}
+	port := s.Port
+	if port == 0 {
+		port = defaultSSHPort
+	}
+	addr := net.JoinHostPort(s.Host, strconv.Itoa(port))
+	client, err = ssh.Dial("tcp", addr, config)
+	if err != nil {
+		return nil, fmt.Errorf("连接SSH跳板机 %s 失败: %w", addr, err)
+	}
+	sshMu.Lock()
+	sshClients[key] = client
+	sshMu.Unlock()
+	go s.keepalive(key, client)
+	return client, nil
+}
+
+// drop 关闭并移除 SSH 会话
+func (s *SSHOptions) drop(client *ssh.Client) {
+	key := fingerprint(s)
+	sshMu.Lock()
+	if sshClients[key] == client {
+		delete(sshClients, key)
+	}
+	sshMu.Unlock()
+	client.Close()
+}
+
+// keepalive 定期发送心跳，会话断开后从缓存中移除
+func (s *SSHOptions) keepalive(key string, client *ssh.Client) {
+	done := make(chan struct{})
+	go func() {
+		_ = client.Wait()
+		close(done)
+	}()
+	ticker := time.NewTicker(sshKeepaliveInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			sshMu.Lock()
+			if sshClients[key] == client {
+				delete(sshClients, key)
+			}
+			sshMu.Unlock()
+			return
+		case <-ticker.C:
+			if _, _, err := client.SendRequest("keepalive@openssh.com", true, nil); err != nil {
+				client.Close()
+			}
+		}
+	}
+}
+
+// clientConfig 生成 SSH 客户端配置（密码、私钥可为密文，在此解密）
+func (s *SSHOptions) clientConfig() (*ssh.ClientConfig, error) {
+	var plain [3]string
+	for i, value := range []string{s.Password, s.PrivateKey, s.Passphrase} {
+		decrypted, err := secret.Decrypt(value)
+		if err != nil {
+			return nil, fmt.Errorf("解密SSH凭据失败: %w", err)
+		}
+		plain[i] = decrypted
+	}
+	password, privateKey, passphrase := plain[0], plain[1], plain[2]
+
+	var auths []ssh.AuthMethod
+	if privateKey != "" {
+		var (
+			signer ssh.Signer
+			err    error
+		)
+		if passphrase != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+		} else {
+			signer, err = ssh.ParsePrivateKey([]byte(privateKey))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("SSH私钥格式错误: %w", err)
+		}
+		auths = append(auths, ssh.PublicKeys(signer))
+	}
+	if password != "" {
+		auths = append(auths, ssh.Password(password))
+	}
+	if len(auths) == 0 {
+		return nil, errors.New("SSH跳板机未配置密码或私钥")
+	}
+
+	var hostKeyCallback ssh.HostKeyCallback
+	switch {
+	case s.HostKey != "":
+		publicKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(s.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("SSH跳板机公钥格式错误: %w", err)
+		}
+		hostKeyCallback = ssh.FixedHostKey(publicKey)
+	case s.InsecureIgnoreHostKey:
+		hostKeyCallback = ssh.InsecureIgnoreHostKey()
+	default:
+		return nil, errors.New("SSH跳板机未配置公钥，如确需跳过校验请显式开启 ssh_insecure_ignore_host_key")
+	}
+	return &ssh.ClientConfig{
+		User:            s.User,
+		Auth:            auths,
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         sshHandshakeTimeout,
+	}, nil
+}
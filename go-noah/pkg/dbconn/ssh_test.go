@@ -0,0 +1,40 @@
+package dbconn
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSSHClientConfigHostKey(t *testing.T) {
+	const hostKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOMqqnkVzrm0SdG6UOoqKLsabgH5C9okWi0dh2l9GKJl"
+	testCases := []struct {
+		Name      string
+		HostKey   string
+		Insecure  bool
+		ExpectErr string // 期望错误包含的消息
+	}{
+		{Name: "配置公钥", HostKey: hostKey},
+		{Name: "未配置公钥", ExpectErr: "未配置公钥"},
+		{Name: "显式跳过校验", Insecure: true},
+		{Name: "配置公钥时忽略跳过校验", HostKey: hostKey, Insecure: true},
+		{Name: "公钥格式错误", HostKey: "ssh-ed25519 bad", ExpectErr: "公钥格式错误"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			opts := &SSHOptions{Host: "127.0.0.1", User: "noah", Password: "secret", HostKey: tc.HostKey, InsecureIgnoreHostKey: tc.Insecure}
+			config, err := opts.clientConfig()
+			if tc.ExpectErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.ExpectErr) {
+					t.Fatalf("期望错误包含 %q，实际 %v", tc.ExpectErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("生成SSH客户端配置失败: %v", err)
+			}
+			if config.HostKeyCallback == nil {
+				t.Errorf("未设置公钥校验")
+			}
+		})
+	}
+}
@@ -0,0 +1,422 @@
+package dbpool
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"go-noah/pkg/global"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"go.uber.org/zap"
+)
+
+// Role 连接池用途，同一实例不同用途使用独立的连接池，互不抢占连接
+type Role string
+
+const (
+	RoleDAS      Role = "das"      // 数据查询、元数据查询
+	RoleInspect  Role = "inspect"  // 语法审核
+	RoleExecutor Role = "executor" // 工单执行
+	RoleControl  Role = "control"  // 执行监控、终止语句（KILL），与执行连接分开，执行连接耗尽时仍可取消任务
+)
+
+// 默认参数
+const (
+	defaultMaxOpenConns        = 10
+	defaultMaxIdleConns        = 2
+	defaultConnMaxIdleTime     = 5 * time.Minute
+	defaultConnMaxLifetime     = 30 * time.Minute
+	defaultPoolIdleTimeout     = 10 * time.Minute
+	defaultHealthCheckInterval = time.Minute
+	defaultAcquireTimeout      = 10 * time.Second
+	// defaultControlMaxOpenConns 监控、终止连接池默认最大连接数（不使用全局 max_open_conns）
+	defaultControlMaxOpenConns = 4
+
+	healthCheckTimeout = 3 * time.Second
+	resetTimeout       = 3 * time.Second
+)
+
+// maxDatabaseRetries 获取未切换过库的连接时最多丢弃的连接数
+const maxDatabaseRetries = 8
+
+var paramNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Spec 获取连接的参数
+type Spec struct {
+	InstanceID string // 实例ID，为空时按 DSN 区分连接池（不支持按实例失效）
+	Role       Role
+	DSN        string            // 不含库名的 DSN，同一实例同一用途的 DSN 变化时会重建连接池
+	Database   string            // 获取连接后切换到的库，为空时保证连接未选择任何库
+	Params     map[string]string // 会话变量，连接归还时恢复默认值
+}
+
+// Settings 连接池参数
+type Settings struct {
+	MaxOpenConns        int           // 每个实例每种用途的最大连接数
+	MaxIdleConns        int           // 最大空闲连接数
+	ConnMaxIdleTime     time.Duration // 连接最长空闲时间
+	ConnMaxLifetime     time.Duration // 连接最长存活时间
+	PoolIdleTimeout     time.Duration // 连接池无使用超过该时间后关闭
+	HealthCheckInterval time.Duration // 健康检查间隔
+	AcquireTimeout      time.Duration // 获取连接的最长等待时间
+}
+
+// Conn 从连接池获取的独占连接，使用完成后必须调用 Close 归还
+type Conn struct {
+	*sql.Conn
+	discard bool
+	params  []string // 需要在归还时恢复默认值的会话变量
+}
+
+// Discard 标记连接的会话状态已被修改（如设置了会话变量），归还时关闭而不是放回连接池
+func (c *Conn) Discard() {
+	c.discard = true
+}
+
+// Close 归还连接
+func (c *Conn) Close() error {
+	if !c.discard && len(c.params) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), resetTimeout)
+		for _, name := range c.params {
+			if _, err := c.ExecContext(ctx, fmt.Sprintf("SET SESSION %s = DEFAULT", name)); err != nil {
+				c.discard = true
+				break
+			}
+		}
+		cancel()
+	}
+	if c.discard {
+		// Raw 返回 driver.ErrBadConn 时连接池会关闭该连接
+		_ = c.Conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+	}
+	return c.Conn.Close()
+}
+
+type poolKey struct {
+	instanceID string
+	role       Role
+}
+
+type pool struct {
+	db       *sql.DB
+	dsn      string
+	lastUsed time.Time
+}
+
+// Registry 实例连接池注册表
+type Registry struct {
+	mu       sync.Mutex
+	pools    map[poolKey]*pool
+	settings func(Role) Settings
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// NewRegistry 创建连接池注册表，settings 返回各用途的连接池参数
+func NewRegistry(settings func(Role) Settings) *Registry {
+	return &Registry{
+		pools:    make(map[poolKey]*pool),
+		settings: settings,
+		stop:     make(chan struct{}),
+	}
+}
+
+var (
+	defaultRegistry     *Registry
+	defaultRegistryOnce sync.Once
+)
+
+// Default 返回按 db_pool 配置创建的全局连接池注册表
+func Default() *Registry {
+	defaultRegistryOnce.Do(func() {
+		defaultRegistry = NewRegistry(settingsFromConfig)
+	})
+	return defaultRegistry
+}
+
+// Get 从全局注册表获取连接
+func Get(ctx context.Context, spec Spec) (*Conn, error) {
+	return Default().Get(ctx, spec)
+}
+
+// Invalidate 关闭全局注册表中实例的所有连接池（实例配置修改或删除时调用）
+func Invalidate(instanceID string) {
+	Default().Invalidate(instanceID)
+}
+
+// settingsFromConfig 读取 db_pool 配置，db_pool.<role>.xxx 优先于 db_pool.xxx
+func settingsFromConfig(role Role) Settings {
+	s := Settings{
+		MaxOpenConns:        defaultMaxOpenConns,
+		MaxIdleConns:        defaultMaxIdleConns,
+		ConnMaxIdleTime:     defaultConnMaxIdleTime,
+		ConnMaxLifetime:     defaultConnMaxLifetime,
+		PoolIdleTimeout:     defaultPoolIdleTimeout,
+		HealthCheckInterval: defaultHealthCheckInterval,
+		AcquireTimeout:      defaultAcquireTimeout,
+	}
+	if role == RoleControl {
+		s.MaxOpenConns = defaultControlMaxOpenConns
+	}
+	if global.Conf == nil {
+		return s
+	}
+	getInt := func(name string) int {
+		if v := global.Conf.GetInt(fmt.Sprintf("db_pool.%s.%s", role, name)); v > 0 {
+			return v
+		}
+		return global.Conf.GetInt("db_pool." + name)
+	}
+	maxOpenConns := getInt("max_open_conns")
+	if role == RoleControl {
+		maxOpenConns = global.Conf.GetInt("db_pool.control.max_open_conns")
+	}
+	if maxOpenConns > 0 {
+		s.MaxOpenConns = maxOpenConns
+	}
+	if v := getInt("max_idle_conns"); v > 0 {
+		s.MaxIdleConns = v
+	}
+	if v := getInt("conn_max_idle_seconds"); v > 0 {
+		s.ConnMaxIdleTime = time.Duration(v) * time.Second
+	}
+	if v := getInt("conn_max_lifetime_seconds"); v > 0 {
+		s.ConnMaxLifetime = time.Duration(v) * time.Second
+	}
+	if v := getInt("pool_idle_timeout_seconds"); v > 0 {
+		s.PoolIdleTimeout = time.Duration(v) * time.Second
+	}
+	if v := getInt("acquire_timeout_seconds"); v > 0 {
+		s.AcquireTimeout = time.Duration(v) * time.Second
+	}
+	if v := global.Conf.GetInt("db_pool.health_check_interval_seconds"); v > 0 {
+		s.HealthCheckInterval = time.Duration(v) * time.Second
+	}
+	return s
+}
+
+// Get 获取一个独占连接：按 Spec.Database 切换库，按 Spec.Params 设置会话变量
+func (r *Registry) Get(ctx context.Context, spec Spec) (*Conn, error) {
+	db, err := r.db(spec)
+	if err != nil {
+		return nil, err
+	}
+	acquireCtx, cancel := context.WithTimeout(ctx, r.settings(spec.Role).AcquireTimeout)
+	defer cancel()
+
+	for i := 0; ; i++ {
+		sqlConn, err := db.Conn(acquireCtx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return nil, fmt.Errorf("获取数据库连接超时，连接数已达上限: %w", err)
+			}
+			return nil, err
+		}
+		conn := &Conn{Conn: sqlConn}
+
+		if spec.Database != "" {
+			if _, err := conn.ExecContext(acquireCtx, "USE "+quoteIdentifier(spec.Database)); err != nil {
+				conn.Discard()
+				conn.Close()
+				return nil, err
+			}
+		} else {
+			// 复用的连接可能切换过库，未指定库的调用方需要一个未选择库的连接（避免在其他库中执行）
+			var current sql.NullString
+			if err := conn.QueryRowContext(acquireCtx, "SELECT DATABASE()").Scan(&current); err != nil {
+				conn.Discard()
+				conn.Close()
+				return nil, err
+			}
+			if current.Valid {
+				conn.Discard()
+				conn.Close()
+				if i >= maxDatabaseRetries {
+					return nil, fmt.Errorf("获取未选择库的数据库连接失败")
+				}
+				continue
+			}
+		}
+
+		if err := conn.setParams(acquireCtx, spec.Params); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		return conn, nil
+	}
+}
+
+// setParams 设置会话变量（与 DSN 参数的处理方式一致，charset 使用 SET NAMES）
+func (c *Conn) setParams(ctx context.Context, params map[string]string) error {
+	for name, value := range params {
+		if !paramNamePattern.MatchString(name) {
+			c.Discard()
+			return fmt.Errorf("无效的会话变量: %s", name)
+		}
+		query := fmt.Sprintf("SET SESSION %s = %s", name, value)
+		if name == "charset" {
+			// 字符集无法恢复为 DSN 中的设置，归还时关闭连接
+			query = "SET NAMES " + value
+			c.Discard()
+		} else {
+			c.params = append(c.params, name)
+		}
+		if _, err := c.ExecContext(ctx, query); err != nil {
+			c.Discard()
+			return err
+		}
+	}
+	return nil
+}
+
+// db 获取或创建实例连接池，DSN 变化时（如其他节点修改了实例配置）重建
+func (r *Registry) db(spec Spec) (*sql.DB, error) {
+	r.once.Do(func() { go r.run() })
+
+	key := poolKey{instanceID: spec.InstanceID, role: spec.Role}
+	if key.instanceID == "" {
+		key.instanceID = "dsn:" + spec.DSN
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if p, ok := r.pools[key]; ok {
+		if p.dsn == spec.DSN {
+			p.lastUsed = time.Now()
+			return p.db, nil
+		}
+		delete(r.pools, key)
+		go p.db.Close()
+	}
+
+	db, err := sql.Open("mysql", spec.DSN)
+	if err != nil {
+		return nil, err
+	}
+	s := r.settings(spec.Role)
+	db.SetMaxOpenConns(s.MaxOpenConns)
+	db.SetMaxIdleConns(s.MaxIdleConns)
+	db.SetConnMaxIdleTime(s.ConnMaxIdleTime)
+	db.SetConnMaxLifetime(s.ConnMaxLifetime)
+	r.pools[key] = &pool{db: db, dsn: spec.DSN, lastUsed: time.Now()}
+	return db, nil
+}
+
+// Invalidate 关闭实例的所有连接池，正在使用的连接归还时关闭
+func (r *Registry) Invalidate(instanceID string) {
+	if instanceID == "" {
+		return
+	}
+	r.mu.Lock()
+	var closing []*sql.DB
+	for key, p := range r.pools {
+		if key.instanceID == instanceID {
+			closing = append(closing, p.db)
+			delete(r.pools, key)
+		}
+	}
+	r.mu.Unlock()
+
+	for _, db := range closing {
+		db.Close()
+	}
+}
+
+// Close 停止健康检查并关闭所有连接池
+func (r *Registry) Close() {
+	r.mu.Lock()
+	select {
+	case <-r.stop:
+	default:
+		close(r.stop)
+	}
+	pools := r.pools
+	r.pools = make(map[poolKey]*pool)
+	r.mu.Unlock()
+
+	for _, p := range pools {
+		p.db.Close()
+	}
+}
+
+// run 定期关闭长时间未使用的连接池，并对其余连接池做健康检查
+func (r *Registry) run() {
+	ticker := time.NewTicker(r.settings("").HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.evictAndCheck()
+		}
+	}
+}
+
+func (r *Registry) evictAndCheck() {
+	type entry struct {
+		key poolKey
+		p   *pool
+	}
+	var (
+		idle   []*sql.DB
+		active []entry
+		now    = time.Now()
+	)
+	r.mu.Lock()
+	for key, p := range r.pools {
+		if p.db.Stats().InUse == 0 && now.Sub(p.lastUsed) > r.settings(key.role).PoolIdleTimeout {
+			idle = append(idle, p.db)
+			delete(r.pools, key)
+			continue
+		}
+		active = append(active, entry{key, p})
+	}
+	r.mu.Unlock()
+
+	for _, db := range idle {
+		db.Close()
+	}
+
+	for _, e := range active {
+		// 连接全部被占用时说明实例可用，跳过检查（避免 Ping 等待空闲连接超时）
+		if stats := e.p.db.Stats(); stats.InUse >= stats.MaxOpenConnections {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		err := e.p.db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			continue
+		}
+		// 健康检查失败时移除连接池，下次使用时重新创建
+		r.mu.Lock()
+		if current, ok := r.pools[e.key]; ok && current == e.p {
+			delete(r.pools, e.key)
+		}
+		r.mu.Unlock()
+		e.p.db.Close()
+		if global.Logger != nil {
+			instanceID := e.key.instanceID
+			if strings.HasPrefix(instanceID, "dsn:") {
+				instanceID = ""
+			}
+			global.Logger.Warn("实例连接池健康检查失败，已关闭",
+				zap.String("instance_id", instanceID),
+				zap.String("role", string(e.key.role)),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// quoteIdentifier 转义库名
+func quoteIdentifier(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
@@ -0,0 +1,39 @@
+package dbpool
+
+import (
+	"go-noah/pkg/global"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestSettingsFromConfig(t *testing.T) {
+	testCases := []struct {
+		Name          string
+		Conf          map[string]interface{}
+		Role          Role
+		ExpectMaxOpen int
+	}{
+		{Name: "默认值", Role: RoleExecutor, ExpectMaxOpen: defaultMaxOpenConns},
+		{Name: "监控终止默认值", Role: RoleControl, ExpectMaxOpen: defaultControlMaxOpenConns},
+		{Name: "全局配置", Conf: map[string]interface{}{"db_pool.max_open_conns": 8}, Role: RoleDAS, ExpectMaxOpen: 8},
+		{Name: "用途配置优先", Conf: map[string]interface{}{"db_pool.max_open_conns": 8, "db_pool.executor.max_open_conns": 20}, Role: RoleExecutor, ExpectMaxOpen: 20},
+		{Name: "监控终止不使用全局配置", Conf: map[string]interface{}{"db_pool.max_open_conns": 8}, Role: RoleControl, ExpectMaxOpen: defaultControlMaxOpenConns},
+		{Name: "监控终止不使用执行配置", Conf: map[string]interface{}{"db_pool.executor.max_open_conns": 20}, Role: RoleControl, ExpectMaxOpen: defaultControlMaxOpenConns},
+		{Name: "监控终止配置", Conf: map[string]interface{}{"db_pool.control.max_open_conns": 2}, Role: RoleControl, ExpectMaxOpen: 2},
+	}
+	prev := global.Conf
+	t.Cleanup(func() { global.Conf = prev })
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			conf := viper.New()
+			for key, value := range tc.Conf {
+				conf.Set(key, value)
+			}
+			global.Conf = conf
+			if got := settingsFromConfig(tc.Role).MaxOpenConns; got != tc.ExpectMaxOpen {
+				t.Errorf("期望最大连接数 %d，实际 %d", tc.ExpectMaxOpen, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,286 @@
+package lease
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-noah/pkg/global"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"go.uber.org/zap"
+)
+
+// 租约在 Redis 中的键：lock:<name> 保存持有者，lock:fence:<name> 为单调递增的 fencing token
+// 值格式：<fencing token>|<随机令牌>|<获取时间(unix)>|<节点>
+const (
+	keyPrefix      = "lock:"
+	fenceKeyPrefix = "lock:fence:"
+
+	defaultTTL                = 30 * time.Second
+	defaultUnreachableTimeout = 5 * time.Minute
+	opTimeout                 = 3 * time.Second
+)
+
+var (
+	// ErrLost 租约已丢失（被其他节点接管或 Redis 长时间不可用），持有者必须停止写入
+	// 租约的 Context 以 ErrLost 为原因取消，可通过 context.Cause 与用户取消区分
+	ErrLost = errors.New("执行锁已丢失")
+
+	// acquireScript 键不存在时生成 fencing token 并写入持有者，存在时返回当前持有者
+	acquireScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current then
+	return {0, current}
+end
+local fence = redis.call('INCR', KEYS[2])
+local value = fence .. '|' .. ARGV[1]
+redis.call('SET', KEYS[1], value, 'PX', ARGV[2])
+return {fence, value}
+`)
+	// renewScript 仍为持有者时续期；Redis 短暂不可用导致租约过期且未被其他节点获取时重新写入
+	// （其他节点在此期间获取过租约时 fencing token 已递增，旧持有者的写入仍会被拒绝）
+	renewScript = redis.NewScript(`
+local current = redis.call('GET', KEYS[1])
+if current == ARGV[1] then
+	return redis.call('PEXPIRE', KEYS[1], ARGV[2])
+end
+if not current then
+	redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+	return 1
+end
+return 0
+`)
+	// releaseScript 仍为持有者时释放
+	releaseScript = redis.NewScript(`
+if redis.call('GET', KEYS[1]) == ARGV[1] then
+	return redis.call('DEL', KEYS[1])
+end
+return 0
+`)
+)
+
+// HeldError 租约已被其他持有者占用
+type HeldError struct {
+	Name     string
+	Node     string    // 持有者所在节点
+	Fence    int64     // 持有者的 fencing token
+	Acquired time.Time // 持有者获取租约的时间
+}
+
+func (e *HeldError) Error() string {
+	return fmt.Sprintf("执行锁 %s 由节点 %s 持有（fencing token %d，获取于 %s）",
+		e.Name, e.Node, e.Fence, e.Acquired.Format(time.DateTime))
+}
+
+// Lease 分布式租约：持有期间后台自动续期，被其他节点接管或 Redis 长时间不可用时 Context 以 ErrLost 为原因取消
+// 未配置 Redis 时退化为进程内的锁（单节点部署），此时 Fence 为 0
+type Lease struct {
+	name   string
+	value  string
+	fence  int64
+	ttl    time.Duration
+	ctx    context.Context
+	cancel context.CancelCauseFunc
+	done   chan struct{}
+	local  bool
+	once   sync.Once
+	lost   bool
+	mu     sync.Mutex
+}
+
+// 进程内锁（未配置 Redis 时使用）
+var (
+	localMu     sync.Mutex
+	localLeases = make(map[string]*HeldError)
+)
+
+// NodeName 当前节点名称（主机名:进程ID）
+func NodeName() string {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "unknown"
+	}
+	return hostname + ":" + strconv.Itoa(os.Getpid())
+}
+
+// TTL 租约有效期（execution_lock.ttl_seconds，默认 30 秒），每隔 1/3 有效期续期一次
+func TTL() time.Duration {
+	if global.Conf != nil {
+		if v := global.Conf.GetInt("execution_lock.ttl_seconds"); v > 0 {
+			return time.Duration(v) * time.Second
+		}
+	}
+	return defaultTTL
+}
+
+// UnreachableTimeout Redis 不可用时继续持有租约的最长时间（execution_lock.unreachable_timeout_seconds，默认 5 分钟）
+// 短暂不可用不中断正在执行的任务（如长时间运行的 DDL），超过后视为租约丢失
+func UnreachableTimeout() time.Duration {
+	if global.Conf != nil {
+		if v := global.Conf.GetInt("execution_lock.unreachable_timeout_seconds"); v > 0 {
+			return time.Duration(v) * time.Second
+		}
+	}
+	return defaultUnreachableTimeout
+}
+
+// Acquire 获取租约，已被占用时返回 *HeldError
+// 返回的 Lease 的 Context 派生自 parent，租约丢失或释放时被取消
+func Acquire(parent context.Context, name string, ttl time.Duration) (*Lease, error) {
+	if ttl <= 0 {
+		ttl = TTL()
+	}
+	token := make([]byte, 8)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	holder := fmt.Sprintf("%s|%d|%s", hex.EncodeToString(token), now.Unix(), NodeName())
+
+	l := &Lease{name: name, ttl: ttl, done: make(chan struct{})}
+	if global.Redis == nil {
+		localMu.Lock()
+		defer localMu.Unlock()
+		if held, ok := localLeases[name]; ok {
+			return nil, held
+		}
+		localLeases[name] = &HeldError{Name: name, Node: NodeName(), Acquired: now}
+		l.value = holder
+		l.local = true
+		l.ctx, l.cancel = context.WithCancelCause(parent)
+		close(l.done)
+		return l, nil
+	}
+
+	ctx, cancel := context.WithTimeout(parent, opTimeout)
+	defer cancel()
+	res, err := acquireScript.Run(ctx, global.Redis,
+		[]string{keyPrefix + name, fenceKeyPrefix + name}, holder, ttl.Milliseconds()).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("获取执行锁失败: %w", err)
+	}
+	if len(res) != 2 {
+		return nil, fmt.Errorf("获取执行锁失败: 返回值格式错误")
+	}
+	fence, _ := res[0].(int64)
+	value, _ := res[1].(string)
+	if fence == 0 {
+		return nil, parseHolder(name, value)
+	}
+
+	l.value = value
+	l.fence = fence
+	l.ctx, l.cancel = context.WithCancelCause(parent)
+	go l.keepAlive()
+	return l, nil
+}
+
+// parseHolder 解析租约值中的持有者信息
+func parseHolder(name, value string) *HeldError {
+	held := &HeldError{Name: name, Node: "unknown"}
+	parts := strings.SplitN(value, "|", 4)
+	if len(parts) != 4 {
+		return held
+	}
+	held.Fence, _ = strconv.ParseInt(parts[0], 10, 64)
+	if sec, err := strconv.ParseInt(parts[2], 10, 64); err == nil {
+		held.Acquired = time.Unix(sec, 0)
+	}
+	held.Node = parts[3]
+	return held
+}
+
+// keepAlive 定期续期，Redis 暂时不可用时持续重试，被其他节点接管或超过 UnreachableTimeout 仍未续期成功视为丢失
+func (l *Lease) keepAlive() {
+	defer close(l.done)
+	ticker := time.NewTicker(l.ttl / 3)
+	defer ticker.Stop()
+	renewed := time.Now()
+	unreachableTimeout := max(UnreachableTimeout(), l.ttl)
+	for {
+		select {
+		case <-l.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+		n, err := renewScript.Run(ctx, global.Redis, []string{keyPrefix + l.name}, l.value, l.ttl.Milliseconds()).Int64()
+		cancel()
+		switch {
+		case err == nil && n == 1:
+			renewed = time.Now()
+			continue
+		case err == nil:
+			global.Logger.Error("执行锁已被其他节点接管，停止执行",
+				zap.String("lock", l.name), zap.Int64("fence", l.fence))
+		case time.Since(renewed) < unreachableTimeout:
+			global.Logger.Warn("执行锁续期失败，稍后重试", zap.String("lock", l.name),
+				zap.Duration("since_renewed", time.Since(renewed)), zap.Error(err))
+			continue
+		default:
+			global.Logger.Error("执行锁续期超时，视为已丢失，停止执行",
+				zap.String("lock", l.name), zap.Int64("fence", l.fence), zap.Error(err))
+		}
+		l.mu.Lock()
+		l.lost = true
+		l.mu.Unlock()
+		l.cancel(ErrLost)
+		return
+	}
+}
+
+// Name 租约名称
+func (l *Lease) Name() string {
+	return l.name
+}
+
+// Fence fencing token，单调递增，后获取租约的持有者更大；写入数据时据此拒绝已失去租约的旧持有者
+func (l *Lease) Fence() int64 {
+	return l.fence
+}
+
+// Context 租约的上下文，租约丢失（原因为 ErrLost）或释放时被取消
+func (l *Lease) Context() context.Context {
+	return l.ctx
+}
+
+// Err 租约丢失时返回 ErrLost
+func (l *Lease) Err() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.lost {
+		return ErrLost
+	}
+	return nil
+}
+
+// Release 释放租约（仅当仍为持有者时删除），可重复调用
+func (l *Lease) Release() {
+	l.once.Do(func() {
+		l.cancel(nil)
+		<-l.done
+		if l.local {
+			localMu.Lock()
+			delete(localLeases, l.name)
+			localMu.Unlock()
+			return
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), opTimeout)
+		defer cancel()
+		if err := releaseScript.Run(ctx, global.Redis, []string{keyPrefix + l.name}, l.value).Err(); err != nil {
+			global.Logger.Warn("释放执行锁失败，将在有效期后自动过期", zap.String("lock", l.name), zap.Error(err))
+		}
+	})
+}
+
+// OrderLockName 工单执行锁名称（同一工单的任务串行执行，手动执行、批量执行和定时执行共用）
+func OrderLockName(orderID string) string {
+	return "order:" + orderID
+}
@@ -2,15 +2,18 @@ package noah
 
 import (
 	"context"
+	"fmt"
 	"go-noah/internal/job"
 	"go-noah/internal/repository"
 	insightRepo "go-noah/internal/repository/insight"
 	"go-noah/internal/server"
 	"go-noah/internal/task"
 	"go-noah/pkg/app"
+	"go-noah/pkg/dbpool"
 	"go-noah/pkg/global"
 	"go-noah/pkg/jwt"
 	"go-noah/pkg/log"
+	"go-noah/pkg/secret"
 	"go-noah/pkg/sid"
 
 	"github.com/spf13/viper"
@@ -18,6 +21,10 @@ import (
 )
 
 func NewServerApp(conf *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
+	if err := checkMasterKey(conf); err != nil {
+		return nil, func() {}, err
+	}
+
 	// 只初始化基础设施（存入 global）
 	global.Sid = sid.NewSid()
 	global.JWT = jwt.NewJwt(conf)
@@ -91,11 +98,16 @@ func NewServerApp(conf *viper.Viper, logger *log.Logger) (*app.App, func(), erro
 		if global.Redis != nil {
 			_ = global.Redis.Close()
 		}
+		dbpool.Default().Close()
 	}
 	return a, cleanup, nil
 }
 
 func NewTaskApp(conf *viper.Viper, logger *log.Logger) (*app.App, func(), error) {
+	if err := checkMasterKey(conf); err != nil {
+		return nil, func() {}, err
+	}
+
 	// 只初始化基础设施（存入 global）
 	global.Sid = sid.NewSid()
 	global.DB = repository.NewDB(conf, logger)
@@ -124,6 +136,7 @@ func NewTaskApp(conf *viper.Viper, logger *log.Logger) (*app.App, func(), error)
 		if sqlDB != nil {
 			_ = sqlDB.Close()
 		}
+		dbpool.Default().Close()
 	}
 	return a, cleanup, nil
 }
@@ -150,3 +163,11 @@ func NewMigrateApp(conf *viper.Viper, logger *log.Logger) (*app.App, func(), err
 	}
 	return a, cleanup, nil
 }
+
+// checkMasterKey 检查实例凭据加密主密钥（secret.master_key 或 secret.key_file），未配置或格式错误时拒绝启动
+func checkMasterKey(conf *viper.Viper) error {
+	if _, err := secret.LoadKeyring(conf); err != nil {
+		return fmt.Errorf("加载主密钥失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,261 @@
+package secret
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"go-noah/pkg/global"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// 密文格式：enc:v1:<主密钥ID>:<加密后的数据密钥>:<加密后的内容>
+// 每个值使用独立的随机数据密钥（信封加密），数据密钥由主密钥加密
+const (
+	prefix  = "enc:v1:"
+	keySize = 32
+)
+
+// 环境文件中的变量名
+const (
+	envMasterKey   = "NOAH_MASTER_KEY"
+	envRetiredKeys = "NOAH_RETIRED_KEYS"
+)
+
+var (
+	// ErrNoMasterKey 未配置主密钥
+	ErrNoMasterKey = errors.New("未配置主密钥（secret.master_key 或 secret.key_file），请使用 go run ./cmd/ops gen-key 生成")
+	encoding       = base64.RawURLEncoding
+)
+
+// Keyring 主密钥集合：使用当前主密钥加密，按密文中的主密钥ID选择解密密钥
+type Keyring struct {
+	primary string
+	keys    map[string][]byte
+}
+
+// NewKeyring 创建主密钥集合，密钥为 base64 编码的 32 字节，retired 为轮换前的旧主密钥（仅用于解密）
+func NewKeyring(primary string, retired ...string) (*Keyring, error) {
+	k := &Keyring{keys: make(map[string][]byte)}
+	for i, encoded := range append([]string{primary}, retired...) {
+		encoded = strings.TrimSpace(encoded)
+		if encoded == "" {
+			if i == 0 {
+				return nil, ErrNoMasterKey
+			}
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("主密钥格式错误，需要 base64 编码的 %d 字节", keySize)
+		}
+		id := keyID(key)
+		if i == 0 {
+			k.primary = id
+		}
+		k.keys[id] = key
+	}
+	return k, nil
+}
+
+// LoadKeyring 从配置加载主密钥，配置了 secret.key_file 时从环境文件读取
+func LoadKeyring(conf *viper.Viper) (*Keyring, error) {
+	if path := conf.GetString("secret.key_file"); path != "" {
+		return LoadKeyFile(path)
+	}
+	return NewKeyring(conf.GetString("secret.master_key"), conf.GetStringSlice("secret.retired_keys")...)
+}
+
+// LoadKeyFile 从环境文件加载主密钥（NOAH_MASTER_KEY，旧主密钥 NOAH_RETIRED_KEYS 以逗号分隔）
+func LoadKeyFile(path string) (*Keyring, error) {
+	env, err := readEnvFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return NewKeyring(env[envMasterKey], strings.Split(env[envRetiredKeys], ",")...)
+}
+
+// GenerateKey 生成随机主密钥（base64 编码）
+func GenerateKey() (string, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// IsEncrypted 是否为密文（未加密的值视为明文，兼容加密前写入的数据）
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, prefix)
+}
+
+// Encrypt 使用当前主密钥加密，空值不加密
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, keySize)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(k.keys[k.primary], dataKey, []byte(k.primary))
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataKey, []byte(plaintext), nil)
+	if err != nil {
+		return "", err
+	}
+	return prefix + k.primary + ":" + encoding.EncodeToString(wrapped) + ":" + encoding.EncodeToString(sealed), nil
+}
+
+// Decrypt 解密，明文原样返回
+func (k *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	parts := strings.Split(strings.TrimPrefix(value, prefix), ":")
+	if len(parts) != 3 {
+		return "", errors.New("密文格式错误")
+	}
+	masterKey, ok := k.keys[parts[0]]
+	if !ok {
+		return "", fmt.Errorf("未找到主密钥 %s，请检查 secret.retired_keys 是否包含轮换前的主密钥", parts[0])
+	}
+	wrapped, err := encoding.DecodeString(parts[1])
+	if err != nil {
+		return "", errors.New("密文格式错误")
+	}
+	sealed, err := encoding.DecodeString(parts[2])
+	if err != nil {
+		return "", errors.New("密文格式错误")
+	}
+	dataKey, err := open(masterKey, wrapped, []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("解密数据密钥失败: %w", err)
+	}
+	plaintext, err := open(dataKey, sealed, nil)
+	if err != nil {
+		return "", fmt.Errorf("解密失败: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// 全局主密钥（从 global.Conf 加载，加载成功后缓存）
+var (
+	defaultMu      sync.Mutex
+	defaultKeyring *Keyring
+)
+
+// Default 获取全局主密钥
+func Default() (*Keyring, error) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	if defaultKeyring != nil {
+		return defaultKeyring, nil
+	}
+	if global.Conf == nil {
+		return nil, errors.New("配置未初始化")
+	}
+	keyring, err := LoadKeyring(global.Conf)
+	if err != nil {
+		return nil, err
+	}
+	defaultKeyring = keyring
+	return keyring, nil
+}
+
+// Encrypt 使用全局主密钥加密
+func Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	keyring, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return keyring.Encrypt(plaintext)
+}
+
+// Decrypt 使用全局主密钥解密（建立连接时调用），明文原样返回
+func Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	keyring, err := Default()
+	if err != nil {
+		return "", err
+	}
+	return keyring.Decrypt(value)
+}
+
+// keyID 主密钥ID（密钥摘要前 4 字节）
+func keyID(key []byte) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:4])
+}
+
+// seal AES-GCM 加密，返回 nonce + 密文
+func seal(key, plaintext, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(key, data, additional []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("密文长度错误")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], additional)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// readEnvFile 读取环境文件（KEY=VALUE，忽略空行和 # 注释）
+func readEnvFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取主密钥文件失败: %w", err)
+	}
+	defer f.Close()
+
+	env := make(map[string]string)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(strings.TrimPrefix(line, "export "), "=")
+		if !ok {
+			continue
+		}
+		env[strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"'`)
+	}
+	return env, scanner.Err()
+}
@@ -4,8 +4,12 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net"
 	"os"
+	"regexp"
+	"strconv"
+	"strings"
 	"time"
 
 	"go-noah/pkg/global"
@@ -22,6 +26,8 @@ import (
 //   - panic: 取消执行（紧急停止）
 //   - chunk-size=xxx: 设置 chunk size（例如：chunk-size=1000）
 //   - max-lag-millis=xxx: 设置最大延迟（例如：max-lag-millis=2000）
+//   - nice-ratio=xxx: 设置每拷贝一批后的休眠比例（例如：nice-ratio=0.5）
+//   - unpostpone: 立即执行被推迟的 cut-over
 func GhostControl(socketPath, command string) error {
 	// 连接 Unix socket
 	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
@@ -201,3 +207,171 @@ func GetGhostProgressFromRedis(orderID string) (map[string]interface{}, error) {
 
 	return progressData, nil
 }
+
+// GhostQuery 通过 Unix socket 发送命令给 gh-ost 并读取返回内容（用于 status 等查询类命令）
+func GhostQuery(socketPath, command string) (string, error) {
+	conn, err := net.DialTimeout("unix", socketPath, 5*time.Second)
+	if err != nil {
+		return "", fmt.Errorf("连接 gh-ost socket 失败: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(10 * time.Second)); err != nil {
+		return "", fmt.Errorf("设置超时失败: %w", err)
+	}
+	if _, err := conn.Write([]byte(command + "\n")); err != nil {
+		return "", fmt.Errorf("发送命令失败: %w", err)
+	}
+
+	// gh-ost 返回结果后会关闭连接
+	output, err := io.ReadAll(conn)
+	if err != nil && len(output) == 0 {
+		return "", fmt.Errorf("读取返回内容失败: %w", err)
+	}
+	return string(output), nil
+}
+
+// GhostStatus gh-ost status 命令返回的迁移状态
+type GhostStatus struct {
+	RowsCopied       int64   `json:"rows_copied"`        // 已拷贝行数
+	RowsEstimate     int64   `json:"rows_estimate"`      // 预估总行数
+	Percent          float64 `json:"percent"`            // 拷贝进度（百分比）
+	Applied          int64   `json:"applied"`            // 已应用的 binlog 事件数
+	Backlog          int64   `json:"backlog"`            // 待应用的 binlog 事件数
+	BacklogCapacity  int64   `json:"backlog_capacity"`   // binlog 事件队列容量
+	ElapsedTime      string  `json:"elapsed_time"`       // 已运行时间
+	ETA              string  `json:"eta"`                // 预计剩余时间
+	LagSeconds       float64 `json:"lag_seconds"`        // 复制延迟（秒）
+	HeartbeatLag     float64 `json:"heartbeat_lag"`      // 心跳延迟（秒）
+	State            string  `json:"state"`              // 迁移状态（migrating/throttled/postponing cut-over 等）
+	ChunkSize        int64   `json:"chunk_size"`         // 当前 chunk-size
+	MaxLagMillis     int64   `json:"max_lag_millis"`     // 当前 max-lag-millis
+	NiceRatio        float64 `json:"nice_ratio"`         // 当前 nice-ratio
+	CutOverPostponed bool    `json:"cut_over_postponed"` // 是否正在推迟 cut-over
+}
+
+var (
+	ghostCopyRegex      = regexp.MustCompile(`Copy: (\d+)/(\d+) ([\d.]+)%`)
+	ghostAppliedRegex   = regexp.MustCompile(`Applied: (\d+)`)
+	ghostBacklogRegex   = regexp.MustCompile(`Backlog: (\d+)/(\d+)`)
+	ghostTimeRegex      = regexp.MustCompile(`Time: ([^(;]+)\(total\)`)
+	ghostLagRegex       = regexp.MustCompile(`\bLag: ([\d.]+)s`)
+	ghostHBLagRegex     = regexp.MustCompile(`HeartbeatLag: ([\d.]+)s`)
+	ghostStateRegex     = regexp.MustCompile(`State: ([^;]+)`)
+	ghostETARegex       = regexp.MustCompile(`ETA: (.+)$`)
+	ghostChunkSizeRegex = regexp.MustCompile(`chunk-size: (\d+)`)
+	ghostMaxLagRegex    = regexp.MustCompile(`max-lag-millis: (\d+)ms`)
+	ghostNiceRatioRegex = regexp.MustCompile(`nice-ratio: ([\d.]+)`)
+)
+
+// ParseGhostStatus 解析 gh-ost status 命令的输出
+// 示例：Copy: 5000/6000 83.3%; Applied: 0; Backlog: 0/1000; Time: 3s(total), 3s(copy); streamer: mysql-bin.000004:33233; Lag: 0.01s, HeartbeatLag: 0.01s, State: migrating; ETA: 1s
+func ParseGhostStatus(output string) (*GhostStatus, error) {
+	status := &GhostStatus{}
+	found := false
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "#") {
+			if m := ghostChunkSizeRegex.FindStringSubmatch(line); m != nil {
+				status.ChunkSize, _ = strconv.ParseInt(m[1], 10, 64)
+			}
+			if m := ghostMaxLagRegex.FindStringSubmatch(line); m != nil {
+				status.MaxLagMillis, _ = strconv.ParseInt(m[1], 10, 64)
+			}
+			if m := ghostNiceRatioRegex.FindStringSubmatch(line); m != nil {
+				status.NiceRatio, _ = strconv.ParseFloat(m[1], 64)
+			}
+			if strings.Contains(line, "postpone-cut-over-flag-file") && strings.Contains(line, "[set]") {
+				status.CutOverPostponed = true
+			}
+			continue
+		}
+		m := ghostCopyRegex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		found = true
+		status.RowsCopied, _ = strconv.ParseInt(m[1], 10, 64)
+		status.RowsEstimate, _ = strconv.ParseInt(m[2], 10, 64)
+		status.Percent, _ = strconv.ParseFloat(m[3], 64)
+		if m := ghostAppliedRegex.FindStringSubmatch(line); m != nil {
+			status.Applied, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+		if m := ghostBacklogRegex.FindStringSubmatch(line); m != nil {
+			status.Backlog, _ = strconv.ParseInt(m[1], 10, 64)
+			status.BacklogCapacity, _ = strconv.ParseInt(m[2], 10, 64)
+		}
+		if m := ghostTimeRegex.FindStringSubmatch(line); m != nil {
+			status.ElapsedTime = strings.TrimSpace(m[1])
+		}
+		if m := ghostLagRegex.FindStringSubmatch(line); m != nil {
+			status.LagSeconds, _ = strconv.ParseFloat(m[1], 64)
+		}
+		if m := ghostHBLagRegex.FindStringSubmatch(line); m != nil {
+			status.HeartbeatLag, _ = strconv.ParseFloat(m[1], 64)
+		}
+		if m := ghostStateRegex.FindStringSubmatch(line); m != nil {
+			status.State = strings.TrimSpace(m[1])
+		}
+		if m := ghostETARegex.FindStringSubmatch(line); m != nil {
+			status.ETA = strings.TrimSpace(m[1])
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("未解析到 gh-ost 迁移状态")
+	}
+	if strings.Contains(status.State, "postponing cut-over") {
+		status.CutOverPostponed = true
+	}
+	return status, nil
+}
+
+// GetGhostPostponeFlagPath 根据数据库名和表名生成 gh-ost 推迟 cut-over 标记文件路径
+func GetGhostPostponeFlagPath(database, table string) string {
+	return fmt.Sprintf("/tmp/gh-ost.%s.%s.postpone.flag", database, table)
+}
+
+// ghostCutOverTTLMargin 计划 cut-over 时间到期后继续保留的时间（执行器轮询间隔、gh-ost 尚未进入推迟状态等）
+const ghostCutOverTTLMargin = 24 * time.Hour
+
+// SetGhostCutOverTime 保存工单的计划 cut-over 时间（执行器轮询状态时检查，到期后自动 cut-over）
+func SetGhostCutOverTime(orderID string, cutOverTime time.Time) error {
+	if global.Redis == nil {
+		return fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("ghost:cutover:%s", orderID)
+	return global.Redis.Set(context.Background(), key, cutOverTime.Unix(), ghostCutOverTTL(cutOverTime, time.Now())).Err()
+}
+
+// ghostCutOverTTL 计划 cut-over 时间的保留时间：保留到计划时间之后 ghostCutOverTTLMargin，避免计划时间较远时提前过期
+func ghostCutOverTTL(cutOverTime, now time.Time) time.Duration {
+	ttl := ghostCutOverTTLMargin
+	if cutOverTime.After(now) {
+		ttl += cutOverTime.Sub(now)
+	}
+	return ttl
+}
+
+// GetGhostCutOverTime 获取工单的计划 cut-over 时间，未设置时 ok 为 false
+func GetGhostCutOverTime(orderID string) (cutOverTime time.Time, ok bool, err error) {
+	if global.Redis == nil {
+		return time.Time{}, false, nil
+	}
+	key := fmt.Sprintf("ghost:cutover:%s", orderID)
+	unix, err := global.Redis.Get(context.Background(), key).Int64()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return time.Unix(unix, 0), true, nil
+}
+
+// DeleteGhostCutOverTime 删除工单的计划 cut-over 时间
+func DeleteGhostCutOverTime(orderID string) {
+	if global.Redis == nil {
+		return
+	}
+	_ = global.Redis.Del(context.Background(), fmt.Sprintf("ghost:cutover:%s", orderID)).Err()
+}
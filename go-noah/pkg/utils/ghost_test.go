@@ -0,0 +1,103 @@
+package utils
+
+import (
+	"testing"
+	"time"
+)
+
+// ghostStatusHeader gh-ost status 命令输出的注释部分
+const ghostStatusHeader = "# Migrating `test`.`users`; Ghost table is `test`.`_users_gho`\n" +
+	"# Migrating db1:3306; inspecting db2:3306; executing on noah-1\n" +
+	"# Migration started at Mon Jun 03 10:00:00 +0800 2024\n" +
+	"# chunk-size: 1000; max-lag-millis: 1500ms; dml-batch-size: 10; max-load: Threads_running=25; critical-load: ; nice-ratio: 0.500000\n" +
+	"# throttle-additional-flag-file: /tmp/gh-ost.throttle\n"
+
+func TestParseGhostStatus(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Output    string
+		Expect    GhostStatus
+		ExpectErr bool
+	}{
+		{
+			Name: "拷贝中",
+			Output: ghostStatusHeader +
+				"# postpone-cut-over-flag-file: /tmp/gh-ost.test.users.postpone.flag\n" +
+				"# Serving on unix socket: /tmp/gh-ost.test.users.sock\n" +
+				"Copy: 5000/6000 83.3%; Applied: 12; Backlog: 3/1000; Time: 1m3s(total), 58s(copy); streamer: mysql-bin.000004:33233; Lag: 0.01s, HeartbeatLag: 0.02s, State: migrating; ETA: 11s\n",
+			Expect: GhostStatus{
+				RowsCopied: 5000, RowsEstimate: 6000, Percent: 83.3, Applied: 12, Backlog: 3, BacklogCapacity: 1000,
+				ElapsedTime: "1m3s", ETA: "11s", LagSeconds: 0.01, HeartbeatLag: 0.02, State: "migrating",
+				ChunkSize: 1000, MaxLagMillis: 1500, NiceRatio: 0.5,
+			},
+		},
+		{
+			Name: "限流中",
+			Output: ghostStatusHeader +
+				"Copy: 100/6000 1.7%; Applied: 0; Backlog: 0/1000; Time: 20s(total), 19s(copy); streamer: mysql-bin.000004:1000; Lag: 2.10s, HeartbeatLag: 2.30s, State: throttled, lag=2.100000s; ETA: N/A\n",
+			Expect: GhostStatus{
+				RowsCopied: 100, RowsEstimate: 6000, Percent: 1.7, BacklogCapacity: 1000,
+				ElapsedTime: "20s", ETA: "N/A", LagSeconds: 2.1, HeartbeatLag: 2.3, State: "throttled, lag=2.100000s",
+				ChunkSize: 1000, MaxLagMillis: 1500, NiceRatio: 0.5,
+			},
+		},
+		{
+			Name: "推迟cut-over",
+			Output: ghostStatusHeader +
+				"# postpone-cut-over-flag-file: /tmp/gh-ost.test.users.postpone.flag [set]\n" +
+				"Copy: 6000/6000 100.0%; Applied: 40; Backlog: 0/1000; Time: 2m10s(total), 1m50s(copy); streamer: mysql-bin.000004:50000; Lag: 0.01s, HeartbeatLag: 0.01s, State: postponing cut-over; ETA: due\n",
+			Expect: GhostStatus{
+				RowsCopied: 6000, RowsEstimate: 6000, Percent: 100, Applied: 40, BacklogCapacity: 1000,
+				ElapsedTime: "2m10s", ETA: "due", LagSeconds: 0.01, HeartbeatLag: 0.01, State: "postponing cut-over",
+				ChunkSize: 1000, MaxLagMillis: 1500, NiceRatio: 0.5, CutOverPostponed: true,
+			},
+		},
+		{
+			Name:   "标记文件未设置但处于推迟状态",
+			Output: "Copy: 6000/6000 100.0%; Applied: 0; Backlog: 0/1000; Time: 5s(total), 5s(copy); streamer: mysql-bin.000004:1; Lag: 0.00s, HeartbeatLag: 0.00s, State: postponing cut-over; ETA: due",
+			Expect: GhostStatus{
+				RowsCopied: 6000, RowsEstimate: 6000, Percent: 100, BacklogCapacity: 1000,
+				ElapsedTime: "5s", ETA: "due", State: "postponing cut-over", CutOverPostponed: true,
+			},
+		},
+		{Name: "只有注释", Output: ghostStatusHeader, ExpectErr: true},
+		{Name: "空输出", Output: "", ExpectErr: true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			status, err := ParseGhostStatus(tc.Output)
+			if tc.ExpectErr {
+				if err == nil {
+					t.Fatalf("期望返回错误，实际 %+v", status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("解析失败: %v", err)
+			}
+			if *status != tc.Expect {
+				t.Errorf("期望 %+v，实际 %+v", tc.Expect, *status)
+			}
+		})
+	}
+}
+
+func TestGhostCutOverTTL(t *testing.T) {
+	now := time.Date(2024, 6, 3, 10, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		Name      string
+		CutOver   time.Time
+		ExpectTTL time.Duration
+	}{
+		{Name: "一小时后", CutOver: now.Add(time.Hour), ExpectTTL: time.Hour + ghostCutOverTTLMargin},
+		{Name: "三天后", CutOver: now.Add(72 * time.Hour), ExpectTTL: 72*time.Hour + ghostCutOverTTLMargin},
+		{Name: "已过期", CutOver: now.Add(-time.Hour), ExpectTTL: ghostCutOverTTLMargin},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if ttl := ghostCutOverTTL(tc.CutOver, now); ttl != tc.ExpectTTL {
+				t.Errorf("期望 %s，实际 %s", tc.ExpectTTL, ttl)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	crand "crypto/rand"
+	"math/big"
 	"math/rand"
 	"regexp"
 	"strings"
@@ -187,3 +189,30 @@ func GenerateRandomString(length int) string {
 
 	return string(result)
 }
+
+// TruncateString 按字符截断字符串，超过 maxLen 时保留前 maxLen-3 个字符并以 ... 结尾（用于写入有长度限制的字段）
+func TruncateString(s string, maxLen int) string {
+	runes := []rune(s)
+	if len(runes) <= maxLen {
+		return s
+	}
+	if maxLen <= 3 {
+		return string(runes[:maxLen])
+	}
+	return string(runes[:maxLen-3]) + "..."
+}
+
+// GenerateSecureRandomString 使用 crypto/rand 生成随机字符串（用于密钥等安全场景）
+func GenerateSecureRandomString(length int) (string, error) {
+	const charset = "0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	max := big.NewInt(int64(len(charset)))
+	result := make([]byte, length)
+	for i := range result {
+		n, err := crand.Int(crand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		result[i] = charset[n.Int64()]
+	}
+	return string(result), nil
+}
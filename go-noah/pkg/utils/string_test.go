@@ -0,0 +1,25 @@
+package utils
+
+import "testing"
+
+func TestTruncateString(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Input  string
+		MaxLen int
+		Expect string
+	}{
+		{Name: "未超过长度", Input: "abc", MaxLen: 3, Expect: "abc"},
+		{Name: "超过长度", Input: "abcdef", MaxLen: 5, Expect: "ab..."},
+		{Name: "按字符截断中文", Input: "执行失败：连接超时", MaxLen: 6, Expect: "执行失..."},
+		{Name: "长度不超过省略号", Input: "abcdef", MaxLen: 2, Expect: "ab"},
+		{Name: "空字符串", Input: "", MaxLen: 10, Expect: ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.Name, func(t *testing.T) {
+			if got := TruncateString(tc.Input, tc.MaxLen); got != tc.Expect {
+				t.Errorf("期望 %q，实际 %q", tc.Expect, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"go-noah/pkg/global"
+)
+
+// SetTaskConnectionID 记录任务正在使用的数据库连接ID（用于取消任务时 KILL QUERY）
+func SetTaskConnectionID(taskID string, connectionID int64) error {
+	if global.Redis == nil {
+		return fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("task:connection:%s", taskID)
+	return global.Redis.Set(context.Background(), key, connectionID, 24*time.Hour).Err()
+}
+
+// GetTaskConnectionID 获取任务正在使用的数据库连接ID
+func GetTaskConnectionID(taskID string) (int64, error) {
+	if global.Redis == nil {
+		return 0, fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("task:connection:%s", taskID)
+	val, err := global.Redis.Get(context.Background(), key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("未找到任务的连接ID: %w", err)
+	}
+	return strconv.ParseInt(val, 10, 64)
+}
+
+// DeleteTaskConnectionID 删除任务的连接ID记录
+func DeleteTaskConnectionID(taskID string) {
+	if global.Redis == nil {
+		return
+	}
+	global.Redis.Del(context.Background(), fmt.Sprintf("task:connection:%s", taskID))
+}
+
+// SetTaskCancelled 标记任务已被取消（执行器据此区分取消与执行失败）
+func SetTaskCancelled(taskID string) error {
+	if global.Redis == nil {
+		return fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("task:cancel:%s", taskID)
+	return global.Redis.Set(context.Background(), key, 1, 24*time.Hour).Err()
+}
+
+// IsTaskCancelled 检查任务是否已被取消
+func IsTaskCancelled(taskID string) bool {
+	if global.Redis == nil || taskID == "" {
+		return false
+	}
+	n, err := global.Redis.Exists(context.Background(), fmt.Sprintf("task:cancel:%s", taskID)).Result()
+	return err == nil && n > 0
+}
+
+// ClearTaskCancelled 清除任务取消标记（任务重新执行前调用）
+func ClearTaskCancelled(taskID string) {
+	if global.Redis == nil {
+		return
+	}
+	global.Redis.Del(context.Background(), fmt.Sprintf("task:cancel:%s", taskID))
+}
+
+// TaskCheckpoint 任务执行检查点（执行连接和开始执行时的 binlog 位置）
+// 执行正常结束时删除，服务异常退出时保留，重启后据此核对中断任务的执行结果
+type TaskCheckpoint struct {
+	Node         string    `json:"node"`          // 执行节点
+	ConnectionID int64     `json:"connection_id"` // 执行连接ID
+	Chunked      bool      `json:"chunked"`       // 是否分批执行（每批单独提交）
+	BinlogFile   string    `json:"binlog_file"`   // 开始执行时的 binlog 文件，为空表示 binlog 不可用
+	BinlogPos    int64     `json:"binlog_pos"`    // 开始执行时的 binlog 位置
+	StartedAt    time.Time `json:"started_at"`
+}
+
+// SetTaskCheckpoint 记录任务执行检查点
+func SetTaskCheckpoint(taskID string, checkpoint *TaskCheckpoint) error {
+	if global.Redis == nil {
+		return fmt.Errorf("Redis 未配置")
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("task:checkpoint:%s", taskID)
+	return global.Redis.Set(context.Background(), key, data, 7*24*time.Hour).Err()
+}
+
+// GetTaskCheckpoint 获取任务执行检查点
+func GetTaskCheckpoint(taskID string) (*TaskCheckpoint, error) {
+	if global.Redis == nil {
+		return nil, fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("task:checkpoint:%s", taskID)
+	val, err := global.Redis.Get(context.Background(), key).Bytes()
+	if err != nil {
+		return nil, fmt.Errorf("未找到任务的执行检查点: %w", err)
+	}
+	var checkpoint TaskCheckpoint
+	if err := json.Unmarshal(val, &checkpoint); err != nil {
+		return nil, err
+	}
+	return &checkpoint, nil
+}
+
+// DeleteTaskCheckpoint 删除任务执行检查点
+func DeleteTaskCheckpoint(taskID string) {
+	if global.Redis == nil {
+		return
+	}
+	global.Redis.Del(context.Background(), fmt.Sprintf("task:checkpoint:%s", taskID))
+}
@@ -0,0 +1,39 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go-noah/pkg/global"
+)
+
+// SetTiDBDDLJobID 记录工单正在执行的 TiDB DDL Job ID（用于取消、暂停和恢复）
+func SetTiDBDDLJobID(orderID string, jobID int64) error {
+	if global.Redis == nil {
+		return fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("tidb:ddl:job:%s", orderID)
+	return global.Redis.Set(context.Background(), key, jobID, 24*time.Hour).Err()
+}
+
+// GetTiDBDDLJobID 获取工单正在执行的 TiDB DDL Job ID
+func GetTiDBDDLJobID(orderID string) (int64, error) {
+	if global.Redis == nil {
+		return 0, fmt.Errorf("Redis 未配置")
+	}
+	key := fmt.Sprintf("tidb:ddl:job:%s", orderID)
+	jobID, err := global.Redis.Get(context.Background(), key).Int64()
+	if err != nil {
+		return 0, fmt.Errorf("未找到 TiDB DDL Job，可能执行已完成或未开始: %w", err)
+	}
+	return jobID, nil
+}
+
+// DeleteTiDBDDLJobID 删除工单的 TiDB DDL Job ID 记录
+func DeleteTiDBDDLJobID(orderID string) {
+	if global.Redis == nil {
+		return
+	}
+	global.Redis.Del(context.Background(), fmt.Sprintf("tidb:ddl:job:%s", orderID))
+}